@@ -0,0 +1,41 @@
+package ai
+
+import (
+	"context"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// AIClient 是 AI 后端的接口，与 api.AIClient 相同
+// ctx 被取消时后端放弃正在进行的请求或搜索
+type AIClient interface {
+	GetMove(ctx context.Context, g *game.Game) (game.Point, error)
+	CalculateScore(ctx context.Context, g *game.Game) (game.ScoreResult, error)
+	Analyze(ctx context.Context, g *game.Game, candidates int) (*game.Analysis, error)
+}
+
+// Analyzer 分析对局的当前局面，KataGoClient 满足该接口
+type Analyzer interface {
+	Analyze(ctx context.Context, g *game.Game, candidates int) (*game.Analysis, error)
+}
+
+var (
+	_ AIClient = (*Client)(nil)
+	_ AIClient = (*GTPClient)(nil)
+	_ Analyzer = (*KataGoClient)(nil)
+)
+
+// WithAnalyzer 返回用 analyzer 分析局面、其余请求交给 client 的 AI 后端
+// 例如用 GTP 引擎落子，用 KataGo 分析引擎分析局面
+func WithAnalyzer(client AIClient, analyzer Analyzer) AIClient {
+	return &analyzedClient{AIClient: client, analyzer: analyzer}
+}
+
+type analyzedClient struct {
+	AIClient
+	analyzer Analyzer
+}
+
+func (c *analyzedClient) Analyze(ctx context.Context, g *game.Game, candidates int) (*game.Analysis, error) {
+	return c.analyzer.Analyze(ctx, g, candidates)
+}
@@ -0,0 +1,114 @@
+package ai
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen 表示 AI 服务连续失败或健康检查未通过，熔断器暂时拒绝请求
+var ErrCircuitOpen = errors.New("AI service circuit breaker is open")
+
+// 熔断器的默认设置
+const (
+	DefaultFailureThreshold = 5
+	DefaultBreakerCooldown  = 30 * time.Second
+)
+
+type breakerState int
+
+const (
+	breakerClosed   breakerState = iota // 正常放行请求
+	breakerOpen                         // 拒绝请求，直到冷却结束或健康检查通过
+	breakerHalfOpen                     // 冷却结束后只放行一个试探请求：成功则关闭，失败则重新打开
+)
+
+// breaker 是 AI 服务的熔断器，可以并发使用
+type breaker struct {
+	threshold int
+	cooldown  time.Duration
+
+	mu       sync.Mutex
+	state    breakerState
+	failures int // 连续失败次数
+	openedAt time.Time
+	probing  bool // 试探状态下已放行的请求还没有结果
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	if threshold <= 0 {
+		threshold = DefaultFailureThreshold
+	}
+	if cooldown <= 0 {
+		cooldown = DefaultBreakerCooldown
+	}
+	return &breaker{threshold: threshold, cooldown: cooldown}
+}
+
+// allow 判断是否放行请求，熔断器打开且未冷却、或试探请求还没有结果时返回 ErrCircuitOpen
+// probe 表示放行的是试探请求，这个请求没有结果 (如被取消) 时需要调用 release 让出试探机会
+func (b *breaker) allow() (probe bool, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == breakerOpen {
+		if time.Since(b.openedAt) < b.cooldown {
+			return false, ErrCircuitOpen
+		}
+		b.state = breakerHalfOpen
+	}
+	if b.state == breakerHalfOpen {
+		if b.probing {
+			return false, ErrCircuitOpen
+		}
+		b.probing = true
+		return true, nil
+	}
+	return false, nil
+}
+
+// release 让出没有结果的试探请求占用的试探机会，熔断器的状态不变
+func (b *breaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+// success 记录一次成功的请求，关闭熔断器，返回熔断器此前是否处于打开或试探状态
+func (b *breaker) success() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	recovered := b.state != breakerClosed
+	b.state = breakerClosed
+	b.failures = 0
+	b.probing = false
+	return recovered
+}
+
+// failure 记录一次失败的请求，连续失败达到阈值或试探失败时打开熔断器
+func (b *breaker) failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.failures++
+	b.probing = false
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.open()
+	}
+}
+
+// trip 立即打开熔断器，返回熔断器此前是否处于关闭状态
+func (b *breaker) trip() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tripped := b.state == breakerClosed
+	b.open()
+	return tripped
+}
+
+func (b *breaker) open() {
+	b.state = breakerOpen
+	b.openedAt = time.Now()
+}
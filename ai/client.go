@@ -2,36 +2,91 @@ package ai
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"time"
 
 	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/logger"
 )
 
-// Client 是 AI 服务的客户端
+// 调用 AI 服务的默认设置
+const (
+	DefaultClientTimeout  = 30 * time.Second
+	DefaultRetries        = 2
+	DefaultRetryDelay     = 200 * time.Millisecond
+	DefaultMaxRetryDelay  = 2 * time.Second
+	DefaultHealthInterval = 15 * time.Second
+)
+
+// ErrServiceUnavailable 表示 AI 服务不可用 (连接失败、超时或 5xx/429 响应)，且客户端的重试已经用尽
+var ErrServiceUnavailable = errors.New("AI service unavailable")
+
+// ClientConfig 是 AI 服务客户端的配置
+type ClientConfig struct {
+	BaseURL          string
+	Timeout          time.Duration // 单次请求的超时，0 表示 DefaultClientTimeout
+	Retries          int           // 请求失败后的最大重试次数，0 表示 DefaultRetries，负数表示不重试
+	RetryDelay       time.Duration // 第一次重试前的等待时间，之后每次加倍，0 表示 DefaultRetryDelay
+	MaxRetryDelay    time.Duration // 重试等待时间的上限，0 表示 DefaultMaxRetryDelay
+	FailureThreshold int           // 连续多少次请求失败后打开熔断器，0 表示 DefaultFailureThreshold
+	BreakerCooldown  time.Duration // 熔断器打开多久后允许试探请求，0 表示 DefaultBreakerCooldown
+}
+
+// Client 是 AI 服务的客户端，可以并发使用
+// 请求失败时按带抖动的指数退避重试，连续失败后由熔断器暂停请求，
+// 服务不可用时可以用 WithFallback 交给本地引擎
 type Client struct {
-	baseURL    string
+	cfg        ClientConfig
 	httpClient *http.Client
+	breaker    *breaker
 }
 
-// NewClient 创建一个新的 AI 客户端
+// NewClient 使用默认设置创建一个新的 AI 客户端
 func NewClient(baseURL string) *Client {
+	return NewClientWithConfig(ClientConfig{BaseURL: baseURL})
+}
+
+// NewClientWithConfig 按配置创建 AI 客户端
+func NewClientWithConfig(cfg ClientConfig) *Client {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultClientTimeout
+	}
+	switch {
+	case cfg.Retries == 0:
+		cfg.Retries = DefaultRetries
+	case cfg.Retries < 0:
+		cfg.Retries = 0
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = DefaultRetryDelay
+	}
+	if cfg.MaxRetryDelay <= 0 {
+		cfg.MaxRetryDelay = DefaultMaxRetryDelay
+	}
 	return &Client{
-		baseURL: baseURL,
+		cfg: cfg,
 		httpClient: &http.Client{
-			Timeout: 30 * time.Second,
+			Timeout: cfg.Timeout,
 		},
+		breaker: newBreaker(cfg.FailureThreshold, cfg.BreakerCooldown),
 	}
 }
 
 // MoveRequest 是请求 AI 落子的请求体
+// 落子请求附带对局的 AI 难度及其引擎参数，局面分析不附带
 type MoveRequest struct {
-	Board      [][]int8 `json:"board"`
-	NextPlayer int8     `json:"next_player"`
-	History    []string `json:"history"`
+	BoardSize  int             `json:"board_size"`
+	Board      [][]int8        `json:"board"`
+	NextPlayer int8            `json:"next_player"`
+	History    []string        `json:"history"`
+	Difficulty game.Difficulty `json:"difficulty,omitempty"`
+	Profile    *game.AIProfile `json:"profile,omitempty"`
 }
 
 // MoveResponse 是 AI 返回的落子响应
@@ -43,7 +98,8 @@ type MoveResponse struct {
 
 // ScoreRequest 是请求计分的请求体
 type ScoreRequest struct {
-	Board [][]int8 `json:"board"`
+	BoardSize int      `json:"board_size"`
+	Board     [][]int8 `json:"board"`
 }
 
 // ScoreResponse 是计分响应
@@ -53,127 +109,285 @@ type ScoreResponse struct {
 	Winner     game.Player `json:"winner"`
 }
 
-// GetMove 从 AI 服务获取下一步落子
-func (c *Client) GetMove(g *game.Game) (game.Point, error) {
-	// 构建历史记录列表
-	history := make([]string, 0, len(g.History))
-	for hash := range g.History {
-		history = append(history, hash)
-	}
+// AnalyzeRequest 是请求局面分析的请求体
+type AnalyzeRequest struct {
+	MoveRequest
+	Komi       float64 `json:"komi"`
+	Candidates int     `json:"candidates"`
+}
 
-	// 转换棋盘为数字数组
-	board := make([][]int8, game.BoardSize)
-	for i := 0; i < game.BoardSize; i++ {
-		board[i] = make([]int8, game.BoardSize)
-		for j := 0; j < game.BoardSize; j++ {
-			board[i][j] = int8(g.Board.Grid[i][j])
-		}
+// AnalyzeResponse 是局面分析响应，胜率和目差以黑方为准
+type AnalyzeResponse struct {
+	Winrate    float64             `json:"winrate"`
+	ScoreLead  float64             `json:"score_lead"`
+	Candidates []CandidateResponse `json:"candidates"`
+	Ownership  [][]float64         `json:"ownership"`
+}
+
+// CandidateResponse 是局面分析中的一个候选着法
+type CandidateResponse struct {
+	X         int     `json:"x"`
+	Y         int     `json:"y"`
+	Pass      bool    `json:"pass"`
+	Visits    int     `json:"visits"`
+	Prior     float64 `json:"prior"`
+	Winrate   float64 `json:"winrate"`
+	ScoreLead float64 `json:"score_lead"`
+	PV        []struct {
+		X int `json:"x"`
+		Y int `json:"y"`
+	} `json:"pv"`
+}
+
+// GetMove 从 AI 服务获取下一步落子，ctx 被取消时放弃请求和重试
+func (c *Client) GetMove(ctx context.Context, g *game.Game) (game.Point, error) {
+	// 构建历史记录列表，AI 服务使用棋面字符串判断劫争
+	history, err := g.StateHistory()
+	if err != nil {
+		return game.Point{}, fmt.Errorf("failed to rebuild history: %w", err)
 	}
 
 	// 构建请求
+	profile := g.AIProfile()
 	reqBody := MoveRequest{
-		Board:      board,
+		BoardSize:  g.Board.Size(),
+		Board:      g.Board.ToList(),
 		NextPlayer: int8(g.NextPlayer),
 		History:    history,
+		Difficulty: g.AIDifficulty,
+		Profile:    &profile,
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return game.Point{}, fmt.Errorf("failed to marshal request: %w", err)
+	var moveResp MoveResponse
+	if err := c.post(ctx, "/v1/ai/move", reqBody, &moveResp); err != nil {
+		return game.Point{}, err
 	}
+	return game.Point{X: moveResp.X, Y: moveResp.Y}, nil
+}
 
-	// 发送请求
-	url := fmt.Sprintf("%s/v1/ai/move", c.baseURL)
-	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+// CalculateScore 从 AI 服务获取终局计分，ctx 被取消时放弃请求和重试
+func (c *Client) CalculateScore(ctx context.Context, g *game.Game) (game.ScoreResult, error) {
+	// 构建请求
+	reqBody := ScoreRequest{
+		BoardSize: g.Board.Size(),
+		Board:     g.Board.ToList(),
+	}
+
+	var scoreResp ScoreResponse
+	if err := c.post(ctx, "/v1/game/score", reqBody, &scoreResp); err != nil {
+		return game.ScoreResult{}, err
+	}
+	return game.ScoreResult{
+		BlackScore: scoreResp.BlackScore,
+		WhiteScore: scoreResp.WhiteScore,
+		Winner:     scoreResp.Winner,
+	}, nil
+}
+
+// Analyze 从 AI 服务获取当前局面的胜率、目差、候选着法和归属，ctx 被取消时放弃请求和重试
+func (c *Client) Analyze(ctx context.Context, g *game.Game, candidates int) (*game.Analysis, error) {
+	history, err := g.StateHistory()
 	if err != nil {
-		return game.Point{}, fmt.Errorf("failed to call AI service: %w", err)
+		return nil, fmt.Errorf("failed to rebuild history: %w", err)
 	}
-	defer resp.Body.Close()
 
-	// 读取响应
-	body, err := io.ReadAll(resp.Body)
+	// 构建请求
+	reqBody := AnalyzeRequest{
+		MoveRequest: MoveRequest{
+			BoardSize:  g.Board.Size(),
+			Board:      g.Board.ToList(),
+			NextPlayer: int8(g.NextPlayer),
+			History:    history,
+		},
+		Komi:       g.WhiteBonus(), // 服务按子数计算目差，让子棋补偿一并计入
+		Candidates: candidates,
+	}
+
+	var analyzeResp AnalyzeResponse
+	if err := c.post(ctx, "/v1/ai/analyze", reqBody, &analyzeResp); err != nil {
+		return nil, err
+	}
+
+	analysis := &game.Analysis{
+		Winrate:    analyzeResp.Winrate,
+		ScoreLead:  analyzeResp.ScoreLead,
+		Candidates: make([]game.Candidate, 0, len(analyzeResp.Candidates)),
+		Ownership:  analyzeResp.Ownership,
+	}
+	for _, cand := range analyzeResp.Candidates {
+		pv := make([]game.Point, len(cand.PV))
+		for i, p := range cand.PV {
+			pv[i] = game.Point{X: p.X, Y: p.Y}
+		}
+		analysis.Candidates = append(analysis.Candidates, game.Candidate{
+			Point:     game.Point{X: cand.X, Y: cand.Y},
+			Pass:      cand.Pass,
+			Visits:    cand.Visits,
+			Prior:     cand.Prior,
+			Winrate:   cand.Winrate,
+			ScoreLead: cand.ScoreLead,
+			PV:        pv,
+		})
+	}
+	return analysis, nil
+}
+
+// HealthCheck 检查 AI 服务是否健康
+func (c *Client) HealthCheck() error {
+	return c.HealthCheckContext(context.Background())
+}
+
+// HealthCheckContext 与 HealthCheck 相同，只请求一次，不重试也不经过熔断器
+func (c *Client) HealthCheckContext(ctx context.Context) error {
+	url := fmt.Sprintf("%s/health", c.cfg.BaseURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("failed to create request: %w", err)
+	}
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return game.Point{}, fmt.Errorf("failed to read response: %w", err)
+		return fmt.Errorf("failed to connect to AI service: %w", err)
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		return game.Point{}, fmt.Errorf("AI service returned error: %s (status %d)", string(body), resp.StatusCode)
+		return fmt.Errorf("AI service unhealthy: status %d", resp.StatusCode)
 	}
 
-	// 解析响应
-	var moveResp MoveResponse
-	if err := json.Unmarshal(body, &moveResp); err != nil {
-		return game.Point{}, fmt.Errorf("failed to unmarshal response: %w", err)
-	}
+	return nil
+}
 
-	return game.Point{X: moveResp.X, Y: moveResp.Y}, nil
+// Probe 检查 AI 服务的健康状态并更新熔断器
+// 检查失败时立即打开熔断器，之后的请求直接返回 ErrCircuitOpen 而不必等待超时；检查通过时关闭熔断器
+func (c *Client) Probe(ctx context.Context) error {
+	err := c.HealthCheckContext(ctx)
+	if ctx.Err() != nil {
+		// 取消的检查不说明服务的状态
+		return err
+	}
+	if err != nil {
+		if c.breaker.trip() {
+			logger.Warn("AI service is unavailable", "url", c.cfg.BaseURL, "error", err)
+		}
+		return err
+	}
+	if c.breaker.success() {
+		logger.Info("AI service recovered", "url", c.cfg.BaseURL)
+	}
+	return nil
 }
 
-// CalculateScore 从 AI 服务获取终局计分
-func (c *Client) CalculateScore(g *game.Game) (game.ScoreResult, error) {
-	// 转换棋盘为数字数组
-	board := make([][]int8, game.BoardSize)
-	for i := 0; i < game.BoardSize; i++ {
-		board[i] = make([]int8, game.BoardSize)
-		for j := 0; j < game.BoardSize; j++ {
-			board[i][j] = int8(g.Board.Grid[i][j])
+// WatchHealth 按间隔定期调用 Probe，直到 ctx 被取消
+// 启动时的健康检查失败后，AI 服务恢复时无需重启即可重新使用
+func (c *Client) WatchHealth(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultHealthInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
 		}
+		_ = c.Probe(ctx)
 	}
+}
 
-	// 构建请求
-	reqBody := ScoreRequest{
-		Board: board,
+// post 向 AI 服务发送 JSON 请求并解析响应
+// 连接失败、超时和 5xx/429 响应按带抖动的指数退避重试，AI 服务的请求都是只读的计算，重试是安全的；
+// 熔断器打开、或熔断后的试探请求还没有结果时直接返回 ErrCircuitOpen
+func (c *Client) post(ctx context.Context, path string, reqBody, respBody any) error {
+	probe, err := c.breaker.allow()
+	if err != nil {
+		return err
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		return game.ScoreResult{}, fmt.Errorf("failed to marshal request: %w", err)
+		return fmt.Errorf("failed to marshal request: %w", err)
+	}
+	url := c.cfg.BaseURL + path
+
+	var unavailable bool
+	for attempt := 0; ; attempt++ {
+		unavailable, err = c.send(ctx, url, jsonData, respBody)
+		if !unavailable || attempt >= c.cfg.Retries || !sleepContext(ctx, c.backoff(attempt)) {
+			break
+		}
+	}
+
+	switch {
+	case err == nil:
+		c.breaker.success()
+	case ctx.Err() != nil:
+		// 调用方取消的请求不说明服务的状态
+		if probe {
+			c.breaker.release()
+		}
+		return fmt.Errorf("failed to call AI service: %w", ctx.Err())
+	case unavailable:
+		c.breaker.failure()
+		err = fmt.Errorf("%w: %w", ErrServiceUnavailable, err)
+	default:
+		// 服务能够应答 (如 4xx)，只是请求本身有问题
+		c.breaker.success()
+	}
+	return err
+}
+
+// send 发送一次请求，unavailable 表示失败是否由服务不可用引起、可以重试
+func (c *Client) send(ctx context.Context, url string, body []byte, respBody any) (unavailable bool, err error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return false, fmt.Errorf("failed to create request: %w", err)
 	}
+	req.Header.Set("Content-Type", "application/json")
 
-	// 发送请求
-	url := fmt.Sprintf("%s/v1/game/score", c.baseURL)
-	resp, err := c.httpClient.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		return game.ScoreResult{}, fmt.Errorf("failed to call AI service: %w", err)
+		return true, fmt.Errorf("failed to call AI service: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// 读取响应
-	body, err := io.ReadAll(resp.Body)
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return game.ScoreResult{}, fmt.Errorf("failed to read response: %w", err)
+		return true, fmt.Errorf("failed to read response: %w", err)
 	}
 
 	if resp.StatusCode != http.StatusOK {
-		return game.ScoreResult{}, fmt.Errorf("AI service returned error: %s (status %d)", string(body), resp.StatusCode)
+		unavailable = resp.StatusCode >= http.StatusInternalServerError || resp.StatusCode == http.StatusTooManyRequests
+		return unavailable, fmt.Errorf("AI service returned error: %s (status %d)", string(data), resp.StatusCode)
 	}
 
 	// 解析响应
-	var scoreResp ScoreResponse
-	if err := json.Unmarshal(body, &scoreResp); err != nil {
-		return game.ScoreResult{}, fmt.Errorf("failed to unmarshal response: %w", err)
+	if err := json.Unmarshal(data, respBody); err != nil {
+		return false, fmt.Errorf("failed to unmarshal response: %w", err)
 	}
-
-	return game.ScoreResult{
-		BlackScore: scoreResp.BlackScore,
-		WhiteScore: scoreResp.WhiteScore,
-		Winner:     scoreResp.Winner,
-	}, nil
+	return false, nil
 }
 
-// HealthCheck 检查 AI 服务是否健康
-func (c *Client) HealthCheck() error {
-	url := fmt.Sprintf("%s/health", c.baseURL)
-	resp, err := c.httpClient.Get(url)
-	if err != nil {
-		return fmt.Errorf("failed to connect to AI service: %w", err)
+// backoff 返回第 attempt 次重试 (从 0 开始) 前的等待时间：RetryDelay * 2^attempt，不超过 MaxRetryDelay，
+// 并在其一半到全部之间随机抖动，避免多个请求同时重试
+func (c *Client) backoff(attempt int) time.Duration {
+	d := c.cfg.RetryDelay << attempt
+	if d <= 0 || d > c.cfg.MaxRetryDelay {
+		d = c.cfg.MaxRetryDelay
 	}
-	defer resp.Body.Close()
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("AI service unhealthy: status %d", resp.StatusCode)
+// sleepContext 等待 d，ctx 先被取消时返回 false
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
 	}
-
-	return nil
 }
-
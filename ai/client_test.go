@@ -0,0 +1,315 @@
+package ai
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// TestClient_GetMove_Difficulty 测试落子请求附带对局的 AI 难度和引擎参数
+func TestClient_GetMove_Difficulty(t *testing.T) {
+	var got MoveRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"x": 2, "y": 3, "confidence": 0.5}`))
+	}))
+	defer srv.Close()
+
+	g, err := game.NewGameWithPlayer("user", true, game.GameOptions{BoardSize: 9, AIDifficulty: game.DifficultyEasy})
+	if err != nil {
+		t.Fatal(err)
+	}
+	p, err := NewClient(srv.URL).GetMove(context.Background(), g)
+	if err != nil || p != (game.Point{X: 2, Y: 3}) {
+		t.Fatalf("Expected (2,3), got %v, %v", p, err)
+	}
+	if got.Difficulty != game.DifficultyEasy || got.Profile == nil || *got.Profile != game.DifficultyEasy.Profile() {
+		t.Fatalf("Expected the easy profile in the request, got %+v", got)
+	}
+}
+
+// TestClient_Analyze 测试局面分析的请求和响应转换
+func TestClient_Analyze(t *testing.T) {
+	var got AnalyzeRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/ai/analyze" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"winrate": 0.62, "score_lead": 3.5,
+			"candidates": [{"x": 2, "y": 6, "visits": 40, "prior": 0.3, "winrate": 0.64, "score_lead": 4, "pv": [{"x": 2, "y": 6}, {"x": 6, "y": 2}]}],
+			"ownership": [[1, 0.5], [-1, 0]]}`))
+	}))
+	defer srv.Close()
+
+	// 中国规则的让子棋中白方另得让子补偿，应计入贴目
+	g, err := game.NewGameWithOptions(game.GameOptions{BoardSize: 9, Handicap: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	analysis, err := NewClient(srv.URL).Analyze(context.Background(), g, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if got.Candidates != 3 || got.Komi != g.WhiteBonus() || got.Komi == g.Rules.Komi || got.BoardSize != 9 {
+		t.Fatalf("Unexpected request %+v", got)
+	}
+	if analysis.Winrate != 0.62 || analysis.ScoreLead != 3.5 || len(analysis.Ownership) != 2 {
+		t.Fatalf("Unexpected analysis %+v", analysis)
+	}
+	cand := analysis.Candidates[0]
+	if cand.Point != (game.Point{X: 2, Y: 6}) || cand.Visits != 40 || len(cand.PV) != 2 || cand.PV[1] != (game.Point{X: 6, Y: 2}) {
+		t.Fatalf("Unexpected candidate %+v", cand)
+	}
+}
+
+// stubAnalyzer 返回固定的局面分析
+type stubAnalyzer struct{ analysis *game.Analysis }
+
+func (s stubAnalyzer) Analyze(context.Context, *game.Game, int) (*game.Analysis, error) {
+	return s.analysis, nil
+}
+
+// TestWithAnalyzer 测试局面分析交给单独的分析引擎
+func TestWithAnalyzer(t *testing.T) {
+	want := &game.Analysis{Winrate: 0.4}
+	client := WithAnalyzer(NewGTPClient(GTPConfig{Command: "unused"}), stubAnalyzer{want})
+	if got, err := client.Analyze(context.Background(), game.NewGame(), 1); err != nil || got != want {
+		t.Fatalf("Expected the analyzer's result, got %+v, %v", got, err)
+	}
+}
+
+// flakyServer 返回一个前 failures 次请求以 status 失败、之后正常落子的 AI 服务，以及请求计数
+func flakyServer(t *testing.T, failures int32, status int) (*httptest.Server, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= failures {
+			http.Error(w, "unavailable", status)
+			return
+		}
+		w.Write([]byte(`{"x": 2, "y": 3}`))
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+// isClosed 返回熔断器是否处于关闭状态
+func isClosed(b *breaker) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state == breakerClosed
+}
+
+// TestClient_Retry 测试服务暂时不可用时重试，请求本身有误时不重试
+func TestClient_Retry(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusServiceUnavailable)
+	client := NewClientWithConfig(ClientConfig{BaseURL: srv.URL, Retries: 2, RetryDelay: time.Millisecond})
+	p, err := client.GetMove(context.Background(), game.NewGame())
+	if err != nil || p != (game.Point{X: 2, Y: 3}) || calls.Load() != 3 {
+		t.Fatalf("Expected success on the third attempt, got %v, %v after %d calls", p, err, calls.Load())
+	}
+
+	srv, calls = flakyServer(t, 1, http.StatusBadRequest)
+	client = NewClientWithConfig(ClientConfig{BaseURL: srv.URL, Retries: 2, RetryDelay: time.Millisecond})
+	if _, err := client.GetMove(context.Background(), game.NewGame()); err == nil || calls.Load() != 1 {
+		t.Fatalf("Expected a single failed attempt, got %v after %d calls", err, calls.Load())
+	}
+}
+
+// TestClient_Backoff 测试重试等待时间按指数增长、有上限并带有抖动
+func TestClient_Backoff(t *testing.T) {
+	client := NewClientWithConfig(ClientConfig{RetryDelay: 100 * time.Millisecond, MaxRetryDelay: time.Second})
+	for attempt, max := range []time.Duration{100, 200, 400, 800, 1000, 1000} {
+		max *= time.Millisecond
+		for i := 0; i < 20; i++ {
+			if d := client.backoff(attempt); d < max/2 || d > max {
+				t.Fatalf("Attempt %d: expected delay in [%v, %v], got %v", attempt, max/2, max, d)
+			}
+		}
+	}
+}
+
+// TestClient_CircuitBreaker 测试连续失败后熔断，冷却后试探成功即恢复
+func TestClient_CircuitBreaker(t *testing.T) {
+	srv, calls := flakyServer(t, 2, http.StatusInternalServerError)
+	client := NewClientWithConfig(ClientConfig{
+		BaseURL:          srv.URL,
+		Retries:          -1,
+		FailureThreshold: 2,
+		BreakerCooldown:  50 * time.Millisecond,
+	})
+	g := game.NewGame()
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetMove(context.Background(), g); !errors.Is(err, ErrServiceUnavailable) || errors.Is(err, ErrCircuitOpen) {
+			t.Fatalf("Call %d: expected ErrServiceUnavailable, got %v", i, err)
+		}
+	}
+	if _, err := client.GetMove(context.Background(), g); !errors.Is(err, ErrCircuitOpen) || calls.Load() != 2 {
+		t.Fatalf("Expected ErrCircuitOpen without calling the service, got %v after %d calls", err, calls.Load())
+	}
+
+	time.Sleep(60 * time.Millisecond)
+	if _, err := client.GetMove(context.Background(), g); err != nil {
+		t.Fatalf("Expected the trial request to succeed, got %v", err)
+	}
+	if !isClosed(client.breaker) {
+		t.Fatal("Expected the breaker to close after a successful trial")
+	}
+}
+
+// TestBreaker_HalfOpen 测试冷却结束后只放行一个试探请求，试探没有结果时让出试探机会
+func TestBreaker_HalfOpen(t *testing.T) {
+	b := newBreaker(1, time.Millisecond)
+	b.failure()
+	time.Sleep(5 * time.Millisecond)
+
+	if probe, err := b.allow(); err != nil || !probe {
+		t.Fatalf("Expected a trial request, got %v, %v", probe, err)
+	}
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen while the trial is in flight, got %v", err)
+	}
+
+	// 试探被取消，下一个请求接替试探
+	b.release()
+	if probe, err := b.allow(); err != nil || !probe {
+		t.Fatalf("Expected another trial request, got %v, %v", probe, err)
+	}
+
+	// 试探失败后重新打开
+	b.failure()
+	if _, err := b.allow(); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen after a failed trial, got %v", err)
+	}
+
+	// 试探成功后恢复放行所有请求
+	time.Sleep(5 * time.Millisecond)
+	if _, err := b.allow(); err != nil {
+		t.Fatalf("Expected a trial request, got %v", err)
+	}
+	b.success()
+	for i := 0; i < 2; i++ {
+		if probe, err := b.allow(); err != nil || probe {
+			t.Fatalf("Expected a regular request, got %v, %v", probe, err)
+		}
+	}
+}
+
+// TestClient_Context 测试取消的请求不再重试，也不计入熔断器
+func TestClient_Context(t *testing.T) {
+	srv, calls := flakyServer(t, 100, http.StatusServiceUnavailable)
+	client := NewClientWithConfig(ClientConfig{BaseURL: srv.URL, Retries: 5, RetryDelay: time.Hour, FailureThreshold: 1})
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.GetMove(ctx, game.NewGame()); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	if calls.Load() != 1 || !isClosed(client.breaker) {
+		t.Fatalf("Expected 1 call and a closed breaker, got %d calls", calls.Load())
+	}
+}
+
+// TestClient_Probe 测试健康检查失败时熔断，服务恢复后重新放行
+func TestClient_Probe(t *testing.T) {
+	var healthy atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			http.Error(w, "down", http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"x": 2, "y": 3}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(srv.URL)
+	if err := client.Probe(context.Background()); err == nil {
+		t.Fatal("Expected the health check to fail")
+	}
+	if _, err := client.GetMove(context.Background(), game.NewGame()); !errors.Is(err, ErrCircuitOpen) {
+		t.Fatalf("Expected ErrCircuitOpen, got %v", err)
+	}
+
+	healthy.Store(true)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		client.WatchHealth(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+	deadline := time.Now().Add(2 * time.Second)
+	for !isClosed(client.breaker) && time.Now().Before(deadline) {
+		time.Sleep(5 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if _, err := client.GetMove(context.Background(), game.NewGame()); err != nil {
+		t.Fatalf("Expected the client to recover, got %v", err)
+	}
+}
+
+// stubClient 返回固定结果的 AI 后端
+type stubClient struct {
+	move     game.Point
+	err      error
+	analysis *game.Analysis
+}
+
+func (s stubClient) GetMove(context.Context, *game.Game) (game.Point, error) { return s.move, s.err }
+
+func (s stubClient) CalculateScore(context.Context, *game.Game) (game.ScoreResult, error) {
+	return game.ScoreResult{}, s.err
+}
+
+func (s stubClient) Analyze(context.Context, *game.Game, int) (*game.Analysis, error) {
+	return s.analysis, s.err
+}
+
+// TestWithFallback 测试主后端出错时降级到本地引擎和内置计分
+func TestWithFallback(t *testing.T) {
+	local := stubClient{move: game.Point{X: 4, Y: 4}, analysis: &game.Analysis{Winrate: 0.3}}
+	client := WithFallback(stubClient{err: ErrCircuitOpen}, local)
+
+	g := game.NewGame()
+	if p, err := client.GetMove(context.Background(), g); err != nil || p != local.move {
+		t.Fatalf("Expected the local engine's move, got %v, %v", p, err)
+	}
+	if a, err := client.Analyze(context.Background(), g, 1); err != nil || a != local.analysis {
+		t.Fatalf("Expected the local engine's analysis, got %+v, %v", a, err)
+	}
+
+	// 计分使用对局内置的计分，对局未结束时返回其错误
+	if _, err := client.CalculateScore(context.Background(), g); err == nil {
+		t.Fatal("Expected an error for a game in progress")
+	}
+	_ = g.PassTurn()
+	_ = g.PassTurn()
+	want, _ := g.CalculateScore()
+	if score, err := client.CalculateScore(context.Background(), g); err != nil || score != want {
+		t.Fatalf("Expected the built-in score %+v, got %+v, %v", want, score, err)
+	}
+
+	// 调用方已取消时不降级
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	client = WithFallback(stubClient{err: context.Canceled}, local)
+	if _, err := client.GetMove(ctx, g); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+
+	// 主后端虚手不降级
+	client = WithFallback(stubClient{err: ErrEnginePassed}, local)
+	if _, err := client.GetMove(context.Background(), g); !errors.Is(err, ErrEnginePassed) {
+		t.Fatalf("Expected ErrEnginePassed, got %v", err)
+	}
+}
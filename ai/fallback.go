@@ -0,0 +1,58 @@
+package ai
+
+import (
+	"context"
+	"errors"
+
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/logger"
+)
+
+// WithFallback 返回优先使用 primary、primary 出错 (服务不可用、熔断器打开等) 时降级的 AI 后端：
+// 落子和局面分析交给本地引擎 local，计分使用 game.Game 内置的 CalculateScore
+// primary 虚手或认输不是错误，不会降级；ctx 已被取消时调用方不再需要结果，也不降级；
+// local 为 nil 时落子和局面分析返回 primary 的错误
+func WithFallback(primary, local AIClient) AIClient {
+	return &fallbackClient{primary: primary, local: local}
+}
+
+type fallbackClient struct {
+	primary AIClient
+	local   AIClient
+}
+
+func (c *fallbackClient) GetMove(ctx context.Context, g *game.Game) (game.Point, error) {
+	p, err := c.primary.GetMove(ctx, g)
+	if err == nil || errors.Is(err, ErrEnginePassed) || errors.Is(err, ErrEngineResigned) || c.local == nil || ctx.Err() != nil {
+		return p, err
+	}
+	logFallback("move", err)
+	return c.local.GetMove(ctx, g)
+}
+
+func (c *fallbackClient) CalculateScore(ctx context.Context, g *game.Game) (game.ScoreResult, error) {
+	score, err := c.primary.CalculateScore(ctx, g)
+	if err == nil || ctx.Err() != nil {
+		return score, err
+	}
+	logFallback("score", err)
+	return g.CalculateScore()
+}
+
+func (c *fallbackClient) Analyze(ctx context.Context, g *game.Game, candidates int) (*game.Analysis, error) {
+	analysis, err := c.primary.Analyze(ctx, g, candidates)
+	if err == nil || c.local == nil || ctx.Err() != nil {
+		return analysis, err
+	}
+	logFallback("analysis", err)
+	return c.local.Analyze(ctx, g, candidates)
+}
+
+// logFallback 记录降级，熔断器打开期间每个请求都会降级，只在调试级别记录
+func logFallback(request string, err error) {
+	if errors.Is(err, ErrCircuitOpen) {
+		logger.Debug("AI service unavailable, using fallback", "request", request)
+		return
+	}
+	logger.Warn("AI service failed, using fallback", "request", request, "error", err)
+}
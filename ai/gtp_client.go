@@ -0,0 +1,401 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/gtp"
+)
+
+var (
+	// 与 gtp 包共用，作为 GTP 引擎的后端时 genmove 据此回应 pass 和 resign
+	ErrEnginePassed   = gtp.ErrPass
+	ErrEngineResigned = gtp.ErrResign
+
+	ErrEngineTimeout = errors.New("engine timed out")
+	ErrEngineDied    = errors.New("engine process exited")
+	ErrClientClosed  = errors.New("client is closed")
+
+	ErrAnalysisUnsupported = errors.New("engine does not support analysis")
+
+	// errCommandCanceled 表示等待响应时 ctx 被取消，同时包装 ctx 的错误
+	errCommandCanceled = errors.New("canceled while running")
+)
+
+// 默认的进程池大小和命令超时
+const (
+	DefaultGTPPoolSize = 1
+	DefaultGTPTimeout  = 30 * time.Second
+)
+
+// GTPConfig 是本地 GTP 引擎 (GNU Go、Pachi、KataGo 的 GTP 模式等) 的配置
+type GTPConfig struct {
+	Command  string        // 引擎可执行文件
+	Args     []string      // 命令行参数
+	PoolSize int           // 同时运行的引擎进程数，0 表示 DefaultGTPPoolSize
+	Timeout  time.Duration // 单条命令的超时，0 表示 DefaultGTPTimeout
+}
+
+// GTPClient 通过子进程调用本地 GTP 引擎，实现 api.AIClient
+// 每次请求从进程池取出一个引擎进程，同步局面后执行 genmove 或 final_score；
+// 进程退出或超时后被终止，下次使用时重新启动
+type GTPClient struct {
+	cfg  GTPConfig
+	pool chan *gtpProcess // 空闲的进程槽位，nil 表示尚未启动或已终止
+
+	mu     sync.Mutex
+	closed bool
+}
+
+// NewGTPClient 创建 GTP 引擎客户端，引擎进程在第一次使用时启动
+func NewGTPClient(cfg GTPConfig) *GTPClient {
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = DefaultGTPPoolSize
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultGTPTimeout
+	}
+	c := &GTPClient{cfg: cfg, pool: make(chan *gtpProcess, cfg.PoolSize)}
+	for i := 0; i < cfg.PoolSize; i++ {
+		c.pool <- nil
+	}
+	return c
+}
+
+// GetMove 让引擎为轮到的一方落子
+// 引擎虚手或认输时分别返回 ErrEnginePassed 和 ErrEngineResigned
+// GTP 没有调节棋力的标准命令，AI 难度只通过 AIProfile.Randomness 以一定概率随机落子体现
+func (c *GTPClient) GetMove(ctx context.Context, g *game.Game) (game.Point, error) {
+	if r := g.AIProfile().Randomness; r > 0 && rand.Float64() < r {
+		if moves := g.LegalMoves(); len(moves) > 0 {
+			return moves[rand.Intn(len(moves))], nil
+		}
+	}
+
+	var p game.Point
+	err := c.do(ctx, g, func(proc *gtpProcess) error {
+		color := gtpColor(g.NextPlayer)
+		response, err := proc.command(ctx, "genmove "+color, c.cfg.Timeout)
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(response) {
+		case "pass":
+			return ErrEnginePassed
+		case "resign":
+			return ErrEngineResigned
+		}
+		point, pass, err := gtp.ParseVertex(response, g.Board.Size())
+		if err != nil {
+			return fmt.Errorf("bad genmove response: %w", err)
+		}
+		if pass {
+			return ErrEnginePassed
+		}
+		// 引擎已经在自己的棋盘上落下这一手
+		proc.played = append(proc.played, "play "+color+" "+gtp.FormatVertex(point, g.Board.Size()))
+		p = point
+		return nil
+	})
+	return p, err
+}
+
+// CalculateScore 使用引擎的 final_score 计算当前局面的胜负
+// GTP 只返回胜负目数，结果中胜方得分为目数，负方为 0
+func (c *GTPClient) CalculateScore(ctx context.Context, g *game.Game) (game.ScoreResult, error) {
+	var score game.ScoreResult
+	err := c.do(ctx, g, func(proc *gtpProcess) error {
+		response, err := proc.command(ctx, "final_score", c.cfg.Timeout)
+		if err != nil {
+			return err
+		}
+		result, err := game.ParseResult(response)
+		if err != nil || result.Reason != game.ReasonScore {
+			return fmt.Errorf("bad final_score response %q", response)
+		}
+		score.Winner = result.Winner
+		switch result.Winner {
+		case game.Black:
+			score.BlackScore = result.Margin
+		case game.White:
+			score.WhiteScore = result.Margin
+		}
+		return nil
+	})
+	return score, err
+}
+
+// Analyze 返回 ErrAnalysisUnsupported，GTP 没有标准的局面分析命令
+// 需要分析时用 WithAnalyzer 搭配分析引擎
+func (c *GTPClient) Analyze(context.Context, *game.Game, int) (*game.Analysis, error) {
+	return nil, ErrAnalysisUnsupported
+}
+
+// HealthCheck 启动 (或复用) 一个引擎进程并检查其能否响应命令
+func (c *GTPClient) HealthCheck() error {
+	ctx := context.Background()
+	proc, err := c.acquire(ctx)
+	if err != nil {
+		return err
+	}
+	_, err = proc.command(ctx, "protocol_version", c.cfg.Timeout)
+	c.release(proc, err)
+	return err
+}
+
+// Close 终止所有空闲的引擎进程，正在使用的进程在归还时终止
+func (c *GTPClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	for i := 0; i < cap(c.pool); i++ {
+		select {
+		case proc := <-c.pool:
+			proc.kill()
+			c.pool <- nil
+		default:
+		}
+	}
+	return nil
+}
+
+// do 取出一个引擎进程，同步到对局的局面后执行 fn
+// 引擎进程在同步或执行时退出的，重新启动后再试一次
+func (c *GTPClient) do(ctx context.Context, g *game.Game, fn func(proc *gtpProcess) error) error {
+	setup := gtpSetupCommands(g)
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		var proc *gtpProcess
+		proc, err = c.acquire(ctx)
+		if err != nil {
+			return err
+		}
+		err = proc.sync(ctx, setup, c.cfg.Timeout)
+		if err == nil {
+			err = fn(proc)
+		}
+		c.release(proc, err)
+		if !errors.Is(err, ErrEngineDied) {
+			break
+		}
+	}
+	return err
+}
+
+// acquire 从进程池取出一个进程，需要时启动新进程
+// 所有进程都在使用中时最多等待一个命令超时的时间，ctx 被取消时放弃等待
+func (c *GTPClient) acquire(ctx context.Context) (*gtpProcess, error) {
+	var proc *gtpProcess
+	select {
+	case proc = <-c.pool:
+	case <-time.After(c.cfg.Timeout):
+		return nil, fmt.Errorf("%w: no idle engine process", ErrEngineTimeout)
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+	if closed {
+		proc.kill()
+		c.pool <- nil
+		return nil, ErrClientClosed
+	}
+
+	if proc == nil {
+		var err error
+		if proc, err = startGTPProcess(c.cfg); err != nil {
+			c.pool <- nil
+			return nil, err
+		}
+	}
+	return proc, nil
+}
+
+// release 归还进程；进程超时、退出、命令被取消或客户端已关闭时终止进程，槽位留给之后重新启动
+// 被取消的命令的响应仍会到达，进程的输出已经无法与之后的命令对应
+func (c *GTPClient) release(proc *gtpProcess, err error) {
+	c.mu.Lock()
+	closed := c.closed
+	c.mu.Unlock()
+
+	if closed || errors.Is(err, ErrEngineTimeout) || errors.Is(err, ErrEngineDied) || errors.Is(err, errCommandCanceled) {
+		proc.kill()
+		proc = nil
+	}
+	c.pool <- proc
+}
+
+// gtpSetupCommands 返回在引擎上重现对局局面所需的命令
+// 让子使用 set_free_handicap，棋谱的摆子按连续落子处理
+func gtpSetupCommands(g *game.Game) []string {
+	size := g.Board.Size()
+	cmds := []string{
+		"boardsize " + strconv.Itoa(size),
+		"clear_board",
+		"komi " + strconv.FormatFloat(g.Rules.Komi, 'f', -1, 64),
+	}
+	if g.Setup != nil {
+		for _, p := range g.Setup.Black {
+			cmds = append(cmds, "play b "+gtp.FormatVertex(p, size))
+		}
+		for _, p := range g.Setup.White {
+			cmds = append(cmds, "play w "+gtp.FormatVertex(p, size))
+		}
+	}
+	if len(g.SetupStones) > 0 {
+		vertices := make([]string, len(g.SetupStones))
+		for i, p := range g.SetupStones {
+			vertices[i] = gtp.FormatVertex(p, size)
+		}
+		cmds = append(cmds, "set_free_handicap "+strings.Join(vertices, " "))
+	}
+	for _, m := range g.Moves {
+		vertex := "pass"
+		if !m.Pass {
+			vertex = gtp.FormatVertex(m.Point, size)
+		}
+		cmds = append(cmds, "play "+gtpColor(m.Player)+" "+vertex)
+	}
+	return cmds
+}
+
+func gtpColor(p game.Player) string {
+	if p == game.White {
+		return "w"
+	}
+	return "b"
+}
+
+// gtpProcess 是一个运行中的 GTP 引擎子进程
+type gtpProcess struct {
+	cmd   *exec.Cmd
+	stdin io.WriteCloser
+	lines chan string // 引擎标准输出的每一行，进程退出后关闭
+
+	// played 是已经在引擎上执行过的局面命令，新的局面以其为前缀时只需补上后续着法
+	played []string
+}
+
+// startGTPProcess 启动引擎进程并开始读取其输出
+func startGTPProcess(cfg GTPConfig) (*gtpProcess, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start GTP engine: %w", err)
+	}
+
+	proc := &gtpProcess{cmd: cmd, stdin: stdin, lines: make(chan string, 16)}
+	go func() {
+		defer close(proc.lines)
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			proc.lines <- strings.TrimRight(scanner.Text(), "\r")
+		}
+	}()
+	return proc, nil
+}
+
+// sync 把引擎同步到 setup 描述的局面
+func (p *gtpProcess) sync(ctx context.Context, setup []string, timeout time.Duration) error {
+	start := 0
+	if len(p.played) <= len(setup) && slices.Equal(p.played, setup[:len(p.played)]) {
+		start = len(p.played)
+	}
+	if start == 0 {
+		p.played = nil
+	}
+	for _, cmd := range setup[start:] {
+		if _, err := p.command(ctx, cmd, timeout); err != nil {
+			p.played = nil // 引擎状态未知，下次重新摆放
+			return err
+		}
+		p.played = append(p.played, cmd)
+	}
+	return nil
+}
+
+// command 发送一条命令并等待响应，返回去掉 "=" 前缀的内容
+// 引擎返回 "?" 时返回错误，进程不受影响；ctx 被取消时不再等待响应
+func (p *gtpProcess) command(ctx context.Context, cmd string, timeout time.Duration) (string, error) {
+	if _, err := io.WriteString(p.stdin, cmd+"\n"); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrEngineDied, err)
+	}
+
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+	var response []string
+	for {
+		select {
+		case line, ok := <-p.lines:
+			if !ok {
+				return "", fmt.Errorf("%w while running %q", ErrEngineDied, cmd)
+			}
+			if line != "" {
+				response = append(response, line)
+				continue
+			}
+			if len(response) == 0 {
+				continue // 上一个响应之后多余的空行
+			}
+			return parseGTPResponse(cmd, response)
+		case <-deadline.C:
+			return "", fmt.Errorf("%w while running %q", ErrEngineTimeout, cmd)
+		case <-ctx.Done():
+			return "", fmt.Errorf("%w %q: %w", errCommandCanceled, cmd, ctx.Err())
+		}
+	}
+}
+
+// parseGTPResponse 解析完整的响应，去掉状态符号和可选的命令编号
+func parseGTPResponse(cmd string, lines []string) (string, error) {
+	first := lines[0]
+	status, rest := first[0], strings.TrimLeft(first[1:], "0123456789")
+	text := strings.TrimSpace(strings.Join(append([]string{rest}, lines[1:]...), "\n"))
+	switch status {
+	case '=':
+		return text, nil
+	case '?':
+		return "", fmt.Errorf("GTP engine rejected %q: %s", cmd, text)
+	}
+	return "", fmt.Errorf("malformed GTP response to %q: %q", cmd, first)
+}
+
+// kill 终止进程，nil 表示槽位中没有进程
+func (p *gtpProcess) kill() {
+	if p == nil {
+		return
+	}
+	_ = p.stdin.Close()
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+	}
+	go func() {
+		// 读完剩余输出，避免读取协程阻塞
+		for range p.lines {
+		}
+		_ = p.cmd.Wait()
+	}()
+}
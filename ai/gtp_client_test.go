@@ -0,0 +1,247 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/gtp"
+	"github.com/nankp236270/weiqi-go/logger"
+)
+
+// 设置 GTP_STUB 时测试程序自身作为桩 GTP 引擎运行：
+// normal 正常应答，hang 在 genmove 时不再应答，crash 在标记文件 GTP_STUB_MARKER 不存在时创建该文件并在 genmove 时退出
+// 设置 KATAGO_STUB 时作为桩分析引擎运行，见 runStubAnalysisEngine
+// 其他情况下初始化日志系统，AI 服务客户端的健康检查和降级依赖全局 Logger
+func TestMain(m *testing.M) {
+	if mode := os.Getenv("GTP_STUB"); mode != "" {
+		runStubEngine(mode)
+		os.Exit(0)
+	}
+	if mode := os.Getenv("KATAGO_STUB"); mode != "" {
+		runStubAnalysisEngine(mode)
+		os.Exit(0)
+	}
+	logger.Init(logger.Config{Level: logger.LevelError})
+	os.Exit(m.Run())
+}
+
+// firstLegalMove 总是选择第一个合法的落点
+type firstLegalMove struct{}
+
+func (firstLegalMove) GetMove(_ context.Context, g *game.Game) (game.Point, error) {
+	moves := g.LegalMoves()
+	if len(moves) == 0 {
+		return game.Point{}, errors.New("no legal moves")
+	}
+	return moves[0], nil
+}
+
+func runStubEngine(mode string) {
+	e, err := gtp.NewEngine(firstLegalMove{}, game.GameOptions{BoardSize: 9})
+	if err != nil {
+		os.Exit(1)
+	}
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(line, "genmove") {
+			switch mode {
+			case "hang":
+				select {}
+			case "crash":
+				marker := os.Getenv("GTP_STUB_MARKER")
+				if _, err := os.Stat(marker); err != nil {
+					_ = os.WriteFile(marker, nil, 0o644)
+					os.Exit(1)
+				}
+			}
+		}
+		response, quit, ok := e.Execute(line)
+		if !ok {
+			continue
+		}
+		fmt.Print(response)
+		if quit {
+			return
+		}
+	}
+}
+
+// newStubClient 创建以桩引擎为后端的客户端
+func newStubClient(t *testing.T, mode string, poolSize int, timeout time.Duration) *GTPClient {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("GTP_STUB", mode)
+	t.Setenv("GTP_STUB_MARKER", filepath.Join(t.TempDir(), "crashed"))
+	c := NewGTPClient(GTPConfig{Command: exe, PoolSize: poolSize, Timeout: timeout})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+func newTestGame(t *testing.T) *game.Game {
+	t.Helper()
+	g, err := game.NewGameWithOptions(game.GameOptions{BoardSize: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+// TestGTPClient_GetMove 测试重现局面后由引擎落子，并在之后的请求中复用引擎进程
+func TestGTPClient_GetMove(t *testing.T) {
+	c := newStubClient(t, "normal", 1, 5*time.Second)
+	if err := c.HealthCheck(); err != nil {
+		t.Fatalf("Expected healthy engine, got %v", err)
+	}
+
+	g := newTestGame(t)
+	for i := 0; i < 4; i++ {
+		p, err := c.GetMove(context.Background(), g)
+		if err != nil {
+			t.Fatalf("Move %d: expected no error, got %v", i, err)
+		}
+		if err := g.PlayMove(p); err != nil {
+			t.Fatalf("Move %d: engine returned illegal move %v: %v", i, p, err)
+		}
+	}
+	if len(g.Moves) != 4 {
+		t.Fatalf("Expected 4 moves, got %d", len(g.Moves))
+	}
+}
+
+// TestGTPClient_GetMove_Difficulty 测试低难度下部分着法不经引擎随机选择
+func TestGTPClient_GetMove_Difficulty(t *testing.T) {
+	c := newStubClient(t, "normal", 1, 5*time.Second)
+	g := newTestGame(t)
+	g.AIDifficulty = game.DifficultyBeginner
+
+	random := 0
+	for i := 0; i < 40; i++ {
+		p, err := c.GetMove(context.Background(), g)
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+		if err := g.IsLegalMove(p); err != nil {
+			t.Fatalf("Expected a legal move, got %v: %v", p, err)
+		}
+		if p != (game.Point{}) { // 桩引擎总是下第一个合法点
+			random++
+		}
+	}
+	if random == 0 {
+		t.Fatal("Expected some random moves at beginner difficulty")
+	}
+}
+
+// TestGTPClient_CalculateScore 测试 final_score 转换为计分结果
+func TestGTPClient_CalculateScore(t *testing.T) {
+	c := newStubClient(t, "normal", 1, 5*time.Second)
+	g := newTestGame(t)
+
+	score, err := c.CalculateScore(context.Background(), g)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if score.Winner != game.White || score.WhiteScore != g.Rules.Komi || score.BlackScore != 0 {
+		t.Fatalf("Expected white to win by komi, got %+v", score)
+	}
+}
+
+// TestGTPClient_Timeout 测试引擎无响应时超时，进程被终止并在下次使用时重启
+func TestGTPClient_Timeout(t *testing.T) {
+	c := newStubClient(t, "hang", 1, 300*time.Millisecond)
+	if _, err := c.GetMove(context.Background(), newTestGame(t)); !errors.Is(err, ErrEngineTimeout) {
+		t.Fatalf("Expected ErrEngineTimeout, got %v", err)
+	}
+	if err := c.HealthCheck(); err != nil {
+		t.Fatalf("Expected a restarted engine, got %v", err)
+	}
+}
+
+// TestGTPClient_Canceled 测试请求被取消时不再等待引擎，进程被终止并在下次使用时重启
+func TestGTPClient_Canceled(t *testing.T) {
+	c := newStubClient(t, "hang", 1, time.Minute)
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := c.GetMove(ctx, newTestGame(t)); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("Expected context.DeadlineExceeded, got %v", err)
+	}
+	// 挂起的 stub 只在 genmove 时无响应，重启后的进程可以回应健康检查
+	if err := c.HealthCheck(); err != nil {
+		t.Fatalf("Expected a restarted engine, got %v", err)
+	}
+}
+
+// TestGTPClient_Restart 测试引擎进程退出后自动重启并重试
+func TestGTPClient_Restart(t *testing.T) {
+	c := newStubClient(t, "crash", 1, 5*time.Second)
+	g := newTestGame(t)
+	if err := g.PlayMove(game.Point{X: 4, Y: 4}); err != nil {
+		t.Fatal(err)
+	}
+
+	p, err := c.GetMove(context.Background(), g)
+	if err != nil {
+		t.Fatalf("Expected the move to succeed after a restart, got %v", err)
+	}
+	if err := g.PlayMove(p); err != nil {
+		t.Fatalf("Engine returned illegal move %v: %v", p, err)
+	}
+}
+
+// TestGTPClient_Concurrent 测试多个对局并发使用进程池
+func TestGTPClient_Concurrent(t *testing.T) {
+	c := newStubClient(t, "normal", 2, 5*time.Second)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 6)
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			g, err := game.NewGameWithOptions(game.GameOptions{BoardSize: 9})
+			if err != nil {
+				errs <- err
+				return
+			}
+			// 各对局的局面不同，引擎需要重新摆放
+			if err := g.PlayMove(game.Point{X: i, Y: i}); err != nil {
+				errs <- err
+				return
+			}
+			p, err := c.GetMove(context.Background(), g)
+			if err == nil {
+				err = g.PlayMove(p)
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+// TestGTPClient_Closed 测试关闭后的客户端拒绝请求
+func TestGTPClient_Closed(t *testing.T) {
+	c := newStubClient(t, "normal", 1, 5*time.Second)
+	c.Close()
+	if _, err := c.GetMove(context.Background(), newTestGame(t)); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("Expected ErrClientClosed, got %v", err)
+	}
+}
@@ -0,0 +1,441 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/gtp"
+)
+
+var ErrKataGoQuery = errors.New("KataGo rejected query")
+
+// DefaultKataGoTimeout 是单次分析的默认超时
+const DefaultKataGoTimeout = 60 * time.Second
+
+// KataGoConfig 是 KataGo 分析引擎 (katago analysis -config ... -model ...) 的配置
+// 胜率、目差和归属的视角由引擎配置的 reportAnalysisWinratesAs 决定，客户端不做转换
+type KataGoConfig struct {
+	Command string        // 引擎可执行文件
+	Args    []string      // 命令行参数
+	Timeout time.Duration // 单次分析的超时，0 表示 DefaultKataGoTimeout
+}
+
+// KataGoRules 是 KataGo 的规则描述
+type KataGoRules struct {
+	Ko                 string `json:"ko"`                 // SIMPLE、POSITIONAL 或 SITUATIONAL
+	Scoring            string `json:"scoring"`            // AREA 或 TERRITORY
+	Tax                string `json:"tax"`                // NONE 或 SEKI
+	Suicide            bool   `json:"suicide"`            // 是否允许自杀
+	HasButton          bool   `json:"hasButton"`          // 是否使用 button 规则
+	WhiteHandicapBonus string `json:"whiteHandicapBonus"` // 让子棋白方补偿: 0、N 或 N-1
+}
+
+// KataGoQuery 是发送给分析引擎的查询
+// 着法和摆子使用 GTP 坐标，如 ["B", "D4"]
+type KataGoQuery struct {
+	ID                      string      `json:"id"`
+	Moves                   [][2]string `json:"moves"`
+	InitialStones           [][2]string `json:"initialStones,omitempty"`
+	InitialPlayer           string      `json:"initialPlayer,omitempty"`
+	Rules                   KataGoRules `json:"rules"`
+	Komi                    float64     `json:"komi"`
+	BoardXSize              int         `json:"boardXSize"`
+	BoardYSize              int         `json:"boardYSize"`
+	AnalyzeTurns            []int       `json:"analyzeTurns,omitempty"` // 为空时只分析最后的局面
+	MaxVisits               int         `json:"maxVisits,omitempty"`
+	IncludeOwnership        bool        `json:"includeOwnership,omitempty"`
+	ReportDuringSearchEvery float64     `json:"reportDuringSearchEvery,omitempty"` // 大于 0 时每隔若干秒返回一次中间结果
+}
+
+// KataGoRootInfo 是当前局面的总体评估
+type KataGoRootInfo struct {
+	Winrate       float64 `json:"winrate"`
+	ScoreLead     float64 `json:"scoreLead"`
+	Visits        int     `json:"visits"`
+	CurrentPlayer string  `json:"currentPlayer"`
+}
+
+// KataGoMoveInfo 是一个候选着法的评估，PV 是预想的后续着法
+type KataGoMoveInfo struct {
+	Move      string   `json:"move"`
+	Visits    int      `json:"visits"`
+	Winrate   float64  `json:"winrate"`
+	ScoreLead float64  `json:"scoreLead"`
+	Prior     float64  `json:"prior"`
+	Order     int      `json:"order"`
+	PV        []string `json:"pv"`
+}
+
+// Point 将候选着法转换为棋盘坐标
+func (m KataGoMoveInfo) Point(size int) (p game.Point, pass bool, err error) {
+	return gtp.ParseVertex(m.Move, size)
+}
+
+// KataGoResponse 是分析引擎对某一手局面的分析结果
+// Ownership 按行排列，从棋盘上方开始，与 Board.Grid 的顺序相同
+type KataGoResponse struct {
+	ID             string           `json:"id"`
+	TurnNumber     int              `json:"turnNumber"`
+	IsDuringSearch bool             `json:"isDuringSearch"`
+	RootInfo       KataGoRootInfo   `json:"rootInfo"`
+	MoveInfos      []KataGoMoveInfo `json:"moveInfos"`
+	Ownership      []float64        `json:"ownership,omitempty"`
+	NoResults      bool             `json:"noResults,omitempty"` // 查询在出结果之前被终止
+
+	// 引擎拒绝查询时返回 error，可以继续分析时返回 warning，field 是出错的字段
+	Error   string `json:"error,omitempty"`
+	Warning string `json:"warning,omitempty"`
+	Field   string `json:"field,omitempty"`
+}
+
+// NewKataGoQuery 按对局的规则、贴目、摆子和着法创建分析当前局面的查询
+func NewKataGoQuery(g *game.Game) *KataGoQuery {
+	size := g.Board.Size()
+	q := &KataGoQuery{
+		Moves:      make([][2]string, 0, len(g.Moves)),
+		Rules:      kataGoRules(g.Rules),
+		Komi:       g.Rules.Komi,
+		BoardXSize: size,
+		BoardYSize: size,
+	}
+	if g.Setup != nil {
+		for _, p := range g.Setup.Black {
+			q.InitialStones = append(q.InitialStones, [2]string{"B", gtp.FormatVertex(p, size)})
+		}
+		for _, p := range g.Setup.White {
+			q.InitialStones = append(q.InitialStones, [2]string{"W", gtp.FormatVertex(p, size)})
+		}
+	}
+	for _, p := range g.SetupStones {
+		q.InitialStones = append(q.InitialStones, [2]string{"B", gtp.FormatVertex(p, size)})
+	}
+	if len(q.InitialStones) > 0 {
+		// 摆子后的行棋方，让子棋由白方先行
+		first := g.NextPlayer
+		if len(g.Moves) > 0 {
+			first = g.Moves[0].Player
+		}
+		q.InitialPlayer = kataGoColor(first)
+	}
+	for _, m := range g.Moves {
+		vertex := "pass"
+		if !m.Pass {
+			vertex = gtp.FormatVertex(m.Point, size)
+		}
+		q.Moves = append(q.Moves, [2]string{kataGoColor(m.Player), vertex})
+	}
+	return q
+}
+
+// kataGoRules 将规则集转换为 KataGo 的规则描述，早期未保存规则的对局按中国规则处理
+func kataGoRules(r game.RuleSet) KataGoRules {
+	if r.Name == "" {
+		r = game.ChineseRules
+	}
+	rules := KataGoRules{Ko: "POSITIONAL", Scoring: "AREA", Tax: "NONE", Suicide: r.SuicideAllowed, WhiteHandicapBonus: "0"}
+	switch r.Ko {
+	case game.KoSimple:
+		rules.Ko = "SIMPLE"
+	case game.KoSituationalSuperko:
+		rules.Ko = "SITUATIONAL"
+	}
+	if r.Scoring == game.ScoringTerritory {
+		rules.Scoring, rules.Tax = "TERRITORY", "SEKI"
+	}
+	switch r.HandicapCompensation {
+	case game.CompensationPerStone:
+		rules.WhiteHandicapBonus = "N"
+	case game.CompensationPerStoneLess1:
+		rules.WhiteHandicapBonus = "N-1"
+	}
+	return rules
+}
+
+func kataGoColor(p game.Player) string {
+	if p == game.White {
+		return "W"
+	}
+	return "B"
+}
+
+// KataGoClient 通过 JSON 行协议与 KataGo 分析引擎通信
+// 多个查询共用一个引擎进程，按查询编号分发结果；进程退出后在下一次查询时重新启动
+type KataGoClient struct {
+	cfg KataGoConfig
+
+	mu     sync.Mutex
+	proc   *kataGoProcess
+	nextID uint64
+	closed bool
+}
+
+// NewKataGoClient 创建分析引擎客户端，引擎进程在第一次查询时启动
+func NewKataGoClient(cfg KataGoConfig) *KataGoClient {
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = DefaultKataGoTimeout
+	}
+	return &KataGoClient{cfg: cfg}
+}
+
+// Query 提交查询并把引擎返回的每个结果依次交给 fn，包括中间结果
+// 所有要分析的局面都得到最终结果后返回；ctx 取消或超时时通知引擎终止查询
+// 查询编号由客户端分配，q.ID 会被覆盖
+func (c *KataGoClient) Query(ctx context.Context, q *KataGoQuery, fn func(*KataGoResponse)) error {
+	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
+	defer cancel()
+
+	proc, id, err := c.process()
+	if err != nil {
+		return err
+	}
+	q.ID = id
+	pending := proc.register(id)
+	defer proc.unregister(id)
+
+	if err := proc.send(q); err != nil {
+		return err
+	}
+
+	remaining := len(q.AnalyzeTurns)
+	if remaining == 0 {
+		remaining = 1
+	}
+	for {
+		select {
+		case r := <-pending.responses:
+			switch {
+			case r.Error != "":
+				return fmt.Errorf("%w: %s (field %q)", ErrKataGoQuery, r.Error, r.Field)
+			case r.Warning != "":
+				continue
+			case r.NoResults:
+				remaining--
+			default:
+				fn(r)
+				if !r.IsDuringSearch {
+					remaining--
+				}
+			}
+			if remaining == 0 {
+				return nil
+			}
+		case <-ctx.Done():
+			_ = proc.send(map[string]string{"id": id + "-terminate", "action": "terminate", "terminateId": id})
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return fmt.Errorf("%w: query %s", ErrEngineTimeout, id)
+			}
+			return ctx.Err()
+		case <-proc.done:
+			if len(pending.responses) > 0 {
+				continue // 先处理进程退出前已经收到的结果
+			}
+			return fmt.Errorf("%w while running query %s", ErrEngineDied, id)
+		}
+	}
+}
+
+// AnalyzePosition 分析查询的最后一个局面，返回最终结果
+func (c *KataGoClient) AnalyzePosition(ctx context.Context, q *KataGoQuery) (*KataGoResponse, error) {
+	q.AnalyzeTurns = nil
+	var result *KataGoResponse
+	err := c.Query(ctx, q, func(r *KataGoResponse) {
+		if !r.IsDuringSearch {
+			result = r
+		}
+	})
+	if err == nil && result == nil {
+		err = fmt.Errorf("%w: no results for query %s", ErrKataGoQuery, q.ID)
+	}
+	return result, err
+}
+
+// Analyze 分析对局的当前局面，返回最多 candidates 个候选着法
+// 要求引擎配置 reportAnalysisWinratesAs = BLACK，使胜率、目差和归属以黑方为准
+func (c *KataGoClient) Analyze(ctx context.Context, g *game.Game, candidates int) (*game.Analysis, error) {
+	q := NewKataGoQuery(g)
+	q.IncludeOwnership = true
+	r, err := c.AnalyzePosition(ctx, q)
+	if err != nil {
+		return nil, err
+	}
+
+	size := g.Board.Size()
+	analysis := &game.Analysis{
+		Winrate:    r.RootInfo.Winrate,
+		ScoreLead:  r.RootInfo.ScoreLead,
+		Candidates: []game.Candidate{},
+	}
+	infos := slices.Clone(r.MoveInfos)
+	slices.SortStableFunc(infos, func(a, b KataGoMoveInfo) int { return a.Order - b.Order })
+	for _, info := range infos {
+		if len(analysis.Candidates) == candidates {
+			break
+		}
+		p, pass, err := info.Point(size)
+		if err != nil {
+			return nil, fmt.Errorf("bad candidate move: %w", err)
+		}
+		cand := game.Candidate{Point: p, Pass: pass, Visits: info.Visits, Prior: info.Prior, Winrate: info.Winrate, ScoreLead: info.ScoreLead, PV: []game.Point{}}
+		for _, vertex := range info.PV {
+			p, pass, err := gtp.ParseVertex(vertex, size)
+			if err != nil || pass {
+				break
+			}
+			cand.PV = append(cand.PV, p)
+		}
+		analysis.Candidates = append(analysis.Candidates, cand)
+	}
+	if len(r.Ownership) == size*size {
+		analysis.Ownership = make([][]float64, size)
+		for y := range analysis.Ownership {
+			analysis.Ownership[y] = r.Ownership[y*size : (y+1)*size]
+		}
+	}
+	return analysis, nil
+}
+
+// Close 终止引擎进程
+func (c *KataGoClient) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	if c.proc != nil {
+		c.proc.kill()
+		c.proc = nil
+	}
+	return nil
+}
+
+// process 返回运行中的引擎进程 (需要时启动) 和新的查询编号
+func (c *KataGoClient) process() (*kataGoProcess, string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return nil, "", ErrClientClosed
+	}
+	if c.proc != nil {
+		select {
+		case <-c.proc.done:
+			c.proc = nil
+		default:
+		}
+	}
+	if c.proc == nil {
+		proc, err := startKataGoProcess(c.cfg)
+		if err != nil {
+			return nil, "", err
+		}
+		c.proc = proc
+	}
+	c.nextID++
+	return c.proc, strconv.FormatUint(c.nextID, 10), nil
+}
+
+// kataGoProcess 是一个运行中的分析引擎子进程
+type kataGoProcess struct {
+	cmd  *exec.Cmd
+	done chan struct{} // 进程退出后关闭
+
+	writeMu sync.Mutex
+	stdin   io.WriteCloser
+
+	mu      sync.Mutex
+	pending map[string]*kataGoPending
+}
+
+// kataGoPending 是等待结果的查询
+type kataGoPending struct {
+	responses chan *KataGoResponse
+	finished  chan struct{} // 查询结束 (Query 返回) 后关闭，之后的结果被丢弃
+}
+
+// startKataGoProcess 启动引擎进程，读取协程按查询编号分发结果
+func startKataGoProcess(cfg KataGoConfig) (*kataGoProcess, error) {
+	cmd := exec.Command(cfg.Command, cfg.Args...)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start KataGo: %w", err)
+	}
+
+	proc := &kataGoProcess{cmd: cmd, stdin: stdin, done: make(chan struct{}), pending: map[string]*kataGoPending{}}
+	go func() {
+		defer close(proc.done)
+		scanner := bufio.NewScanner(stdout)
+		scanner.Buffer(make([]byte, 64*1024), 16*1024*1024) // 带归属的结果可能很长
+		for scanner.Scan() {
+			var r KataGoResponse
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil || r.ID == "" {
+				continue // 不属于任何查询的输出
+			}
+			proc.mu.Lock()
+			pending := proc.pending[r.ID]
+			proc.mu.Unlock()
+			if pending == nil {
+				continue
+			}
+			select {
+			case pending.responses <- &r:
+			case <-pending.finished:
+			}
+		}
+		_ = cmd.Wait()
+	}()
+	return proc, nil
+}
+
+func (p *kataGoProcess) register(id string) *kataGoPending {
+	pending := &kataGoPending{responses: make(chan *KataGoResponse, 16), finished: make(chan struct{})}
+	p.mu.Lock()
+	p.pending[id] = pending
+	p.mu.Unlock()
+	return pending
+}
+
+func (p *kataGoProcess) unregister(id string) {
+	p.mu.Lock()
+	pending := p.pending[id]
+	delete(p.pending, id)
+	p.mu.Unlock()
+	if pending != nil {
+		close(pending.finished)
+	}
+}
+
+// send 将查询编码为一行 JSON 写入引擎
+func (p *kataGoProcess) send(v any) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("failed to marshal query: %w", err)
+	}
+	p.writeMu.Lock()
+	defer p.writeMu.Unlock()
+	if _, err := p.stdin.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("%w: %v", ErrEngineDied, err)
+	}
+	return nil
+}
+
+// kill 终止进程，读取协程在输出结束后回收进程
+func (p *kataGoProcess) kill() {
+	_ = p.stdin.Close()
+	if p.cmd.Process != nil {
+		_ = p.cmd.Process.Kill()
+	}
+}
@@ -0,0 +1,256 @@
+package ai
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// runStubAnalysisEngine 模拟 KataGo 分析引擎的 JSON 行协议：
+// normal 为每个要分析的局面返回固定的结果 (设置 reportDuringSearchEvery 时先返回一次中间结果)，
+// 棋盘大于 19 路时返回错误；slow 不返回分析结果，只响应终止请求；
+// crash 在标记文件 KATAGO_STUB_MARKER 不存在时创建该文件并退出
+func runStubAnalysisEngine(mode string) {
+	out := json.NewEncoder(os.Stdout)
+	fmt.Println("KataGo stub ready") // 不属于任何查询的输出
+
+	scanner := bufio.NewScanner(os.Stdin)
+	for scanner.Scan() {
+		var q struct {
+			KataGoQuery
+			Action      string `json:"action"`
+			TerminateID string `json:"terminateId"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &q); err != nil {
+			continue
+		}
+		if q.Action == "terminate" {
+			_ = out.Encode(KataGoResponse{ID: q.TerminateID, NoResults: true})
+			continue
+		}
+
+		switch mode {
+		case "slow":
+			continue
+		case "crash":
+			marker := os.Getenv("KATAGO_STUB_MARKER")
+			if _, err := os.Stat(marker); err != nil {
+				_ = os.WriteFile(marker, nil, 0o644)
+				os.Exit(1)
+			}
+		}
+
+		if q.BoardXSize > 19 {
+			_ = out.Encode(KataGoResponse{ID: q.ID, Error: "board size too large", Field: "boardXSize"})
+			continue
+		}
+		_ = out.Encode(KataGoResponse{ID: q.ID, Warning: "unused field", Field: "foo"})
+
+		turns := q.AnalyzeTurns
+		if len(turns) == 0 {
+			turns = []int{len(q.Moves)}
+		}
+		for _, turn := range turns {
+			r := KataGoResponse{
+				ID:         q.ID,
+				TurnNumber: turn,
+				RootInfo:   KataGoRootInfo{Winrate: 0.5, ScoreLead: float64(turn), Visits: 100},
+				MoveInfos:  []KataGoMoveInfo{{Move: "C3", Visits: 60, Winrate: 0.55, PV: []string{"C3", "G7"}}},
+			}
+			if q.ReportDuringSearchEvery > 0 {
+				during := r
+				during.IsDuringSearch = true
+				_ = out.Encode(during)
+			}
+			if q.IncludeOwnership {
+				r.Ownership = make([]float64, q.BoardXSize*q.BoardYSize)
+			}
+			_ = out.Encode(r)
+		}
+	}
+}
+
+// newStubAnalysisClient 创建以桩分析引擎为后端的客户端
+func newStubAnalysisClient(t *testing.T, mode string, timeout time.Duration) *KataGoClient {
+	t.Helper()
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KATAGO_STUB", mode)
+	t.Setenv("KATAGO_STUB_MARKER", filepath.Join(t.TempDir(), "crashed"))
+	c := NewKataGoClient(KataGoConfig{Command: exe, Timeout: timeout})
+	t.Cleanup(func() { c.Close() })
+	return c
+}
+
+// TestNewKataGoQuery 测试对局的规则、让子和着法转换为查询
+func TestNewKataGoQuery(t *testing.T) {
+	g, err := game.NewGameWithOptions(game.GameOptions{BoardSize: 9, Rules: "japanese", Handicap: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := g.PlayMove(game.Point{X: 4, Y: 4}); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.PassTurn(); err != nil {
+		t.Fatal(err)
+	}
+
+	q := NewKataGoQuery(g)
+	if q.BoardXSize != 9 || q.BoardYSize != 9 || q.Komi != g.Rules.Komi {
+		t.Fatalf("Unexpected board size or komi: %+v", q)
+	}
+	want := KataGoRules{Ko: "SIMPLE", Scoring: "TERRITORY", Tax: "SEKI", WhiteHandicapBonus: "0"}
+	if q.Rules != want {
+		t.Fatalf("Expected rules %+v, got %+v", want, q.Rules)
+	}
+	if len(q.InitialStones) != 2 || q.InitialStones[0][0] != "B" || q.InitialPlayer != "W" {
+		t.Fatalf("Expected two black handicap stones with white to play, got %v %q", q.InitialStones, q.InitialPlayer)
+	}
+	if len(q.Moves) != 2 || q.Moves[0] != [2]string{"W", "E5"} || q.Moves[1] != [2]string{"B", "pass"} {
+		t.Fatalf("Unexpected moves %v", q.Moves)
+	}
+}
+
+// TestKataGoClient_Query 测试多个局面的分析结果和中间结果依次返回
+func TestKataGoClient_Query(t *testing.T) {
+	c := newStubAnalysisClient(t, "normal", 5*time.Second)
+	g, err := game.NewGameWithOptions(game.GameOptions{BoardSize: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	g.PlayMove(game.Point{X: 2, Y: 2})
+	g.PlayMove(game.Point{X: 6, Y: 6})
+
+	q := NewKataGoQuery(g)
+	q.AnalyzeTurns = []int{0, 2}
+	q.IncludeOwnership = true
+	q.ReportDuringSearchEvery = 0.1
+
+	var during, final []*KataGoResponse
+	err = c.Query(context.Background(), q, func(r *KataGoResponse) {
+		if r.IsDuringSearch {
+			during = append(during, r)
+		} else {
+			final = append(final, r)
+		}
+	})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(during) != 2 || len(final) != 2 {
+		t.Fatalf("Expected 2 intermediate and 2 final results, got %d and %d", len(during), len(final))
+	}
+	r := final[1]
+	if r.TurnNumber != 2 || len(r.Ownership) != 81 || len(r.MoveInfos[0].PV) != 2 {
+		t.Fatalf("Unexpected final result %+v", r)
+	}
+	if p, pass, err := r.MoveInfos[0].Point(9); err != nil || pass || p != (game.Point{X: 2, Y: 6}) {
+		t.Fatalf("Unexpected candidate point %v, %v, %v", p, pass, err)
+	}
+}
+
+// TestKataGoClient_Concurrent 测试并发的查询按编号得到各自的结果
+func TestKataGoClient_Concurrent(t *testing.T) {
+	c := newStubAnalysisClient(t, "normal", 5*time.Second)
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(turn int) {
+			defer wg.Done()
+			q := &KataGoQuery{BoardXSize: 19, BoardYSize: 19, Moves: make([][2]string, turn)}
+			for j := range q.Moves {
+				q.Moves[j] = [2]string{"B", "pass"}
+			}
+			r, err := c.AnalyzePosition(context.Background(), q)
+			if err == nil && (r.ID != q.ID || r.TurnNumber != turn) {
+				err = fmt.Errorf("query %s got result %s for turn %d", q.ID, r.ID, r.TurnNumber)
+			}
+			errs <- err
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			t.Fatalf("Expected no error, got %v", err)
+		}
+	}
+}
+
+// TestKataGoClient_Error 测试引擎拒绝查询
+func TestKataGoClient_Error(t *testing.T) {
+	c := newStubAnalysisClient(t, "normal", 5*time.Second)
+	_, err := c.AnalyzePosition(context.Background(), &KataGoQuery{BoardXSize: 25, BoardYSize: 25})
+	if !errors.Is(err, ErrKataGoQuery) {
+		t.Fatalf("Expected ErrKataGoQuery, got %v", err)
+	}
+}
+
+// TestKataGoClient_Timeout 测试超时和取消的查询被终止
+func TestKataGoClient_Timeout(t *testing.T) {
+	c := newStubAnalysisClient(t, "slow", 200*time.Millisecond)
+	q := &KataGoQuery{BoardXSize: 9, BoardYSize: 9}
+	if _, err := c.AnalyzePosition(context.Background(), q); !errors.Is(err, ErrEngineTimeout) {
+		t.Fatalf("Expected ErrEngineTimeout, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := c.AnalyzePosition(ctx, q); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+}
+
+// TestKataGoClient_Restart 测试引擎进程退出后下一次查询重新启动进程
+func TestKataGoClient_Restart(t *testing.T) {
+	c := newStubAnalysisClient(t, "crash", 5*time.Second)
+	q := &KataGoQuery{BoardXSize: 9, BoardYSize: 9}
+	if _, err := c.AnalyzePosition(context.Background(), q); !errors.Is(err, ErrEngineDied) {
+		t.Fatalf("Expected ErrEngineDied, got %v", err)
+	}
+	if _, err := c.AnalyzePosition(context.Background(), q); err != nil {
+		t.Fatalf("Expected the restarted engine to answer, got %v", err)
+	}
+
+	c.Close()
+	if _, err := c.AnalyzePosition(context.Background(), q); !errors.Is(err, ErrClientClosed) {
+		t.Fatalf("Expected ErrClientClosed, got %v", err)
+	}
+}
+
+// TestKataGoClient_Analyze 测试分析结果转换为对局的局面分析
+func TestKataGoClient_Analyze(t *testing.T) {
+	c := newStubAnalysisClient(t, "normal", 5*time.Second)
+	g, err := game.NewGameWithOptions(game.GameOptions{BoardSize: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	analysis, err := c.Analyze(context.Background(), g, 5)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if analysis.Winrate != 0.5 || len(analysis.Candidates) != 1 {
+		t.Fatalf("Unexpected analysis %+v", analysis)
+	}
+	cand := analysis.Candidates[0]
+	if cand.Point != (game.Point{X: 2, Y: 6}) || len(cand.PV) != 2 || cand.PV[1] != (game.Point{X: 6, Y: 2}) {
+		t.Fatalf("Unexpected candidate %+v", cand)
+	}
+	if len(analysis.Ownership) != 9 || len(analysis.Ownership[8]) != 9 {
+		t.Fatalf("Expected a 9x9 ownership map, got %v", analysis.Ownership)
+	}
+}
@@ -0,0 +1,266 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/ai"
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/logger"
+	"github.com/nankp236270/weiqi-go/storage"
+)
+
+// DefaultAIDriveInterval 是后台检查 AI 回合的默认间隔
+const DefaultAIDriveInterval = 2 * time.Second
+
+// 后台让 AI 回应时的重试设置，第 n 次重试前等待 DefaultAIRetryDelay * 2^(n-1)
+const (
+	DefaultAIRetries    = 3
+	DefaultAIRetryDelay = time.Second
+)
+
+var (
+	ErrNotAITurn        = errors.New("it is not the AI's turn")
+	ErrAIMoveInProgress = errors.New("AI move already in progress")
+	ErrGameChanged      = errors.New("game changed while the AI was thinking")
+)
+
+// AIDriver 定期扫描进行中的人机对局，轮到 AI 行棋时让 AI 落子
+// AI 执黑、执白或双方都由 AI 执子的对局都由它推进，客户端不需要调用 ai-move；
+// 人类一方的着法保存后由 Schedule 立即在后台回应，定期扫描负责服务重启或重试用尽后遗留的 AI 回合
+type AIDriver struct {
+	store      storage.GameStore
+	client     AIClient
+	interval   time.Duration
+	retries    int
+	retryDelay time.Duration
+
+	mu     sync.Mutex
+	active map[string]bool // 正在等待 AI 着法的对局
+
+	wg sync.WaitGroup // Schedule 启动的后台落子
+
+	// ctx 是驱动自己的上下文，Stop 时取消，进行中的 AI 请求和重试等待随之结束
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// NewAIDriver 创建一个 AI 行棋驱动
+func NewAIDriver(store storage.GameStore, client AIClient, interval time.Duration) *AIDriver {
+	if interval <= 0 {
+		interval = DefaultAIDriveInterval
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	return &AIDriver{
+		store:      store,
+		client:     client,
+		interval:   interval,
+		retries:    DefaultAIRetries,
+		retryDelay: DefaultAIRetryDelay,
+		active:     make(map[string]bool),
+		ctx:        ctx,
+		cancel:     cancel,
+	}
+}
+
+// Run 立即扫描一次，之后按间隔定期扫描，直到 ctx 被取消或驱动停止
+func (d *AIDriver) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		d.Sweep()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-d.ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Stop 取消进行中的 AI 请求和重试等待，并等待 Schedule 启动的后台落子退出
+// 停止后 Move 立即返回取消错误
+func (d *AIDriver) Stop() {
+	d.cancel()
+	d.wg.Wait()
+}
+
+// Sweep 为所有轮到 AI 行棋的对局各走一手，返回成功落子的对局数
+// 每局只走一手，AI 自我对弈的对局在之后的扫描中继续
+func (d *AIDriver) Sweep() int {
+	games, err := d.store.GetPlayingGames()
+	if err != nil {
+		logger.Error("failed to list playing games", "error", err)
+		return 0
+	}
+
+	moved := 0
+	for _, info := range games {
+		if d.ctx.Err() != nil {
+			break
+		}
+		if !info.IsAIGame {
+			continue
+		}
+		if _, err := d.Move(d.ctx, info.ID); err != nil {
+			if retryable(err) || aiUnavailable(err) {
+				logger.Warn("AI move failed", "game_id", info.ID, "error", err)
+			}
+			continue
+		}
+		moved++
+	}
+	return moved
+}
+
+// Schedule 在后台让 AI 回应刚保存的着法，AI 服务出错时按指数退避重试
+// 客户端通过查询对局状态得知 AI 的着法
+func (d *AIDriver) Schedule(gameID string) {
+	d.wg.Add(1)
+	go func() {
+		defer d.wg.Done()
+		d.moveWithRetry(gameID)
+	}()
+}
+
+// moveWithRetry 让 AI 走一手，失败时最多重试 d.retries 次，每次的等待时间加倍
+// 重试用尽或 AI 服务不可用时留给下一次定期扫描
+func (d *AIDriver) moveWithRetry(gameID string) {
+	delay := d.retryDelay
+	for attempt := 1; ; attempt++ {
+		_, err := d.Move(d.ctx, gameID)
+		if aiUnavailable(err) {
+			logger.Warn("AI service unavailable, leaving the move to the next sweep", "game_id", gameID, "error", err)
+			return
+		}
+		if err == nil || !retryable(err) {
+			return
+		}
+		if attempt > d.retries {
+			logger.Error("AI move failed, leaving it to the next sweep", "game_id", gameID, "attempts", attempt, "error", err)
+			return
+		}
+		logger.Warn("AI move failed, retrying", "game_id", gameID, "attempt", attempt, "delay", delay, "error", err)
+		select {
+		case <-d.ctx.Done():
+			return
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// Move 在轮到 AI 行棋时让 AI 走一手并保存，返回更新后的对局
+// 不是 AI 的回合时返回 ErrNotAITurn，同一对局已有 AI 在思考时返回 ErrAIMoveInProgress；
+// 从读取到保存之间对局被修改 (如认输、悔棋或超时判负) 时放弃这一手并返回 ErrGameChanged；
+// ctx 被取消或驱动停止时放弃 AI 请求
+func (d *AIDriver) Move(ctx context.Context, gameID string) (*game.Game, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	defer context.AfterFunc(d.ctx, cancel)()
+	if err := d.ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	if !d.begin(gameID) {
+		return nil, ErrAIMoveInProgress
+	}
+	defer d.end(gameID)
+
+	g, err := d.store.GetGame(gameID)
+	if err != nil {
+		return nil, err
+	}
+	if !aiToMove(g) {
+		return nil, ErrNotAITurn
+	}
+
+	version := g.Version
+	move, err := d.client.GetMove(ctx, g)
+	if ctx.Err() != nil {
+		return nil, ctx.Err()
+	}
+
+	// 在最新的对局状态上执行 AI 的着法，只在 AI 思考期间没有其他保存时继续
+	g, loadErr := d.store.GetGame(gameID)
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	if !aiToMove(g) || g.Version != version {
+		return nil, ErrGameChanged
+	}
+	if err := applyAIMove(g, move, err); err != nil {
+		saveTimeout(d.store, gameID, g, version, err)
+		return nil, err
+	}
+
+	// 双方都是 AI 时由 AI 确认点目结果
+	if g.Status == game.GameStatusScoring && g.IsAIPlayer(game.Black) && g.IsAIPlayer(game.White) {
+		if err := g.AcceptScore(game.Black); err == nil {
+			_ = g.AcceptScore(game.White)
+		}
+	}
+
+	// 条件保存，检查之后才落地的人类请求或超时判负不会被覆盖
+	if err := d.store.UpdateGameIfVersion(gameID, g, version); err != nil {
+		if errors.Is(err, storage.ErrVersionConflict) {
+			return nil, ErrGameChanged
+		}
+		return nil, fmt.Errorf("failed to update game state: %w", err)
+	}
+	return g, nil
+}
+
+func (d *AIDriver) begin(gameID string) bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.active[gameID] {
+		return false
+	}
+	d.active[gameID] = true
+	return true
+}
+
+func (d *AIDriver) end(gameID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.active, gameID)
+}
+
+// retryable 判断 AI 落子失败后是否需要重试
+// 不是 AI 的回合、已有 AI 在思考、AI 超时判负或请求被取消时重试没有意义；
+// AI 服务不可用时客户端已经重试过，不再叠加一层重试
+func retryable(err error) bool {
+	return !errors.Is(err, ErrNotAITurn) && !errors.Is(err, ErrAIMoveInProgress) && !errors.Is(err, game.ErrTimeOut) &&
+		!errors.Is(err, context.Canceled) && !aiUnavailable(err)
+}
+
+// aiUnavailable 判断 AI 落子是否因为 AI 服务不可用或熔断器打开而失败
+func aiUnavailable(err error) bool {
+	return errors.Is(err, ai.ErrServiceUnavailable) || errors.Is(err, ai.ErrCircuitOpen)
+}
+
+// aiToMove 判断对局是否正在等待 AI 行棋
+func aiToMove(g *game.Game) bool {
+	return !g.GameOver && g.Status == game.GameStatusPlaying && g.IsAIPlayer(g.NextPlayer)
+}
+
+// applyAIMove 在对局中执行 AI 后端返回的结果：落子，或在后端虚手、认输时虚手、认输
+// err 是 GetMove 返回的错误，其他错误原样返回
+func applyAIMove(g *game.Game, move game.Point, err error) error {
+	switch {
+	case errors.Is(err, ai.ErrEnginePassed):
+		return g.PassTurn()
+	case errors.Is(err, ai.ErrEngineResigned):
+		return g.Resign(g.NextPlayer)
+	case err != nil:
+		return fmt.Errorf("AI service error: %w", err)
+	}
+	return g.PlayMove(move)
+}
@@ -0,0 +1,277 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/ai"
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/storage"
+)
+
+// scriptedAI 总是选择第一个合法着法，err 不为空时返回 err
+type scriptedAI struct {
+	fakeAI
+	err    error
+	fails  int // 只有前 fails 次请求返回 err，0 表示总是返回 err
+	calls  int
+	onMove func() // 在返回着法之前调用，模拟 AI 思考期间的操作
+}
+
+func (s *scriptedAI) GetMove(_ context.Context, g *game.Game) (game.Point, error) {
+	s.calls++
+	if s.onMove != nil {
+		s.onMove()
+	}
+	if s.err != nil && (s.fails == 0 || s.calls <= s.fails) {
+		return game.Point{}, s.err
+	}
+	return g.LegalMoves()[0], nil
+}
+
+// newSeatGame 创建 AI 坐在 seat 一方的 9 路人机对局
+func newSeatGame(t *testing.T, seat game.AISeat) *game.Game {
+	t.Helper()
+	g, err := game.NewGameWithPlayer("human", true, game.GameOptions{BoardSize: 9, AISeat: seat})
+	if err != nil {
+		t.Fatalf("Failed to create game: %v", err)
+	}
+	return g
+}
+
+// TestAIDriver_Sweep 测试只在轮到 AI 行棋的对局中落子
+func TestAIDriver_Sweep(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	_ = store.CreateGame("ai-black", newSeatGame(t, game.AISeatBlack))
+	_ = store.CreateGame("ai-white", newSeatGame(t, game.AISeatWhite))
+	human := game.NewGame()
+	human.Status = game.GameStatusPlaying
+	_ = store.CreateGame("human", human)
+
+	client := &scriptedAI{}
+	driver := NewAIDriver(store, client, time.Second)
+	if moved := driver.Sweep(); moved != 1 {
+		t.Fatalf("Expected 1 AI move, got %d", moved)
+	}
+
+	black, _ := store.GetGame("ai-black")
+	if len(black.Moves) != 1 || black.Moves[0].Player != game.Black || black.NextPlayer != game.White {
+		t.Fatalf("Expected AI to open as black, got %d moves", len(black.Moves))
+	}
+	white, _ := store.GetGame("ai-white")
+	if len(white.Moves) != 0 {
+		t.Fatal("Expected AI to wait for the human to move first")
+	}
+	if client.calls != 1 {
+		t.Fatalf("Expected 1 AI request, got %d", client.calls)
+	}
+
+	if _, err := driver.Move(context.Background(), "ai-black"); !errors.Is(err, ErrNotAITurn) {
+		t.Fatalf("Expected ErrNotAITurn, got %v", err)
+	}
+}
+
+// TestAIDriver_SelfPlay 测试双方都由 AI 执子时每次扫描推进一手
+func TestAIDriver_SelfPlay(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	_ = store.CreateGame("self-play", newSeatGame(t, game.AISeatBoth))
+
+	driver := NewAIDriver(store, &scriptedAI{}, time.Second)
+	for i := 0; i < 3; i++ {
+		if moved := driver.Sweep(); moved != 1 {
+			t.Fatalf("Sweep %d: expected 1 AI move, got %d", i, moved)
+		}
+	}
+
+	g, _ := store.GetGame("self-play")
+	if len(g.Moves) != 3 || g.NextPlayer != game.White {
+		t.Fatalf("Expected 3 moves with white to play, got %d moves", len(g.Moves))
+	}
+}
+
+// TestAIDriver_SelfPlayScoring 测试 AI 自我对弈双方虚手后自动确认点目结果
+func TestAIDriver_SelfPlayScoring(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	_ = store.CreateGame("self-play", newSeatGame(t, game.AISeatBoth))
+
+	driver := NewAIDriver(store, &scriptedAI{err: ai.ErrEnginePassed}, time.Second)
+	driver.Sweep()
+	driver.Sweep()
+
+	g, _ := store.GetGame("self-play")
+	if !g.GameOver || g.Status != game.GameStatusFinished {
+		t.Fatalf("Expected game to finish after two passes, got status %s", g.Status)
+	}
+}
+
+// TestAIDriver_GameChanged 测试 AI 思考期间对局被修改时放弃这一手
+func TestAIDriver_GameChanged(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	_ = store.CreateGame("ai-black", newSeatGame(t, game.AISeatBlack))
+
+	client := &scriptedAI{}
+	client.onMove = func() {
+		// 人类一方在 AI 思考期间认输
+		g, _ := store.GetGame("ai-black")
+		_ = g.Resign(game.White)
+		_ = store.UpdateGame("ai-black", g)
+	}
+	driver := NewAIDriver(store, client, time.Second)
+	if _, err := driver.Move(context.Background(), "ai-black"); !errors.Is(err, ErrGameChanged) {
+		t.Fatalf("Expected ErrGameChanged, got %v", err)
+	}
+
+	g, _ := store.GetGame("ai-black")
+	if len(g.Moves) != 0 || !g.GameOver {
+		t.Fatal("Expected the resignation to stand without an AI move")
+	}
+}
+
+// TestAIDriver_ConcurrentSave 测试读取最新状态之后、保存之前对局被修改时不会覆盖
+func TestAIDriver_ConcurrentSave(t *testing.T) {
+	resigned := newSeatGame(t, game.AISeatBlack)
+	_ = resigned.Resign(game.White)
+	store := &racingStore{InMemoryGameStore: storage.NewInMemoryGameStore(), moved: resigned}
+	_ = store.CreateGame("ai-black", newSeatGame(t, game.AISeatBlack))
+
+	driver := NewAIDriver(store, &scriptedAI{}, time.Second)
+	if _, err := driver.Move(context.Background(), "ai-black"); !errors.Is(err, ErrGameChanged) {
+		t.Fatalf("Expected ErrGameChanged, got %v", err)
+	}
+
+	g, _ := store.GetGame("ai-black")
+	if g.Status != game.GameStatusFinished || g.Result == nil || g.Result.Reason != resigned.Result.Reason || len(g.Moves) != 0 {
+		t.Fatal("Expected the concurrent resignation to stand without an AI move")
+	}
+}
+
+// TestAIDriver_Run 测试驱动启动时立即扫描并在取消后退出
+func TestAIDriver_Run(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	_ = store.CreateGame("ai-black", newSeatGame(t, game.AISeatBlack))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		NewAIDriver(store, &scriptedAI{}, time.Hour).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected driver to stop after cancel")
+	}
+
+	g, _ := store.GetGame("ai-black")
+	if len(g.Moves) != 1 {
+		t.Fatal("Expected AI to move on the first sweep")
+	}
+}
+
+// TestAIDriver_ScheduleRetry 测试后台回应在 AI 服务出错时重试
+func TestAIDriver_ScheduleRetry(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	_ = store.CreateGame("ai-black", newSeatGame(t, game.AISeatBlack))
+
+	client := &scriptedAI{err: errors.New("connection refused"), fails: 2}
+	driver := NewAIDriver(store, client, time.Hour)
+	driver.retryDelay = time.Millisecond
+	driver.Schedule("ai-black")
+	driver.wg.Wait()
+
+	g, _ := store.GetGame("ai-black")
+	if len(g.Moves) != 1 || client.calls != 3 {
+		t.Fatalf("Expected AI to move on the third attempt, got %d moves after %d calls", len(g.Moves), client.calls)
+	}
+}
+
+// TestAIDriver_ScheduleGiveUp 测试重试用尽后放弃，留给定期扫描
+func TestAIDriver_ScheduleGiveUp(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	_ = store.CreateGame("ai-black", newSeatGame(t, game.AISeatBlack))
+
+	client := &scriptedAI{err: errors.New("connection refused")}
+	driver := NewAIDriver(store, client, time.Hour)
+	driver.retries = 2
+	driver.retryDelay = time.Millisecond
+	driver.Schedule("ai-black")
+	driver.wg.Wait()
+
+	g, _ := store.GetGame("ai-black")
+	if len(g.Moves) != 0 || client.calls != 3 {
+		t.Fatalf("Expected 3 failed attempts without a move, got %d moves after %d calls", len(g.Moves), client.calls)
+	}
+
+	// 不是 AI 的回合时不请求 AI
+	_ = g.PlayMove(game.Point{X: 4, Y: 4})
+	_ = store.UpdateGame("ai-black", g)
+	driver.Schedule("ai-black")
+	driver.wg.Wait()
+	if client.calls != 3 {
+		t.Fatalf("Expected no AI request on the human's turn, got %d calls", client.calls)
+	}
+}
+
+// TestAIDriver_ScheduleUnavailable 测试 AI 服务不可用时不重复客户端已经做过的重试，留给定期扫描
+func TestAIDriver_ScheduleUnavailable(t *testing.T) {
+	for _, err := range []error{ai.ErrServiceUnavailable, ai.ErrCircuitOpen} {
+		store := storage.NewInMemoryGameStore()
+		_ = store.CreateGame("ai-black", newSeatGame(t, game.AISeatBlack))
+
+		client := &scriptedAI{err: err}
+		driver := NewAIDriver(store, client, time.Hour)
+		driver.retryDelay = time.Millisecond
+		driver.Schedule("ai-black")
+		driver.wg.Wait()
+		if client.calls != 1 {
+			t.Fatalf("%v: expected a single attempt, got %d calls", err, client.calls)
+		}
+	}
+}
+
+// blockingAI 一直思考到请求被取消
+type blockingAI struct {
+	fakeAI
+	started chan struct{}
+}
+
+func (b *blockingAI) GetMove(ctx context.Context, _ *game.Game) (game.Point, error) {
+	close(b.started)
+	<-ctx.Done()
+	return game.Point{}, ctx.Err()
+}
+
+// TestAIDriver_Stop 测试停止驱动时取消进行中的 AI 请求，不再落子或重试
+func TestAIDriver_Stop(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	_ = store.CreateGame("ai-black", newSeatGame(t, game.AISeatBlack))
+
+	client := &blockingAI{started: make(chan struct{})}
+	driver := NewAIDriver(store, client, time.Hour)
+	driver.Schedule("ai-black")
+	<-client.started
+
+	stopped := make(chan struct{})
+	go func() {
+		driver.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected Stop to cancel the pending AI request")
+	}
+
+	g, _ := store.GetGame("ai-black")
+	if len(g.Moves) != 0 {
+		t.Fatalf("Expected no AI move after Stop, got %d moves", len(g.Moves))
+	}
+	if _, err := driver.Move(context.Background(), "ai-black"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled after Stop, got %v", err)
+	}
+}
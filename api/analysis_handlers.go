@@ -0,0 +1,69 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+// 局面分析默认和最多返回的候选着法数
+const (
+	defaultAnalysisCandidates = 5
+	maxAnalysisCandidates     = 20
+)
+
+// getAnalysis 返回当前局面的胜率、目差、候选着法和归属 (GET /v1/games/:id/analysis?candidates=5)
+// 只对已结束的对局和复盘棋谱开放，避免对局中借助 AI 作弊
+func (s *Server) getAnalysis(c *gin.Context) {
+	gameID := c.Param("id")
+
+	if s.aiClient == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"error": "AI service not configured",
+		})
+		return
+	}
+
+	candidates := defaultAnalysisCandidates
+	if v := c.Query("candidates"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > maxAnalysisCandidates {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": fmt.Sprintf("candidates must be between 1 and %d", maxAnalysisCandidates),
+			})
+			return
+		}
+		candidates = n
+	}
+
+	g, err := s.store.GetGame(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "game not found",
+		})
+		return
+	}
+
+	// 复盘棋谱的 GameOver 也为 true
+	if !g.GameOver {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "analysis is only available for finished games or review",
+		})
+		return
+	}
+
+	analysis, err := s.aiClient.Analyze(c.Request.Context(), g, candidates)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": fmt.Sprintf("AI service error: %v", err),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"move_number": len(g.Moves),
+		"analysis":    analysis,
+	})
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/logger"
+	"github.com/nankp236270/weiqi-go/storage"
+)
+
+// DefaultClockCheckInterval 是后台检查超时的默认间隔
+const DefaultClockCheckInterval = 5 * time.Second
+
+// maxConflictRetries 是条件保存因对局被并发修改而失败时，重新读取再尝试的次数
+const maxConflictRetries = 3
+
+// ClockWatcher 定期扫描进行中的对局，结束轮到行棋一方已超时的对局
+// 对局状态全部从存储中读取，服务重启后第一次扫描就会处理停机期间超时的对局
+type ClockWatcher struct {
+	store    storage.GameStore
+	interval time.Duration
+}
+
+// NewClockWatcher 创建一个超时检查器
+func NewClockWatcher(store storage.GameStore, interval time.Duration) *ClockWatcher {
+	if interval <= 0 {
+		interval = DefaultClockCheckInterval
+	}
+	return &ClockWatcher{
+		store:    store,
+		interval: interval,
+	}
+}
+
+// Run 立即扫描一次，之后按间隔定期扫描，直到 ctx 被取消
+func (w *ClockWatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		w.Sweep()
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep 检查所有进行中的对局，返回因超时结束的对局数
+func (w *ClockWatcher) Sweep() int {
+	games, err := w.store.GetPlayingGames()
+	if err != nil {
+		logger.Error("failed to list playing games", "error", err)
+		return 0
+	}
+
+	ended := 0
+	for _, info := range games {
+		if w.check(info.ID) {
+			ended++
+		}
+	}
+	return ended
+}
+
+// check 检查一局是否超时，超时时保存结果并返回 true
+// 只在对局没有被其他请求修改过时保存，读取之后有人落子时重新读取再检查，
+// 避免用过期的状态覆盖刚保存的着法或结果
+func (w *ClockWatcher) check(gameID string) bool {
+	for attempt := 0; attempt <= maxConflictRetries; attempt++ {
+		g, err := w.store.GetGame(gameID)
+		if err != nil {
+			logger.Warn("failed to load game for clock check", "game_id", gameID, "error", err)
+			return false
+		}
+		// g 是存储返回的副本，超时判负只有条件保存成功后才会发布
+		version := g.Version
+		if !g.CheckTimeout() {
+			return false
+		}
+
+		err = w.store.UpdateGameIfVersion(gameID, g, version)
+		if errors.Is(err, storage.ErrVersionConflict) {
+			continue
+		}
+		if err != nil {
+			logger.Error("failed to save timed out game", "game_id", gameID, "error", err)
+			return false
+		}
+		logger.Info("game ended by timeout", "game_id", gameID, "result", g.Result.String())
+		return true
+	}
+	logger.Warn("game kept changing during clock check, leaving it to the next sweep", "game_id", gameID)
+	return false
+}
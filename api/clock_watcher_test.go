@@ -0,0 +1,135 @@
+package api
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/storage"
+)
+
+// newIdleGame 创建一个轮到黑棋、黑棋已离开 idle 秒的对局
+func newIdleGame(idle int64) *game.Game {
+	g := game.NewGame()
+	g.PlayerBlack = "black-player"
+	g.PlayerWhite = "white-player"
+	g.Status = game.GameStatusPlaying
+	g.BlackTimeLeft = 60
+	g.LastMoveTime = time.Now().Unix() - idle
+	return g
+}
+
+// TestClockWatcher_Sweep 测试后台检查结束超时的对局
+func TestClockWatcher_Sweep(t *testing.T) {
+	// 模拟服务重启：存储中已有对局，由新的检查器接管
+	store := storage.NewInMemoryGameStore()
+	_ = store.CreateGame("idle", newIdleGame(120))
+	_ = store.CreateGame("active", newIdleGame(10))
+
+	watcher := NewClockWatcher(store, time.Second)
+	if ended := watcher.Sweep(); ended != 1 {
+		t.Fatalf("Expected 1 game to end, got %d", ended)
+	}
+
+	idle, _ := store.GetGame("idle")
+	if idle.Status != game.GameStatusFinished || idle.Result == nil || idle.Result.String() != "W+T" {
+		t.Fatalf("Expected idle game to end W+T, got status %s result %v", idle.Status, idle.Result)
+	}
+
+	active, _ := store.GetGame("active")
+	if active.Status != game.GameStatusPlaying || active.BlackTimeLeft != 60 {
+		t.Fatalf("Expected active game to be untouched, got status %s time %d", active.Status, active.BlackTimeLeft)
+	}
+
+	playing, _ := store.GetPlayingGames()
+	if len(playing) != 1 {
+		t.Fatalf("Expected 1 playing game left, got %d", len(playing))
+	}
+}
+
+// TestClockWatcher_Run 测试检查器启动时立即扫描并在取消后退出
+func TestClockWatcher_Run(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	_ = store.CreateGame("idle", newIdleGame(120))
+
+	// 已取消的 ctx 下 Run 仍会先扫描一次再退出
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	done := make(chan struct{})
+	go func() {
+		NewClockWatcher(store, time.Hour).Run(ctx)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Expected watcher to stop after cancel")
+	}
+
+	g, _ := store.GetGame("idle")
+	if !g.GameOver {
+		t.Fatal("Expected idle game to end on the first sweep")
+	}
+}
+
+// racingStore 在第一次条件保存之前保存另一份对局状态，模拟检查期间有人落子
+type racingStore struct {
+	*storage.InMemoryGameStore
+	moved *game.Game
+}
+
+func (s *racingStore) UpdateGameIfVersion(gameID string, g *game.Game, version int64) error {
+	if s.moved != nil {
+		moved := s.moved
+		s.moved = nil
+		_ = s.InMemoryGameStore.UpdateGame(gameID, moved)
+	}
+	return s.InMemoryGameStore.UpdateGameIfVersion(gameID, g, version)
+}
+
+// TestClockWatcher_ConcurrentMove 测试检查期间对局被修改时不会用过期状态覆盖
+func TestClockWatcher_ConcurrentMove(t *testing.T) {
+	store := &racingStore{InMemoryGameStore: storage.NewInMemoryGameStore(), moved: newIdleGame(0)}
+	_ = store.CreateGame("racing", newIdleGame(120))
+
+	watcher := NewClockWatcher(store, time.Second)
+	if ended := watcher.Sweep(); ended != 0 {
+		t.Fatalf("Expected no game to end, got %d", ended)
+	}
+
+	g, _ := store.GetGame("racing")
+	if g.GameOver || g.Status != game.GameStatusPlaying || g.BlackTimeLeft != 60 {
+		t.Fatalf("Expected the concurrent move to be kept, got status %s result %v", g.Status, g.Result)
+	}
+	if g.Version != 1 {
+		t.Fatalf("Expected version 1 after the concurrent save, got %d", g.Version)
+	}
+}
+
+// conflictStore 的条件保存总是失败，模拟对局一直被其他请求修改
+type conflictStore struct {
+	*storage.InMemoryGameStore
+}
+
+func (s conflictStore) UpdateGameIfVersion(string, *game.Game, int64) error {
+	return storage.ErrVersionConflict
+}
+
+// TestClockWatcher_ConflictKeepsGame 测试条件保存失败时超时判负不会落到存储中的对局上
+func TestClockWatcher_ConflictKeepsGame(t *testing.T) {
+	store := conflictStore{storage.NewInMemoryGameStore()}
+	_ = store.CreateGame("busy", newIdleGame(120))
+
+	watcher := NewClockWatcher(store, time.Second)
+	if ended := watcher.Sweep(); ended != 0 {
+		t.Fatalf("Expected no game to end, got %d", ended)
+	}
+
+	g, _ := store.GetGame("busy")
+	if g.GameOver || g.Status != game.GameStatusPlaying || g.Result != nil {
+		t.Fatalf("Expected the stored game to keep playing, got status %s result %v", g.Status, g.Result)
+	}
+}
@@ -0,0 +1,40 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// previewMove 试算落子结果，不修改对局 (POST /v1/games/:id/preview)
+// 返回能否落子、将被提掉的棋子以及落子后的劫点，供客户端在落子前提示
+func (s *Server) previewMove(c *gin.Context) {
+	gameID := c.Param("id")
+
+	var moveRequest game.Point
+	if err := c.ShouldBindJSON(&moveRequest); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body, excepted format: {\"x\": number, \"y\": number}",
+		})
+		return
+	}
+
+	g, err := s.store.GetGame(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "game not found",
+		})
+		return
+	}
+
+	preview, err := g.PreviewMove(moveRequest)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, preview)
+}
@@ -0,0 +1,169 @@
+package api
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"path/filepath"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/nankp236270/weiqi-go/auth"
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/logger"
+	"github.com/nankp236270/weiqi-go/record"
+)
+
+// maxRecordSize 是上传棋谱文件的大小上限
+const maxRecordSize = 1 << 20
+
+// exportSGF 导出对局的 SGF 棋谱 (GET /v1/games/:id/sgf)
+func (s *Server) exportSGF(c *gin.Context) {
+	gameID := c.Param("id")
+
+	g, err := s.store.GetGame(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "game not found",
+		})
+		return
+	}
+
+	rec := g.Record()
+	// 本站对局没有记录棋手姓名，使用用户名；导入的棋谱保留原有信息，座位上是导入者
+	if g.Info == nil {
+		rec.Info.BlackName = s.playerName(g.PlayerBlack)
+		rec.Info.WhiteName = s.playerName(g.PlayerWhite)
+	}
+
+	// 保存过棋谱树 (复盘变化) 时连同变化图一起导出
+	sgf := record.EncodeSGF(rec)
+	if t, err := s.store.GetGameTree(gameID); err == nil && t != nil {
+		sgf = []byte(record.TreeToSGF(rec, t).SGF())
+	}
+
+	c.Header("Content-Disposition", `attachment; filename="`+gameID+`.sgf"`)
+	c.Data(http.StatusOK, "application/x-go-sgf; charset=utf-8", sgf)
+}
+
+// playerName 返回玩家在棋谱中显示的名称
+func (s *Server) playerName(playerID string) string {
+	if playerID == "" || playerID == game.AIPlayerID {
+		return playerID
+	}
+	if s.userStore != nil {
+		if u, err := s.userStore.GetUserByID(playerID); err == nil {
+			return u.Username
+		}
+	}
+	return playerID
+}
+
+// importGame 上传棋谱创建对局 (POST /v1/games/import)
+// 请求体可以直接是棋谱文本，也可以是 multipart 表单中的 file 字段
+// 支持 SGF、GIB、NGF 和 UGI/UGF，格式由 format 参数或文件扩展名指定，否则根据内容识别
+// 有结果的棋谱创建为已结束的对局，没有结果的创建为复盘状态
+func (s *Server) importGame(c *gin.Context) {
+	data, filename, err := readRecordUpload(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	format, err := record.ParseFormat(c.Query("format"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	if format == "" && filename != "" {
+		format, _ = record.ParseFormat(filepath.Ext(filename)) // 无法识别的扩展名根据内容识别
+	}
+	if format == "" {
+		if format, err = record.DetectFormat(data); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	rec, err := record.Decode(data, format)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+	g, err := rec.Game()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// 认证模式下导入者占据双方座位，只有导入者可以编辑复盘变化
+	if userID, ok := auth.GetUserID(c); ok {
+		g.PlayerBlack, g.PlayerWhite = userID, userID
+	}
+
+	gameID := uuid.New().String()
+	if err := s.store.CreateGame(gameID, g); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to create game",
+		})
+		return
+	}
+	// SGF 中的变化图保存为棋谱树，变化图有误时只导入主线
+	if format == record.FormatSGF {
+		if err := s.importGameTree(gameID, data); err != nil {
+			logger.Warn("failed to import SGF variations", "game_id", gameID, "error", err)
+		}
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"game_id": gameID,
+		"state":   newGameState(g),
+	})
+}
+
+// importGameTree 将 SGF 第一局棋谱 (含变化图、评注和标记) 保存为对局的棋谱树
+func (s *Server) importGameTree(gameID string, data []byte) error {
+	trees, err := record.ParseSGF(data)
+	if err != nil {
+		return err
+	}
+	t, err := record.TreeFromSGF(trees[0])
+	if err != nil {
+		return err
+	}
+	return s.store.SaveGameTree(gameID, t)
+}
+
+// readRecordUpload 读取上传的棋谱内容，通过表单上传时同时返回文件名
+func readRecordUpload(c *gin.Context) (data []byte, filename string, err error) {
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxRecordSize)
+	body := io.Reader(c.Request.Body)
+	if file, err := c.FormFile("file"); err == nil {
+		f, err := file.Open()
+		if err != nil {
+			return nil, "", err
+		}
+		defer f.Close()
+		body = io.LimitReader(f, maxRecordSize+1)
+		filename = file.Filename
+	}
+
+	data, err = io.ReadAll(body)
+	if err != nil || len(data) > maxRecordSize {
+		return nil, "", errors.New("record file is too large or unreadable")
+	}
+	if len(data) == 0 {
+		return nil, "", errors.New("empty record file")
+	}
+	return data, filename, nil
+}
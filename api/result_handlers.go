@@ -0,0 +1,94 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/logger"
+	"github.com/nankp236270/weiqi-go/storage"
+)
+
+// ResignRequest 认输请求
+type ResignRequest struct {
+	Player game.Player `json:"player"` // 认输的一方 (black, white)，启用认证时可省略，由登录用户的座位决定
+}
+
+// resignGame 处理认输请求 (POST /v1/games/:id/resign)
+func (s *Server) resignGame(c *gin.Context) {
+	gameID := c.Param("id")
+
+	var req ResignRequest
+	_ = c.ShouldBindJSON(&req) // 启用认证时可以没有请求体
+
+	g, err := s.store.GetGame(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "game not found",
+		})
+		return
+	}
+
+	// 未启用认证时无法识别用户，必须指明认输的一方，且不能替 AI 认输
+	if s.userStore == nil || s.jwtManager == nil {
+		if req.Player == game.Empty {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": "player is required, excepted format: {\"player\": \"black\" | \"white\"}",
+			})
+			return
+		}
+		if g.IsAIPlayer(req.Player) {
+			c.JSON(http.StatusForbidden, gin.H{
+				"error": "cannot resign for the AI",
+			})
+			return
+		}
+	}
+
+	player, ok := s.requestPlayer(c, g, req.Player)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "not a player in this game",
+		})
+		return
+	}
+	if req.Player != game.Empty && req.Player != player {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "cannot resign for the opponent",
+		})
+		return
+	}
+
+	if err := g.Resign(player); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := s.store.UpdateGame(gameID, g); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to update game state",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, newGameState(g))
+}
+
+// saveTimeout 落子或虚手因超时失败时，对局已判负结束，需要保存结果
+// 以读取对局时的版本号 version 为条件保存，对局已被其他请求修改时放弃
+func saveTimeout(store storage.GameStore, gameID string, g *game.Game, version int64, err error) {
+	if !errors.Is(err, game.ErrTimeOut) {
+		return
+	}
+	updateErr := store.UpdateGameIfVersion(gameID, g, version)
+	if errors.Is(updateErr, storage.ErrVersionConflict) {
+		logger.Warn("game changed before the timeout was saved", "game_id", gameID)
+		return
+	}
+	if updateErr != nil {
+		logger.Error("failed to save timed out game", "game_id", gameID, "error", updateErr)
+	}
+}
@@ -0,0 +1,194 @@
+package api
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/logger"
+)
+
+// scoringResponse 返回对局状态和按当前死子标记计算的得分
+func scoringResponse(c *gin.Context, g *game.Game) {
+	score, err := g.CalculateScore()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to calculate score",
+		})
+		return
+	}
+
+	message := "scoring"
+	if g.GameOver {
+		message = "game over"
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"message": message,
+		"state":   newGameState(g),
+		"score":   score,
+	})
+}
+
+// toggleDeadStones 切换棋块的死活标记 (POST /v1/games/:id/score/dead)
+func (s *Server) toggleDeadStones(c *gin.Context) {
+	gameID := c.Param("id")
+
+	var point game.Point
+	if err := c.ShouldBindJSON(&point); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body, excepted format: {\"x\": number, \"y\": number}",
+		})
+		return
+	}
+
+	g, err := s.store.GetGame(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "game not found",
+		})
+		return
+	}
+
+	// 双方都可以修改标记，只需确认是对局玩家
+	if _, ok := s.requestPlayer(c, g, game.Black); !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "not a player in this game",
+		})
+		return
+	}
+
+	if err := g.ToggleDeadStones(point); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := s.store.UpdateGame(gameID, g); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to update game state",
+		})
+		return
+	}
+
+	scoringResponse(c, g)
+}
+
+// acceptScore 确认当前死子标记 (POST /v1/games/:id/score/accept)
+func (s *Server) acceptScore(c *gin.Context) {
+	s.answerScore(c, true)
+}
+
+// rejectScore 不同意死子标记并恢复对局 (POST /v1/games/:id/score/reject)
+func (s *Server) rejectScore(c *gin.Context) {
+	s.answerScore(c, false)
+}
+
+// answerScore 回应点目阶段的死子标记
+func (s *Server) answerScore(c *gin.Context, accept bool) {
+	gameID := c.Param("id")
+
+	g, err := s.store.GetGame(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "game not found",
+		})
+		return
+	}
+
+	if g.Status != game.GameStatusScoring || g.Scoring == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": game.ErrNotScoring.Error(),
+		})
+		return
+	}
+
+	// 未登录时视为尚未确认的一方在回应，AI 对局中总是由人类一方回应
+	fallback := game.Black
+	if g.Scoring.BlackAccepted || g.IsAIPlayer(game.Black) {
+		fallback = game.White
+	}
+	player, ok := s.requestPlayer(c, g, fallback)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "not a player in this game",
+		})
+		return
+	}
+
+	aiRejected := false
+	if accept {
+		err = g.AcceptScore(player)
+		// AI 对局中由 AI 核对人类一方确认的标记，不同意时恢复对局
+		if opponent := player.Opponent(); err == nil && g.IsAIPlayer(opponent) {
+			if s.aiAcceptsMarking(c.Request.Context(), g, opponent) {
+				err = g.AcceptScore(opponent)
+			} else {
+				aiRejected = true
+				err = g.RejectScore(opponent)
+			}
+		}
+	} else {
+		err = g.RejectScore(player)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := s.store.UpdateGame(gameID, g); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to update game state",
+		})
+		return
+	}
+
+	// 不同意标记时对局恢复，可能轮到 AI 行棋
+	if aiRejected {
+		c.JSON(http.StatusOK, gin.H{
+			"message": "marking rejected by AI",
+			"state":   newGameState(g),
+		})
+		s.scheduleAI(gameID, g)
+		return
+	}
+	if !accept {
+		c.JSON(http.StatusOK, newGameState(g))
+		s.scheduleAI(gameID, g)
+		return
+	}
+	scoringResponse(c, g)
+}
+
+// aiAcceptsMarking 判断执 ai 一方的 AI 是否同意当前的死子标记
+// 标记对 AI 有利，或按标记计分的胜负与引擎的形势判断一致时同意；
+// 没有 AI 客户端或引擎出错时，只在 AI 的棋子都没有被标记为死子时同意
+func (s *Server) aiAcceptsMarking(ctx context.Context, g *game.Game, ai game.Player) bool {
+	marked, err := g.CalculateScore()
+	if err != nil {
+		return false
+	}
+	if marked.Winner == ai {
+		return true
+	}
+
+	if s.aiClient != nil {
+		// 引擎自己判断死活，不能参考待核对的标记 (降级时的内置计分会按标记计算)
+		unmarked := *g
+		unmarked.Scoring = &game.ScoringState{}
+		engine, err := s.aiClient.CalculateScore(ctx, &unmarked)
+		if err == nil {
+			return engine.Winner == marked.Winner
+		}
+		logger.Warn("AI failed to check dead stone marking", "error", err)
+	}
+	for _, p := range g.Scoring.DeadStones {
+		if g.Board.Grid[p.Y][p.X] == ai {
+			return false
+		}
+	}
+	return true
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
@@ -25,12 +26,16 @@ type Server struct {
 	userStore  user.Store       // 用户存储
 	aiClient   AIClient         // AI 服务客户端（可选）
 	jwtManager *auth.JWTManager // JWT 管理器
+	clock      *ClockWatcher    // 后台超时检查
+	driver     *AIDriver        // 后台推进 AI 回合，没有 AI 客户端时为 nil
 }
 
 // AIClient 定义 AI 客户端接口
+// ctx 被取消 (请求断开或服务停止) 时客户端放弃正在进行的请求
 type AIClient interface {
-	GetMove(g *game.Game) (game.Point, error)
-	CalculateScore(g *game.Game) (game.ScoreResult, error)
+	GetMove(ctx context.Context, g *game.Game) (game.Point, error)
+	CalculateScore(ctx context.Context, g *game.Game) (game.ScoreResult, error)
+	Analyze(ctx context.Context, g *game.Game, candidates int) (*game.Analysis, error)
 }
 
 // corsMiddleware 处理 CORS 跨域请求
@@ -64,13 +69,13 @@ func NewServerWithAI(addr string, store storage.GameStore, aiClient AIClient) *S
 func NewServerWithAuth(addr string, store storage.GameStore, userStore user.Store, aiClient AIClient, jwtManager *auth.JWTManager) *Server {
 	// 使用自定义的 Gin 实例（不使用默认中间件）
 	router := gin.New()
-	
+
 	// 添加恢复中间件
 	router.Use(gin.Recovery())
-	
+
 	// 添加 CORS 中间件
 	router.Use(corsMiddleware())
-	
+
 	// 添加结构化日志中间件
 	router.Use(logger.RequestLoggerMiddleware())
 
@@ -79,6 +84,10 @@ func NewServerWithAuth(addr string, store storage.GameStore, userStore user.Stor
 		userStore:  userStore,
 		aiClient:   aiClient,
 		jwtManager: jwtManager,
+		clock:      NewClockWatcher(store, DefaultClockCheckInterval),
+	}
+	if aiClient != nil {
+		server.driver = NewAIDriver(store, aiClient, DefaultAIDriveInterval)
 	}
 
 	// 注册路由
@@ -100,22 +109,49 @@ func NewServerWithAuth(addr string, store storage.GameStore, userStore user.Stor
 			// 公开端点
 			games.GET("/waiting", server.listWaitingGames) // 获取等待中的游戏
 			games.GET("/:id", server.getGame)
+			games.GET("/:id/sgf", server.exportSGF) // 导出 SGF 棋谱
 
 			// 需要认证的端点（可选）
 			if userStore != nil && jwtManager != nil {
 				games.POST("", auth.AuthMiddleware(jwtManager), server.createGame)
+				games.POST("/import", auth.AuthMiddleware(jwtManager), server.importGame)
 				games.POST("/:id/join", auth.AuthMiddleware(jwtManager), server.joinGame)
 				games.GET("/my", auth.AuthMiddleware(jwtManager), server.listMyGames)
 			} else {
 				games.POST("", server.createGame) // 无认证模式
+				games.POST("/import", server.importGame)
 			}
 
 			// 游戏操作端点
 			games.POST("/:id/move", server.playMove)
 			games.POST("/:id/pass", server.passTurn)
 
+			// 只有对局双方可以进行的操作，认证模式下必须登录，由 requestPlayer 确认入座
+			seated := games.Group("")
+			if userStore != nil && jwtManager != nil {
+				seated.Use(auth.AuthMiddleware(jwtManager))
+			}
+			seated.POST("/:id/undo", server.requestUndo)
+			seated.POST("/:id/undo/accept", server.acceptUndo)
+			seated.POST("/:id/undo/decline", server.declineUndo)
+			seated.POST("/:id/score/dead", server.toggleDeadStones)
+			seated.POST("/:id/score/accept", server.acceptScore)
+			seated.POST("/:id/score/reject", server.rejectScore)
+			seated.POST("/:id/resign", server.resignGame)
+
+			games.POST("/:id/preview", server.previewMove)
+
+			// 复盘变化图，编辑限于对局双方
+			games.GET("/:id/tree", server.getGameTree)
+			games.GET("/:id/tree/nodes/:node", server.getTreePosition)
+			seated.POST("/:id/tree/nodes", server.addTreeNode)
+			seated.PUT("/:id/tree/nodes/:node", server.annotateTreeNode)
+			seated.DELETE("/:id/tree/nodes/:node", server.deleteTreeNode)
+			seated.POST("/:id/tree/nodes/:node/promote", server.promoteTreeNode)
+
 			if aiClient != nil {
-				games.POST("/:id/ai-move", server.aiMove) // AI 落子端点
+				games.POST("/:id/ai-move", server.aiMove)      // AI 落子端点
+				games.GET("/:id/analysis", server.getAnalysis) // 终局或复盘的局面分析
 			}
 		}
 	}
@@ -137,12 +173,25 @@ func (s *Server) Start() error {
 		}
 	}()
 
+	// 启动后台超时检查
+	watchCtx, stopWatch := context.WithCancel(context.Background())
+	defer stopWatch()
+	go s.clock.Run(watchCtx)
+	if s.driver != nil {
+		go s.driver.Run(watchCtx)
+	}
+
 	// 等待中断信号
 	quit := make(chan os.Signal, 1)
 	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
 	<-quit // 阻塞在这里, 直到接收到一个信号
 
 	logger.Info("shutting down server...")
+	stopWatch()
+	if s.driver != nil {
+		// 放弃进行中的 AI 请求，等待后台落子退出
+		s.driver.Stop()
+	}
 
 	// 创建一个有超时的上下文
 	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
@@ -160,44 +209,52 @@ func (s *Server) Start() error {
 
 // CreateGameRequest 创建游戏请求
 type CreateGameRequest struct {
-	IsAIGame bool `json:"is_ai_game"` // 是否为人机对弈
+	IsAIGame          bool              `json:"is_ai_game"`         // 是否为人机对弈
+	BoardSize         int               `json:"board_size"`         // 棋盘大小 (9, 13, 19)，默认 19
+	Handicap          int               `json:"handicap"`           // 让子数 (2-9)，默认分先
+	HandicapPlacement string            `json:"handicap_placement"` // 让子摆放方式 (fixed, free)，默认 fixed
+	Rules             string            `json:"rules"`              // 规则 (chinese, japanese, aga, new_zealand, tromp_taylor)，默认 chinese
+	Komi              *float64          `json:"komi"`               // 自定义贴目，默认使用规则的贴目
+	TimeControl       *game.TimeControl `json:"time_control"`       // 计时方式 (absolute, byoyomi, canadian, fischer)，默认每方 1 小时包干
+	AIDifficulty      string            `json:"ai_difficulty"`      // AI 难度 (beginner, easy, medium, hard, strong)，默认 strong
+	AISeat            string            `json:"ai_seat"`            // AI 执子的一方 (black, white, both)，默认 white
 }
 
 // createGame 处理创建新游戏的请求 (POST /v1/games)
 func (s *Server) createGame(c *gin.Context) {
 	var req CreateGameRequest
 	if err := c.ShouldBindJSON(&req); err != nil {
-		// 如果没有请求体，默认创建等待玩家的游戏
-		req.IsAIGame = false
+		// 如果没有请求体，默认创建等待玩家的 19 路游戏
+		req = CreateGameRequest{}
 	}
 
 	gameID := uuid.New().String()
-	var newGame *game.Game
 
-	// 如果启用了认证，绑定创建者
+	// 如果启用了认证，绑定创建者；未登录用户创建匿名游戏（兼容模式）
+	var creatorID string
 	if s.userStore != nil && s.jwtManager != nil {
-		userID, exists := c.Get("user_id")
-		if exists {
-			newGame = game.NewGameWithPlayer(userID.(string), req.IsAIGame)
-		} else {
-			// 未登录用户创建游戏（兼容模式）
-			newGame = game.NewGame()
-			newGame.IsAIGame = req.IsAIGame
-			if req.IsAIGame {
-				newGame.PlayerWhite = "AI"
-				newGame.Status = game.GameStatusPlaying
-			}
-		}
-	} else {
-		// 未启用认证
-		newGame = game.NewGame()
-		newGame.IsAIGame = req.IsAIGame
-		if req.IsAIGame {
-			newGame.PlayerWhite = "AI"
-			newGame.Status = game.GameStatusPlaying
+		if userID, exists := c.Get("user_id"); exists {
+			creatorID = userID.(string)
 		}
 	}
 
+	newGame, err := game.NewGameWithPlayer(creatorID, req.IsAIGame, game.GameOptions{
+		BoardSize:         req.BoardSize,
+		Handicap:          req.Handicap,
+		HandicapPlacement: game.HandicapPlacement(req.HandicapPlacement),
+		Rules:             req.Rules,
+		Komi:              req.Komi,
+		TimeControl:       req.TimeControl,
+		AIDifficulty:      game.Difficulty(req.AIDifficulty),
+		AISeat:            game.AISeat(req.AISeat),
+	})
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
 	if err := s.store.CreateGame(gameID, newGame); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": "failed to create game",
@@ -207,8 +264,20 @@ func (s *Server) createGame(c *gin.Context) {
 
 	c.JSON(http.StatusCreated, gin.H{
 		"game_id": gameID,
-		"state":   newGame,
+		"state":   newGameState(newGame),
 	})
+	s.scheduleAI(gameID, newGame)
+}
+
+// gameState 是对局状态的响应，在对局字段之外附带局面分析
+type gameState struct {
+	*game.Game
+	Annotations *game.Annotations `json:"annotations"`
+}
+
+// newGameState 返回附带局面分析的对局状态
+func newGameState(g *game.Game) gameState {
+	return gameState{Game: g, Annotations: g.Annotations()}
 }
 
 // getGame 获得指定游戏的状态 (GET /v1/games/:id)
@@ -223,7 +292,7 @@ func (s *Server) getGame(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, g)
+	c.JSON(http.StatusOK, newGameState(g))
 }
 
 // playMove 处理落子请求 (POST /v1/games/:id/move)
@@ -260,8 +329,12 @@ func (s *Server) playMove(c *gin.Context) {
 		}
 		// 未登录用户可以继续（兼容模式）
 	}
+	if !s.checkHumanTurn(c, g) {
+		return
+	}
 
 	if err := g.PlayMove(moveRequest); err != nil {
+		saveTimeout(s.store, gameID, g, g.Version, err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -275,7 +348,8 @@ func (s *Server) playMove(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, g)
+	c.JSON(http.StatusOK, newGameState(g))
+	s.scheduleAI(gameID, g)
 }
 
 // passTurn 处理虚手请求 (POST /v1/games/:id/pass)
@@ -289,8 +363,12 @@ func (s *Server) passTurn(c *gin.Context) {
 		})
 		return
 	}
+	if !s.checkHumanTurn(c, g) {
+		return
+	}
 
 	if err := g.PassTurn(); err != nil {
+		saveTimeout(s.store, gameID, g, g.Version, err)
 		c.JSON(http.StatusBadRequest, gin.H{
 			"error": err.Error(),
 		})
@@ -305,24 +383,14 @@ func (s *Server) passTurn(c *gin.Context) {
 		return
 	}
 
-	// 如果游戏结束, 则返回最终得分
-	if g.GameOver {
-		score, err := g.CalculateScore()
-		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{
-				"error": "failed to calculate score",
-			})
-			return
-		}
-		c.JSON(http.StatusOK, gin.H{
-			"message": "game over",
-			"state":   g,
-			"score":   score,
-		})
+	// 连续虚手进入点目阶段, 返回按当前标记预估的得分
+	if g.Status == game.GameStatusScoring {
+		scoringResponse(c, g)
 		return
 	}
 
-	c.JSON(http.StatusOK, g)
+	c.JSON(http.StatusOK, newGameState(g))
+	s.scheduleAI(gameID, g)
 }
 
 // aiMove 处理 AI 落子请求 (POST /v1/games/:id/ai-move)
@@ -353,33 +421,54 @@ func (s *Server) aiMove(c *gin.Context) {
 		})
 		return
 	}
-
-	// 调用 AI 服务获取落子
-	move, err := s.aiClient.GetMove(g)
-	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": fmt.Sprintf("AI service error: %v", err),
+	if g.Status == game.GameStatusScoring {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": game.ErrScoringInProgress.Error(),
+		})
+		return
+	}
+	if !aiToMove(g) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": ErrNotAITurn.Error(),
 		})
 		return
 	}
 
-	// 执行落子
-	if err := g.PlayMove(move); err != nil {
+	// 与后台的 AI 落子共用同一入口，同一对局不会同时有两个 AI 请求
+	g, err = s.driver.Move(c.Request.Context(), gameID)
+	if err != nil {
+		if errors.Is(err, ErrNotAITurn) || errors.Is(err, ErrAIMoveInProgress) || errors.Is(err, ErrGameChanged) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error": fmt.Sprintf("failed to play AI move: %v", err),
 		})
 		return
 	}
 
-	// 更新游戏状态
-	if err := s.store.UpdateGame(gameID, g); err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{
-			"error": "failed to update game state",
+	c.JSON(http.StatusOK, newGameState(g))
+}
+
+// checkHumanTurn 检查人机对局中是否轮到人类一方，轮到 AI 时返回 409
+func (s *Server) checkHumanTurn(c *gin.Context, g *game.Game) bool {
+	if g.IsAIPlayer(g.NextPlayer) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "it is the AI's turn",
 		})
-		return
+		return false
 	}
+	return true
+}
 
-	c.JSON(http.StatusOK, g)
+// scheduleAI 在对局保存并返回响应之后，如果轮到 AI 行棋，在后台让 AI 回应
+// 在响应之后调用，避免后台落子与响应的序列化同时访问内存存储中的同一对局
+func (s *Server) scheduleAI(gameID string, g *game.Game) {
+	if s.driver != nil && aiToMove(g) {
+		s.driver.Schedule(gameID)
+	}
 }
 
 // joinGame 处理加入游戏请求 (POST /v1/games/:id/join)
@@ -420,7 +509,7 @@ func (s *Server) joinGame(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, g)
+	c.JSON(http.StatusOK, newGameState(g))
 }
 
 // listMyGames 获取当前用户的游戏列表 (GET /v1/games/my)
@@ -2,15 +2,32 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/nankp236270/weiqi-go/auth"
 	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/logger"
 	"github.com/nankp236270/weiqi-go/storage"
+	"github.com/nankp236270/weiqi-go/user"
 )
 
+// TestMain 初始化日志系统，请求日志中间件依赖全局 Logger
+func TestMain(m *testing.M) {
+	gin.SetMode(gin.TestMode)
+	logger.Init(logger.Config{Level: logger.LevelError})
+	os.Exit(m.Run())
+}
+
 // TestCreateGame 测试创建游戏的 API
 func TestCreateGame(t *testing.T) {
 	store := storage.NewInMemoryGameStore()
@@ -43,9 +60,9 @@ func TestCreateGame(t *testing.T) {
 	}
 
 	// 验证初始状态
-	nextPlayer := state["next_player"].(float64)
-	if int(nextPlayer) != int(game.Black) {
-		t.Fatalf("Expected next_player to be Black (1), got %v", nextPlayer)
+	nextPlayer, _ := state["next_player"].(string)
+	if nextPlayer != "Black" {
+		t.Fatalf("Expected next_player to be Black, got %v", state["next_player"])
 	}
 
 	gameOver := state["game_over"].(bool)
@@ -54,6 +71,243 @@ func TestCreateGame(t *testing.T) {
 	}
 }
 
+// TestCreateGame_BoardSize 测试创建指定大小棋盘的游戏
+func TestCreateGame_BoardSize(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"board_size": 9})
+	req, _ := http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		GameID string    `json:"game_id"`
+		State  game.Game `json:"state"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if response.State.Board.Size() != 9 {
+		t.Fatalf("Expected 9x9 board, got %d", response.State.Board.Size())
+	}
+
+	// 等待列表中应显示棋盘大小
+	games, _ := store.GetWaitingGames()
+	if len(games) != 1 || games[0].BoardSize != 9 {
+		t.Fatalf("Expected waiting game with board_size 9, got %+v", games)
+	}
+}
+
+// TestCreateGame_InvalidBoardSize 测试不支持的棋盘大小
+func TestCreateGame_InvalidBoardSize(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"board_size": 15})
+	req, _ := http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCreateGame_Handicap 测试创建让子棋
+func TestCreateGame_Handicap(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"board_size": 13, "handicap": 3})
+	req, _ := http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		State game.Game `json:"state"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response.State.Handicap != 3 || len(response.State.SetupStones) != 3 {
+		t.Fatalf("Expected 3 handicap stones, got %+v", response.State.SetupStones)
+	}
+	if response.State.NextPlayer != game.White {
+		t.Fatal("Expected White to move first")
+	}
+
+	// 非法的让子数
+	jsonData, _ = json.Marshal(map[string]interface{}{"handicap": 12})
+	req, _ = http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCreateGame_Rules 测试选择规则和贴目
+func TestCreateGame_Rules(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"rules": "aga", "komi": 5.5})
+	req, _ := http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		State game.Game `json:"state"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response.State.Rules.Name != "aga" || response.State.Rules.Komi != 5.5 {
+		t.Fatalf("Expected AGA rules with komi 5.5, got %+v", response.State.Rules)
+	}
+
+	// 未知规则
+	jsonData, _ = json.Marshal(map[string]interface{}{"rules": "ing"})
+	req, _ = http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCreateGame_AIDifficulty 测试创建指定难度的人机对局
+func TestCreateGame_AIDifficulty(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"is_ai_game": true, "ai_difficulty": "beginner"})
+	req, _ := http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		State game.Game `json:"state"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response.State.AIDifficulty != game.DifficultyBeginner {
+		t.Fatalf("Expected beginner difficulty, got %q", response.State.AIDifficulty)
+	}
+
+	// 未知难度
+	jsonData, _ = json.Marshal(map[string]interface{}{"is_ai_game": true, "ai_difficulty": "9dan"})
+	req, _ = http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCreateGame_AISeat 测试创建 AI 执黑的人机对局
+func TestCreateGame_AISeat(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	jsonData, _ := json.Marshal(map[string]interface{}{"is_ai_game": true, "ai_seat": "black"})
+	req, _ := http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response struct {
+		State game.Game `json:"state"`
+	}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response.State.PlayerBlack != game.AIPlayerID || response.State.PlayerWhite == game.AIPlayerID {
+		t.Fatalf("Expected AI to play black, got %q vs %q", response.State.PlayerBlack, response.State.PlayerWhite)
+	}
+
+	// 无效的座位
+	jsonData, _ = json.Marshal(map[string]interface{}{"is_ai_game": true, "ai_seat": "red"})
+	req, _ = http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
+// TestCreateGame_TimeControl 测试创建使用读秒计时的游戏
+func TestCreateGame_TimeControl(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	body := map[string]interface{}{
+		"time_control": map[string]interface{}{
+			"type":        "byoyomi",
+			"main_time":   600,
+			"periods":     5,
+			"period_time": 30,
+		},
+	}
+	jsonData, _ := json.Marshal(body)
+	req, _ := http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	state := response["state"].(map[string]interface{})
+	if state["black_time_left"] != float64(600) {
+		t.Fatalf("Expected 600s main time, got %v", state["black_time_left"])
+	}
+	overtime, _ := state["white_overtime"].(map[string]interface{})
+	if overtime["periods_left"] != float64(5) {
+		t.Fatalf("Expected 5 periods left, got %v", state["white_overtime"])
+	}
+
+	// 非法的计时设置
+	jsonData, _ = json.Marshal(map[string]interface{}{"time_control": map[string]interface{}{"type": "byoyomi"}})
+	req, _ = http.NewRequest("POST", "/v1/games", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w.Code)
+	}
+}
+
 // TestGetGame 测试获取游戏状态的 API
 func TestGetGame(t *testing.T) {
 	store := storage.NewInMemoryGameStore()
@@ -84,6 +338,118 @@ func TestGetGame(t *testing.T) {
 	}
 }
 
+// TestGetGame_Moves 测试游戏状态中包含有序的棋谱
+func TestGetGame_Moves(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	gameID := "test-game-moves"
+	g := game.NewGame()
+	_ = g.PlayMove(game.Point{X: 3, Y: 3})
+	_ = g.PassTurn()
+	_ = store.CreateGame(gameID, g)
+
+	req, _ := http.NewRequest("GET", "/v1/games/"+gameID, nil)
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	var response game.Game
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+
+	if len(response.Moves) != 2 {
+		t.Fatalf("Expected 2 moves, got %d", len(response.Moves))
+	}
+	if response.Moves[0].Point != (game.Point{X: 3, Y: 3}) || response.Moves[0].Player != game.Black {
+		t.Fatalf("Unexpected first move: %+v", response.Moves[0])
+	}
+	if !response.Moves[1].Pass || response.Moves[1].Player != game.White {
+		t.Fatalf("Unexpected second move: %+v", response.Moves[1])
+	}
+}
+
+// TestGetGame_Annotations 测试游戏状态中包含局面分析
+func TestGetGame_Annotations(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	gameID := "test-game-annotations"
+	g := game.NewGame()
+	g.Status = game.GameStatusPlaying
+	_ = g.PlayMove(game.Point{X: 0, Y: 0})
+	_ = g.PlayMove(game.Point{X: 1, Y: 0}) // 角上黑子被叫吃
+	_ = store.CreateGame(gameID, g)
+
+	req, _ := http.NewRequest("GET", "/v1/games/"+gameID, nil)
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	var response struct {
+		game.Game
+		Annotations game.Annotations `json:"annotations"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatalf("Failed to parse response: %v", err)
+	}
+	if len(response.Moves) != 2 {
+		t.Fatalf("Expected game fields alongside annotations, got %d moves", len(response.Moves))
+	}
+
+	a := response.Annotations
+	if a.LastMove == nil || a.LastMove.Point != (game.Point{X: 1, Y: 0}) {
+		t.Fatalf("Expected last move at (1,0), got %+v", a.LastMove)
+	}
+	if len(a.Groups) != 2 || !a.Groups[0].Atari || a.Groups[0].Player != game.Black {
+		t.Fatalf("Expected black corner stone in atari, got %+v", a.Groups)
+	}
+}
+
+// TestStateResponses_Annotations 测试虚手、认输、悔棋和加入游戏的响应与获取游戏状态相同，包含局面分析
+func TestStateResponses_Annotations(t *testing.T) {
+	hasAnnotations := func(t *testing.T, w *httptest.ResponseRecorder) {
+		t.Helper()
+		var response struct {
+			Status      game.GameStatus   `json:"status"`
+			Annotations *game.Annotations `json:"annotations"`
+		}
+		if w.Code != http.StatusOK {
+			t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil || response.Status == "" || response.Annotations == nil {
+			t.Fatalf("Expected game state with annotations, got %s", w.Body.String())
+		}
+	}
+	send := func(server *Server, path, body, token string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+	g := game.NewGame()
+	g.Status = game.GameStatusPlaying
+	_ = g.PlayMove(game.Point{X: 3, Y: 3})
+	_ = store.CreateGame("playing", g)
+
+	hasAnnotations(t, send(server, "/v1/games/playing/undo", "", ""))
+	hasAnnotations(t, send(server, "/v1/games/playing/undo/accept", "", ""))
+	hasAnnotations(t, send(server, "/v1/games/playing/pass", "", ""))
+	hasAnnotations(t, send(server, "/v1/games/playing/resign", `{"player": "black"}`, ""))
+
+	authStore := storage.NewInMemoryGameStore()
+	authServer, token := newAuthTestServer(t, authStore)
+	waiting, _ := game.NewGameWithPlayer("alice", false, game.GameOptions{})
+	_ = authStore.CreateGame("waiting", waiting)
+	hasAnnotations(t, send(authServer, "/v1/games/waiting/join", "", token("bob")))
+}
+
 // TestGetGame_NotFound 测试获取不存在的游戏
 func TestGetGame_NotFound(t *testing.T) {
 	store := storage.NewInMemoryGameStore()
@@ -216,8 +582,8 @@ func TestPassTurn(t *testing.T) {
 	}
 }
 
-// TestPassTurn_GameOver 测试连续虚手导致游戏结束
-func TestPassTurn_GameOver(t *testing.T) {
+// TestPassTurn_Scoring 测试连续虚手后进入点目阶段
+func TestPassTurn_Scoring(t *testing.T) {
 	store := storage.NewInMemoryGameStore()
 	server := NewServer(":8080", store)
 
@@ -246,74 +612,857 @@ func TestPassTurn_GameOver(t *testing.T) {
 		t.Fatalf("Failed to parse response: %v", err)
 	}
 
-	// 验证游戏结束
+	// 验证进入点目阶段而不是直接终局
 	message, ok := response["message"].(string)
-	if !ok || message != "game over" {
-		t.Fatal("Expected 'game over' message")
+	if !ok || message != "scoring" {
+		t.Fatalf("Expected 'scoring' message, got %v", response["message"])
+	}
+	state, _ := response["state"].(map[string]interface{})
+	if state["status"] != string(game.GameStatusScoring) || state["game_over"] != false {
+		t.Fatalf("Expected scoring status, got %v", state["status"])
 	}
 
-	// 验证包含计分结果
+	// 验证包含预估计分结果
 	_, hasScore := response["score"]
 	if !hasScore {
 		t.Fatal("Expected score in response")
 	}
 }
 
-// TestCompleteGameFlow 测试完整的游戏流程
-func TestCompleteGameFlow(t *testing.T) {
+// TestScoring_AcceptAndReject 测试点目阶段的标记、确认和恢复对局
+func TestScoring_AcceptAndReject(t *testing.T) {
 	store := storage.NewInMemoryGameStore()
 	server := NewServer(":8080", store)
 
-	// 1. 创建游戏
-	req, _ := http.NewRequest("POST", "/v1/games", nil)
-	w := httptest.NewRecorder()
-	server.httpServer.Handler.ServeHTTP(w, req)
+	gameID := "test-game-scoring"
+	g := game.NewGame()
+	g.Status = game.GameStatusPlaying
+	_ = g.PlayMove(game.Point{X: 3, Y: 3})
+	_ = g.PlayMove(game.Point{X: 15, Y: 15})
+	_ = g.PassTurn()
+	_ = g.PassTurn()
+	_ = store.CreateGame(gameID, g)
 
-	var createResponse map[string]interface{}
-	json.Unmarshal(w.Body.Bytes(), &createResponse)
-	gameID := createResponse["game_id"].(string)
+	post := func(path string, body interface{}) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/v1/games/"+gameID+path, bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(w, req)
+		return w
+	}
 
-	// 2. 黑棋落子
-	move1 := map[string]int{"x": 3, "y": 3}
-	jsonData1, _ := json.Marshal(move1)
-	req1, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/move", bytes.NewBuffer(jsonData1))
-	req1.Header.Set("Content-Type", "application/json")
-	w1 := httptest.NewRecorder()
-	server.httpServer.Handler.ServeHTTP(w1, req1)
+	// 标记白子为死子
+	if w := post("/score/dead", map[string]int{"x": 15, "y": 15}); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
 
-	if w1.Code != http.StatusOK {
-		t.Fatalf("Black move failed: %s", w1.Body.String())
+	// 不同意标记，恢复对局
+	if w := post("/score/reject", nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	stored, _ := store.GetGame(gameID)
+	if stored.Status != game.GameStatusPlaying || stored.Scoring != nil {
+		t.Fatalf("Expected play to resume, got status %s", stored.Status)
 	}
 
-	// 3. 白棋落子
-	move2 := map[string]int{"x": 3, "y": 4}
-	jsonData2, _ := json.Marshal(move2)
-	req2, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/move", bytes.NewBuffer(jsonData2))
-	req2.Header.Set("Content-Type", "application/json")
-	w2 := httptest.NewRecorder()
-	server.httpServer.Handler.ServeHTTP(w2, req2)
+	// 再次连续虚手，双方确认后终局
+	_ = post("/pass", nil)
+	_ = post("/pass", nil)
+	_ = post("/score/dead", map[string]int{"x": 15, "y": 15})
+	_ = post("/score/accept", nil)
+	w := post("/score/accept", nil)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
 
-	if w2.Code != http.StatusOK {
-		t.Fatalf("White move failed: %s", w2.Body.String())
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response["message"] != "game over" {
+		t.Fatalf("Expected 'game over' message, got %v", response["message"])
+	}
+	score, _ := response["score"].(map[string]interface{})
+	if score["winner"] != "Black" {
+		t.Fatalf("Expected Black to win after marking White's stone dead, got %v", score["winner"])
 	}
+}
 
-	// 4. 获取游戏状态
-	req3, _ := http.NewRequest("GET", "/v1/games/"+gameID, nil)
-	w3 := httptest.NewRecorder()
-	server.httpServer.Handler.ServeHTTP(w3, req3)
+// TestScoring_AIChecksMarking 测试 AI 对局中 AI 只同意与自己判断一致的死子标记
+func TestScoring_AIChecksMarking(t *testing.T) {
+	newScoringGame := func() *game.Game {
+		g, _ := game.NewGameWithPlayer("human", true, game.GameOptions{})
+		_ = g.PlayMove(game.Point{X: 3, Y: 3})
+		_ = g.PlayMove(game.Point{X: 15, Y: 15})
+		_ = g.PassTurn()
+		_ = g.PassTurn()
+		return g
+	}
+	newPost := func(server *Server, gameID string) func(path string, body interface{}) *httptest.ResponseRecorder {
+		return func(path string, body interface{}) *httptest.ResponseRecorder {
+			jsonData, _ := json.Marshal(body)
+			req, _ := http.NewRequest("POST", "/v1/games/"+gameID+path, bytes.NewBuffer(jsonData))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			server.httpServer.Handler.ServeHTTP(w, req)
+			return w
+		}
+	}
 
-	var finalState game.Game
-	json.Unmarshal(w3.Body.Bytes(), &finalState)
+	// 没有 AI 客户端时，AI 不同意把自己的棋子标记为死子
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+	_ = store.CreateGame("no-engine", newScoringGame())
+	post := newPost(server, "no-engine")
+	_ = post("/score/dead", map[string]int{"x": 15, "y": 15})
+	if w := post("/score/accept", nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	stored, _ := store.GetGame("no-engine")
+	if stored.GameOver || stored.Status != game.GameStatusPlaying {
+		t.Fatalf("Expected AI to reject the marking and resume play, got status %s", stored.Status)
+	}
 
-	// 验证棋盘状态
-	if finalState.Board.Grid[3][3] != game.Black {
-		t.Fatal("Expected black stone at (3,3)")
+	// 标记对 AI 有利时同意
+	_ = store.CreateGame("favourable", newScoringGame())
+	post = newPost(server, "favourable")
+	_ = post("/score/dead", map[string]int{"x": 3, "y": 3})
+	if w := post("/score/accept", nil); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
 	}
-	if finalState.Board.Grid[3][4] != game.White {
-		t.Fatal("Expected white stone at (3,4)")
+	stored, _ = store.GetGame("favourable")
+	if !stored.GameOver || stored.Result.Winner != game.White {
+		t.Fatalf("Expected AI to accept a marking in its favour, got status %s", stored.Status)
 	}
-	if finalState.NextPlayer != game.Black {
-		t.Fatal("Expected next player to be Black")
+
+	// 有 AI 客户端时按引擎的形势判断核对
+	aiStore := storage.NewInMemoryGameStore()
+	engine := &fakeAI{score: game.ScoreResult{Winner: game.Black}}
+	aiServer := NewServerWithAI(":8080", aiStore, engine)
+	_ = aiStore.CreateGame("engine-agrees", newScoringGame())
+	post = newPost(aiServer, "engine-agrees")
+	_ = post("/score/dead", map[string]int{"x": 15, "y": 15})
+	_ = post("/score/accept", nil)
+	stored, _ = aiStore.GetGame("engine-agrees")
+	if !stored.GameOver || stored.Result.Winner != game.Black {
+		t.Fatalf("Expected AI to accept a marking the engine agrees with, got status %s", stored.Status)
 	}
-}
 
+	engine.score = game.ScoreResult{Winner: game.White}
+	_ = aiStore.CreateGame("engine-disagrees", newScoringGame())
+	post = newPost(aiServer, "engine-disagrees")
+	_ = post("/score/dead", map[string]int{"x": 15, "y": 15})
+	w := post("/score/accept", nil)
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response["message"] != "marking rejected by AI" {
+		t.Fatalf("Expected AI to reject the marking, got %s", w.Body.String())
+	}
+	stored, _ = aiStore.GetGame("engine-disagrees")
+	if stored.GameOver || stored.Scoring != nil {
+		t.Fatalf("Expected play to resume, got status %s", stored.Status)
+	}
+}
+
+// TestResign 测试认输并记录对局结果
+func TestResign(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	gameID := "test-game-resign"
+	g := game.NewGame()
+	g.Status = game.GameStatusPlaying
+	_ = g.PlayMove(game.Point{X: 3, Y: 3})
+	_ = store.CreateGame(gameID, g)
+
+	resign := func(body interface{}) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/resign", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未登录时必须指明认输的一方
+	if w := resign(nil); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d without player, got %d", http.StatusBadRequest, w.Code)
+	}
+
+	w := resign(map[string]string{"player": "white"})
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	result, _ := response["result"].(map[string]interface{})
+	if result["notation"] != "B+R" || result["reason"] != "resign" {
+		t.Fatalf("Expected B+R resign result, got %v", response["result"])
+	}
+
+	// 已结束的对局不能再认输
+	if w2 := resign(map[string]string{"player": "black"}); w2.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w2.Code)
+	}
+}
+
+// TestResign_Seat 测试不能替 AI 或对手认输
+func TestResign_Seat(t *testing.T) {
+	resign := func(server *Server, gameID string, body interface{}, token string) *httptest.ResponseRecorder {
+		jsonData, _ := json.Marshal(body)
+		req, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/resign", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 未启用认证时不能替 AI 认输
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+	aiGame, _ := game.NewGameWithPlayer("human", true, game.GameOptions{})
+	_ = store.CreateGame("ai-game", aiGame)
+	if w := resign(server, "ai-game", map[string]string{"player": "white"}, ""); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d when resigning for the AI, got %d", http.StatusForbidden, w.Code)
+	}
+
+	// 启用认证时只能由入座的玩家为自己认输
+	authStore := storage.NewInMemoryGameStore()
+	authServer, token := newAuthTestServer(t, authStore)
+	g, _ := game.NewGameWithPlayer("alice", false, game.GameOptions{})
+	_ = g.JoinGame("bob")
+	_ = authStore.CreateGame("pvp", g)
+
+	if w := resign(authServer, "pvp", nil, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d for anonymous resign, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := resign(authServer, "pvp", nil, token("mallory")); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d for spectator resign, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := resign(authServer, "pvp", map[string]string{"player": "white"}, token("alice")); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d when resigning for the opponent, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := resign(authServer, "pvp", nil, token("bob")); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+	stored, _ := authStore.GetGame("pvp")
+	if stored.Result == nil || stored.Result.Winner != game.Black {
+		t.Fatalf("Expected Black to win after White resigned, got %v", stored.Result)
+	}
+}
+
+// TestUndo_RequestAndAccept 测试悔棋请求和同意流程
+func TestUndo_RequestAndAccept(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	gameID := "test-game-undo"
+	g := game.NewGame()
+	g.Status = game.GameStatusPlaying
+	_ = g.PlayMove(game.Point{X: 3, Y: 3})
+	_ = store.CreateGame(gameID, g)
+
+	// 发起悔棋
+	jsonData, _ := json.Marshal(map[string]int{"count": 1})
+	req, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/undo", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var response game.Game
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	if response.PendingUndo == nil || response.PendingUndo.RequestedBy != game.Black {
+		t.Fatal("Expected pending undo requested by Black")
+	}
+
+	// 对手同意
+	req2, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/undo/accept", nil)
+	w2 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w2, req2)
+
+	if w2.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w2.Code, w2.Body.String())
+	}
+
+	var accepted game.Game
+	_ = json.Unmarshal(w2.Body.Bytes(), &accepted)
+	if len(accepted.Moves) != 0 || accepted.NextPlayer != game.Black || accepted.Board.Grid[3][3] != game.Empty {
+		t.Fatal("Expected the move to be taken back")
+	}
+}
+
+// stubUserStore 满足 user.Store 接口，测试中只需要开启认证模式
+type stubUserStore struct {
+	user.Store
+}
+
+// newAuthTestServer 创建启用认证的服务器，返回按用户 ID 签发令牌的函数
+func newAuthTestServer(t *testing.T, store storage.GameStore) (*Server, func(userID string) string) {
+	t.Helper()
+	jwtManager := auth.NewJWTManager("test-secret", time.Hour)
+	server := NewServerWithAuth(":8080", store, stubUserStore{}, nil, jwtManager)
+	token := func(userID string) string {
+		tok, err := jwtManager.GenerateToken(userID, userID)
+		if err != nil {
+			t.Fatalf("Failed to generate token: %v", err)
+		}
+		return tok
+	}
+	return server, token
+}
+
+// TestUndo_AuthRequired 测试认证模式下悔棋需要登录且只能由对局玩家操作
+func TestUndo_AuthRequired(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server, token := newAuthTestServer(t, store)
+
+	gameID := "test-game-undo-auth"
+	g, _ := game.NewGameWithPlayer("alice", false, game.GameOptions{})
+	_ = g.JoinGame("bob")
+	_ = g.PlayMove(game.Point{X: 3, Y: 3})
+	_ = store.CreateGame(gameID, g)
+
+	post := func(path, userID string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest("POST", "/v1/games/"+gameID+path, nil)
+		if userID != "" {
+			req.Header.Set("Authorization", "Bearer "+token(userID))
+		}
+		w := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := post("/undo", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d for anonymous undo, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := post("/undo", "alice"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	// 匿名用户和旁观者都不能替对手回应
+	if w := post("/undo/accept", ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d for anonymous accept, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := post("/undo/accept", "mallory"); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d for spectator accept, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := post("/undo/accept", "alice"); w.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d when answering own request, got %d", http.StatusBadRequest, w.Code)
+	}
+	if w := post("/undo/accept", "bob"); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	stored, _ := store.GetGame(gameID)
+	if len(stored.Moves) != 0 {
+		t.Fatalf("Expected the move to be taken back, got %d moves", len(stored.Moves))
+	}
+}
+
+// TestUndo_AIGameAutoAccept 测试 AI 对局中悔棋自动被同意
+func TestUndo_AIGameAutoAccept(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	gameID := "test-game-undo-ai"
+	g, _ := game.NewGameWithPlayer("human", true, game.GameOptions{})
+	_ = g.PlayMove(game.Point{X: 3, Y: 3})
+	_ = g.PlayMove(game.Point{X: 15, Y: 15})
+	_ = store.CreateGame(gameID, g)
+
+	jsonData, _ := json.Marshal(map[string]int{"count": 2})
+	req, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/undo", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	stored, _ := store.GetGame(gameID)
+	if len(stored.Moves) != 0 || stored.PendingUndo != nil {
+		t.Fatalf("Expected AI to accept the undo, got %d moves", len(stored.Moves))
+	}
+}
+
+// TestPlayMove_AIReply 测试人类落子保存后服务端在后台让 AI 回应
+func TestPlayMove_AIReply(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServerWithAI(":8080", store, &scriptedAI{})
+
+	g, _ := game.NewGameWithPlayer("human", true, game.GameOptions{BoardSize: 9})
+	_ = store.CreateGame("ai-game", g)
+
+	jsonData, _ := json.Marshal(map[string]int{"x": 4, "y": 4})
+	req, _ := http.NewRequest("POST", "/v1/games/ai-game/move", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	server.driver.wg.Wait()
+	stored, _ := store.GetGame("ai-game")
+	if len(stored.Moves) != 2 || stored.Moves[1].Player != game.White || stored.NextPlayer != game.Black {
+		t.Fatalf("Expected AI to reply as white, got %d moves", len(stored.Moves))
+	}
+
+	// 轮到人类时不能调用 ai-move
+	req, _ = http.NewRequest("POST", "/v1/games/ai-game/ai-move", nil)
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+}
+
+// TestPlayMove_AITurn 测试轮到 AI 时人类不能代替 AI 落子或虚手
+func TestPlayMove_AITurn(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	g, _ := game.NewGameWithPlayer("human", true, game.GameOptions{BoardSize: 9, AISeat: game.AISeatBlack})
+	_ = store.CreateGame("ai-game", g)
+
+	jsonData, _ := json.Marshal(map[string]int{"x": 4, "y": 4})
+	req, _ := http.NewRequest("POST", "/v1/games/ai-game/move", bytes.NewBuffer(jsonData))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	req, _ = http.NewRequest("POST", "/v1/games/ai-game/pass", nil)
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusConflict, w.Code, w.Body.String())
+	}
+
+	if len(g.Moves) != 0 {
+		t.Fatal("Expected no move to be played for the AI")
+	}
+}
+
+// TestAIMove 测试 ai-move 只在轮到 AI 时落子
+func TestAIMove(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServerWithAI(":8080", store, &scriptedAI{})
+
+	g, _ := game.NewGameWithPlayer("human", true, game.GameOptions{BoardSize: 9, AISeat: game.AISeatBlack})
+	_ = store.CreateGame("ai-game", g)
+
+	req, _ := http.NewRequest("POST", "/v1/games/ai-game/ai-move", nil)
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w.Code, w.Body.String())
+	}
+
+	var state game.Game
+	_ = json.Unmarshal(w.Body.Bytes(), &state)
+	if len(state.Moves) != 1 || state.NextPlayer != game.White {
+		t.Fatalf("Expected AI to open as black, got %d moves", len(state.Moves))
+	}
+
+	// 再次调用时已轮到人类
+	req, _ = http.NewRequest("POST", "/v1/games/ai-game/ai-move", nil)
+	w = httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d", http.StatusConflict, w.Code)
+	}
+}
+
+// TestCompleteGameFlow 测试完整的游戏流程
+func TestCompleteGameFlow(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	// 1. 创建游戏
+	req, _ := http.NewRequest("POST", "/v1/games", nil)
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	var createResponse map[string]interface{}
+	json.Unmarshal(w.Body.Bytes(), &createResponse)
+	gameID := createResponse["game_id"].(string)
+
+	// 2. 黑棋落子
+	move1 := map[string]int{"x": 3, "y": 3}
+	jsonData1, _ := json.Marshal(move1)
+	req1, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/move", bytes.NewBuffer(jsonData1))
+	req1.Header.Set("Content-Type", "application/json")
+	w1 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w1, req1)
+
+	if w1.Code != http.StatusOK {
+		t.Fatalf("Black move failed: %s", w1.Body.String())
+	}
+
+	// 3. 白棋落子
+	move2 := map[string]int{"x": 3, "y": 4}
+	jsonData2, _ := json.Marshal(move2)
+	req2, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/move", bytes.NewBuffer(jsonData2))
+	req2.Header.Set("Content-Type", "application/json")
+	w2 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w2, req2)
+
+	if w2.Code != http.StatusOK {
+		t.Fatalf("White move failed: %s", w2.Body.String())
+	}
+
+	// 4. 获取游戏状态
+	req3, _ := http.NewRequest("GET", "/v1/games/"+gameID, nil)
+	w3 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w3, req3)
+
+	var finalState game.Game
+	json.Unmarshal(w3.Body.Bytes(), &finalState)
+
+	// 验证棋盘状态
+	if finalState.Board.Grid[3][3] != game.Black {
+		t.Fatal("Expected black stone at (3,3)")
+	}
+	if finalState.Board.Grid[3][4] != game.White {
+		t.Fatal("Expected white stone at (3,4)")
+	}
+	if finalState.NextPlayer != game.Black {
+		t.Fatal("Expected next player to be Black")
+	}
+}
+
+// TestPreviewMove 测试试算落子结果且不修改对局
+func TestPreviewMove(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	gameID := "test-game-preview"
+	g := game.NewGame()
+	g.Status = game.GameStatusPlaying
+	_ = g.PlayMove(game.Point{X: 3, Y: 3})
+	_ = store.CreateGame(gameID, g)
+
+	preview := func(p game.Point) (int, map[string]interface{}) {
+		jsonData, _ := json.Marshal(p)
+		req, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/preview", bytes.NewBuffer(jsonData))
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(w, req)
+
+		var response map[string]interface{}
+		_ = json.Unmarshal(w.Body.Bytes(), &response)
+		return w.Code, response
+	}
+
+	code, response := preview(game.Point{X: 4, Y: 4})
+	if code != http.StatusOK || response["legal"] != true {
+		t.Fatalf("Expected legal preview, got %d %v", code, response)
+	}
+
+	code, response = preview(game.Point{X: 3, Y: 3})
+	if code != http.StatusOK || response["legal"] != false || response["reason"] != game.ErrPointNotEmpty.Error() {
+		t.Fatalf("Expected occupied point to be illegal, got %d %v", code, response)
+	}
+
+	stored, _ := store.GetGame(gameID)
+	if len(stored.Moves) != 1 {
+		t.Fatalf("Expected preview not to change the game, got %d moves", len(stored.Moves))
+	}
+}
+
+// TestImportAndExportSGF 测试上传 SGF 棋谱创建对局并导出
+func TestImportAndExportSGF(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	sgf := `(;FF[4]GM[1]SZ[9]KM[7.5]PB[Alice]PW[Bob]RE[B+R];B[ee]C[center];W[cc](;B[gg])(;B[gc]))`
+	req, _ := http.NewRequest("POST", "/v1/games/import", bytes.NewBufferString(sgf))
+	req.Header.Set("Content-Type", "application/x-go-sgf")
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	gameID, _ := response["game_id"].(string)
+
+	g, err := store.GetGame(gameID)
+	if err != nil {
+		t.Fatalf("Expected imported game to be stored, got %v", err)
+	}
+	if g.Status != game.GameStatusFinished || len(g.Moves) != 3 || g.Result.String() != "B+R" {
+		t.Fatalf("Unexpected imported game: status %s, %d moves", g.Status, len(g.Moves))
+	}
+
+	req2, _ := http.NewRequest("GET", "/v1/games/"+gameID+"/sgf", nil)
+	w2 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w2, req2)
+	if w2.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d", http.StatusOK, w2.Code)
+	}
+	body := w2.Body.String()
+	for _, want := range []string{"PB[Alice]", "RE[B+R]", ";B[ee]C[center]", ";B[gg]"} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("Expected %q in exported SGF:\n%s", want, body)
+		}
+	}
+
+	// 非法的棋谱
+	req3, _ := http.NewRequest("POST", "/v1/games/import", bytes.NewBufferString("not a record"))
+	w3 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w3, req3)
+	if w3.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w3.Code)
+	}
+}
+
+// TestImportGame_GIB 测试通过表单上传 GIB 棋谱并自动识别格式
+func TestImportGame_GIB(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	gib := "\\HS\n\\[GAMEBLACKNAME=黑方 (5D)\\]\n\\[GAMEWHITENAME=白方 (6D)\\]\n" +
+		"\\[GAMEINFOMAIN=GRLT:3,ZIPSU:0,GONGJE:65\\]\n\\HE\n\\GS\nINI 0 1 0 &4\nSTO 0 2 1 15 3\n\\GE\n"
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, _ := form.CreateFormFile("file", "game.gib")
+	_, _ = part.Write([]byte(gib))
+	_ = form.Close()
+
+	req, _ := http.NewRequest("POST", "/v1/games/import", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var response map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &response)
+	g, _ := store.GetGame(response["game_id"].(string))
+	if g == nil || g.Info.BlackName != "黑方" || g.Result.String() != "B+R" || len(g.Moves) != 1 {
+		t.Fatalf("Unexpected imported game: %+v", g)
+	}
+
+	// 指定了无法识别的格式
+	req2, _ := http.NewRequest("POST", "/v1/games/import?format=doc", bytes.NewBufferString(gib))
+	w2 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w2, req2)
+	if w2.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w2.Code)
+	}
+}
+
+// TestGameTree 测试导入带变化图的棋谱后编辑变化、提升主线并导出
+func TestGameTree(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server := NewServer(":8080", store)
+
+	sgf := `(;FF[4]GM[1]SZ[9]RE[W+R];B[ee];W[cc](;B[gg])(;B[gc]C[variation]))`
+	req, _ := http.NewRequest("POST", "/v1/games/import", bytes.NewBufferString(sgf))
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var imported map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &imported)
+	gameID, _ := imported["game_id"].(string)
+
+	tree, err := store.GetGameTree(gameID)
+	if err != nil || tree == nil || len(tree.Nodes) != 5 {
+		t.Fatalf("Expected imported variations to be saved, got %v, %v", tree, err)
+	}
+
+	// 在第一手后加入一个变化，没有指定颜色时由白方落子
+	body := `{"parent": 1, "move": {"x": 2, "y": 6}, "comment": "new idea"}`
+	req2, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/tree/nodes", bytes.NewBufferString(body))
+	req2.Header.Set("Content-Type", "application/json")
+	w2 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w2, req2)
+	if w2.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w2.Code, w2.Body.String())
+	}
+	var added struct {
+		Node struct {
+			ID   int `json:"id"`
+			Move struct {
+				Player game.Player `json:"player"`
+			} `json:"move"`
+		} `json:"node"`
+	}
+	_ = json.Unmarshal(w2.Body.Bytes(), &added)
+	if added.Node.Move.Player != game.White {
+		t.Fatalf("Expected the move to be played by white, got %v", added.Node.Move.Player)
+	}
+
+	// 非法的变化
+	req3, _ := http.NewRequest("POST", "/v1/games/"+gameID+"/tree/nodes", bytes.NewBufferString(`{"parent": 1, "move": {"x": 4, "y": 4}}`))
+	req3.Header.Set("Content-Type", "application/json")
+	w3 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w3, req3)
+	if w3.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d, got %d", http.StatusBadRequest, w3.Code)
+	}
+
+	nodeURL := "/v1/games/" + gameID + "/tree/nodes/" + strconv.Itoa(added.Node.ID)
+	req4, _ := http.NewRequest("POST", nodeURL+"/promote", nil)
+	w4 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w4, req4)
+	if w4.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w4.Code, w4.Body.String())
+	}
+
+	req5, _ := http.NewRequest("GET", "/v1/games/"+gameID+"/sgf", nil)
+	w5 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w5, req5)
+	exported := w5.Body.String()
+	for _, want := range []string{"(;W[cg]C[new idea])", "(;W[cc]", "C[variation]"} {
+		if !strings.Contains(exported, want) {
+			t.Fatalf("Expected %q in exported SGF:\n%s", want, exported)
+		}
+	}
+
+	req6, _ := http.NewRequest("GET", nodeURL, nil)
+	w6 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w6, req6)
+	if w6.Code != http.StatusOK || !strings.Contains(w6.Body.String(), `"next_player":"Black"`) {
+		t.Fatalf("Expected node position, got %d. Body: %s", w6.Code, w6.Body.String())
+	}
+
+	// 进行中的对局不能编辑变化
+	playing := game.NewGame()
+	_ = store.CreateGame("playing", playing)
+	req7, _ := http.NewRequest("DELETE", "/v1/games/playing/tree/nodes/1", nil)
+	w7 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w7, req7)
+	if w7.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d, got %d", http.StatusConflict, w7.Code)
+	}
+}
+
+// TestGameTree_AuthRequired 测试认证模式下只有对局双方可以编辑复盘变化
+func TestGameTree_AuthRequired(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	server, token := newAuthTestServer(t, store)
+
+	send := func(method, path, body, userID string) *httptest.ResponseRecorder {
+		req, _ := http.NewRequest(method, path, bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		if userID != "" {
+			req.Header.Set("Authorization", "Bearer "+token(userID))
+		}
+		w := httptest.NewRecorder()
+		server.httpServer.Handler.ServeHTTP(w, req)
+		return w
+	}
+
+	// 导入者占据双方座位
+	w := send("POST", "/v1/games/import", `(;FF[4]GM[1]SZ[9]RE[W+R];B[ee];W[cc])`, "alice")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+	var imported map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &imported)
+	gameID, _ := imported["game_id"].(string)
+
+	path := "/v1/games/" + gameID + "/tree/nodes"
+	body := `{"parent": 1, "move": {"x": 2, "y": 6}}`
+	if w := send("POST", path, body, ""); w.Code != http.StatusUnauthorized {
+		t.Fatalf("Expected status %d for anonymous edit, got %d", http.StatusUnauthorized, w.Code)
+	}
+	if w := send("POST", path, body, "mallory"); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d for spectator edit, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := send("DELETE", path+"/1", "", "mallory"); w.Code != http.StatusForbidden {
+		t.Fatalf("Expected status %d for spectator delete, got %d", http.StatusForbidden, w.Code)
+	}
+	if w := send("POST", path, body, "alice"); w.Code != http.StatusCreated {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusCreated, w.Code, w.Body.String())
+	}
+
+	// 任何人都可以查看变化图
+	if w := send("GET", "/v1/games/"+gameID+"/tree", "", ""); w.Code != http.StatusOK {
+		t.Fatalf("Expected status %d for anonymous view, got %d", http.StatusOK, w.Code)
+	}
+}
+
+// fakeAI 是测试用的 AI 客户端，只支持局面分析和形势判断
+type fakeAI struct {
+	candidates int              // 最近一次分析请求的候选着法数
+	score      game.ScoreResult // CalculateScore 返回的形势判断
+}
+
+func (f *fakeAI) GetMove(context.Context, *game.Game) (game.Point, error) {
+	return game.Point{}, nil
+}
+
+func (f *fakeAI) CalculateScore(context.Context, *game.Game) (game.ScoreResult, error) {
+	return f.score, nil
+}
+
+func (f *fakeAI) Analyze(_ context.Context, g *game.Game, candidates int) (*game.Analysis, error) {
+	f.candidates = candidates
+	return &game.Analysis{Winrate: 0.7, ScoreLead: 5, Candidates: []game.Candidate{{Point: game.Point{X: 2, Y: 2}}}}, nil
+}
+
+// TestGetAnalysis 测试局面分析只对已结束的对局开放
+func TestGetAnalysis(t *testing.T) {
+	store := storage.NewInMemoryGameStore()
+	ai := &fakeAI{}
+	server := NewServerWithAI(":8080", store, ai)
+
+	playing := game.NewGame()
+	store.CreateGame("playing", playing)
+	req, _ := http.NewRequest("GET", "/v1/games/playing/analysis", nil)
+	w := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w, req)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("Expected status %d for a game in progress, got %d", http.StatusConflict, w.Code)
+	}
+
+	finished := game.NewGame()
+	finished.Status = game.GameStatusPlaying
+	finished.PlayMove(game.Point{X: 3, Y: 3})
+	if err := finished.Resign(game.White); err != nil {
+		t.Fatal(err)
+	}
+	store.CreateGame("finished", finished)
+
+	req2, _ := http.NewRequest("GET", "/v1/games/finished/analysis?candidates=50", nil)
+	w2 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w2, req2)
+	if w2.Code != http.StatusBadRequest {
+		t.Fatalf("Expected status %d for too many candidates, got %d", http.StatusBadRequest, w2.Code)
+	}
+
+	req3, _ := http.NewRequest("GET", "/v1/games/finished/analysis?candidates=3", nil)
+	w3 := httptest.NewRecorder()
+	server.httpServer.Handler.ServeHTTP(w3, req3)
+	if w3.Code != http.StatusOK {
+		t.Fatalf("Expected status %d, got %d. Body: %s", http.StatusOK, w3.Code, w3.Body.String())
+	}
+	var response struct {
+		MoveNumber int           `json:"move_number"`
+		Analysis   game.Analysis `json:"analysis"`
+	}
+	_ = json.Unmarshal(w3.Body.Bytes(), &response)
+	if ai.candidates != 3 || response.MoveNumber != 1 || response.Analysis.Winrate != 0.7 || len(response.Analysis.Candidates) != 1 {
+		t.Fatalf("Unexpected analysis response %s", w3.Body.String())
+	}
+}
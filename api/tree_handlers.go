@@ -0,0 +1,283 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/gametree"
+)
+
+// treeNodeRequest 是添加变化节点的请求体，move 和 setup 至少有一个，都为空时添加只有评注的节点
+type treeNodeRequest struct {
+	Parent  gametree.NodeID  `json:"parent"`
+	Move    *treeMoveRequest `json:"move"`
+	Setup   *gametree.Setup  `json:"setup"`
+	Comment string           `json:"comment"`
+}
+
+// treeMoveRequest 是变化中的一手棋，player 为空时由父节点局面的行棋方落子
+type treeMoveRequest struct {
+	Player game.Player `json:"player"`
+	Pass   bool        `json:"pass"`
+	X      int         `json:"x"`
+	Y      int         `json:"y"`
+}
+
+// treeAnnotateRequest 是修改节点评注和标记的请求体
+type treeAnnotateRequest struct {
+	Comment *string           `json:"comment"`
+	Markup  []gametree.Markup `json:"markup"`
+}
+
+// loadGameTree 读取对局和棋谱树，还没有保存过棋谱树时按对局棋谱生成
+// 读取失败时已写入错误响应，返回 ok 为 false
+func (s *Server) loadGameTree(c *gin.Context) (g *game.Game, t *gametree.Tree, ok bool) {
+	gameID := c.Param("id")
+
+	g, err := s.store.GetGame(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "game not found",
+		})
+		return nil, nil, false
+	}
+	t, err = s.store.GetGameTree(gameID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to load game tree",
+		})
+		return nil, nil, false
+	}
+	if t == nil {
+		t = gametree.FromGame(g)
+	}
+	return g, t, true
+}
+
+// loadEditableTree 在 loadGameTree 的基础上要求请求者是对局的一方 (认证模式下)，且对局已经结束，进行中的对局不能编辑变化
+func (s *Server) loadEditableTree(c *gin.Context) (*gametree.Tree, bool) {
+	g, t, ok := s.loadGameTree(c)
+	if !ok {
+		return nil, false
+	}
+	if _, ok := s.requestPlayer(c, g, game.Black); !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "not a player in this game",
+		})
+		return nil, false
+	}
+	if !g.GameOver {
+		c.JSON(http.StatusConflict, gin.H{
+			"error": "variations can only be edited after the game is over",
+		})
+		return nil, false
+	}
+	return t, true
+}
+
+// saveGameTree 保存棋谱树，失败时写入错误响应
+func (s *Server) saveGameTree(c *gin.Context, t *gametree.Tree) bool {
+	if err := s.store.SaveGameTree(c.Param("id"), t); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to save game tree",
+		})
+		return false
+	}
+	return true
+}
+
+// nodeParam 解析路径中的节点编号，失败时写入错误响应
+func nodeParam(c *gin.Context) (gametree.NodeID, bool) {
+	id, err := strconv.Atoi(c.Param("node"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid node id",
+		})
+		return 0, false
+	}
+	return gametree.NodeID(id), true
+}
+
+// treeError 按错误类型写入响应：节点不存在返回 404，其余 (非法着法等) 返回 400
+func treeError(c *gin.Context, err error) {
+	status := http.StatusBadRequest
+	if errors.Is(err, gametree.ErrNodeNotFound) {
+		status = http.StatusNotFound
+	}
+	c.JSON(status, gin.H{
+		"error": err.Error(),
+	})
+}
+
+// getGameTree 返回对局的棋谱树和当前节点的局面 (GET /v1/games/:id/tree)
+func (s *Server) getGameTree(c *gin.Context) {
+	_, t, ok := s.loadGameTree(c)
+	if !ok {
+		return
+	}
+
+	pos, err := t.Position(t.Current)
+	if err != nil {
+		treeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"tree":     t,
+		"position": pos,
+	})
+}
+
+// getTreePosition 重放到指定节点并返回该节点的局面 (GET /v1/games/:id/tree/nodes/:node)
+func (s *Server) getTreePosition(c *gin.Context) {
+	id, ok := nodeParam(c)
+	if !ok {
+		return
+	}
+	_, t, ok := s.loadGameTree(c)
+	if !ok {
+		return
+	}
+
+	node, err := t.Node(id)
+	if err != nil {
+		treeError(c, err)
+		return
+	}
+	pos, err := t.Position(id)
+	if err != nil {
+		treeError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"node":     node,
+		"position": pos,
+	})
+}
+
+// addTreeNode 在指定节点后添加变化 (POST /v1/games/:id/tree/nodes)
+// 着法在父节点的局面上检查合法性，父节点已有相同着法时返回已有节点
+func (s *Server) addTreeNode(c *gin.Context) {
+	var req treeNodeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+	t, ok := s.loadEditableTree(c)
+	if !ok {
+		return
+	}
+
+	var move *gametree.Move
+	if req.Move != nil {
+		move = &gametree.Move{Player: req.Move.Player, Pass: req.Move.Pass, Point: game.Point{X: req.Move.X, Y: req.Move.Y}}
+		if move.Player == game.Empty {
+			pos, err := t.Position(req.Parent)
+			if err != nil {
+				treeError(c, err)
+				return
+			}
+			move.Player = pos.NextPlayer
+		}
+	}
+	node, err := t.Add(req.Parent, move, req.Setup)
+	if err != nil {
+		treeError(c, err)
+		return
+	}
+	if req.Comment != "" {
+		node.Comment = req.Comment
+	}
+	pos, err := t.Position(node.ID)
+	if err != nil {
+		treeError(c, err)
+		return
+	}
+	if !s.saveGameTree(c, t) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"node":     node,
+		"position": pos,
+	})
+}
+
+// annotateTreeNode 修改节点的评注和标记 (PUT /v1/games/:id/tree/nodes/:node)
+// 请求中没有给出的字段保持不变
+func (s *Server) annotateTreeNode(c *gin.Context) {
+	id, ok := nodeParam(c)
+	if !ok {
+		return
+	}
+	var req treeAnnotateRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": "invalid request body",
+		})
+		return
+	}
+	t, ok := s.loadEditableTree(c)
+	if !ok {
+		return
+	}
+
+	if req.Comment != nil {
+		if err := t.SetComment(id, *req.Comment); err != nil {
+			treeError(c, err)
+			return
+		}
+	}
+	if req.Markup != nil {
+		if err := t.SetMarkup(id, req.Markup); err != nil {
+			treeError(c, err)
+			return
+		}
+	}
+	node, err := t.Node(id)
+	if err != nil {
+		treeError(c, err)
+		return
+	}
+	if !s.saveGameTree(c, t) {
+		return
+	}
+
+	c.JSON(http.StatusOK, node)
+}
+
+// deleteTreeNode 删除节点及其后续变化 (DELETE /v1/games/:id/tree/nodes/:node)
+func (s *Server) deleteTreeNode(c *gin.Context) {
+	s.editTree(c, (*gametree.Tree).Delete)
+}
+
+// promoteTreeNode 把到指定节点的变化提升为主线 (POST /v1/games/:id/tree/nodes/:node/promote)
+func (s *Server) promoteTreeNode(c *gin.Context) {
+	s.editTree(c, (*gametree.Tree).Promote)
+}
+
+// editTree 对路径中的节点执行一次编辑，保存后返回整棵棋谱树
+func (s *Server) editTree(c *gin.Context, edit func(*gametree.Tree, gametree.NodeID) error) {
+	id, ok := nodeParam(c)
+	if !ok {
+		return
+	}
+	t, ok := s.loadEditableTree(c)
+	if !ok {
+		return
+	}
+
+	if err := edit(t, id); err != nil {
+		treeError(c, err)
+		return
+	}
+	if !s.saveGameTree(c, t) {
+		return
+	}
+
+	c.JSON(http.StatusOK, t)
+}
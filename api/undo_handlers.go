@@ -0,0 +1,152 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/nankp236270/weiqi-go/auth"
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// UndoRequest 悔棋请求
+type UndoRequest struct {
+	Count int `json:"count"` // 需要撤销的手数，默认 1
+}
+
+// requestPlayer 返回当前请求用户在对局中的执子颜色
+// 认证模式下只认登录用户的座位，用户不在对局中时返回 false
+// 无认证模式下无法识别用户，使用 fallback 作为执子颜色
+func (s *Server) requestPlayer(c *gin.Context, g *game.Game, fallback game.Player) (game.Player, bool) {
+	if s.userStore != nil && s.jwtManager != nil {
+		userID, exists := auth.GetUserID(c)
+		if !exists {
+			return game.Empty, false
+		}
+		color := g.PlayerColor(userID)
+		return color, color != game.Empty
+	}
+	return fallback, true
+}
+
+// requestUndo 处理悔棋请求 (POST /v1/games/:id/undo)
+func (s *Server) requestUndo(c *gin.Context) {
+	gameID := c.Param("id")
+
+	var req UndoRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		// 没有请求体时默认悔一手
+		req.Count = 1
+	}
+	if req.Count == 0 {
+		req.Count = 1
+	}
+
+	g, err := s.store.GetGame(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "game not found",
+		})
+		return
+	}
+
+	// 未登录时视为刚落子的一方发起悔棋，AI 对局中总是由人类一方发起
+	fallback := g.NextPlayer.Opponent()
+	if g.IsAIPlayer(fallback) {
+		fallback = fallback.Opponent()
+	}
+	player, ok := s.requestPlayer(c, g, fallback)
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "not a player in this game",
+		})
+		return
+	}
+
+	if err := g.RequestUndo(player, req.Count); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	// AI 对局中由 AI 自动同意悔棋
+	if opponent := player.Opponent(); g.IsAIPlayer(opponent) {
+		if err := g.AcceptUndo(opponent); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error": err.Error(),
+			})
+			return
+		}
+	}
+
+	if err := s.store.UpdateGame(gameID, g); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to update game state",
+		})
+		return
+	}
+
+	// 悔棋后可能轮到 AI 重新行棋
+	c.JSON(http.StatusOK, newGameState(g))
+	s.scheduleAI(gameID, g)
+}
+
+// acceptUndo 同意悔棋请求 (POST /v1/games/:id/undo/accept)
+func (s *Server) acceptUndo(c *gin.Context) {
+	s.answerUndo(c, true)
+}
+
+// declineUndo 拒绝悔棋请求 (POST /v1/games/:id/undo/decline)
+func (s *Server) declineUndo(c *gin.Context) {
+	s.answerUndo(c, false)
+}
+
+// answerUndo 回应悔棋请求
+func (s *Server) answerUndo(c *gin.Context, accept bool) {
+	gameID := c.Param("id")
+
+	g, err := s.store.GetGame(gameID)
+	if err != nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error": "game not found",
+		})
+		return
+	}
+
+	if g.PendingUndo == nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": game.ErrNoUndoRequest.Error(),
+		})
+		return
+	}
+
+	// 未登录时视为发起方的对手在回应
+	player, ok := s.requestPlayer(c, g, g.PendingUndo.RequestedBy.Opponent())
+	if !ok {
+		c.JSON(http.StatusForbidden, gin.H{
+			"error": "not a player in this game",
+		})
+		return
+	}
+
+	if accept {
+		err = g.AcceptUndo(player)
+	} else {
+		err = g.DeclineUndo(player)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error": err.Error(),
+		})
+		return
+	}
+
+	if err := s.store.UpdateGame(gameID, g); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error": "failed to update game state",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, newGameState(g))
+}
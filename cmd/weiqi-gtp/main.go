@@ -0,0 +1,51 @@
+// weiqi-gtp 是以 game 包为后端的 GTP 引擎，在标准输入输出上使用 GTP v2 通信
+// 可以接入 GoGui、Sabaki、twogtp 等程序，也可以用于运行 GTP 回归测试
+//
+// 用法:
+//
+//	weiqi-gtp [-size 19] [-rules chinese] [-komi 7.5] [-ai-url http://localhost:8000]
+//
+// genmove 使用 AI 服务落子，服务地址默认取环境变量 AI_SERVICE_URL
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/nankp236270/weiqi-go/ai"
+	"github.com/nankp236270/weiqi-go/api"
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/gtp"
+)
+
+func main() {
+	size := flag.Int("size", game.BoardSize, "board size (9, 13 or 19)")
+	rules := flag.String("rules", "chinese", "rule set (chinese, japanese, aga, new_zealand, tromp_taylor)")
+	komi := flag.Float64("komi", 0, "komi, defaults to the rule set's komi")
+	aiURL := flag.String("ai-url", os.Getenv("AI_SERVICE_URL"), "AI service URL used by genmove")
+	flag.Parse()
+
+	opts := game.GameOptions{BoardSize: *size, Rules: *rules}
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "komi" {
+			opts.Komi = komi
+		}
+	})
+
+	// 没有配置 AI 服务时 genmove 返回错误，其它命令仍可使用
+	var client api.AIClient
+	if *aiURL != "" {
+		client = ai.NewClient(*aiURL)
+	}
+
+	engine, err := gtp.NewEngine(client, opts)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "weiqi-gtp:", err)
+		os.Exit(2)
+	}
+	if err := engine.Run(os.Stdin, os.Stdout); err != nil {
+		fmt.Fprintln(os.Stderr, "weiqi-gtp:", err)
+		os.Exit(1)
+	}
+}
@@ -3,21 +3,34 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
 
 // Config 结构体用于存放从环境变量加载的所有配置项
 type Config struct {
-	MongoURI       string
-	DBName         string
-	CollectionName string
-	UserCollection string
-	ServerPort     string
-	AIServiceURL   string
-	JWTSecret      string
-	LogLevel       string
-	LogJSON        bool
+	MongoURI         string
+	DBName           string
+	CollectionName   string
+	UserCollection   string
+	ServerPort       string
+	AIServiceURL     string
+	AITimeout        time.Duration // 单次请求 AI 服务的超时
+	AIRetries        int           // 请求 AI 服务失败后的最大重试次数
+	AIHealthInterval time.Duration // 后台检查 AI 服务健康状态的间隔
+	AIBackend        string        // AI 后端: http (AI 服务)、gtp (本地 GTP 引擎) 或 mcts (内置引擎)
+	GTPCommand       string        // 启动 GTP 引擎的命令行，如 "gnugo --mode gtp"
+	GTPPoolSize      int
+	GTPTimeout       time.Duration
+	KataGoCommand    string // 启动 KataGo 分析引擎的命令行，设置后由其负责局面分析
+	KataGoTimeout    time.Duration
+	MCTSPlayouts     int           // 内置引擎每步的最大模拟次数
+	MCTSTime         time.Duration // 内置引擎每步的最长思考时间
+	JWTSecret        string
+	LogLevel         string
+	LogJSON          bool
 }
 
 // LoadConfig 加载 .env 问卷和环境变量, 并返回一个 Config 结构体
@@ -30,15 +43,26 @@ func LoadConfig() *Config {
 	}
 
 	cfg := &Config{
-		MongoURI:       getEnv("MONGO_URI", ""),
-		DBName:         getEnv("DB_NAME", "weiqi"),
-		CollectionName: getEnv("COLLECTION_NAME", "games"),
-		UserCollection: getEnv("USER_COLLECTION", "users"),
-		ServerPort:     getEnv("SERVER_PORT", "8080"),
-		AIServiceURL:   getEnv("AI_SERVICE_URL", ""),
-		JWTSecret:      getEnv("JWT_SECRET", ""),
-		LogLevel:       getEnv("LOG_LEVEL", "info"),
-		LogJSON:        getEnv("LOG_JSON", "false") == "true",
+		MongoURI:         getEnv("MONGO_URI", ""),
+		DBName:           getEnv("DB_NAME", "weiqi"),
+		CollectionName:   getEnv("COLLECTION_NAME", "games"),
+		UserCollection:   getEnv("USER_COLLECTION", "users"),
+		ServerPort:       getEnv("SERVER_PORT", "8080"),
+		AIServiceURL:     getEnv("AI_SERVICE_URL", ""),
+		AITimeout:        getEnvDuration("AI_TIMEOUT", 30*time.Second),
+		AIRetries:        getEnvInt("AI_RETRIES", 2),
+		AIHealthInterval: getEnvDuration("AI_HEALTH_INTERVAL", 15*time.Second),
+		AIBackend:        getEnv("AI_BACKEND", "http"),
+		GTPCommand:       getEnv("GTP_COMMAND", ""),
+		GTPPoolSize:      getEnvInt("GTP_POOL_SIZE", 1),
+		GTPTimeout:       getEnvDuration("GTP_TIMEOUT", 30*time.Second),
+		KataGoCommand:    getEnv("KATAGO_COMMAND", ""),
+		KataGoTimeout:    getEnvDuration("KATAGO_TIMEOUT", 60*time.Second),
+		MCTSPlayouts:     getEnvInt("MCTS_PLAYOUTS", 3000),
+		MCTSTime:         getEnvDuration("MCTS_TIME", 5*time.Second),
+		JWTSecret:        getEnv("JWT_SECRET", ""),
+		LogLevel:         getEnv("LOG_LEVEL", "info"),
+		LogJSON:          getEnv("LOG_JSON", "false") == "true",
 	}
 
 	if cfg.MongoURI == "" {
@@ -50,6 +74,10 @@ func LoadConfig() *Config {
 		cfg.JWTSecret = "default-secret-change-in-production"
 	}
 
+	if cfg.AIBackend == "gtp" && cfg.GTPCommand == "" {
+		log.Fatal("GTP_COMMAND environment variable is required when AI_BACKEND is gtp")
+	}
+
 	return cfg
 }
 
@@ -60,3 +88,31 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// getEnvInt 读取整数类型的环境变量, 格式错误时使用默认值
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+// getEnvDuration 读取时长类型的环境变量 (如 "30s"), 格式错误时使用默认值
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	value, ok := os.LookupEnv(key)
+	if !ok {
+		return fallback
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		log.Printf("Warning: invalid %s %q, using %s", key, value, fallback)
+		return fallback
+	}
+	return d
+}
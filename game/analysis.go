@@ -0,0 +1,21 @@
+package game
+
+// Candidate 是局面分析给出的一个候选着法
+type Candidate struct {
+	Point     Point   `json:"point"`
+	Pass      bool    `json:"pass,omitempty"`
+	Visits    int     `json:"visits"`     // 搜索次数
+	Prior     float64 `json:"prior"`      // 策略网络给出的先验概率
+	Winrate   float64 `json:"winrate"`    // 下在这里之后黑方的胜率 (0-1)
+	ScoreLead float64 `json:"score_lead"` // 下在这里之后黑方预计领先的目数
+	PV        []Point `json:"pv"`         // 主要变化，从这一手开始，遇到虚手时截止
+}
+
+// Analysis 是 AI 对局面的评估
+// 胜率和目差都以黑方为准，负的目差表示白方领先
+type Analysis struct {
+	Winrate    float64     `json:"winrate"`
+	ScoreLead  float64     `json:"score_lead"`
+	Candidates []Candidate `json:"candidates"`          // 按推荐程度排列
+	Ownership  [][]float64 `json:"ownership,omitempty"` // 每个点的归属，与 Board.Grid 同序，1 为黑方、-1 为白方
+}
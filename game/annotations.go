@@ -0,0 +1,68 @@
+package game
+
+import "slices"
+
+// Group 描述棋盘上的一块棋 (相连的同色棋子)
+type Group struct {
+	Player    Player  `json:"player"`
+	Stones    []Point `json:"stones"`
+	Liberties int     `json:"liberties"`
+	Atari     bool    `json:"atari"` // 只剩一口气，被叫吃
+}
+
+// Annotations 是对当前局面的分析结果，供客户端标注棋盘
+type Annotations struct {
+	LastMove *Move   `json:"last_move,omitempty"` // 最后一手 (落子或虚手)
+	KoPoint  *Point  `json:"ko_point,omitempty"`  // 轮到行棋的一方不能立即回提的劫点
+	Captured []Point `json:"captured,omitempty"`  // 最后一手提掉的棋子
+	Groups   []Group `json:"groups"`              // 棋盘上所有的棋块及其气数
+}
+
+// Groups 返回棋盘上所有的棋块，按每块棋第一颗棋子的行列顺序排列
+func (b *Board) Groups() []Group {
+	st := b.sync()
+	size := b.Size()
+
+	groups := []Group{}
+	for i, color := range st.shadow {
+		if color == Empty || st.root[i] != int16(i) {
+			continue
+		}
+		stones, liberties := st.liberties(int16(i))
+		groups = append(groups, Group{
+			Player:    color,
+			Stones:    stones,
+			Liberties: liberties,
+			Atari:     liberties == 1,
+		})
+	}
+
+	// 代表点不一定是棋块中最靠前的棋子，按首子位置排序使结果稳定
+	first := func(g Group) int {
+		lowest := size * size
+		for _, p := range g.Stones {
+			if i := p.Y*size + p.X; i < lowest {
+				lowest = i
+			}
+		}
+		return lowest
+	}
+	slices.SortFunc(groups, func(a, b Group) int {
+		return first(a) - first(b)
+	})
+	return groups
+}
+
+// Annotations 分析当前局面，返回最后一手、劫点、提子和各棋块的气数
+func (g *Game) Annotations() *Annotations {
+	a := &Annotations{
+		KoPoint: g.KoPoint,
+		Groups:  g.Board.Groups(),
+	}
+	if len(g.Moves) > 0 {
+		last := g.Moves[len(g.Moves)-1]
+		a.LastMove = &last
+		a.Captured = last.Captured
+	}
+	return a
+}
@@ -0,0 +1,72 @@
+package game
+
+import "testing"
+
+// TestAnnotations 测试局面分析包含最后一手、劫点、提子和叫吃的棋块
+func TestAnnotations(t *testing.T) {
+	g := newRulesGame(t, "japanese")
+	if a := g.Annotations(); a.LastMove != nil || len(a.Groups) != 0 {
+		t.Fatalf("Expected no annotations on empty board, got %+v", a)
+	}
+
+	setupKo(g)
+	_ = g.PlayMove(Point{X: 2, Y: 1}) // 黑提劫
+
+	a := g.Annotations()
+	if a.LastMove == nil || a.LastMove.Point != (Point{X: 2, Y: 1}) || a.LastMove.Player != Black {
+		t.Fatalf("Expected last move black (2,1), got %+v", a.LastMove)
+	}
+	if a.KoPoint == nil || *a.KoPoint != (Point{X: 1, Y: 1}) {
+		t.Fatalf("Expected ko point at (1,1), got %v", a.KoPoint)
+	}
+	if len(a.Captured) != 1 || a.Captured[0] != (Point{X: 1, Y: 1}) {
+		t.Fatalf("Expected capture at (1,1), got %v", a.Captured)
+	}
+
+	// 提劫的黑子只剩一口气
+	var found bool
+	for _, group := range a.Groups {
+		if len(group.Stones) == 1 && group.Stones[0] == (Point{X: 2, Y: 1}) {
+			found = true
+			if group.Player != Black || group.Liberties != 1 || !group.Atari {
+				t.Fatalf("Expected black stone at (2,1) in atari, got %+v", group)
+			}
+		}
+	}
+	if !found {
+		t.Fatal("Expected a group for the stone at (2,1)")
+	}
+	if len(a.Groups) != 7 {
+		t.Fatalf("Expected 7 groups, got %d", len(a.Groups))
+	}
+
+	// 虚手后没有劫点和提子
+	_ = g.PassTurn()
+	if a := g.Annotations(); a.KoPoint != nil || len(a.Captured) != 0 || !a.LastMove.Pass {
+		t.Fatalf("Expected pass without ko point, got %+v", a)
+	}
+}
+
+// TestBoard_Groups 测试相连的棋子归为同一块棋并正确计算气数
+func TestBoard_Groups(t *testing.T) {
+	b := NewBoardWithSize(9)
+	for _, p := range []Point{{X: 4, Y: 4}, {X: 5, Y: 4}, {X: 0, Y: 0}} {
+		_, _ = b.PlaceStone(Black, p)
+	}
+	_, _ = b.PlaceStone(White, Point{X: 1, Y: 0})
+
+	groups := b.Groups()
+	if len(groups) != 3 {
+		t.Fatalf("Expected 3 groups, got %d", len(groups))
+	}
+	// 按首子位置排序：(0,0) 黑、(1,0) 白、(4,4)-(5,4) 黑
+	if groups[0].Liberties != 1 || !groups[0].Atari {
+		t.Fatalf("Expected corner stone in atari, got %+v", groups[0])
+	}
+	if groups[1].Player != White || groups[1].Liberties != 2 {
+		t.Fatalf("Expected white stone with 2 liberties, got %+v", groups[1])
+	}
+	if len(groups[2].Stones) != 2 || groups[2].Liberties != 6 {
+		t.Fatalf("Expected two-stone group with 6 liberties, got %+v", groups[2])
+	}
+}
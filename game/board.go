@@ -7,9 +7,22 @@ import (
 	"strings"
 )
 
-// BoardSize 定义了棋盘的大小
+// BoardSize 定义了默认棋盘的大小
 const BoardSize = 19
 
+// SupportedBoardSizes 列出了创建对局时允许选择的棋盘大小
+var SupportedBoardSizes = []int{9, 13, 19}
+
+// IsSupportedBoardSize 判断给定的棋盘大小是否受支持
+func IsSupportedBoardSize(size int) bool {
+	for _, s := range SupportedBoardSizes {
+		if s == size {
+			return true
+		}
+	}
+	return false
+}
+
 // Player 定义了棋盘上的点的状态: 空, 黑棋, 白棋
 type Player int8
 
@@ -19,6 +32,18 @@ const (
 	White Player = 2
 )
 
+// String 返回棋子颜色的名称
+func (p Player) String() string {
+	switch p {
+	case Black:
+		return "Black"
+	case White:
+		return "White"
+	default:
+		return "Empty"
+	}
+}
+
 // MarshalJSON 自定义 JSON 序列化
 func (p Player) MarshalJSON() ([]byte, error) {
 	switch p {
@@ -52,27 +77,43 @@ type Point struct {
 }
 
 // Board 定义了棋盘的结构
+// Grid[行][列]，棋盘大小由 Grid 的长度决定
+// 落子和提子时增量维护棋块、气数和 Zobrist 哈希，
+// 直接修改 Grid 后这些信息会在下次使用时自动重建
 type Board struct {
-	Grid [BoardSize][BoardSize]Player `json:"-"`
+	Grid [][]Player `json:"-"`
+
+	chains *chainState // 增量维护的棋块信息，为空或与 Grid 不一致时重建
 }
 
-// MarshalJSON 自定义 Board 的 JSON 序列化，将 Grid 转换为数字数组
-func (b *Board) MarshalJSON() ([]byte, error) {
-	// 创建数字数组
-	grid := make([][]int8, BoardSize)
-	for i := 0; i < BoardSize; i++ {
-		grid[i] = make([]int8, BoardSize)
-		for j := 0; j < BoardSize; j++ {
+// Size 返回棋盘的路数
+func (b *Board) Size() int {
+	return len(b.Grid)
+}
+
+// ToList 将棋盘转换为数字二维数组 (0=空, 1=黑, 2=白)
+func (b *Board) ToList() [][]int8 {
+	size := b.Size()
+	grid := make([][]int8, size)
+	for i := 0; i < size; i++ {
+		grid[i] = make([]int8, size)
+		for j := 0; j < size; j++ {
 			grid[i][j] = int8(b.Grid[i][j])
 		}
 	}
-	
+	return grid
+}
+
+// MarshalJSON 自定义 Board 的 JSON 序列化，将 Grid 转换为数字数组
+func (b *Board) MarshalJSON() ([]byte, error) {
 	// 序列化为 JSON
 	type Alias struct {
+		Size int      `json:"size"`
 		Grid [][]int8 `json:"grid"`
 	}
 	return json.Marshal(&Alias{
-		Grid: grid,
+		Size: b.Size(),
+		Grid: b.ToList(),
 	})
 }
 
@@ -85,14 +126,20 @@ func (b *Board) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &alias); err != nil {
 		return err
 	}
-	
-	// 转换回 Player 类型
-	for i := 0; i < BoardSize && i < len(alias.Grid); i++ {
-		for j := 0; j < BoardSize && j < len(alias.Grid[i]); j++ {
+
+	// 转换回 Player 类型，棋盘大小以 grid 的行数为准
+	size := len(alias.Grid)
+	if size == 0 {
+		size = BoardSize
+	}
+	b.Grid = newGrid(size)
+	b.chains = nil
+	for i := 0; i < size && i < len(alias.Grid); i++ {
+		for j := 0; j < size && j < len(alias.Grid[i]); j++ {
 			b.Grid[i][j] = Player(alias.Grid[i][j])
 		}
 	}
-	
+
 	return nil
 }
 
@@ -101,115 +148,152 @@ var (
 	ErrPointOutOfBounds = errors.New("point is outside the board")
 	ErrPointNotEmpty    = errors.New("point is not empty")
 	ErrSuicideMove      = errors.New("suicide move is not allowed")
+	ErrInvalidBoardSize = errors.New("board size must be 9, 13 or 19")
 )
 
-// NewBoard 创建并返回一个空的棋盘
+// NewBoard 创建并返回一个默认大小 (19x19) 的空棋盘
 func NewBoard() *Board {
-	return &Board{}
+	return NewBoardWithSize(BoardSize)
+}
+
+// NewBoardWithSize 创建并返回一个指定大小的空棋盘
+func NewBoardWithSize(size int) *Board {
+	return &Board{Grid: newGrid(size)}
+}
+
+// newGrid 分配一个 size x size 的空网格
+func newGrid(size int) [][]Player {
+	cells := make([]Player, size*size)
+	grid := make([][]Player, size)
+	for i := range grid {
+		grid[i] = cells[i*size : (i+1)*size : (i+1)*size]
+	}
+	return grid
+}
+
+// InBounds 判断坐标是否在棋盘内
+func (b *Board) InBounds(p Point) bool {
+	size := b.Size()
+	return p.X >= 0 && p.X < size && p.Y >= 0 && p.Y < size
 }
 
 // PlaceStone 在指定坐标落下指定颜色的旗子
 // 包含了对落子合法性的基础检查
 // 它会处理提子逻辑，并返回提子的数量
 func (b *Board) PlaceStone(player Player, p Point) (captures int, err error) {
-	// 1. 基础合法性检查
+	captured, _, err := b.placeStone(player, p, false)
+	return len(captured), err
+}
+
+// placeStone 是 PlaceStone 的实现，返回被提掉的对方棋子坐标
+// allowSuicide 为 true 时自杀合法，落子方被提掉的棋子通过 suicided 返回
+func (b *Board) placeStone(player Player, p Point, allowSuicide bool) (capturedStones, suicided []Point, err error) {
+	// 1. 基础合法性检查，非法落子不会修改棋盘
+	st, i, effect, err := b.checkMove(player, p, allowSuicide)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 2. 落子，并与相邻的己方棋块合并
+	st.addStone(b.Grid, i, player)
+
+	// 3. 移除被提的对方棋块
+	for _, r := range effect.captures[:effect.nCaptures] {
+		capturedStones = append(capturedStones, st.removeChain(b.Grid, r)...)
+	}
+
+	// 4. 规则允许自杀时，移除落子方整块棋
+	if effect.suicide {
+		return capturedStones, st.removeChain(b.Grid, st.root[i]), nil
+	}
+
+	return capturedStones, nil, nil
+}
+
+// checkMove 检查落子是否合法并分析其结果，不修改棋盘
+func (b *Board) checkMove(player Player, p Point, allowSuicide bool) (*chainState, int16, moveEffect, error) {
 	// 检查坐标是否在棋盘内
-	if p.X < 0 || p.X >= BoardSize || p.Y < 0 || p.Y >= BoardSize {
-		return 0, ErrPointOutOfBounds
+	if !b.InBounds(p) {
+		return nil, 0, moveEffect{}, ErrPointOutOfBounds
 	}
 	// 检查该位置是否为空
 	// 注意：Grid[行][列]，而 Point.X 是列，Point.Y 是行
 	if b.Grid[p.Y][p.X] != Empty {
-		return 0, ErrPointNotEmpty
+		return nil, 0, moveEffect{}, ErrPointNotEmpty
 	}
 
-	// 2. 试探性地落子
-	b.Grid[p.Y][p.X] = player
-
-	// 3. 检查并移除对方被提的子
-	opponent := getOpponent(player)
-	var capturedStones []Point
-	for _, n := range getNeighbors(p) {
-		if b.Grid[n.Y][n.X] == opponent {
-			group, liberties := b.findGroupAndLiberties(n)
-			if liberties == 0 {
-				capturedStones = append(capturedStones, group...)
-				for _, stone := range group {
-					b.Grid[stone.Y][stone.X] = Empty
-				}
-			}
-		}
+	st := b.sync()
+	i := int16(p.Y*b.Size() + p.X)
+	effect := st.analyze(i, player)
+	// 自杀禁令检查：落子后既没有提子，己方棋块也没有气
+	if effect.suicide && !allowSuicide {
+		return nil, 0, moveEffect{}, ErrSuicideMove
 	}
+	return st, i, effect, nil
+}
 
-	// 4. 自杀禁令检查
-	// 在提子后，检查落子点所在的棋块是否还有气
-	_, newLiberties := b.findGroupAndLiberties(p)
-	if newLiberties == 0 {
-		// 这是一个自杀点，回滚所有操作
-		b.Grid[p.Y][p.X] = Empty               // 撤销落子
-		for _, stone := range capturedStones { // 将被提的子放回去
-			b.Grid[stone.Y][stone.X] = opponent
-		}
-		return 0, ErrSuicideMove
+// hashAfter 返回落子后棋面的哈希，不修改棋盘，用于在落子前检查全局同形
+func (b *Board) hashAfter(player Player, p Point, allowSuicide bool) (uint64, error) {
+	st, i, effect, err := b.checkMove(player, p, allowSuicide)
+	if err != nil {
+		return 0, err
 	}
-
-	return len(capturedStones), nil
+	return st.hashAfter(i, player, effect), nil
 }
 
-// findGroupAndLiberties 使用广度优先搜索(BFS)寻找一个点所在的棋块及其气数
-// visitedInGroup 用于记录棋块内的点，防止重复搜索
-// visitedLiberties 用于记录气点，防止重复计数
-func (b *Board) findGroupAndLiberties(startPoint Point) (group []Point, liberties int) {
-	if b.Grid[startPoint.Y][startPoint.X] == Empty {
-		return nil, 0
+// sync 返回与 Grid 一致的棋块信息，Grid 被直接修改过时重新构建
+func (b *Board) sync() *chainState {
+	if b.chains == nil || !b.chains.matches(b.Grid) {
+		b.chains = newChainState(b.Grid)
 	}
+	return b.chains
+}
 
-	player := b.Grid[startPoint.Y][startPoint.X]
-	q := []Point{startPoint}
-	visitedInGroup := map[Point]bool{startPoint: true}
-	visitedLiberties := map[Point]bool{}
+// Hash 返回当前棋面的 Zobrist 哈希 (不含行棋方)
+func (b *Board) Hash() uint64 {
+	return b.sync().hash
+}
 
-	for len(q) > 0 {
-		current := q[0]
-		q = q[1:]
-		group = append(group, current)
+// Rehash 丢弃增量维护的信息，根据 Grid 重新计算哈希
+func (b *Board) Rehash() uint64 {
+	b.chains = nil
+	return b.Hash()
+}
 
-		for _, n := range getNeighbors(current) {
-			switch b.Grid[n.Y][n.X] {
-			case Empty:
-				if !visitedLiberties[n] {
-					visitedLiberties[n] = true
-				}
-			case player:
-				if !visitedInGroup[n] {
-					visitedInGroup[n] = true
-					q = append(q, n)
-				}
-			}
-		}
+// findGroupAndLiberties 返回一个点所在的棋块及其气数 (相邻空点去重)
+func (b *Board) findGroupAndLiberties(startPoint Point) (group []Point, liberties int) {
+	if b.Grid[startPoint.Y][startPoint.X] == Empty {
+		return nil, 0
 	}
 
-	return group, len(visitedLiberties)
+	st := b.sync()
+	return st.liberties(st.root[startPoint.Y*b.Size()+startPoint.X])
 }
 
-// getNeighbors 返回一个点的所有合法邻居坐标
-func getNeighbors(p Point) []Point {
+// neighbors 返回一个点的所有合法邻居坐标
+func (b *Board) neighbors(p Point) []Point {
+	size := b.Size()
 	neighbors := make([]Point, 0, 4)
 	if p.X > 0 {
 		neighbors = append(neighbors, Point{X: p.X - 1, Y: p.Y})
 	}
-	if p.X < BoardSize-1 {
+	if p.X < size-1 {
 		neighbors = append(neighbors, Point{X: p.X + 1, Y: p.Y})
 	}
 	if p.Y > 0 {
 		neighbors = append(neighbors, Point{X: p.X, Y: p.Y - 1})
 	}
-	if p.Y < BoardSize-1 {
+	if p.Y < size-1 {
 		neighbors = append(neighbors, Point{X: p.X, Y: p.Y + 1})
 	}
 	return neighbors
 }
 
+// Opponent 返回对手的颜色
+func (p Player) Opponent() Player {
+	return getOpponent(p)
+}
+
 // getOpponent 返回对手的颜色
 func getOpponent(player Player) Player {
 	if player == Black {
@@ -218,12 +302,14 @@ func getOpponent(player Player) Player {
 	return Black
 }
 
-// StateHash 将当前棋盘状态转换为一个唯一的字符串，用作哈希键
+// StateHash 将当前棋盘状态转换为一个唯一的字符串
+// 劫争判定使用 Hash，这里的字符串用于与 AI 服务交换棋面
 func (b *Board) StateHash() string {
+	size := b.Size()
 	var sb strings.Builder
-	sb.Grow(BoardSize * BoardSize) // 预分配内存以提高性能
-	for i := 0; i < BoardSize; i++ {
-		for j := 0; j < BoardSize; j++ {
+	sb.Grow(size * size) // 预分配内存以提高性能
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
 			sb.WriteByte(byte(b.Grid[i][j]) + '0') // 将 0,1,2 转换为 '0','1','2'
 		}
 	}
@@ -232,23 +318,29 @@ func (b *Board) StateHash() string {
 
 // Clone 创建并返回当前棋盘的一个深拷贝
 func (b *Board) Clone() *Board {
-	clone := NewBoard()
-	clone.Grid = b.Grid // 在 Go 中，数组是值类型，直接赋值就是拷贝
+	clone := NewBoardWithSize(b.Size())
+	for i := range b.Grid {
+		copy(clone.Grid[i], b.Grid[i])
+	}
+	if b.chains != nil && b.chains.matches(b.Grid) {
+		clone.chains = b.chains.clone()
+	}
 	return clone
 }
 
 // String 方法让 Board 类型可以被方便地被打印出来, 用于调试
 func (b *Board) String() string {
+	size := b.Size()
 	var sb strings.Builder
 	sb.WriteString("   ")
-	for i := 0; i < BoardSize; i++ {
+	for i := 0; i < size; i++ {
 		sb.WriteString(fmt.Sprintf("%2d ", i))
 	}
 	sb.WriteString("\n")
 
-	for i := 0; i < BoardSize; i++ {
+	for i := 0; i < size; i++ {
 		sb.WriteString(fmt.Sprintf("%2d ", i))
-		for j := 0; j < BoardSize; j++ {
+		for j := 0; j < size; j++ {
 			switch b.Grid[i][j] {
 			case Empty:
 				sb.WriteString(" . ")
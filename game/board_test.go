@@ -195,3 +195,40 @@ func TestSuicide_WithCaptureAllowed(t *testing.T) {
 		t.Fatalf("Black group should have been captured")
 	}
 }
+
+// TestNewBoardWithSize 测试创建不同大小的棋盘
+func TestNewBoardWithSize(t *testing.T) {
+	for _, size := range SupportedBoardSizes {
+		board := NewBoardWithSize(size)
+		if board.Size() != size {
+			t.Fatalf("Expected board size %d, got %d", size, board.Size())
+		}
+
+		// 最后一个点在棋盘内，超出一格则越界
+		if _, err := board.PlaceStone(Black, Point{X: size - 1, Y: size - 1}); err != nil {
+			t.Fatalf("Expected corner move to succeed on %dx%d, got %v", size, size, err)
+		}
+		if _, err := board.PlaceStone(Black, Point{X: size, Y: 0}); !errors.Is(err, ErrPointOutOfBounds) {
+			t.Fatalf("Expected ErrPointOutOfBounds on %dx%d, got %v", size, size, err)
+		}
+	}
+}
+
+// TestCaptureOnSmallBoardEdge 测试 9 路棋盘边缘的提子
+func TestCaptureOnSmallBoardEdge(t *testing.T) {
+	board := NewBoardWithSize(9)
+	// 白子位于右下角 (8,8)，黑棋占据 (7,8)，再下 (8,7) 提子
+	board.Grid[8][8] = White
+	board.Grid[8][7] = Black
+
+	captures, err := board.PlaceStone(Black, Point{X: 8, Y: 7})
+	if err != nil {
+		t.Fatalf("Expected no error, but got %v", err)
+	}
+	if captures != 1 {
+		t.Fatalf("Expected 1 capture, but got %d", captures)
+	}
+	if board.Grid[8][8] != Empty {
+		t.Fatal("Expected corner stone to be captured")
+	}
+}
@@ -0,0 +1,383 @@
+package game
+
+import (
+	"slices"
+	"sync"
+)
+
+// boardGeometry 保存某一路数棋盘不变的几何信息，同路数的棋盘共享
+type boardGeometry struct {
+	size int
+	adj  [][]int16 // 每个点的相邻点
+	zidx []int16   // 每个点在 Zobrist 随机数表中的下标
+}
+
+var geometries sync.Map // size -> *boardGeometry
+
+// geometryFor 返回指定路数棋盘的几何信息
+func geometryFor(size int) *boardGeometry {
+	if geo, ok := geometries.Load(size); ok {
+		return geo.(*boardGeometry)
+	}
+
+	n := size * size
+	geo := &boardGeometry{
+		size: size,
+		adj:  make([][]int16, n),
+		zidx: make([]int16, n),
+	}
+	backing := make([]int16, 0, 4*n)
+	for i := 0; i < n; i++ {
+		x, y := i%size, i/size
+		start := len(backing)
+		if x > 0 {
+			backing = append(backing, int16(i-1))
+		}
+		if x < size-1 {
+			backing = append(backing, int16(i+1))
+		}
+		if y > 0 {
+			backing = append(backing, int16(i-size))
+		}
+		if y < size-1 {
+			backing = append(backing, int16(i+size))
+		}
+		geo.adj[i] = backing[start:len(backing):len(backing)]
+		geo.zidx[i] = int16(y*BoardSize + x)
+	}
+
+	actual, _ := geometries.LoadOrStore(size, geo)
+	return actual.(*boardGeometry)
+}
+
+// chainState 增量维护棋盘上的棋块 (相连的同色棋子)、气数和哈希
+// 每个棋块的棋子串成一个循环链表，棋块的统计信息记录在代表点上。
+// 气数为伪气数：每个 (棋子, 相邻空点) 对计一次，棋块无气当且仅当伪气数为 0
+type chainState struct {
+	geo    *boardGeometry
+	shadow []Player // 上次同步时 Grid 的副本，用于发现对 Grid 的直接修改
+	next   []int16  // 同一棋块中的下一颗棋子 (循环链表)
+	root   []int16  // 棋子所在棋块的代表点，空点为 -1
+	libs   []int16  // 代表点：棋块的伪气数
+	stones []int16  // 代表点：棋块的棋子数
+	hash   uint64   // 棋面的 Zobrist 哈希 (不含行棋方)
+
+	mark    []uint32 // 统计真实气数时用于去重
+	markGen uint32
+}
+
+// newChainState 根据 Grid 完整构建棋块信息
+func newChainState(grid [][]Player) *chainState {
+	size := len(grid)
+	n := size * size
+	st := &chainState{
+		geo:    geometryFor(size),
+		shadow: make([]Player, n),
+		next:   make([]int16, n),
+		root:   make([]int16, n),
+		libs:   make([]int16, n),
+		stones: make([]int16, n),
+		mark:   make([]uint32, n),
+	}
+
+	for y, row := range grid {
+		copy(st.shadow[y*size:(y+1)*size], row)
+	}
+	for i, color := range st.shadow {
+		st.root[i] = -1
+		if color != Empty {
+			st.hash ^= zobristStones[st.geo.zidx[i]][color]
+		}
+	}
+
+	// 逐个棋块做一次深度优先遍历，串起链表并统计伪气数
+	stack := make([]int16, 0, n)
+	for i, color := range st.shadow {
+		if color == Empty || st.root[i] != -1 {
+			continue
+		}
+		r := int16(i)
+		st.root[r], st.next[r] = r, r
+		stack = append(stack[:0], r)
+		for len(stack) > 0 {
+			s := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			st.stones[r]++
+			for _, nb := range st.geo.adj[s] {
+				switch {
+				case st.shadow[nb] == Empty:
+					st.libs[r]++
+				case st.shadow[nb] == color && st.root[nb] == -1:
+					st.root[nb] = r
+					st.next[nb], st.next[r] = st.next[r], nb
+					stack = append(stack, nb)
+				}
+			}
+		}
+	}
+	return st
+}
+
+// matches 判断 Grid 是否与上次同步时一致
+func (st *chainState) matches(grid [][]Player) bool {
+	size := st.geo.size
+	if len(grid) != size {
+		return false
+	}
+	for y, row := range grid {
+		if !slices.Equal(row, st.shadow[y*size:(y+1)*size]) {
+			return false
+		}
+	}
+	return true
+}
+
+// clone 返回一个独立的副本，几何信息共享
+func (st *chainState) clone() *chainState {
+	return &chainState{
+		geo:    st.geo,
+		shadow: slices.Clone(st.shadow),
+		next:   slices.Clone(st.next),
+		root:   slices.Clone(st.root),
+		libs:   slices.Clone(st.libs),
+		stones: slices.Clone(st.stones),
+		hash:   st.hash,
+		mark:   make([]uint32, len(st.mark)),
+	}
+}
+
+// copyFrom 把 o 的棋块信息复制到 st，两者的路数必须相同，不分配内存
+func (st *chainState) copyFrom(o *chainState) {
+	copy(st.shadow, o.shadow)
+	copy(st.next, o.next)
+	copy(st.root, o.root)
+	copy(st.libs, o.libs)
+	copy(st.stones, o.stones)
+	st.hash = o.hash
+}
+
+// key 返回点 i 放置指定颜色棋子时的 Zobrist 随机数
+func (st *chainState) key(i int16, color Player) uint64 {
+	return zobristStones[st.geo.zidx[i]][color]
+}
+
+// adjacency 返回点 i 与棋块 r 相邻的次数，即在 i 落子后棋块 r 失去的伪气数
+func (st *chainState) adjacency(i, r int16) int16 {
+	var count int16
+	for _, nb := range st.geo.adj[i] {
+		if st.shadow[nb] != Empty && st.root[nb] == r {
+			count++
+		}
+	}
+	return count
+}
+
+// moveEffect 描述一手棋落下后的结果，由 analyze 在不修改棋盘的情况下算出
+type moveEffect struct {
+	captures  [4]int16 // 被提掉的对方棋块的代表点
+	nCaptures int
+	suicide   bool // 落子后己方棋块无气
+}
+
+// analyze 判断在空点 i 落下 color 后会提掉哪些棋块、是否自杀，不修改棋盘
+func (st *chainState) analyze(i int16, color Player) moveEffect {
+	var effect moveEffect
+	hasLiberty := false
+	for _, nb := range st.geo.adj[i] {
+		switch st.shadow[nb] {
+		case Empty:
+			hasLiberty = true
+		case color:
+			// 己方棋块在 i 之外还有气，则连接后仍然有气
+			if r := st.root[nb]; st.libs[r] > st.adjacency(i, r) {
+				hasLiberty = true
+			}
+		default:
+			r := st.root[nb]
+			if st.libs[r] == st.adjacency(i, r) && !slices.Contains(effect.captures[:effect.nCaptures], r) {
+				effect.captures[effect.nCaptures] = r
+				effect.nCaptures++
+			}
+		}
+	}
+	effect.suicide = !hasLiberty && effect.nCaptures == 0
+	return effect
+}
+
+// hashAfter 返回按 analyze 的结果落子后的棋面哈希
+func (st *chainState) hashAfter(i int16, color Player, effect moveEffect) uint64 {
+	hash := st.hash
+	if effect.suicide {
+		// 落下的子和相连的己方棋块一起被提掉
+		var merged [4]int16
+		n := 0
+		for _, nb := range st.geo.adj[i] {
+			if r := st.root[nb]; st.shadow[nb] == color && !slices.Contains(merged[:n], r) {
+				merged[n] = r
+				n++
+				hash ^= st.chainHash(r)
+			}
+		}
+		return hash
+	}
+
+	hash ^= st.key(i, color)
+	for _, r := range effect.captures[:effect.nCaptures] {
+		hash ^= st.chainHash(r)
+	}
+	return hash
+}
+
+// chainHash 返回棋块 r 全部棋子的哈希
+func (st *chainState) chainHash(r int16) uint64 {
+	var hash uint64
+	color := st.shadow[r]
+	for s := r; ; {
+		hash ^= st.key(s, color)
+		if s = st.next[s]; s == r {
+			break
+		}
+	}
+	return hash
+}
+
+// setCell 同时修改 Grid 和副本并更新哈希
+func (st *chainState) setCell(grid [][]Player, i int16, color Player) {
+	size := int16(st.geo.size)
+	if old := st.shadow[i]; old != Empty {
+		st.hash ^= st.key(i, old)
+	}
+	if color != Empty {
+		st.hash ^= st.key(i, color)
+	}
+	st.shadow[i] = color
+	grid[i/size][i%size] = color
+}
+
+// addStone 在空点 i 放置一颗棋子，并与相邻的同色棋块合并
+// 不处理提子，调用方需要随后移除无气的棋块
+func (st *chainState) addStone(grid [][]Player, i int16, color Player) {
+	st.setCell(grid, i, color)
+	st.root[i], st.next[i] = i, i
+	st.stones[i], st.libs[i] = 1, 0
+
+	for _, nb := range st.geo.adj[i] {
+		if st.shadow[nb] == Empty {
+			st.libs[i]++
+		} else {
+			st.libs[st.root[nb]]--
+		}
+	}
+	for _, nb := range st.geo.adj[i] {
+		if st.shadow[nb] == color && st.root[nb] != st.root[i] {
+			st.merge(st.root[i], st.root[nb])
+		}
+	}
+}
+
+// merge 合并两个棋块，较小的棋块并入较大的棋块
+func (st *chainState) merge(a, b int16) {
+	if st.stones[a] < st.stones[b] {
+		a, b = b, a
+	}
+	for s := b; ; {
+		st.root[s] = a
+		if s = st.next[s]; s == b {
+			break
+		}
+	}
+	st.next[a], st.next[b] = st.next[b], st.next[a]
+	st.stones[a] += st.stones[b]
+	st.libs[a] += st.libs[b]
+}
+
+// removeChain 从棋盘上移除棋块 r，返回被移除的棋子坐标
+func (st *chainState) removeChain(grid [][]Player, r int16) []Point {
+	size := st.geo.size
+	points := make([]Point, 0, st.stones[r])
+	for s := r; ; {
+		points = append(points, Point{X: int(s) % size, Y: int(s) / size})
+		if s = st.next[s]; s == r {
+			break
+		}
+	}
+	st.clearChain(grid, r)
+	return points
+}
+
+// clearChain 从棋盘上移除棋块 r，返回被移除的棋子数
+// 移除时不改动链表，棋块的棋子仍可以从 r 开始遍历
+func (st *chainState) clearChain(grid [][]Player, r int16) int {
+	n := int(st.stones[r])
+	for s := r; ; {
+		st.setCell(grid, s, Empty)
+		st.root[s] = -1
+		if s = st.next[s]; s == r {
+			break
+		}
+	}
+	// 提子后相邻的棋块各自获得气
+	for s := r; ; {
+		for _, nb := range st.geo.adj[s] {
+			if st.shadow[nb] != Empty {
+				st.libs[st.root[nb]]++
+			}
+		}
+		if s = st.next[s]; s == r {
+			break
+		}
+	}
+	return n
+}
+
+// nextMark 开始一次新的去重，返回本次使用的标记
+func (st *chainState) nextMark() uint32 {
+	st.markGen++
+	if st.markGen == 0 {
+		clear(st.mark)
+		st.markGen = 1
+	}
+	return st.markGen
+}
+
+// countLiberties 统计棋块 r 的真实气数，数到 limit 为止；last 是最后数到的一口气
+func (st *chainState) countLiberties(r int16, limit int) (n int, last int16) {
+	gen := st.nextMark()
+	last = -1
+	for s := r; ; {
+		for _, nb := range st.geo.adj[s] {
+			if st.shadow[nb] == Empty && st.mark[nb] != gen {
+				st.mark[nb] = gen
+				n++
+				last = nb
+				if n >= limit {
+					return n, last
+				}
+			}
+		}
+		if s = st.next[s]; s == r {
+			break
+		}
+	}
+	return n, last
+}
+
+// liberties 返回棋块 r 的棋子和真实气数 (相邻空点去重)
+func (st *chainState) liberties(r int16) (group []Point, liberties int) {
+	gen := st.nextMark()
+	size := st.geo.size
+	group = make([]Point, 0, st.stones[r])
+	for s := r; ; {
+		group = append(group, Point{X: int(s) % size, Y: int(s) / size})
+		for _, nb := range st.geo.adj[s] {
+			if st.shadow[nb] == Empty && st.mark[nb] != gen {
+				st.mark[nb] = gen
+				liberties++
+			}
+		}
+		if s = st.next[s]; s == r {
+			break
+		}
+	}
+	return group, liberties
+}
@@ -0,0 +1,284 @@
+package game
+
+import (
+	"math/rand"
+	"slices"
+	"testing"
+)
+
+// legacyPlaceStone 是改为增量维护棋块之前的落子实现：
+// 每次落子都用 BFS 重新搜索相邻棋块，用于对比测试和基准测试
+func legacyPlaceStone(b *Board, player Player, p Point, allowSuicide bool) (capturedStones, suicided []Point, err error) {
+	if !b.InBounds(p) {
+		return nil, nil, ErrPointOutOfBounds
+	}
+	if b.Grid[p.Y][p.X] != Empty {
+		return nil, nil, ErrPointNotEmpty
+	}
+
+	b.Grid[p.Y][p.X] = player
+
+	opponent := getOpponent(player)
+	for _, n := range b.neighbors(p) {
+		if b.Grid[n.Y][n.X] == opponent {
+			group, liberties := legacyGroupAndLiberties(b, n)
+			if liberties == 0 {
+				capturedStones = append(capturedStones, group...)
+				for _, stone := range group {
+					b.Grid[stone.Y][stone.X] = Empty
+				}
+			}
+		}
+	}
+
+	ownGroup, newLiberties := legacyGroupAndLiberties(b, p)
+	if newLiberties == 0 {
+		if allowSuicide {
+			for _, stone := range ownGroup {
+				b.Grid[stone.Y][stone.X] = Empty
+			}
+			return capturedStones, ownGroup, nil
+		}
+
+		b.Grid[p.Y][p.X] = Empty
+		for _, stone := range capturedStones {
+			b.Grid[stone.Y][stone.X] = opponent
+		}
+		return nil, nil, ErrSuicideMove
+	}
+
+	return capturedStones, nil, nil
+}
+
+// legacyGroupAndLiberties 是改为增量维护棋块之前的 BFS 棋块搜索
+func legacyGroupAndLiberties(b *Board, startPoint Point) (group []Point, liberties int) {
+	if b.Grid[startPoint.Y][startPoint.X] == Empty {
+		return nil, 0
+	}
+
+	player := b.Grid[startPoint.Y][startPoint.X]
+	q := []Point{startPoint}
+	visitedInGroup := map[Point]bool{startPoint: true}
+	visitedLiberties := map[Point]bool{}
+
+	for len(q) > 0 {
+		current := q[0]
+		q = q[1:]
+		group = append(group, current)
+
+		for _, n := range b.neighbors(current) {
+			switch b.Grid[n.Y][n.X] {
+			case Empty:
+				visitedLiberties[n] = true
+			case player:
+				if !visitedInGroup[n] {
+					visitedInGroup[n] = true
+					q = append(q, n)
+				}
+			}
+		}
+	}
+
+	return group, len(visitedLiberties)
+}
+
+// sortPoints 按行列排序，便于比较两种实现返回的坐标
+func sortPoints(points []Point) []Point {
+	points = slices.Clone(points)
+	slices.SortFunc(points, func(a, b Point) int {
+		if a.Y != b.Y {
+			return a.Y - b.Y
+		}
+		return a.X - b.X
+	})
+	return points
+}
+
+// randomPlayout 在棋盘上随机选点，通过 place 落子 moves 次，非法的点跳过
+func randomPlayout(b *Board, seed int64, moves int, place func(player Player, p Point) ([]Point, []Point, error)) {
+	rng := rand.New(rand.NewSource(seed))
+	size := b.Size()
+	player := Black
+	for i := 0; i < moves; i++ {
+		p := Point{X: rng.Intn(size), Y: rng.Intn(size)}
+		if _, _, err := place(player, p); err == nil {
+			player = getOpponent(player)
+		}
+	}
+}
+
+// TestChain_MatchesLegacy 测试随机对局中增量实现与原实现的落子结果完全一致
+func TestChain_MatchesLegacy(t *testing.T) {
+	for _, size := range []int{9, 13, 19} {
+		for _, allowSuicide := range []bool{false, true} {
+			b := NewBoardWithSize(size)
+			legacy := NewBoardWithSize(size)
+
+			step := 0
+			randomPlayout(b, int64(size), 3000, func(player Player, p Point) ([]Point, []Point, error) {
+				step++
+				captured, suicided, err := b.placeStone(player, p, allowSuicide)
+				wantCaptured, wantSuicided, wantErr := legacyPlaceStone(legacy, player, p, allowSuicide)
+
+				if err != wantErr {
+					t.Fatalf("size %d step %d: expected error %v at %v, got %v", size, step, wantErr, p, err)
+				}
+				if !slices.Equal(sortPoints(captured), sortPoints(wantCaptured)) || !slices.Equal(sortPoints(suicided), sortPoints(wantSuicided)) {
+					t.Fatalf("size %d step %d: removed stones differ at %v", size, step, p)
+				}
+				for y := range b.Grid {
+					if !slices.Equal(b.Grid[y], legacy.Grid[y]) {
+						t.Fatalf("size %d step %d: boards differ at row %d", size, step, y)
+					}
+				}
+				return captured, suicided, err
+			})
+
+			// 增量维护的哈希和气数与重新构建的结果一致
+			if b.Hash() != b.Clone().Rehash() {
+				t.Fatalf("size %d: incremental hash differs from rebuilt hash", size)
+			}
+			for y := 0; y < size; y++ {
+				for x := 0; x < size; x++ {
+					p := Point{X: x, Y: y}
+					group, liberties := b.findGroupAndLiberties(p)
+					wantGroup, wantLiberties := legacyGroupAndLiberties(b, p)
+					if liberties != wantLiberties || !slices.Equal(sortPoints(group), sortPoints(wantGroup)) {
+						t.Fatalf("size %d: group at %v differs, got %d liberties, expected %d", size, p, liberties, wantLiberties)
+					}
+				}
+			}
+		}
+	}
+}
+
+// TestChain_DirectGridWrite 测试直接修改 Grid 后棋块信息会自动重建
+func TestChain_DirectGridWrite(t *testing.T) {
+	b := NewBoardWithSize(9)
+	if _, err := b.PlaceStone(Black, Point{X: 1, Y: 0}); err != nil {
+		t.Fatalf("Expected valid move, got %v", err)
+	}
+
+	// 绕过 PlaceStone 直接摆放白子
+	b.Grid[0][0] = White
+	group, liberties := b.findGroupAndLiberties(Point{X: 0, Y: 0})
+	if len(group) != 1 || liberties != 1 {
+		t.Fatalf("Expected white stone with 1 liberty, got %d stones and %d liberties", len(group), liberties)
+	}
+
+	// 黑棋提掉直接摆放的白子
+	captures, err := b.PlaceStone(Black, Point{X: 0, Y: 1})
+	if err != nil || captures != 1 {
+		t.Fatalf("Expected 1 capture, got %d (err %v)", captures, err)
+	}
+	if b.Hash() != b.Clone().Rehash() {
+		t.Fatal("Expected hash to match the rebuilt hash")
+	}
+}
+
+// TestChain_DirectGridWriteAfterMoves 测试棋块信息已经建立后直接修改 Grid 仍会生效
+func TestChain_DirectGridWriteAfterMoves(t *testing.T) {
+	g := NewGame()
+	g.Board.Grid[0][1] = White
+	for _, p := range []Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 1, Y: 1}} {
+		if err := g.PlayMove(p); err != nil {
+			t.Fatalf("Expected valid move at %v, got %v", p, err)
+		}
+		if p.X != 1 {
+			if err := g.PassTurn(); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	if g.Board.Grid[0][1] != Empty || g.CapturesByB != 1 {
+		t.Fatalf("Expected black to capture the placed white stone, got %d captures", g.CapturesByB)
+	}
+}
+
+// TestChain_IllegalMoveKeepsBoard 测试非法落子不会修改棋盘
+func TestChain_IllegalMoveKeepsBoard(t *testing.T) {
+	b := NewBoardWithSize(9)
+	for _, p := range []Point{{X: 1, Y: 0}, {X: 0, Y: 1}} {
+		if _, err := b.PlaceStone(Black, p); err != nil {
+			t.Fatalf("Expected valid move, got %v", err)
+		}
+	}
+	before := b.Hash()
+
+	if _, err := b.PlaceStone(White, Point{X: 0, Y: 0}); err != ErrSuicideMove {
+		t.Fatalf("Expected ErrSuicideMove, got %v", err)
+	}
+	if b.Grid[0][0] != Empty || b.Hash() != before {
+		t.Fatal("Expected board to be unchanged after an illegal move")
+	}
+}
+
+// benchmarkPlayout 在 19 路棋盘上反复进行随机对局
+func benchmarkPlayout(bench *testing.B, place func(b *Board, player Player, p Point) ([]Point, []Point, error)) {
+	for i := 0; i < bench.N; i++ {
+		b := NewBoard()
+		randomPlayout(b, int64(i), 400, func(player Player, p Point) ([]Point, []Point, error) {
+			return place(b, player, p)
+		})
+	}
+}
+
+// BenchmarkPlayout_Incremental 增量维护棋块的随机对局
+func BenchmarkPlayout_Incremental(bench *testing.B) {
+	benchmarkPlayout(bench, func(b *Board, player Player, p Point) ([]Point, []Point, error) {
+		return b.placeStone(player, p, false)
+	})
+}
+
+// BenchmarkPlayout_Legacy 原 BFS 实现的随机对局
+func BenchmarkPlayout_Legacy(bench *testing.B) {
+	benchmarkPlayout(bench, func(b *Board, player Player, p Point) ([]Point, []Point, error) {
+		return legacyPlaceStone(b, player, p, false)
+	})
+}
+
+// BenchmarkGroupAndLiberties_Incremental 在中盘局面上查询全部棋块的气数
+func BenchmarkGroupAndLiberties_Incremental(bench *testing.B) {
+	b := midgameBoard()
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		for y := 0; y < BoardSize; y++ {
+			for x := 0; x < BoardSize; x++ {
+				b.findGroupAndLiberties(Point{X: x, Y: y})
+			}
+		}
+	}
+}
+
+// BenchmarkGroupAndLiberties_Legacy 原 BFS 实现查询全部棋块的气数
+func BenchmarkGroupAndLiberties_Legacy(bench *testing.B) {
+	b := midgameBoard()
+	bench.ResetTimer()
+	for i := 0; i < bench.N; i++ {
+		for y := 0; y < BoardSize; y++ {
+			for x := 0; x < BoardSize; x++ {
+				legacyGroupAndLiberties(b, Point{X: x, Y: y})
+			}
+		}
+	}
+}
+
+// BenchmarkPlayMove 通过 Game.PlayMove 进行随机对局，包含劫争检查和落子记录
+func BenchmarkPlayMove(bench *testing.B) {
+	for i := 0; i < bench.N; i++ {
+		g := NewGame()
+		rng := rand.New(rand.NewSource(int64(i)))
+		for j := 0; j < 200; j++ {
+			_ = g.PlayMove(Point{X: rng.Intn(BoardSize), Y: rng.Intn(BoardSize)})
+		}
+	}
+}
+
+// midgameBoard 返回随机下了 200 手的 19 路棋盘
+func midgameBoard() *Board {
+	b := NewBoard()
+	randomPlayout(b, 1, 200, func(player Player, p Point) ([]Point, []Point, error) {
+		return b.placeStone(player, p, false)
+	})
+	return b
+}
@@ -0,0 +1,70 @@
+package game
+
+import (
+	"errors"
+	"strings"
+)
+
+// Difficulty 表示 AI 的难度等级
+type Difficulty string
+
+const (
+	DifficultyBeginner Difficulty = "beginner" // 入门：经常随手乱下，25 级左右的初学者可以赢
+	DifficultyEasy     Difficulty = "easy"     // 简单：时常下出缓手
+	DifficultyMedium   Difficulty = "medium"   // 中等：偶尔失误
+	DifficultyHard     Difficulty = "hard"     // 困难：很少失误
+	DifficultyStrong   Difficulty = "strong"   // 最强：引擎全力对局
+)
+
+// DefaultDifficulty 是未指定难度时使用的等级，与引入难度之前的 AI 相同
+const DefaultDifficulty = DifficultyStrong
+
+var ErrUnknownDifficulty = errors.New("unknown AI difficulty")
+
+// AIProfile 是难度等级对应的引擎参数，随请求传给 AI 后端
+// 后端只需支持其中的一部分，例如只能限制随机落子的 GTP 引擎
+type AIProfile struct {
+	Playouts    int     `json:"playouts"`     // 每步搜索的模拟次数上限，0 表示使用后端的设置
+	Randomness  float64 `json:"randomness"`   // 不经搜索随机落子的概率 (0-1)
+	Temperature float64 `json:"temperature"`  // 按搜索结果抽样选点的温度，0 表示总是选择最好的着法
+	MistakeRate float64 `json:"mistake_rate"` // 故意从次优的候选着法中选点的概率 (0-1)
+}
+
+// difficultyProfiles 是各难度等级的引擎参数
+var difficultyProfiles = map[Difficulty]AIProfile{
+	DifficultyBeginner: {Playouts: 100, Randomness: 0.3, Temperature: 1.5, MistakeRate: 0.4},
+	DifficultyEasy:     {Playouts: 300, Randomness: 0.1, Temperature: 1.0, MistakeRate: 0.2},
+	DifficultyMedium:   {Playouts: 1000, Randomness: 0.02, Temperature: 0.5, MistakeRate: 0.08},
+	DifficultyHard:     {Playouts: 3000, Temperature: 0.2, MistakeRate: 0.02},
+	DifficultyStrong:   {},
+}
+
+// Difficulties 按从易到难的顺序返回所有难度等级
+func Difficulties() []Difficulty {
+	return []Difficulty{DifficultyBeginner, DifficultyEasy, DifficultyMedium, DifficultyHard, DifficultyStrong}
+}
+
+// DifficultyByName 按名称查找难度等级，名称为空时返回 DefaultDifficulty
+func DifficultyByName(name string) (Difficulty, error) {
+	d := Difficulty(strings.ToLower(strings.TrimSpace(name)))
+	if d == "" {
+		return DefaultDifficulty, nil
+	}
+	if _, ok := difficultyProfiles[d]; !ok {
+		return "", ErrUnknownDifficulty
+	}
+	return d, nil
+}
+
+// Profile 返回难度等级对应的引擎参数，未知的等级按 DefaultDifficulty 处理
+func (d Difficulty) Profile() AIProfile {
+	if p, ok := difficultyProfiles[d]; ok {
+		return p
+	}
+	return difficultyProfiles[DefaultDifficulty]
+}
+
+// AIProfile 返回对局中 AI 使用的引擎参数，早期未保存难度的对局按 DefaultDifficulty 处理
+func (g *Game) AIProfile() AIProfile {
+	return g.AIDifficulty.Profile()
+}
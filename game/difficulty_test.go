@@ -0,0 +1,62 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestDifficultyByName 测试按名称查找难度等级
+func TestDifficultyByName(t *testing.T) {
+	tests := map[string]Difficulty{
+		"":         DefaultDifficulty,
+		"beginner": DifficultyBeginner,
+		" Medium ": DifficultyMedium,
+		"strong":   DifficultyStrong,
+	}
+	for name, want := range tests {
+		d, err := DifficultyByName(name)
+		if err != nil || d != want {
+			t.Fatalf("DifficultyByName(%q) = %q, %v; expected %q", name, d, err, want)
+		}
+	}
+	if _, err := DifficultyByName("9dan"); !errors.Is(err, ErrUnknownDifficulty) {
+		t.Fatalf("Expected ErrUnknownDifficulty, got %v", err)
+	}
+}
+
+// TestDifficulty_Profiles 测试难度越高，随机落子和失误越少、模拟次数越多
+func TestDifficulty_Profiles(t *testing.T) {
+	levels := Difficulties()
+	for i := 1; i < len(levels); i++ {
+		easier, harder := levels[i-1].Profile(), levels[i].Profile()
+		if harder.Randomness > easier.Randomness || harder.MistakeRate > easier.MistakeRate ||
+			harder.Temperature > easier.Temperature {
+			t.Fatalf("Expected %s to be stronger than %s: %+v vs %+v", levels[i], levels[i-1], harder, easier)
+		}
+		if harder.Playouts != 0 && harder.Playouts < easier.Playouts {
+			t.Fatalf("Expected %s to search at least as much as %s", levels[i], levels[i-1])
+		}
+	}
+	if (Difficulty("").Profile() != AIProfile{}) {
+		t.Fatal("Expected games without a difficulty to use the full-strength profile")
+	}
+}
+
+// TestNewGameWithPlayer_AIDifficulty 测试人机对局保存难度，未指定时使用默认难度
+func TestNewGameWithPlayer_AIDifficulty(t *testing.T) {
+	g, err := NewGameWithPlayer("user", true, GameOptions{AIDifficulty: DifficultyEasy})
+	if err != nil || g.AIDifficulty != DifficultyEasy {
+		t.Fatalf("Expected easy difficulty, got %q, %v", g.AIDifficulty, err)
+	}
+	g, _ = NewGameWithPlayer("user", true, GameOptions{})
+	if g.AIDifficulty != DefaultDifficulty {
+		t.Fatalf("Expected default difficulty, got %q", g.AIDifficulty)
+	}
+	g, _ = NewGameWithPlayer("user", false, GameOptions{AIDifficulty: DifficultyEasy})
+	if g.AIDifficulty != "" {
+		t.Fatalf("Expected no difficulty for human games, got %q", g.AIDifficulty)
+	}
+	if _, err := NewGameWithPlayer("user", true, GameOptions{AIDifficulty: "9dan"}); !errors.Is(err, ErrUnknownDifficulty) {
+		t.Fatalf("Expected ErrUnknownDifficulty, got %v", err)
+	}
+}
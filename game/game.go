@@ -2,14 +2,32 @@ package game
 
 import (
 	"errors"
+	"fmt"
+	"maps"
+	"slices"
 	"time"
 )
 
 var (
-	ErrKoViolation = errors.New("move violates Ko rule (positional superko")
-	ErrTimeOut     = errors.New("player time out")
+	ErrKoViolation       = errors.New("move violates Ko rule")
+	ErrTimeOut           = errors.New("player time out")
+	ErrInvalidMoveNumber = errors.New("move number is out of range")
 )
 
+// Move 记录对局中的一手棋 (落子或虚手)
+type Move struct {
+	Number    int       `json:"number" bson:"number"`                         // 手数，从 1 开始
+	Player    Player    `json:"player" bson:"player"`                         // 行棋方
+	Pass      bool      `json:"pass" bson:"pass"`                             // 是否为虚手
+	Point     Point     `json:"point" bson:"point"`                           // 落子坐标 (虚手时无意义)
+	Captured  []Point   `json:"captured,omitempty" bson:"captured,omitempty"` // 本手提掉的棋子
+	Suicided  []Point   `json:"suicided,omitempty" bson:"suicided,omitempty"` // 规则允许自杀时本手自杀提掉的己方棋子
+	Timestamp int64     `json:"timestamp" bson:"timestamp"`                   // 落子时间戳（秒）
+	TimeLeft  int64     `json:"time_left" bson:"time_left"`                   // 落子后行棋方剩余时间（秒）
+	Overtime  *Overtime `json:"overtime,omitempty" bson:"overtime,omitempty"` // 落子后行棋方的读秒状态 (读秒类计时)
+	Comment   string    `json:"comment,omitempty" bson:"comment,omitempty"`   // 评注 (导入的棋谱)
+}
+
 // ScoreResult 包含了计分的详细结果
 type ScoreResult struct {
 	BlackScore float64 `json:"black_score"`
@@ -23,62 +41,197 @@ type GameStatus string
 const (
 	GameStatusWaiting  GameStatus = "waiting"  // 等待玩家加入
 	GameStatusPlaying  GameStatus = "playing"  // 进行中
+	GameStatusScoring  GameStatus = "scoring"  // 连续虚手后的点目阶段
 	GameStatusFinished GameStatus = "finished" // 已结束
+	GameStatusReview   GameStatus = "review"   // 导入的没有结果的棋谱，仅供复盘
 )
 
 // Game 结构体管理整个对局的状态
 type Game struct {
-	Board           *Board          `json:"board" bson:"board"`
-	History         map[string]bool `json:"-" bson:"history"` // 存储棋盘状态的哈希，用于 Ko 规则检查 & 使用 json:"-" 来在API响应中隐藏这个字段
-	NextPlayer      Player          `json:"next_player" bson:"next_player"`
-	Passes          int             `json:"passes" bson:"passes"`
-	GameOver        bool            `json:"game_over" bson:"game_over"`
-	CapturesByB     int             `json:"captures_by_b" bson:"captures_by_b"`
-	CapturesByW     int             `json:"captures_by_w" bson:"captures_by_w"`
-	PlayerBlack     string          `json:"player_black_id" bson:"player_black"`     // 黑棋玩家 ID
-	PlayerWhite     string          `json:"player_white_id" bson:"player_white"`     // 白棋玩家 ID
-	Status          GameStatus      `json:"status" bson:"status"`                    // 游戏状态
-	IsAIGame        bool            `json:"is_ai_game" bson:"is_ai_game"`            // 是否为人机对弈
-	BlackTimeLeft   int64           `json:"black_time_left" bson:"black_time_left"`  // 黑棋剩余时间（秒）
-	WhiteTimeLeft   int64           `json:"white_time_left" bson:"white_time_left"`  // 白棋剩余时间（秒）
-	LastMoveTime    int64           `json:"last_move_time" bson:"last_move_time"`    // 上次落子时间戳
-	TimePerPlayer   int64           `json:"time_per_player" bson:"time_per_player"`  // 每位玩家总时间（秒）
+	Board             *Board            `json:"board" bson:"board"`
+	History           PositionHistory   `json:"-" bson:"history"`                                                 // 存储出现过的局面的 Zobrist 哈希，用于 Ko 规则检查 & 使用 json:"-" 来在API响应中隐藏这个字段
+	Moves             []Move            `json:"moves" bson:"moves"`                                               // 按顺序记录的每一手棋
+	PendingUndo       *UndoRequest      `json:"pending_undo,omitempty" bson:"pending_undo,omitempty"`             // 等待对手回应的悔棋请求
+	Handicap          int               `json:"handicap" bson:"handicap"`                                         // 让子数，0 表示分先
+	HandicapPlacement HandicapPlacement `json:"handicap_placement,omitempty" bson:"handicap_placement,omitempty"` // 让子摆放方式
+	SetupStones       []Point           `json:"setup_stones,omitempty" bson:"setup_stones,omitempty"`             // 已摆放的黑方让子
+	Setup             *Setup            `json:"setup,omitempty" bson:"setup,omitempty"`                           // 导入棋谱的摆子局面
+	Info              *RecordInfo       `json:"info,omitempty" bson:"info,omitempty"`                             // 棋谱中的对局信息
+	Rules             RuleSet           `json:"rules" bson:"rules"`                                               // 对局规则
+	KoPoint           *Point            `json:"ko_point,omitempty" bson:"ko_point,omitempty"`                     // 下一手禁止立即回提的劫点
+	Scoring           *ScoringState     `json:"scoring,omitempty" bson:"scoring,omitempty"`                       // 点目阶段的死子标记
+	NextPlayer        Player            `json:"next_player" bson:"next_player"`
+	Passes            int               `json:"passes" bson:"passes"`
+	GameOver          bool              `json:"game_over" bson:"game_over"`
+	Result            *Result           `json:"result,omitempty" bson:"result,omitempty"` // 对局结果，结束后才有值
+	CapturesByB       int               `json:"captures_by_b" bson:"captures_by_b"`
+	CapturesByW       int               `json:"captures_by_w" bson:"captures_by_w"`
+	PlayerBlack       string            `json:"player_black_id" bson:"player_black"`                    // 黑棋玩家 ID
+	PlayerWhite       string            `json:"player_white_id" bson:"player_white"`                    // 白棋玩家 ID
+	Status            GameStatus        `json:"status" bson:"status"`                                   // 游戏状态
+	IsAIGame          bool              `json:"is_ai_game" bson:"is_ai_game"`                           // 是否为人机对弈
+	AIDifficulty      Difficulty        `json:"ai_difficulty,omitempty" bson:"ai_difficulty,omitempty"` // AI 难度，人机对弈才有
+	BlackTimeLeft     int64             `json:"black_time_left" bson:"black_time_left"`                 // 黑棋剩余时间（秒）
+	WhiteTimeLeft     int64             `json:"white_time_left" bson:"white_time_left"`                 // 白棋剩余时间（秒）
+	LastMoveTime      int64             `json:"last_move_time" bson:"last_move_time"`                   // 上次落子时间戳
+	TimePerPlayer     int64             `json:"time_per_player" bson:"time_per_player"`                 // 每位玩家基本时间（秒）
+	TimeControl       TimeControl       `json:"time_control" bson:"time_control"`                       // 计时方式
+	BlackOvertime     Overtime          `json:"black_overtime" bson:"black_overtime"`                   // 黑棋读秒状态
+	WhiteOvertime     Overtime          `json:"white_overtime" bson:"white_overtime"`                   // 白棋读秒状态
+	Version           int64             `json:"-" bson:"-"`                                             // 存储版本号，由存储层在每次保存时递增，用于条件更新
 }
 
-// NewGame 创建一个新的游戏实例
+// AIPlayerID 是 AI 在对局中占用座位时使用的玩家 ID
+const AIPlayerID = "AI"
+
+// AISeat 表示人机对弈中由 AI 执子的一方
+type AISeat string
+
+const (
+	AISeatWhite AISeat = "white" // AI 执白，创建者执黑
+	AISeatBlack AISeat = "black" // AI 执黑，创建者执白
+	AISeatBoth  AISeat = "both"  // 双方都由 AI 执子，创建者观战
+)
+
+var ErrInvalidAISeat = errors.New("ai seat must be \"black\", \"white\" or \"both\"")
+
+// GameOptions 创建对局时可选择的设置
+type GameOptions struct {
+	BoardSize         int               `json:"board_size"`         // 棋盘大小 (9, 13, 19)，0 表示默认 19 路
+	Handicap          int               `json:"handicap"`           // 让子数 (2-9)，0 表示分先
+	HandicapPlacement HandicapPlacement `json:"handicap_placement"` // 让子摆放方式，默认固定星位
+	Rules             string            `json:"rules"`              // 规则名称 (chinese, japanese, aga, new_zealand, tromp_taylor)，默认中国规则
+	Komi              *float64          `json:"komi"`               // 自定义贴目，为空时使用规则默认值 (让子棋为 0.5)
+	TimeControl       *TimeControl      `json:"time_control"`       // 计时方式，为空时为每方 1 小时包干
+	AIDifficulty      Difficulty        `json:"ai_difficulty"`      // 人机对弈的 AI 难度，为空时为 DefaultDifficulty
+	AISeat            AISeat            `json:"ai_seat"`            // 人机对弈中 AI 执子的一方，默认执白
+}
+
+// NewGame 创建一个新的游戏实例 (默认 19 路棋盘)
 func NewGame() *Game {
-	board := NewBoard()
-	initialHash := board.StateHash()
-	history := make(map[string]bool)
-	history[initialHash] = true
-
-	// 中国围棋规则：每方 1 小时（3600 秒）
-	const defaultTimePerPlayer = 3600
-
-	return &Game{
-		Board:         board,
-		History:       history,
-		NextPlayer:    Black,
-		Status:        GameStatusWaiting,
-		BlackTimeLeft: defaultTimePerPlayer,
-		WhiteTimeLeft: defaultTimePerPlayer,
-		TimePerPlayer: defaultTimePerPlayer,
+	g, _ := NewGameWithOptions(GameOptions{})
+	return g
+}
+
+// NewGameWithOptions 按指定设置创建一个新的游戏实例
+func NewGameWithOptions(opts GameOptions) (*Game, error) {
+	size := opts.BoardSize
+	if size == 0 {
+		size = BoardSize
+	}
+	if !IsSupportedBoardSize(size) {
+		return nil, ErrInvalidBoardSize
+	}
+
+	rules, err := RuleSetByName(opts.Rules)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case opts.Komi != nil:
+		rules.Komi = *opts.Komi
+	case opts.Handicap > 0:
+		rules.Komi = 0.5 // 让子棋只贴半目以避免和棋
+	}
+
+	g := newGame(size, rules)
+	if opts.TimeControl != nil {
+		if err := opts.TimeControl.Validate(); err != nil {
+			return nil, err
+		}
+		g.setupClocks(*opts.TimeControl)
+	}
+	if err := g.setupHandicap(opts.Handicap, opts.HandicapPlacement); err != nil {
+		return nil, err
 	}
+
+	return g, nil
+}
+
+// newGame 创建一个使用指定规则的空白对局
+func newGame(size int, rules RuleSet) *Game {
+	g := &Game{
+		Board:      NewBoardWithSize(size),
+		NextPlayer: Black,
+		Status:     GameStatusWaiting,
+		Rules:      rules,
+	}
+	// 默认每方 1 小时包干
+	g.setupClocks(AbsoluteTime(DefaultMainTime))
+	g.History = PositionHistory{g.positionKey(g.Board, g.NextPlayer): true}
+	return g
 }
 
 // NewGameWithPlayer 创建一个由指定玩家发起的游戏
-func NewGameWithPlayer(playerID string, isAIGame bool) *Game {
-	g := NewGame()
+// playerID 为空表示匿名创建 (未启用认证时)；人机对弈中创建者坐在 AI 对面的座位上
+func NewGameWithPlayer(playerID string, isAIGame bool, opts GameOptions) (*Game, error) {
+	g, err := NewGameWithOptions(opts)
+	if err != nil {
+		return nil, err
+	}
 	g.PlayerBlack = playerID
 	g.IsAIGame = isAIGame
-	
+
 	if isAIGame {
-		g.PlayerWhite = "AI"
-		g.Status = GameStatusPlaying // AI 游戏立即开始
+		difficulty, err := DifficultyByName(string(opts.AIDifficulty))
+		if err != nil {
+			return nil, err
+		}
+		g.AIDifficulty = difficulty
+
+		switch opts.AISeat {
+		case "", AISeatWhite:
+			g.PlayerWhite = AIPlayerID
+		case AISeatBlack:
+			g.PlayerBlack, g.PlayerWhite = AIPlayerID, playerID
+		case AISeatBoth:
+			g.PlayerBlack, g.PlayerWhite = AIPlayerID, AIPlayerID
+		default:
+			return nil, ErrInvalidAISeat
+		}
+		g.Status = GameStatusPlaying           // AI 游戏立即开始
 		g.LastMoveTime = getCurrentTimestamp() // 记录游戏开始时间
 	}
-	
-	return g
+
+	return g, nil
+}
+
+// Clone 返回对局的深拷贝，修改副本不会影响原对局
+func (g *Game) Clone() *Game {
+	c := *g
+	if g.Board != nil {
+		c.Board = g.Board.Clone()
+	}
+	c.History = maps.Clone(g.History)
+	c.Moves = slices.Clone(g.Moves)
+	for i := range c.Moves {
+		c.Moves[i].Captured = slices.Clone(c.Moves[i].Captured)
+		c.Moves[i].Suicided = slices.Clone(c.Moves[i].Suicided)
+		c.Moves[i].Overtime = clonePtr(c.Moves[i].Overtime)
+	}
+	c.PendingUndo = clonePtr(g.PendingUndo)
+	c.SetupStones = slices.Clone(g.SetupStones)
+	if g.Setup != nil {
+		c.Setup = &Setup{Black: slices.Clone(g.Setup.Black), White: slices.Clone(g.Setup.White), NextPlayer: g.Setup.NextPlayer}
+	}
+	c.Info = clonePtr(g.Info)
+	c.KoPoint = clonePtr(g.KoPoint)
+	if g.Scoring != nil {
+		scoring := *g.Scoring
+		scoring.DeadStones = slices.Clone(g.Scoring.DeadStones)
+		c.Scoring = &scoring
+	}
+	c.Result = clonePtr(g.Result)
+	return &c
+}
+
+// clonePtr 复制指针指向的值，nil 保持为 nil
+func clonePtr[T any](p *T) *T {
+	if p == nil {
+		return nil
+	}
+	v := *p
+	return &v
 }
 
 // CanPlayerMove 检查指定玩家是否可以在当前回合落子
@@ -86,11 +239,11 @@ func (g *Game) CanPlayerMove(playerID string) bool {
 	if g.GameOver {
 		return false
 	}
-	
+
 	if g.Status != GameStatusPlaying {
 		return false
 	}
-	
+
 	// 检查是否轮到该玩家
 	if g.NextPlayer == Black {
 		return g.PlayerBlack == playerID
@@ -98,20 +251,44 @@ func (g *Game) CanPlayerMove(playerID string) bool {
 	return g.PlayerWhite == playerID
 }
 
+// PlayerColor 返回指定玩家在对局中的执子颜色，不是对局玩家时返回 Empty
+func (g *Game) PlayerColor(playerID string) Player {
+	switch playerID {
+	case "":
+		return Empty
+	case g.PlayerBlack:
+		return Black
+	case g.PlayerWhite:
+		return White
+	}
+	return Empty
+}
+
+// IsAIPlayer 判断指定颜色是否由 AI 执子
+func (g *Game) IsAIPlayer(color Player) bool {
+	if !g.IsAIGame {
+		return false
+	}
+	if color == Black {
+		return g.PlayerBlack == AIPlayerID
+	}
+	return g.PlayerWhite == AIPlayerID
+}
+
 // JoinGame 玩家加入游戏（作为白棋）
 func (g *Game) JoinGame(playerID string) error {
 	if g.Status != GameStatusWaiting {
 		return errors.New("game is not waiting for players")
 	}
-	
+
 	if g.PlayerBlack == playerID {
 		return errors.New("cannot join your own game")
 	}
-	
+
 	if g.PlayerWhite != "" {
 		return errors.New("game is full")
 	}
-	
+
 	g.PlayerWhite = playerID
 	g.Status = GameStatusPlaying
 	g.LastMoveTime = getCurrentTimestamp() // 记录游戏开始时间
@@ -120,8 +297,8 @@ func (g *Game) JoinGame(playerID string) error {
 
 // PlayMove 是进行一步棋的核心方法，采用克隆模式保证原子性
 func (g *Game) PlayMove(p Point) error {
-	if g.GameOver {
-		return errors.New("game is over")
+	if err := g.checkPlayable(); err != nil {
+		return err
 	}
 
 	// 0. 更新时间
@@ -129,33 +306,64 @@ func (g *Game) PlayMove(p Point) error {
 		return err // 超时
 	}
 
-	// 1. 克隆棋盘，在副本上操作
-	tempBoard := g.Board.Clone()
+	// 自由让子阶段，黑方的落子作为让子摆放
+	if g.HandicapPending() {
+		return g.placeHandicapStone(p)
+	}
 
-	// 2. 在克隆的棋盘上尝试落子
-	captures, err := tempBoard.PlaceStone(g.NextPlayer, p)
+	player := g.NextPlayer
+	captured, suicided, err := g.applyMove(p)
 	if err != nil {
-		return err // 来自 PlaceStone 的错误 (越界, 非空, 自杀)
+		return err
 	}
 
-	// 3. 检查 Ko 规则
-	newHash := tempBoard.StateHash()
-	if g.History[newHash] {
-		return ErrKoViolation
+	g.recordMove(Move{Player: player, Point: p, Captured: captured, Suicided: suicided})
+	return nil
+}
+
+// applyMove 在棋盘上落子并更新局面状态，不涉及计时和棋谱记录
+func (g *Game) applyMove(p Point) (captured, suicided []Point, err error) {
+	rules := g.rules()
+
+	// 1. 不修改棋盘，先检查落子是否合法 (越界, 非空, 自杀, 劫争)
+	newKey, err := g.checkMove(p)
+	if err != nil {
+		return nil, nil, err
 	}
+	next := getOpponent(g.NextPlayer)
 
-	// 4. 所有检查通过，正式更新游戏状态
-	g.Board = tempBoard // 将主棋盘指向新的状态
-	g.History[newHash] = true
-	g.NextPlayer = getOpponent(g.NextPlayer)
+	// 2. 所有检查通过，在棋盘上落子并正式更新游戏状态
+	captured, suicided, err = g.Board.placeStone(g.NextPlayer, p, rules.SuicideAllowed)
+	if err != nil {
+		return nil, nil, err
+	}
+	g.KoPoint = koPoint(g.Board, p, captured)
+	g.History[newKey] = true
+	g.NextPlayer = next
 	g.Passes = 0               // 任何成功的落子都会重置pass计数
 	if g.NextPlayer == Black { // 刚刚是白棋下的
-		g.CapturesByW += captures
+		g.CapturesByW += len(captured)
+		g.CapturesByB += len(suicided)
 	} else { // 刚刚是黑棋下的
-		g.CapturesByB += captures
+		g.CapturesByB += len(captured)
+		g.CapturesByW += len(suicided)
 	}
 
-	return nil
+	return captured, suicided, nil
+}
+
+// koPoint 判断刚落下的一子是否形成单劫，返回对方下一手禁止回提的点
+// 条件：只提掉一子，且落下的子单独成块、只剩被提子处一口气
+func koPoint(b *Board, p Point, captured []Point) *Point {
+	if len(captured) != 1 {
+		return nil
+	}
+	group, liberties := b.findGroupAndLiberties(p)
+	if len(group) != 1 || liberties != 1 {
+		return nil
+	}
+	ko := captured[0]
+	return &ko
 }
 
 // PassTurn 处理玩家虚手
@@ -163,42 +371,151 @@ func (g *Game) PassTurn() error {
 	if g.GameOver {
 		return errors.New("game is over")
 	}
+	if g.Status == GameStatusScoring {
+		return ErrScoringInProgress
+	}
+	if g.HandicapPending() {
+		return ErrHandicapNotPlaced
+	}
 
 	// 更新时间
 	if err := g.UpdateTime(); err != nil {
 		return err // 超时
 	}
 
+	player := g.NextPlayer
+	g.applyPass()
+	g.recordMove(Move{Player: player, Pass: true})
+
+	return nil
+}
+
+// applyPass 执行虚手并更新局面状态，不涉及计时和棋谱记录
+func (g *Game) applyPass() {
+	// 虚手交子规则下，虚手方向对方交一颗提子
+	if g.rules().PassStones {
+		if g.NextPlayer == Black {
+			g.CapturesByW++
+		} else {
+			g.CapturesByB++
+		}
+	}
+
 	g.Passes++
+	g.KoPoint = nil
 	g.NextPlayer = getOpponent(g.NextPlayer)
+	g.History[g.positionKey(g.Board, g.NextPlayer)] = true
 
+	// 连续两次虚手后进入点目阶段，双方确认死子后才终局
 	if g.Passes >= 2 {
-		g.GameOver = true
-		g.Status = GameStatusFinished
+		g.startScoring()
 	}
+}
 
-	return nil
+// recordMove 补全手数、时间信息后将一手棋追加到棋谱
+func (g *Game) recordMove(m Move) {
+	g.PendingUndo = nil // 任何新的一手都会使未回应的悔棋请求失效
+	m.Number = len(g.Moves) + 1
+	m.Timestamp = getCurrentTimestamp()
+
+	if g.Status != GameStatusWaiting { // 未开始的对局不计时
+		g.completeTurn(m.Player)
+	}
+	timeLeft, ot := g.clock(m.Player)
+	m.TimeLeft = *timeLeft
+	if tc := g.timeControl(); tc.Type == TimeByoYomi || tc.Type == TimeCanadian {
+		snapshot := *ot
+		m.Overtime = &snapshot
+	}
+	g.Moves = append(g.Moves, m)
+}
+
+// Replay 按棋谱在空棋盘上重放前 n 手，返回重建的局面
+// 只重建与局面相关的状态 (棋盘、劫争历史、提子数、轮次、虚手数和棋谱)，
+// 玩家、计时等其它信息不会被复制
+func (g *Game) Replay(n int) (*Game, error) {
+	return g.replay(n, nil)
 }
 
-// 后期需要修改替换或者完全优化
-// CalculateScore 根据中国规则计算最终得分
-// 简化：假设终局时棋盘上所有棋子都是活棋
+// StateHistory 按棋谱重放整局，返回让子摆放后以及每一手之后的棋面字符串 (StateHash)
+// 用于需要旧版字符串历史的外部服务
+func (g *Game) StateHistory() ([]string, error) {
+	states := make([]string, 0, len(g.Moves)+1)
+	_, err := g.replay(len(g.Moves), func(r *Game) {
+		states = append(states, r.Board.StateHash())
+	})
+	return states, err
+}
+
+// replay 是 Replay 的实现，visit 不为空时在让子摆放后和每一手之后被调用
+func (g *Game) replay(n int, visit func(r *Game)) (*Game, error) {
+	if n < 0 || n > len(g.Moves) {
+		return nil, ErrInvalidMoveNumber
+	}
+
+	r := newGame(g.Board.Size(), g.rules())
+
+	// 先摆放棋谱的摆子局面和让子
+	if g.Setup != nil {
+		r.Setup = g.Setup
+		if err := r.applySetup(); err != nil {
+			return nil, fmt.Errorf("replay setup: %w", err)
+		}
+	}
+	r.Handicap = g.Handicap
+	r.HandicapPlacement = g.HandicapPlacement
+	for _, p := range g.SetupStones {
+		if err := r.placeHandicapStone(p); err != nil {
+			return nil, fmt.Errorf("replay handicap stone %v: %w", p, err)
+		}
+	}
+	if visit != nil {
+		visit(r)
+	}
+
+	for _, m := range g.Moves[:n] {
+		if m.Player != r.NextPlayer {
+			return nil, fmt.Errorf("replay move %d: expected %v to move", m.Number, r.NextPlayer)
+		}
+		if m.Pass {
+			r.applyPass()
+		} else if _, _, err := r.applyMove(m.Point); err != nil {
+			return nil, fmt.Errorf("replay move %d: %w", m.Number, err)
+		}
+		if visit != nil {
+			visit(r)
+		}
+	}
+
+	r.Moves = append([]Move(nil), g.Moves[:n]...)
+	return r, nil
+}
+
+// CalculateScore 根据对局规则计算得分 (数子法或数目法)
+// 点目阶段标记的死子先从棋盘上移除，数目法下计入对方提子；
+// 点目阶段中调用时返回按当前标记计算的预估结果
 func (g *Game) CalculateScore() (ScoreResult, error) {
-	if !g.GameOver {
+	if !g.GameOver && g.Status != GameStatusScoring {
 		return ScoreResult{}, errors.New("game is not over yet")
 	}
 
+	board, deadBlack, deadWhite := g.scoringBoard()
+
 	blackStones := 0
 	whiteStones := 0
 	blackTerritory := 0
 	whiteTerritory := 0
 
-	visited := [BoardSize][BoardSize]bool{}
+	size := board.Size()
+	visited := make([][]bool, size)
+	for i := range visited {
+		visited[i] = make([]bool, size)
+	}
 
 	// 1. 计算双方棋子数
-	for i := 0; i < BoardSize; i++ {
-		for j := 0; j < BoardSize; j++ {
-			switch g.Board.Grid[i][j] {
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			switch board.Grid[i][j] {
 			case Black:
 				blackStones++
 			case White:
@@ -208,10 +525,10 @@ func (g *Game) CalculateScore() (ScoreResult, error) {
 	}
 
 	// 2. 使用BFS计算领地
-	for i := 0; i < BoardSize; i++ {
-		for j := 0; j < BoardSize; j++ {
-			if g.Board.Grid[i][j] == Empty && !visited[i][j] {
-				q := []Point{{i, j}}
+	for i := 0; i < size; i++ {
+		for j := 0; j < size; j++ {
+			if board.Grid[i][j] == Empty && !visited[i][j] {
+				q := []Point{{X: j, Y: i}}
 				visited[i][j] = true
 				area := 0
 				touchesBlack := false
@@ -222,13 +539,13 @@ func (g *Game) CalculateScore() (ScoreResult, error) {
 					q = q[1:]
 					area++
 
-					for _, n := range getNeighbors(current) {
-						if g.Board.Grid[n.X][n.Y] == Black {
+					for _, n := range board.neighbors(current) {
+						if board.Grid[n.Y][n.X] == Black {
 							touchesBlack = true
-						} else if g.Board.Grid[n.X][n.Y] == White {
+						} else if board.Grid[n.Y][n.X] == White {
 							touchesWhite = true
-						} else if !visited[n.X][n.Y] {
-							visited[n.X][n.Y] = true
+						} else if !visited[n.Y][n.X] {
+							visited[n.Y][n.X] = true
 							q = append(q, n)
 						}
 					}
@@ -243,16 +560,26 @@ func (g *Game) CalculateScore() (ScoreResult, error) {
 		}
 	}
 
-	result := ScoreResult{
-		BlackScore: float64(blackStones + blackTerritory),
-		WhiteScore: float64(whiteStones+whiteTerritory) + 3.75, // Komi for White
+	rules := g.rules()
+	var result ScoreResult
+	if rules.Scoring == ScoringTerritory {
+		// 数目法：围空 + 提子 (含死子)
+		result.BlackScore = float64(blackTerritory + g.CapturesByB + deadWhite)
+		result.WhiteScore = float64(whiteTerritory + g.CapturesByW + deadBlack)
+	} else {
+		// 数子法：棋子 + 围空
+		result.BlackScore = float64(blackStones + blackTerritory)
+		result.WhiteScore = float64(whiteStones + whiteTerritory)
 	}
+	result.WhiteScore += g.WhiteBonus()
 
-	// 根据规则，黑棋得分 > 184.25 才算赢
-	if result.BlackScore > 184.25 {
+	switch {
+	case result.BlackScore > result.WhiteScore:
 		result.Winner = Black
-	} else {
+	case result.WhiteScore > result.BlackScore:
 		result.Winner = White
+	default:
+		result.Winner = Empty // 和棋
 	}
 
 	return result, nil
@@ -268,33 +595,22 @@ func (g *Game) UpdateTime() error {
 	if g.GameOver || g.Status != GameStatusPlaying {
 		return nil
 	}
-	
+
 	if g.LastMoveTime == 0 {
 		g.LastMoveTime = getCurrentTimestamp()
 		return nil
 	}
-	
+
 	// 计算经过的时间
 	now := getCurrentTimestamp()
 	elapsed := now - g.LastMoveTime
-	
-	// 扣除当前玩家的时间
-	if g.NextPlayer == Black {
-		g.BlackTimeLeft -= elapsed
-		if g.BlackTimeLeft <= 0 {
-			g.BlackTimeLeft = 0
-			g.GameOver = true
-			return ErrTimeOut
-		}
-	} else {
-		g.WhiteTimeLeft -= elapsed
-		if g.WhiteTimeLeft <= 0 {
-			g.WhiteTimeLeft = 0
-			g.GameOver = true
-			return ErrTimeOut
-		}
+
+	// 扣除当前玩家的时间，包括读秒
+	if !g.chargeTime(g.NextPlayer, elapsed) {
+		g.finish(Result{Winner: g.NextPlayer.Opponent(), Reason: ReasonTimeout})
+		return ErrTimeOut
 	}
-	
+
 	g.LastMoveTime = now
 	return nil
 }
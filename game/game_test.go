@@ -83,7 +83,7 @@ func TestKoRule_SimpleKo(t *testing.T) {
 	g.Board.Grid[2][2] = White
 
 	// 更新历史记录
-	g.History[g.Board.StateHash()] = true
+	g.History[g.Board.Rehash()] = true
 
 	// 黑棋在 (1,2) 落子，提掉白棋 (1,1)
 	g.NextPlayer = Black
@@ -130,7 +130,7 @@ func TestKoRule_AllowedAfterOtherMove(t *testing.T) {
 	g.Board.Grid[1][3] = White
 	g.Board.Grid[2][2] = White
 
-	g.History[g.Board.StateHash()] = true
+	g.History[g.Board.Rehash()] = true
 
 	// 黑棋提劫
 	g.NextPlayer = Black
@@ -182,8 +182,9 @@ func TestPassTurn(t *testing.T) {
 	if g.Passes != 2 {
 		t.Fatalf("Expected 2 passes, got %d", g.Passes)
 	}
-	if !g.GameOver {
-		t.Fatal("Game should be over after two consecutive passes")
+	// 连续虚手后进入点目阶段，而不是直接终局
+	if g.GameOver || g.Status != GameStatusScoring {
+		t.Fatalf("Expected scoring phase after two consecutive passes, got status %s", g.Status)
 	}
 }
 
@@ -221,7 +222,7 @@ func TestCaptureTracking(t *testing.T) {
 	g.Board.Grid[1][1] = White // 被包围的白子
 
 	g.NextPlayer = Black
-	g.History[g.Board.StateHash()] = true
+	g.History[g.Board.Rehash()] = true
 
 	// 黑棋在 (2,1) 落子提掉白子
 	err := g.PlayMove(Point{X: 2, Y: 1})
@@ -263,9 +264,8 @@ func TestCalculateScore_EmptyBoard(t *testing.T) {
 		t.Fatalf("Expected no error, got %v", err)
 	}
 
-	// 空棋盘：黑0子，白0子 + 3.75贴子
-	// 白方得分 = 0 + 3.75 = 3.75
-	// 黑方需要 > 184.25 才能赢，所以白方应该赢
+	// 空棋盘：黑0子，白0子 + 7.5贴目 (中国规则)
+	// 白方得分 = 0 + 7.5 = 7.5，所以白方应该赢
 	if result.Winner != White {
 		t.Fatalf("Expected White to win on empty board, got %v", result.Winner)
 	}
@@ -295,8 +295,150 @@ func TestCalculateScore_SimpleGame(t *testing.T) {
 		t.Fatalf("Expected BlackScore >= 3, got %f", result.BlackScore)
 	}
 
-	// 白方有贴子 3.75
+	// 白方有贴目
 	if result.WhiteScore < 3.75 {
 		t.Fatalf("Expected WhiteScore >= 3.75, got %f", result.WhiteScore)
 	}
 }
+
+// TestNewGameWithOptions_BoardSize 测试按棋盘大小创建游戏
+func TestNewGameWithOptions_BoardSize(t *testing.T) {
+	g, err := NewGameWithOptions(GameOptions{BoardSize: 13})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if g.Board.Size() != 13 {
+		t.Fatalf("Expected 13x13 board, got %d", g.Board.Size())
+	}
+
+	if _, err := NewGameWithOptions(GameOptions{BoardSize: 15}); !errors.Is(err, ErrInvalidBoardSize) {
+		t.Fatalf("Expected ErrInvalidBoardSize, got %v", err)
+	}
+}
+
+// TestCalculateScore_SmallBoard 测试 9 路棋盘的计分阈值
+func TestCalculateScore_SmallBoard(t *testing.T) {
+	g, _ := NewGameWithOptions(GameOptions{BoardSize: 9})
+
+	// 黑棋占据第 5 列 (含) 以左全部区域，白棋不落子
+	for y := 0; y < 9; y++ {
+		g.Board.Grid[y][4] = Black
+	}
+	g.Board.Grid[0][5] = White
+	g.GameOver = true
+
+	result, err := g.CalculateScore()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 黑方: 9 子 + 36 目 = 45，白方: 1 子 + 7.5 贴目
+	if result.BlackScore != 45 {
+		t.Fatalf("Expected BlackScore 45, got %f", result.BlackScore)
+	}
+	if result.Winner != Black {
+		t.Fatalf("Expected Black to win, got %v", result.Winner)
+	}
+}
+
+// TestMoves_Recorded 测试落子和虚手都会按顺序记录到棋谱
+func TestMoves_Recorded(t *testing.T) {
+	g := NewGame()
+
+	_ = g.PlayMove(Point{X: 3, Y: 3})
+	_ = g.PassTurn()
+	_ = g.PlayMove(Point{X: 15, Y: 15})
+
+	if len(g.Moves) != 3 {
+		t.Fatalf("Expected 3 moves, got %d", len(g.Moves))
+	}
+
+	first, second, third := g.Moves[0], g.Moves[1], g.Moves[2]
+	if first.Number != 1 || first.Player != Black || first.Pass || first.Point != (Point{X: 3, Y: 3}) {
+		t.Fatalf("Unexpected first move: %+v", first)
+	}
+	if second.Number != 2 || second.Player != White || !second.Pass {
+		t.Fatalf("Unexpected second move: %+v", second)
+	}
+	if third.Number != 3 || third.Player != Black || third.Point != (Point{X: 15, Y: 15}) {
+		t.Fatalf("Unexpected third move: %+v", third)
+	}
+	if first.Timestamp == 0 || first.TimeLeft != g.TimePerPlayer {
+		t.Fatalf("Expected timestamp and clock to be recorded, got %+v", first)
+	}
+
+	// 非法落子不会被记录
+	_ = g.PlayMove(Point{X: 3, Y: 3})
+	if len(g.Moves) != 3 {
+		t.Fatalf("Expected illegal move not to be recorded, got %d moves", len(g.Moves))
+	}
+}
+
+// TestReplay 测试按棋谱重建局面
+func TestReplay(t *testing.T) {
+	g := NewGame()
+
+	// 黑棋在角上提掉白子 (0,0)
+	moves := []Point{{X: 1, Y: 0}, {X: 0, Y: 0}, {X: 0, Y: 1}}
+	for _, p := range moves {
+		if err := g.PlayMove(p); err != nil {
+			t.Fatalf("Expected valid move at %v, got %v", p, err)
+		}
+	}
+	if len(g.Moves[2].Captured) != 1 || g.Moves[2].Captured[0] != (Point{X: 0, Y: 0}) {
+		t.Fatalf("Expected capture of (0,0) to be recorded, got %v", g.Moves[2].Captured)
+	}
+
+	replayed, err := g.Replay(len(g.Moves))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if replayed.Board.StateHash() != g.Board.StateHash() {
+		t.Fatal("Expected replayed board to match current board")
+	}
+	if replayed.CapturesByB != 1 || replayed.NextPlayer != White {
+		t.Fatalf("Unexpected replayed state: captures=%d next=%v", replayed.CapturesByB, replayed.NextPlayer)
+	}
+
+	// 重放前两手，白子应仍在棋盘上
+	partial, err := g.Replay(2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if partial.Board.Grid[0][0] != White || partial.NextPlayer != Black || len(partial.Moves) != 2 {
+		t.Fatal("Expected position after move 2 to be restored")
+	}
+
+	if _, err := g.Replay(4); !errors.Is(err, ErrInvalidMoveNumber) {
+		t.Fatalf("Expected ErrInvalidMoveNumber, got %v", err)
+	}
+}
+
+// TestNewGameWithPlayer_AISeat 测试 AI 可以执黑、执白或双方都由 AI 执子
+func TestNewGameWithPlayer_AISeat(t *testing.T) {
+	tests := []struct {
+		seat         AISeat
+		black, white string
+	}{
+		{"", "user", AIPlayerID},
+		{AISeatWhite, "user", AIPlayerID},
+		{AISeatBlack, AIPlayerID, "user"},
+		{AISeatBoth, AIPlayerID, AIPlayerID},
+	}
+	for _, tt := range tests {
+		g, err := NewGameWithPlayer("user", true, GameOptions{AISeat: tt.seat})
+		if err != nil {
+			t.Fatalf("Seat %q: expected no error, got %v", tt.seat, err)
+		}
+		if g.PlayerBlack != tt.black || g.PlayerWhite != tt.white {
+			t.Fatalf("Seat %q: expected %s vs %s, got %s vs %s", tt.seat, tt.black, tt.white, g.PlayerBlack, g.PlayerWhite)
+		}
+		if g.Status != GameStatusPlaying {
+			t.Fatalf("Seat %q: expected game to start immediately, got %s", tt.seat, g.Status)
+		}
+	}
+
+	if _, err := NewGameWithPlayer("user", true, GameOptions{AISeat: "red"}); !errors.Is(err, ErrInvalidAISeat) {
+		t.Fatalf("Expected ErrInvalidAISeat, got %v", err)
+	}
+}
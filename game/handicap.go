@@ -0,0 +1,109 @@
+package game
+
+import "errors"
+
+// HandicapPlacement 表示让子的摆放方式
+type HandicapPlacement string
+
+const (
+	HandicapFixed HandicapPlacement = "fixed" // 固定摆放在星位
+	HandicapFree  HandicapPlacement = "free"  // 由黑方在白方第一手之前自由摆放
+)
+
+// 让子数的范围
+const (
+	MinHandicap = 2
+	MaxHandicap = 9
+)
+
+var (
+	ErrInvalidHandicap          = errors.New("handicap must be between 2 and 9 stones")
+	ErrInvalidHandicapPlacement = errors.New("handicap placement must be \"fixed\" or \"free\"")
+	ErrHandicapNotPlaced        = errors.New("black must place all handicap stones first")
+)
+
+// HandicapPoints 返回指定棋盘大小下固定让子的星位坐标
+// 摆放顺序与 GTP fixed_handicap 一致
+func HandicapPoints(size, handicap int) ([]Point, error) {
+	if handicap < MinHandicap || handicap > MaxHandicap {
+		return nil, ErrInvalidHandicap
+	}
+
+	// 9 路棋盘的星位在三三，更大的棋盘在四四
+	edge := 3
+	if size < 13 {
+		edge = 2
+	}
+	lo, mid, hi := edge, size/2, size-1-edge
+
+	// Point.X 是列，Point.Y 是行 (行 0 在棋盘上方)
+	corners := []Point{{X: lo, Y: hi}, {X: hi, Y: lo}, {X: lo, Y: lo}, {X: hi, Y: hi}}
+	center := Point{X: mid, Y: mid}
+	sides := []Point{{X: lo, Y: mid}, {X: hi, Y: mid}}
+	topBottom := []Point{{X: mid, Y: hi}, {X: mid, Y: lo}}
+
+	switch handicap {
+	case 2, 3, 4:
+		return append([]Point(nil), corners[:handicap]...), nil
+	case 5:
+		return append(append([]Point(nil), corners...), center), nil
+	}
+
+	points := append(append([]Point(nil), corners...), sides...)
+	if handicap >= 8 {
+		points = append(points, topBottom...)
+	}
+	if handicap%2 == 1 {
+		points = append(points, center)
+	}
+	return points, nil
+}
+
+// setupHandicap 按设置摆放让子，固定让子立即摆好，自由让子等待黑方落子
+func (g *Game) setupHandicap(handicap int, placement HandicapPlacement) error {
+	if handicap == 0 {
+		return nil
+	}
+	if placement == "" {
+		placement = HandicapFixed
+	}
+	if placement != HandicapFixed && placement != HandicapFree {
+		return ErrInvalidHandicapPlacement
+	}
+
+	points, err := HandicapPoints(g.Board.Size(), handicap)
+	if err != nil {
+		return err
+	}
+
+	g.Handicap = handicap
+	g.HandicapPlacement = placement
+	if placement == HandicapFixed {
+		for _, p := range points {
+			if err := g.placeHandicapStone(p); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// HandicapPending 判断黑方是否仍需自由摆放让子
+func (g *Game) HandicapPending() bool {
+	return g.Handicap > 0 && len(g.SetupStones) < g.Handicap
+}
+
+// placeHandicapStone 摆放一颗让子，全部摆完后轮到白方行棋
+func (g *Game) placeHandicapStone(p Point) error {
+	if _, _, err := g.Board.placeStone(Black, p, false); err != nil {
+		return err
+	}
+	g.SetupStones = append(g.SetupStones, p)
+
+	if !g.HandicapPending() {
+		// 让子摆放完毕，以当前局面作为劫争历史的起点
+		g.NextPlayer = White
+		g.History = PositionHistory{g.positionKey(g.Board, g.NextPlayer): true}
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestHandicapPoints 测试各棋盘大小的固定让子星位
+func TestHandicapPoints(t *testing.T) {
+	points, err := HandicapPoints(19, 2)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// D4 和 Q16
+	if len(points) != 2 || points[0] != (Point{X: 3, Y: 15}) || points[1] != (Point{X: 15, Y: 3}) {
+		t.Fatalf("Unexpected 2-stone handicap points: %v", points)
+	}
+
+	for _, size := range SupportedBoardSizes {
+		for n := MinHandicap; n <= MaxHandicap; n++ {
+			points, err := HandicapPoints(size, n)
+			if err != nil {
+				t.Fatalf("Expected no error for %d stones on %dx%d, got %v", n, size, size, err)
+			}
+			if len(points) != n {
+				t.Fatalf("Expected %d points on %dx%d, got %d", n, size, size, len(points))
+			}
+			seen := map[Point]bool{}
+			for _, p := range points {
+				if seen[p] {
+					t.Fatalf("Duplicate handicap point %v for %d stones on %dx%d", p, n, size, size)
+				}
+				seen[p] = true
+			}
+		}
+	}
+
+	// 9 路棋盘使用三三星位
+	points, _ = HandicapPoints(9, 5)
+	if points[4] != (Point{X: 4, Y: 4}) || points[2] != (Point{X: 2, Y: 2}) {
+		t.Fatalf("Unexpected 9x9 handicap points: %v", points)
+	}
+
+	if _, err := HandicapPoints(19, 1); !errors.Is(err, ErrInvalidHandicap) {
+		t.Fatalf("Expected ErrInvalidHandicap, got %v", err)
+	}
+}
+
+// TestHandicap_Fixed 测试固定让子开局
+func TestHandicap_Fixed(t *testing.T) {
+	g, err := NewGameWithOptions(GameOptions{Handicap: 4})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if g.NextPlayer != White {
+		t.Fatal("Expected White to move first in a handicap game")
+	}
+	for _, p := range []Point{{X: 3, Y: 3}, {X: 15, Y: 3}, {X: 3, Y: 15}, {X: 15, Y: 15}} {
+		if g.Board.Grid[p.Y][p.X] != Black {
+			t.Fatalf("Expected handicap stone at %v", p)
+		}
+	}
+	if len(g.SetupStones) != 4 || len(g.Moves) != 0 {
+		t.Fatal("Expected handicap stones to be setup stones, not moves")
+	}
+}
+
+// TestHandicap_Free 测试自由让子摆放
+func TestHandicap_Free(t *testing.T) {
+	g, err := NewGameWithOptions(GameOptions{BoardSize: 9, Handicap: 2, HandicapPlacement: HandicapFree})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if g.NextPlayer != Black || !g.HandicapPending() {
+		t.Fatal("Expected Black to place handicap stones first")
+	}
+	if err := g.PassTurn(); !errors.Is(err, ErrHandicapNotPlaced) {
+		t.Fatalf("Expected ErrHandicapNotPlaced, got %v", err)
+	}
+
+	_ = g.PlayMove(Point{X: 2, Y: 2})
+	if g.NextPlayer != Black {
+		t.Fatal("Expected Black to keep placing stones")
+	}
+	if err := g.PlayMove(Point{X: 2, Y: 2}); !errors.Is(err, ErrPointNotEmpty) {
+		t.Fatalf("Expected ErrPointNotEmpty, got %v", err)
+	}
+	_ = g.PlayMove(Point{X: 6, Y: 6})
+
+	if g.HandicapPending() || g.NextPlayer != White {
+		t.Fatal("Expected White to move after all handicap stones are placed")
+	}
+	if len(g.Moves) != 0 {
+		t.Fatalf("Expected no moves recorded during placement, got %d", len(g.Moves))
+	}
+
+	// 让子在重放和悔棋后保留
+	_ = g.PlayMove(Point{X: 4, Y: 4})
+	if err := g.Undo(1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if g.Board.Grid[2][2] != Black || g.Board.Grid[6][6] != Black || g.NextPlayer != White {
+		t.Fatal("Expected handicap stones to survive undo")
+	}
+}
+
+// TestHandicap_Invalid 测试非法的让子设置
+func TestHandicap_Invalid(t *testing.T) {
+	if _, err := NewGameWithOptions(GameOptions{Handicap: 10}); !errors.Is(err, ErrInvalidHandicap) {
+		t.Fatalf("Expected ErrInvalidHandicap, got %v", err)
+	}
+	if _, err := NewGameWithOptions(GameOptions{Handicap: 2, HandicapPlacement: "random"}); !errors.Is(err, ErrInvalidHandicapPlacement) {
+		t.Fatalf("Expected ErrInvalidHandicapPlacement, got %v", err)
+	}
+}
+
+// TestHandicap_ScoreCompensation 测试让子棋计分时的补偿
+func TestHandicap_ScoreCompensation(t *testing.T) {
+	g, _ := NewGameWithOptions(GameOptions{Handicap: 4})
+	g.GameOver = true
+
+	result, err := g.CalculateScore()
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	// 中国规则让子棋贴 0.5 目，白方另得让子数 (4 目) 的补偿
+	if result.WhiteScore != 4.5 {
+		t.Fatalf("Expected WhiteScore 4.5, got %f", result.WhiteScore)
+	}
+	// 黑方 4 子占据全盘: 361 > 4.5
+	if result.Winner != Black {
+		t.Fatalf("Expected Black to win, got %v", result.Winner)
+	}
+}
@@ -0,0 +1,113 @@
+package game
+
+import "errors"
+
+// MovePreview 描述在某点落子的结果，由 PreviewMove 在不修改对局的情况下算出
+type MovePreview struct {
+	Point    Point   `json:"point"`
+	Player   Player  `json:"player"`             // 行棋方
+	Legal    bool    `json:"legal"`              // 是否可以落子
+	Reason   string  `json:"reason,omitempty"`   // 不能落子的原因
+	Captured []Point `json:"captured,omitempty"` // 将被提掉的对方棋子
+	Suicided []Point `json:"suicided,omitempty"` // 规则允许自杀时将被提掉的己方棋子
+	KoPoint  *Point  `json:"ko_point,omitempty"` // 落子后对方不能立即回提的劫点
+}
+
+// checkPlayable 检查对局当前是否可以落子
+func (g *Game) checkPlayable() error {
+	if g.GameOver {
+		return errors.New("game is over")
+	}
+	if g.Status == GameStatusScoring {
+		return ErrScoringInProgress
+	}
+	return nil
+}
+
+// checkMove 检查轮到行棋的一方能否在 p 落子，返回落子后的劫争历史键
+// 按对局规则检查自杀和劫争，不修改棋盘
+func (g *Game) checkMove(p Point) (uint64, error) {
+	rules := g.rules()
+
+	// 单劫规则只禁止立即回提
+	if rules.Ko == KoSimple && g.KoPoint != nil && *g.KoPoint == p {
+		return 0, ErrKoViolation
+	}
+
+	hash, err := g.Board.hashAfter(g.NextPlayer, p, rules.SuicideAllowed)
+	if err != nil {
+		return 0, err // 来自 PlaceStone 的错误 (越界, 非空, 自杀)
+	}
+
+	next := getOpponent(g.NextPlayer)
+	key := g.historyKey(hash, next)
+	if rules.Ko != KoSimple && g.History[key] {
+		return 0, ErrKoViolation
+	}
+	return key, nil
+}
+
+// IsLegalMove 检查轮到行棋的一方能否在 p 落子，不能落子时返回原因
+// 不修改对局，也不扣除时间
+func (g *Game) IsLegalMove(p Point) error {
+	if err := g.checkPlayable(); err != nil {
+		return err
+	}
+	if g.HandicapPending() {
+		_, err := g.Board.hashAfter(Black, p, false)
+		return err
+	}
+	_, err := g.checkMove(p)
+	return err
+}
+
+// LegalMoves 返回轮到行棋的一方所有可以落子的点 (不含虚手)，按行列顺序排列
+func (g *Game) LegalMoves() []Point {
+	if g.checkPlayable() != nil {
+		return nil
+	}
+
+	size := g.Board.Size()
+	moves := make([]Point, 0, size*size)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			p := Point{X: x, Y: y}
+			if g.Board.Grid[y][x] == Empty && g.IsLegalMove(p) == nil {
+				moves = append(moves, p)
+			}
+		}
+	}
+	return moves
+}
+
+// PreviewMove 试算轮到行棋的一方在 p 落子的结果，不修改对局
+// 对局已结束或处于点目阶段时返回错误；落子本身不合法时 Legal 为 false 并给出原因
+func (g *Game) PreviewMove(p Point) (*MovePreview, error) {
+	if err := g.checkPlayable(); err != nil {
+		return nil, err
+	}
+
+	preview := &MovePreview{Point: p, Player: g.NextPlayer}
+	if g.HandicapPending() {
+		preview.Player = Black
+	}
+	if err := g.IsLegalMove(p); err != nil {
+		preview.Reason = err.Error()
+		return preview, nil
+	}
+
+	// 在棋盘副本上落子，得到提子和劫点
+	board := g.Board.Clone()
+	captured, suicided, err := board.placeStone(preview.Player, p, g.rules().SuicideAllowed && !g.HandicapPending())
+	if err != nil {
+		preview.Reason = err.Error()
+		return preview, nil
+	}
+	preview.Legal = true
+	preview.Captured = captured
+	preview.Suicided = suicided
+	if !g.HandicapPending() {
+		preview.KoPoint = koPoint(board, p, captured)
+	}
+	return preview, nil
+}
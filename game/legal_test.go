@@ -0,0 +1,81 @@
+package game
+
+import (
+	"errors"
+	"slices"
+	"testing"
+)
+
+// TestLegalMoves_EmptyBoard 测试空棋盘上所有点都可以落子
+func TestLegalMoves_EmptyBoard(t *testing.T) {
+	g := newRulesGame(t, "chinese")
+	if moves := g.LegalMoves(); len(moves) != 81 {
+		t.Fatalf("Expected 81 legal moves, got %d", len(moves))
+	}
+}
+
+// TestLegalMoves_SuicideAndSuperko 测试合法点排除自杀点和全局同形
+func TestLegalMoves_SuicideAndSuperko(t *testing.T) {
+	g := newRulesGame(t, "chinese")
+	setupKo(g)
+	_ = g.PlayMove(Point{X: 2, Y: 1}) // 黑提劫
+
+	moves := g.LegalMoves()
+	if slices.Contains(moves, Point{X: 1, Y: 1}) {
+		t.Fatal("Expected immediate recapture to be excluded by superko")
+	}
+	if slices.Contains(moves, Point{X: 0, Y: 0}) {
+		t.Fatal("Expected suicide point (0,0) to be excluded")
+	}
+	if !slices.Contains(moves, Point{X: 8, Y: 8}) {
+		t.Fatal("Expected (8,8) to be legal")
+	}
+
+	// 允许自杀的规则下，单子自杀会重复当前局面，仍被全局同形禁止
+	g = newRulesGame(t, "tromp_taylor")
+	setupKo(g)
+	_ = g.PlayMove(Point{X: 2, Y: 1})
+	if err := g.IsLegalMove(Point{X: 0, Y: 0}); !errors.Is(err, ErrKoViolation) {
+		t.Fatalf("Expected single stone suicide to violate superko, got %v", err)
+	}
+}
+
+// TestPreviewMove 测试试算落子结果且不修改对局
+func TestPreviewMove(t *testing.T) {
+	g := newRulesGame(t, "japanese")
+	setupKo(g)
+	before := g.PositionHash()
+
+	preview, err := g.PreviewMove(Point{X: 2, Y: 1})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !preview.Legal || preview.Player != Black {
+		t.Fatalf("Expected legal black move, got %+v", preview)
+	}
+	if len(preview.Captured) != 1 || preview.Captured[0] != (Point{X: 1, Y: 1}) {
+		t.Fatalf("Expected capture at (1,1), got %v", preview.Captured)
+	}
+	if preview.KoPoint == nil || *preview.KoPoint != (Point{X: 1, Y: 1}) {
+		t.Fatalf("Expected ko point at (1,1), got %v", preview.KoPoint)
+	}
+	if g.PositionHash() != before || len(g.Moves) != 0 || g.Board.Grid[1][2] != Empty {
+		t.Fatal("Expected game to be unchanged after preview")
+	}
+
+	// 提劫后白棋不能立即回提
+	_ = g.PlayMove(Point{X: 2, Y: 1})
+	preview, _ = g.PreviewMove(Point{X: 1, Y: 1})
+	if preview.Legal || preview.Reason != ErrKoViolation.Error() {
+		t.Fatalf("Expected ko violation, got %+v", preview)
+	}
+	if err := g.IsLegalMove(Point{X: 1, Y: 1}); !errors.Is(err, ErrKoViolation) {
+		t.Fatalf("Expected ErrKoViolation, got %v", err)
+	}
+
+	// 对局结束后不能试算
+	_ = g.Resign(White)
+	if _, err := g.PreviewMove(Point{X: 5, Y: 5}); err == nil {
+		t.Fatal("Expected error after game over")
+	}
+}
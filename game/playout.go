@@ -0,0 +1,184 @@
+package game
+
+import "slices"
+
+// 本文件提供模拟对局 (如 MCTS 的随机对局) 需要的落子和查询
+// 只按单劫规则处理劫争，不记录历史和提子坐标，全局同形由调用方在对局层面检查
+
+// CopyFrom 把 o 的局面复制到 b，路数相同时复用 b 已有的内存
+func (b *Board) CopyFrom(o *Board) {
+	st := o.sync()
+	if b.Size() != o.Size() {
+		b.Grid = newGrid(o.Size())
+		b.chains = nil
+	}
+	for y := range o.Grid {
+		copy(b.Grid[y], o.Grid[y])
+	}
+	if b.chains == nil || b.chains.geo != st.geo {
+		b.chains = st.clone()
+		return
+	}
+	b.chains.copyFrom(st)
+}
+
+// CanPlay 判断 player 能否在 p 落子 (越界、非空和自杀)，不考虑劫争
+func (b *Board) CanPlay(player Player, p Point) bool {
+	if b.InBounds(p) && b.Grid[p.Y][p.X] == Empty && b.emptyNeighbors(p) > 0 {
+		return true // 有气的落子不会自杀，不需要棋块信息
+	}
+	_, _, _, err := b.checkMove(player, p, false)
+	return err == nil
+}
+
+// Play 由 player 在 p 落子，不允许自杀，不考虑劫争
+// 返回对方下一手不能立即回提的劫点，没有时为 nil
+func (b *Board) Play(player Player, p Point) (ko *Point, err error) {
+	st, i, effect, err := b.checkMove(player, p, false)
+	if err != nil {
+		return nil, err
+	}
+	st.addStone(b.Grid, i, player)
+	captured, at := 0, int16(-1)
+	for _, r := range effect.captures[:effect.nCaptures] {
+		captured += st.clearChain(b.Grid, r)
+		at = r
+	}
+
+	// 只提一子，且落下的子是单独一子、只剩被提处一口气时形成劫
+	if captured == 1 && st.stones[st.root[i]] == 1 && st.libs[i] == 1 {
+		size := b.Size()
+		return &Point{X: int(at) % size, Y: int(at) / size}, nil
+	}
+	return nil, nil
+}
+
+// Liberties 返回 p 所在棋块的气数，数到 limit 为止，last 是最后数到的一口气
+// p 为空点时返回 0
+func (b *Board) Liberties(p Point, limit int) (n int, last Point) {
+	if b.Grid[p.Y][p.X] == Empty {
+		return 0, Point{}
+	}
+	st := b.sync()
+	size := b.Size()
+	n, i := st.countLiberties(st.root[p.Y*size+p.X], limit)
+	if n == 0 {
+		return 0, Point{}
+	}
+	return n, Point{X: int(i) % size, Y: int(i) / size}
+}
+
+// AtariLiberties 把 points 各点所在棋块及其相邻棋块中只剩一口气的棋块的气追加到 libs 后返回
+// 对方的这些棋块可以提，己方的可以长出逃跑；空点只检查相邻棋块
+func (b *Board) AtariLiberties(libs []Point, points ...Point) []Point {
+	st := b.sync()
+	size := b.Size()
+	seen := make([]int16, 0, 5*len(points))
+	check := func(q int16) {
+		if st.shadow[q] == Empty || slices.Contains(seen, st.root[q]) {
+			return
+		}
+		seen = append(seen, st.root[q])
+		if n, lib := st.countLiberties(st.root[q], 2); n == 1 {
+			libs = append(libs, Point{X: int(lib) % size, Y: int(lib) / size})
+		}
+	}
+	for _, p := range points {
+		i := int16(p.Y*size + p.X)
+		check(i)
+		for _, nb := range st.geo.adj[i] {
+			check(nb)
+		}
+	}
+	return libs
+}
+
+// ChainSize 返回 p 所在棋块的棋子数，p 为空点时返回 0
+func (b *Board) ChainSize(p Point) int {
+	if b.Grid[p.Y][p.X] == Empty {
+		return 0
+	}
+	st := b.sync()
+	return int(st.stones[st.root[p.Y*b.Size()+p.X]])
+}
+
+// IsEye 判断空点 p 是否是 player 的眼：上下左右都是 player 的棋子，
+// 对角上的对方棋子不足以破眼 (边角上没有，中腹最多一个)
+func (b *Board) IsEye(player Player, p Point) bool {
+	if b.Grid[p.Y][p.X] != Empty {
+		return false
+	}
+	for _, d := range [4]Point{{X: -1}, {X: 1}, {Y: -1}, {Y: 1}} {
+		if q := (Point{X: p.X + d.X, Y: p.Y + d.Y}); b.InBounds(q) && b.Grid[q.Y][q.X] != player {
+			return false
+		}
+	}
+	bad, edge := 0, false
+	for _, d := range [4]Point{{X: -1, Y: -1}, {X: 1, Y: -1}, {X: -1, Y: 1}, {X: 1, Y: 1}} {
+		q := Point{X: p.X + d.X, Y: p.Y + d.Y}
+		if !b.InBounds(q) {
+			edge = true
+		} else if b.Grid[q.Y][q.X] == getOpponent(player) {
+			bad++
+		}
+	}
+	if edge {
+		return bad == 0
+	}
+	return bad < 2
+}
+
+// SelfAtari 判断 player 在空点 p 落子后是否不提子、己方棋块只剩一口气
+func (b *Board) SelfAtari(player Player, p Point) bool {
+	if b.emptyNeighbors(p) >= 2 {
+		return false
+	}
+	st := b.sync()
+	i := int16(p.Y*b.Size() + p.X)
+	if st.analyze(i, player).nCaptures > 0 {
+		return false // 能提子时不算自紧气
+	}
+
+	gen := st.nextMark()
+	libs := 0
+	count := func(q int16) bool {
+		if st.mark[q] != gen {
+			st.mark[q] = gen
+			libs++
+		}
+		return libs >= 2
+	}
+	st.mark[i] = gen // 落子点本身不是气
+	for _, nb := range st.geo.adj[i] {
+		if st.shadow[nb] == Empty && count(nb) {
+			return false
+		}
+	}
+	for _, nb := range st.geo.adj[i] {
+		if st.shadow[nb] != player {
+			continue
+		}
+		for s := st.root[nb]; ; {
+			for _, q := range st.geo.adj[s] {
+				if st.shadow[q] == Empty && count(q) {
+					return false
+				}
+			}
+			if s = st.next[s]; s == st.root[nb] {
+				break
+			}
+		}
+	}
+	return true
+}
+
+// emptyNeighbors 返回 p 上下左右的空点数，只读取 Grid
+func (b *Board) emptyNeighbors(p Point) int {
+	n := 0
+	for _, d := range [4]Point{{X: -1}, {X: 1}, {Y: -1}, {Y: 1}} {
+		if q := (Point{X: p.X + d.X, Y: p.Y + d.Y}); b.InBounds(q) && b.Grid[q.Y][q.X] == Empty {
+			n++
+		}
+	}
+	return n
+}
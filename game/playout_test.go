@@ -0,0 +1,137 @@
+package game
+
+import (
+	"slices"
+	"testing"
+)
+
+// TestPlayout_MatchesPlaceStone 测试随机对局中 Play 与 PlaceStone 的落子结果和劫点一致
+func TestPlayout_MatchesPlaceStone(t *testing.T) {
+	for _, size := range []int{9, 19} {
+		b := NewBoardWithSize(size)
+		want := NewBoardWithSize(size)
+
+		step := 0
+		randomPlayout(b, int64(size), 3000, func(player Player, p Point) ([]Point, []Point, error) {
+			step++
+			canPlay := b.CanPlay(player, p)
+			ko, err := b.Play(player, p)
+			captured, _, wantErr := want.placeStone(player, p, false)
+
+			if err != wantErr || canPlay != (err == nil) {
+				t.Fatalf("size %d step %d: expected error %v at %v, got %v (CanPlay %v)", size, step, wantErr, p, err, canPlay)
+			}
+			if b.Hash() != want.Hash() {
+				t.Fatalf("size %d step %d: boards differ after %v", size, step, p)
+			}
+			if wantKo := koPoint(want, p, captured); (ko == nil) != (wantKo == nil) || ko != nil && *ko != *wantKo {
+				t.Fatalf("size %d step %d: expected ko %v, got %v", size, step, wantKo, ko)
+			}
+			return captured, nil, err
+		})
+	}
+}
+
+// TestPlayout_CopyFrom 测试复制局面后两块棋盘互不影响，直接修改 Grid 后仍能复制
+func TestPlayout_CopyFrom(t *testing.T) {
+	root := NewBoardWithSize(9)
+	_, _ = root.PlaceStone(Black, Point{X: 2, Y: 2})
+	b := NewBoardWithSize(9)
+
+	b.CopyFrom(root)
+	if _, err := b.Play(White, Point{X: 3, Y: 2}); err != nil {
+		t.Fatal(err)
+	}
+	if root.Grid[2][3] != Empty {
+		t.Fatal("Expected the source board to be unchanged")
+	}
+
+	root.Grid[0][0] = White
+	b.CopyFrom(root)
+	if b.Hash() != root.Rehash() || b.Grid[2][3] != Empty {
+		t.Fatal("Expected CopyFrom to restore the source position")
+	}
+	if n, _ := b.Liberties(Point{X: 0, Y: 0}, 4); n != 2 {
+		t.Fatalf("Expected the directly written stone to have 2 liberties, got %d", n)
+	}
+
+	// 路数不同时重新分配
+	b.CopyFrom(NewBoardWithSize(13))
+	if b.Size() != 13 || !b.CanPlay(Black, Point{X: 12, Y: 12}) {
+		t.Fatal("Expected CopyFrom to resize the board")
+	}
+}
+
+// TestPlayout_Liberties 测试棋块的气数、最后一口气和棋子数
+func TestPlayout_Liberties(t *testing.T) {
+	b := NewBoardWithSize(9)
+	for _, p := range []Point{{X: 0, Y: 0}, {X: 1, Y: 0}} {
+		_, _ = b.PlaceStone(Black, p)
+	}
+	_, _ = b.PlaceStone(White, Point{X: 0, Y: 1})
+
+	if n, _ := b.Liberties(Point{X: 1, Y: 0}, 10); n != 2 || b.ChainSize(Point{X: 0, Y: 0}) != 2 {
+		t.Fatalf("Expected 2 stones with 2 liberties, got %d liberties and %d stones", n, b.ChainSize(Point{X: 0, Y: 0}))
+	}
+	if n, _ := b.Liberties(Point{X: 1, Y: 0}, 1); n != 1 {
+		t.Fatalf("Expected counting to stop at the limit, got %d", n)
+	}
+	_, _ = b.PlaceStone(White, Point{X: 2, Y: 0})
+	if n, last := b.Liberties(Point{X: 0, Y: 0}, 2); n != 1 || last != (Point{X: 1, Y: 1}) {
+		t.Fatalf("Expected the last liberty at (1,1), got %d at %v", n, last)
+	}
+	if n, _ := b.Liberties(Point{X: 5, Y: 5}, 2); n != 0 || b.ChainSize(Point{X: 5, Y: 5}) != 0 {
+		t.Fatal("Expected an empty point to have no chain")
+	}
+
+	// 白棋 (2,0) 和 (0,1) 旁的黑棋只剩一口气，黑棋可以长出，白棋可以提
+	libs := b.AtariLiberties(nil, Point{X: 2, Y: 0})
+	if !slices.Equal(libs, []Point{{X: 1, Y: 1}}) {
+		t.Fatalf("Expected the atari liberty (1,1), got %v", libs)
+	}
+	if libs := b.AtariLiberties(nil, Point{X: 2, Y: 0}, Point{X: 0, Y: 1}); len(libs) != 1 {
+		t.Fatalf("Expected each chain to be reported once, got %v", libs)
+	}
+}
+
+// TestPlayout_Eye 测试眼的判断：对角有对方棋子的边上的点不是眼
+func TestPlayout_Eye(t *testing.T) {
+	b := NewBoardWithSize(9)
+	// 黑棋围住 (1,0)
+	for _, p := range []Point{{X: 0, Y: 0}, {X: 2, Y: 0}, {X: 1, Y: 1}} {
+		_, _ = b.PlaceStone(Black, p)
+	}
+	p := Point{X: 1, Y: 0}
+	if !b.IsEye(Black, p) || b.IsEye(White, p) {
+		t.Fatal("Expected (1,0) to be a black eye")
+	}
+	_, _ = b.PlaceStone(White, Point{X: 2, Y: 1})
+	if b.IsEye(Black, p) {
+		t.Fatal("Expected a white stone on the diagonal to break the edge eye")
+	}
+}
+
+// TestPlayout_SelfAtari 测试自紧气的判断：能提子时不算自紧气
+func TestPlayout_SelfAtari(t *testing.T) {
+	b := NewBoardWithSize(9)
+	_, _ = b.PlaceStone(Black, Point{X: 1, Y: 0})
+	_, _ = b.PlaceStone(White, Point{X: 2, Y: 0})
+	_, _ = b.PlaceStone(White, Point{X: 1, Y: 1})
+
+	// 黑棋在角上接上后只剩 (0,1) 一口气
+	if !b.SelfAtari(Black, Point{X: 0, Y: 0}) {
+		t.Fatal("Expected connecting in the corner to be a self-atari")
+	}
+	if b.SelfAtari(Black, Point{X: 5, Y: 5}) {
+		t.Fatal("Expected an open point not to be a self-atari")
+	}
+
+	// 白棋 (2,0) 只剩 (3,0) 一口气时，黑棋在 (3,0) 提子不算自紧气
+	// 不提子的话黑棋落在 (3,0) 没有气
+	_, _ = b.PlaceStone(Black, Point{X: 2, Y: 1})
+	_, _ = b.PlaceStone(White, Point{X: 4, Y: 0})
+	_, _ = b.PlaceStone(White, Point{X: 3, Y: 1})
+	if b.SelfAtari(Black, Point{X: 3, Y: 0}) {
+		t.Fatal("Expected a capturing move not to be a self-atari")
+	}
+}
@@ -0,0 +1,182 @@
+package game
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+)
+
+var ErrInvalidRecord = errors.New("invalid game record")
+
+// Setup 是棋谱中在第一手之前摆放的局面 (SGF 的 AB/AW/PL)
+type Setup struct {
+	Black      []Point `json:"black,omitempty" bson:"black,omitempty"`             // 摆放的黑子
+	White      []Point `json:"white,omitempty" bson:"white,omitempty"`             // 摆放的白子
+	NextPlayer Player  `json:"next_player,omitempty" bson:"next_player,omitempty"` // 摆放后的行棋方，Empty 表示黑先
+}
+
+// RecordInfo 是棋谱中记录的对局信息，导入棋谱时保存，导出棋谱时写出
+type RecordInfo struct {
+	BlackName string `json:"black_name,omitempty" bson:"black_name,omitempty"`
+	BlackRank string `json:"black_rank,omitempty" bson:"black_rank,omitempty"`
+	WhiteName string `json:"white_name,omitempty" bson:"white_name,omitempty"`
+	WhiteRank string `json:"white_rank,omitempty" bson:"white_rank,omitempty"`
+	Date      string `json:"date,omitempty" bson:"date,omitempty"`       // 对局日期，如 2025-12-03
+	Event     string `json:"event,omitempty" bson:"event,omitempty"`     // 比赛名称
+	Place     string `json:"place,omitempty" bson:"place,omitempty"`     // 对局地点或平台
+	Comment   string `json:"comment,omitempty" bson:"comment,omitempty"` // 对局评注
+}
+
+// Record 是与格式无关的棋谱，SGF 等外部格式都先转换为 Record 再导入
+// 只包含主线，变化图由棋谱树单独保存
+type Record struct {
+	Size        int          // 棋盘大小，0 表示 19 路
+	Komi        float64      // 贴目
+	Rules       string       // 规则名称，无法识别时按中国规则导入
+	Handicap    int          // 让子数
+	TimeControl *TimeControl // 计时设置，为空表示未记录
+	Info        RecordInfo
+	Setup       Setup   // 让子和摆子
+	Moves       []Move  // 按顺序的每一手，只使用 Player、Pass、Point 和 Comment
+	Result      *Result // 对局结果，为空表示没有记录结果
+}
+
+// handicapSetup 判断摆子是否就是让子：只有黑子、数量与让子数相同且白先
+func (rec *Record) handicapSetup() bool {
+	s := rec.Setup
+	return rec.Handicap > 0 && len(s.Black) == rec.Handicap && len(s.White) == 0 && s.NextPlayer != Black
+}
+
+// Game 按棋谱重建对局：有结果的棋谱导入为已结束的对局，没有结果的导入为复盘状态
+// 棋谱中的每一手都按对局规则检查，非法的一手返回 ErrInvalidRecord
+func (rec *Record) Game() (*Game, error) {
+	rules, err := RuleSetByName(rec.Rules)
+	if err != nil {
+		rules = ChineseRules
+	}
+	komi := rec.Komi
+	opts := GameOptions{
+		BoardSize:   rec.Size,
+		Rules:       rules.Name,
+		Komi:        &komi,
+		TimeControl: rec.TimeControl,
+	}
+
+	hasSetup := len(rec.Setup.Black) > 0 || len(rec.Setup.White) > 0 || rec.Setup.NextPlayer != Empty
+	switch {
+	case rec.handicapSetup():
+		opts.Handicap, opts.HandicapPlacement = rec.Handicap, HandicapFree
+	case rec.Handicap > 0 && !hasSetup:
+		// 棋谱只记录了让子数，按固定星位摆放
+		opts.Handicap, opts.HandicapPlacement = rec.Handicap, HandicapFixed
+	}
+
+	g, err := NewGameWithOptions(opts)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidRecord, err)
+	}
+	info := rec.Info
+	g.Info = &info
+
+	if opts.HandicapPlacement == HandicapFree {
+		for _, p := range rec.Setup.Black {
+			if err := g.placeHandicapStone(p); err != nil {
+				return nil, fmt.Errorf("%w: handicap stone %v: %v", ErrInvalidRecord, p, err)
+			}
+		}
+	} else if hasSetup && opts.Handicap == 0 {
+		g.Setup = &Setup{
+			Black:      slices.Clone(rec.Setup.Black),
+			White:      slices.Clone(rec.Setup.White),
+			NextPlayer: rec.Setup.NextPlayer,
+		}
+		if err := g.applySetup(); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, m := range rec.Moves {
+		if m.Player != g.NextPlayer {
+			return nil, fmt.Errorf("%w: move %d: expected %v to move", ErrInvalidRecord, i+1, g.NextPlayer)
+		}
+		var captured, suicided []Point
+		if m.Pass {
+			g.applyPass()
+		} else if captured, suicided, err = g.applyMove(m.Point); err != nil {
+			return nil, fmt.Errorf("%w: move %d: %v", ErrInvalidRecord, i+1, err)
+		}
+		g.Moves = append(g.Moves, Move{
+			Number:   i + 1,
+			Player:   m.Player,
+			Pass:     m.Pass,
+			Point:    m.Point,
+			Captured: captured,
+			Suicided: suicided,
+			Comment:  m.Comment,
+		})
+	}
+
+	// 棋谱中连续虚手不进入点目阶段，结果以棋谱记录为准
+	g.Scoring = nil
+	if rec.Result != nil {
+		g.finish(*rec.Result)
+	} else {
+		g.GameOver = true
+		g.Status = GameStatusReview
+	}
+	return g, nil
+}
+
+// applySetup 在空棋盘上摆放棋谱的摆子局面，并以此作为劫争历史的起点
+func (g *Game) applySetup() error {
+	for _, stones := range []struct {
+		color  Player
+		points []Point
+	}{{Black, g.Setup.Black}, {White, g.Setup.White}} {
+		for _, p := range stones.points {
+			if _, _, err := g.Board.placeStone(stones.color, p, false); err != nil {
+				return fmt.Errorf("%w: setup stone %v: %v", ErrInvalidRecord, p, err)
+			}
+		}
+	}
+	if g.Setup.NextPlayer != Empty {
+		g.NextPlayer = g.Setup.NextPlayer
+	}
+	g.History = PositionHistory{g.positionKey(g.Board, g.NextPlayer): true}
+	return nil
+}
+
+// Record 导出对局的棋谱，让子作为黑方摆子写出
+// 没有记录对局日期时，使用第一手的时间
+func (g *Game) Record() *Record {
+	rules := g.rules()
+	tc := g.timeControl()
+	rec := &Record{
+		Size:        g.Board.Size(),
+		Komi:        rules.Komi,
+		Rules:       rules.Name,
+		Handicap:    g.Handicap,
+		TimeControl: &tc,
+		Result:      g.Result,
+	}
+	if g.Info != nil {
+		rec.Info = *g.Info
+	}
+	if g.Setup != nil {
+		rec.Setup = Setup{
+			Black:      slices.Clone(g.Setup.Black),
+			White:      slices.Clone(g.Setup.White),
+			NextPlayer: g.Setup.NextPlayer,
+		}
+	}
+	rec.Setup.Black = append(rec.Setup.Black, g.SetupStones...)
+
+	for _, m := range g.Moves {
+		rec.Moves = append(rec.Moves, Move{Player: m.Player, Pass: m.Pass, Point: m.Point, Comment: m.Comment})
+	}
+	if rec.Info.Date == "" && len(g.Moves) > 0 && g.Moves[0].Timestamp > 0 {
+		rec.Info.Date = time.Unix(g.Moves[0].Timestamp, 0).Format("2006-01-02")
+	}
+	return rec
+}
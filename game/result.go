@@ -0,0 +1,177 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strconv"
+	"strings"
+)
+
+var (
+	ErrGameNotActive       = errors.New("game is not in progress")
+	ErrInvalidResultReason = errors.New("invalid result reason")
+	ErrInvalidResult       = errors.New("invalid result notation")
+)
+
+// ResultReason 表示对局结束的原因
+type ResultReason string
+
+const (
+	ReasonResign      ResultReason = "resign"      // 认输
+	ReasonTimeout     ResultReason = "timeout"     // 超时负
+	ReasonScore       ResultReason = "score"       // 终局计分
+	ReasonForfeit     ResultReason = "forfeit"     // 判负
+	ReasonAbandonment ResultReason = "abandonment" // 弃局
+)
+
+// Result 记录对局的最终结果
+// Winner 为 Empty 表示和棋 (计分) 或无胜负 (弃局)
+type Result struct {
+	Winner Player       `json:"winner" bson:"winner"`
+	Margin float64      `json:"margin" bson:"margin"` // 胜负目数，仅计分结果有意义
+	Reason ResultReason `json:"reason" bson:"reason"`
+}
+
+// String 返回常用的对局结果记法，如 "B+R"、"W+3.5"、"B+T"
+func (r Result) String() string {
+	var prefix string
+	switch r.Winner {
+	case Black:
+		prefix = "B+"
+	case White:
+		prefix = "W+"
+	default:
+		if r.Reason == ReasonScore {
+			return "Draw"
+		}
+		return "Void"
+	}
+
+	switch r.Reason {
+	case ReasonResign:
+		return prefix + "R"
+	case ReasonTimeout:
+		return prefix + "T"
+	case ReasonForfeit, ReasonAbandonment:
+		return prefix + "F"
+	default:
+		if r.Margin == 0 {
+			return prefix // 胜负目数未知
+		}
+		return prefix + strconv.FormatFloat(r.Margin, 'f', -1, 64)
+	}
+}
+
+// ParseResult 解析对局结果记法，兼容 SGF RE 属性的常见写法
+// 如 "B+R"、"W+Resign"、"B+3.5"、"W+T"、"B+F"、"B+" (目数未知)、"0"/"Draw"、"Void"
+func ParseResult(s string) (*Result, error) {
+	s = strings.TrimSpace(s)
+	switch strings.ToLower(s) {
+	case "0", "draw", "jigo":
+		return &Result{Winner: Empty, Reason: ReasonScore}, nil
+	case "void":
+		return &Result{Winner: Empty, Reason: ReasonAbandonment}, nil
+	}
+
+	winner, detail, ok := strings.Cut(s, "+")
+	if !ok {
+		return nil, ErrInvalidResult
+	}
+	r := &Result{}
+	switch strings.ToUpper(winner) {
+	case "B":
+		r.Winner = Black
+	case "W":
+		r.Winner = White
+	default:
+		return nil, ErrInvalidResult
+	}
+
+	switch strings.ToLower(detail) {
+	case "r", "resign":
+		r.Reason = ReasonResign
+	case "t", "time":
+		r.Reason = ReasonTimeout
+	case "f", "forfeit":
+		r.Reason = ReasonForfeit
+	case "":
+		r.Reason = ReasonScore
+	default:
+		margin, err := strconv.ParseFloat(detail, 64)
+		if err != nil || margin < 0 {
+			return nil, ErrInvalidResult
+		}
+		r.Reason, r.Margin = ReasonScore, margin
+	}
+	return r, nil
+}
+
+// MarshalJSON 在 JSON 中附带结果记法，便于前端直接展示
+func (r Result) MarshalJSON() ([]byte, error) {
+	type Alias Result
+	return json.Marshal(&struct {
+		Alias
+		Notation string `json:"notation"`
+	}{
+		Alias:    Alias(r),
+		Notation: r.String(),
+	})
+}
+
+// active 判断对局是否仍在进行 (对弈中或点目阶段)
+func (g *Game) active() bool {
+	return !g.GameOver && (g.Status == GameStatusPlaying || g.Status == GameStatusScoring)
+}
+
+// finish 记录对局结果并结束对局
+func (g *Game) finish(result Result) {
+	g.Result = &result
+	g.GameOver = true
+	g.Status = GameStatusFinished
+	g.PendingUndo = nil
+}
+
+// Resign 指定玩家认输，对手获胜
+func (g *Game) Resign(player Player) error {
+	if !g.active() {
+		return ErrGameNotActive
+	}
+	if player != Black && player != White {
+		return ErrNotAPlayer
+	}
+
+	g.finish(Result{Winner: player.Opponent(), Reason: ReasonResign})
+	return nil
+}
+
+// Forfeit 判指定玩家负，reason 只能是判负或弃局
+func (g *Game) Forfeit(player Player, reason ResultReason) error {
+	if !g.active() {
+		return ErrGameNotActive
+	}
+	if player != Black && player != White {
+		return ErrNotAPlayer
+	}
+	if reason != ReasonForfeit && reason != ReasonAbandonment {
+		return ErrInvalidResultReason
+	}
+
+	g.finish(Result{Winner: player.Opponent(), Reason: reason})
+	return nil
+}
+
+// finishByScore 按当前死子标记计分并记录结果
+func (g *Game) finishByScore() error {
+	score, err := g.CalculateScore()
+	if err != nil {
+		return err
+	}
+
+	g.finish(Result{
+		Winner: score.Winner,
+		Margin: math.Abs(score.BlackScore - score.WhiteScore),
+		Reason: ReasonScore,
+	})
+	return nil
+}
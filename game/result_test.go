@@ -0,0 +1,111 @@
+package game
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// TestResult_String 测试对局结果记法
+func TestResult_String(t *testing.T) {
+	cases := []struct {
+		result Result
+		want   string
+	}{
+		{Result{Winner: Black, Reason: ReasonResign}, "B+R"},
+		{Result{Winner: White, Margin: 3.5, Reason: ReasonScore}, "W+3.5"},
+		{Result{Winner: Black, Margin: 12, Reason: ReasonScore}, "B+12"},
+		{Result{Winner: Black, Reason: ReasonTimeout}, "B+T"},
+		{Result{Winner: White, Reason: ReasonForfeit}, "W+F"},
+		{Result{Winner: Empty, Reason: ReasonScore}, "Draw"},
+		{Result{Winner: Empty, Reason: ReasonAbandonment}, "Void"},
+	}
+
+	for _, c := range cases {
+		if got := c.result.String(); got != c.want {
+			t.Errorf("Expected %q for %+v, got %q", c.want, c.result, got)
+		}
+	}
+
+	data, _ := json.Marshal(Result{Winner: White, Margin: 3.5, Reason: ReasonScore})
+	if !strings.Contains(string(data), `"notation":"W+3.5"`) {
+		t.Fatalf("Expected notation in JSON, got %s", data)
+	}
+}
+
+// TestResign 测试认输后记录结果并结束对局
+func TestResign(t *testing.T) {
+	g := newPlayingGame()
+	_ = g.PlayMove(Point{X: 3, Y: 3})
+
+	if err := g.Resign(White); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !g.GameOver || g.Status != GameStatusFinished {
+		t.Fatalf("Expected game to be finished, got status %s", g.Status)
+	}
+	if g.Result == nil || g.Result.String() != "B+R" {
+		t.Fatalf("Expected B+R, got %v", g.Result)
+	}
+
+	if err := g.Resign(Black); !errors.Is(err, ErrGameNotActive) {
+		t.Fatalf("Expected ErrGameNotActive, got %v", err)
+	}
+}
+
+// TestTimeout_RecordsResult 测试超时判负并记录结果
+func TestTimeout_RecordsResult(t *testing.T) {
+	g := newPlayingGame()
+	g.BlackTimeLeft = 10
+	g.LastMoveTime = getCurrentTimestamp() - 60
+
+	if err := g.PlayMove(Point{X: 3, Y: 3}); !errors.Is(err, ErrTimeOut) {
+		t.Fatalf("Expected ErrTimeOut, got %v", err)
+	}
+	if g.Status != GameStatusFinished || g.Result == nil || g.Result.String() != "W+T" {
+		t.Fatalf("Expected W+T, got %v", g.Result)
+	}
+}
+
+// TestScoring_RecordsResult 测试双方确认点目后记录计分结果
+func TestScoring_RecordsResult(t *testing.T) {
+	g := newScoringGame(t)
+	_ = g.ToggleDeadStones(Point{X: 1, Y: 4})
+	_ = g.AcceptScore(Black)
+	_ = g.AcceptScore(White)
+
+	// 黑 45 : 白 43.5
+	if g.Result == nil || g.Result.String() != "B+1.5" {
+		t.Fatalf("Expected B+1.5, got %v", g.Result)
+	}
+}
+
+// TestParseResult 测试解析结果记法
+func TestParseResult(t *testing.T) {
+	for notation, want := range map[string]string{
+		"B+R":      "B+R",
+		"W+Resign": "W+R",
+		"B+3.5":    "B+3.5",
+		"W+Time":   "W+T",
+		"B+F":      "B+F",
+		"W+":       "W+",
+		"0":        "Draw",
+		"Void":     "Void",
+		" b+0.5 ":  "B+0.5",
+	} {
+		r, err := ParseResult(notation)
+		if err != nil {
+			t.Fatalf("Expected %q to parse, got %v", notation, err)
+		}
+		if r.String() != want {
+			t.Fatalf("Expected %q to parse as %s, got %s", notation, want, r.String())
+		}
+	}
+
+	for _, notation := range []string{"", "?", "X+R", "B+abc", "B+-1"} {
+		if _, err := ParseResult(notation); !errors.Is(err, ErrInvalidResult) {
+			t.Fatalf("Expected ErrInvalidResult for %q, got %v", notation, err)
+		}
+	}
+}
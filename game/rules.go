@@ -0,0 +1,146 @@
+package game
+
+import (
+	"errors"
+	"strings"
+)
+
+// ScoringMethod 表示计分方式
+type ScoringMethod string
+
+const (
+	ScoringArea      ScoringMethod = "area"      // 数子法：棋子 + 围空
+	ScoringTerritory ScoringMethod = "territory" // 数目法：围空 + 提子
+)
+
+// KoRule 表示劫争 (全局同形) 的判定方式
+type KoRule string
+
+const (
+	KoSimple             KoRule = "simple"      // 只禁止立即回提单劫
+	KoPositionalSuperko  KoRule = "positional"  // 禁止重复任何出现过的棋盘局面
+	KoSituationalSuperko KoRule = "situational" // 禁止重复相同行棋方的棋盘局面
+)
+
+// HandicapCompensation 表示让子棋中白方得到的补偿方式
+type HandicapCompensation string
+
+const (
+	CompensationNone          HandicapCompensation = "none" // 无补偿
+	CompensationPerStone      HandicapCompensation = "n"    // 每颗让子补偿 1 目
+	CompensationPerStoneLess1 HandicapCompensation = "n-1"  // 让子数减 1 目
+)
+
+var ErrUnknownRuleSet = errors.New("unknown rule set")
+
+// RuleSet 描述一套围棋规则
+type RuleSet struct {
+	Name                 string               `json:"name" bson:"name"`
+	Scoring              ScoringMethod        `json:"scoring" bson:"scoring"`
+	Komi                 float64              `json:"komi" bson:"komi"` // 贴目（目）
+	Ko                   KoRule               `json:"ko" bson:"ko"`
+	SuicideAllowed       bool                 `json:"suicide_allowed" bson:"suicide_allowed"`             // 是否允许自杀
+	PassStones           bool                 `json:"pass_stones" bson:"pass_stones"`                     // 虚手时是否向对方交一颗提子
+	HandicapCompensation HandicapCompensation `json:"handicap_compensation" bson:"handicap_compensation"` // 让子棋白方补偿
+}
+
+// 预定义的规则集
+var (
+	ChineseRules = RuleSet{
+		Name:                 "chinese",
+		Scoring:              ScoringArea,
+		Komi:                 7.5,
+		Ko:                   KoPositionalSuperko,
+		HandicapCompensation: CompensationPerStone,
+	}
+	JapaneseRules = RuleSet{
+		Name:                 "japanese",
+		Scoring:              ScoringTerritory,
+		Komi:                 6.5,
+		Ko:                   KoSimple,
+		HandicapCompensation: CompensationNone,
+	}
+	AGARules = RuleSet{
+		Name:                 "aga",
+		Scoring:              ScoringArea,
+		Komi:                 7.5,
+		Ko:                   KoSituationalSuperko,
+		PassStones:           true,
+		HandicapCompensation: CompensationPerStoneLess1,
+	}
+	NewZealandRules = RuleSet{
+		Name:                 "new_zealand",
+		Scoring:              ScoringArea,
+		Komi:                 7,
+		Ko:                   KoSituationalSuperko,
+		SuicideAllowed:       true,
+		HandicapCompensation: CompensationPerStone,
+	}
+	TrompTaylorRules = RuleSet{
+		Name:                 "tromp_taylor",
+		Scoring:              ScoringArea,
+		Komi:                 7.5,
+		Ko:                   KoPositionalSuperko,
+		SuicideAllowed:       true,
+		HandicapCompensation: CompensationNone,
+	}
+)
+
+// RuleSetByName 按名称查找预定义规则集，名称为空时返回中国规则
+func RuleSetByName(name string) (RuleSet, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", "chinese", "cn":
+		return ChineseRules, nil
+	case "japanese", "jp":
+		return JapaneseRules, nil
+	case "aga":
+		return AGARules, nil
+	case "new_zealand", "nz":
+		return NewZealandRules, nil
+	case "tromp_taylor", "tromp-taylor", "tt":
+		return TrompTaylorRules, nil
+	}
+	return RuleSet{}, ErrUnknownRuleSet
+}
+
+// handicapCompensation 返回让子棋中白方应得的补偿（目）
+func (r RuleSet) handicapCompensation(handicap int) float64 {
+	if handicap == 0 {
+		return 0
+	}
+	switch r.HandicapCompensation {
+	case CompensationPerStone:
+		return float64(handicap)
+	case CompensationPerStoneLess1:
+		return float64(handicap - 1)
+	}
+	return 0
+}
+
+// WhiteBonus 返回白方在计分时得到的贴目与让子棋补偿之和（目）
+func (g *Game) WhiteBonus() float64 {
+	rules := g.rules()
+	return rules.Komi + rules.handicapCompensation(g.Handicap)
+}
+
+// rules 返回对局使用的规则，早期未保存规则的对局按中国规则处理
+func (g *Game) rules() RuleSet {
+	if g.Rules.Name == "" {
+		return ChineseRules
+	}
+	return g.Rules
+}
+
+// positionKey 返回用于全局同形判定的局面哈希
+// 情境超级劫需要区分下一手的行棋方
+func (g *Game) positionKey(b *Board, next Player) uint64 {
+	return g.historyKey(b.Hash(), next)
+}
+
+// historyKey 根据棋面哈希返回劫争历史中使用的键
+func (g *Game) historyKey(boardHash uint64, next Player) uint64 {
+	if g.rules().Ko == KoSituationalSuperko {
+		return boardHash ^ sideToMoveKey(next)
+	}
+	return boardHash
+}
@@ -0,0 +1,202 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+// newRulesGame 创建一个使用指定规则、已开始的 9 路对局
+func newRulesGame(t *testing.T, rules string) *Game {
+	t.Helper()
+	g, err := NewGameWithOptions(GameOptions{BoardSize: 9, Rules: rules})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	g.Status = GameStatusPlaying
+	return g
+}
+
+// resetHistory 在手动摆放棋子后重置劫争历史
+func resetHistory(g *Game) {
+	g.Board.Rehash()
+	g.History = PositionHistory{g.positionKey(g.Board, g.NextPlayer): true}
+}
+
+// setupKo 摆出一个黑棋可以提劫的局面
+// . X O .
+// X O . O
+// . X O .
+func setupKo(g *Game) {
+	g.Board.Grid[0][1] = Black
+	g.Board.Grid[1][0] = Black
+	g.Board.Grid[2][1] = Black
+	g.Board.Grid[0][2] = White
+	g.Board.Grid[1][1] = White
+	g.Board.Grid[1][3] = White
+	g.Board.Grid[2][2] = White
+	g.NextPlayer = Black
+	resetHistory(g)
+}
+
+// TestRuleSetByName 测试按名称选择规则
+func TestRuleSetByName(t *testing.T) {
+	for name, want := range map[string]RuleSet{
+		"":             ChineseRules,
+		"Japanese":     JapaneseRules,
+		"aga":          AGARules,
+		"nz":           NewZealandRules,
+		"tromp-taylor": TrompTaylorRules,
+	} {
+		got, err := RuleSetByName(name)
+		if err != nil {
+			t.Fatalf("Expected no error for %q, got %v", name, err)
+		}
+		if got != want {
+			t.Fatalf("Expected %s rules for %q, got %s", want.Name, name, got.Name)
+		}
+	}
+
+	if _, err := RuleSetByName("ing"); !errors.Is(err, ErrUnknownRuleSet) {
+		t.Fatalf("Expected ErrUnknownRuleSet, got %v", err)
+	}
+}
+
+// TestRules_Komi 测试贴目设置
+func TestRules_Komi(t *testing.T) {
+	g, _ := NewGameWithOptions(GameOptions{Rules: "japanese"})
+	if g.Rules.Komi != 6.5 {
+		t.Fatalf("Expected default komi 6.5, got %f", g.Rules.Komi)
+	}
+
+	komi := 0.0
+	g, _ = NewGameWithOptions(GameOptions{Rules: "japanese", Komi: &komi})
+	if g.Rules.Komi != 0 {
+		t.Fatalf("Expected custom komi 0, got %f", g.Rules.Komi)
+	}
+
+	g, _ = NewGameWithOptions(GameOptions{Handicap: 2})
+	if g.Rules.Komi != 0.5 {
+		t.Fatalf("Expected handicap komi 0.5, got %f", g.Rules.Komi)
+	}
+}
+
+// TestRules_SimpleKo 测试单劫规则禁止立即回提，但在别处落子后可以回提
+func TestRules_SimpleKo(t *testing.T) {
+	g := newRulesGame(t, "japanese")
+	setupKo(g)
+
+	if err := g.PlayMove(Point{X: 2, Y: 1}); err != nil {
+		t.Fatalf("Expected valid ko capture, got %v", err)
+	}
+	if g.KoPoint == nil || *g.KoPoint != (Point{X: 1, Y: 1}) {
+		t.Fatalf("Expected ko point at (1,1), got %v", g.KoPoint)
+	}
+	if err := g.PlayMove(Point{X: 1, Y: 1}); !errors.Is(err, ErrKoViolation) {
+		t.Fatalf("Expected ErrKoViolation, got %v", err)
+	}
+
+	// 双方各在别处落一手 (找劫材)，白棋即可回提
+	_ = g.PlayMove(Point{X: 7, Y: 7})
+	_ = g.PlayMove(Point{X: 7, Y: 6})
+	if err := g.PlayMove(Point{X: 1, Y: 1}); err != nil {
+		t.Fatalf("Expected recapture after ko threat, got %v", err)
+	}
+}
+
+// TestRules_PositionalSuperko 测试全局同形禁止立即回提
+func TestRules_PositionalSuperko(t *testing.T) {
+	g := newRulesGame(t, "chinese")
+	setupKo(g)
+
+	_ = g.PlayMove(Point{X: 2, Y: 1})
+	if err := g.PlayMove(Point{X: 1, Y: 1}); !errors.Is(err, ErrKoViolation) {
+		t.Fatalf("Expected ErrKoViolation, got %v", err)
+	}
+}
+
+// TestRules_Suicide 测试规则是否允许自杀
+func TestRules_Suicide(t *testing.T) {
+	for _, tc := range []struct {
+		rules   string
+		allowed bool
+	}{
+		{"chinese", false},
+		{"new_zealand", true},
+		{"tromp_taylor", true},
+	} {
+		g := newRulesGame(t, tc.rules)
+		// 白棋在 (1,0) 落子后与 (0,0) 的白子一起无气
+		g.Board.Grid[0][0] = White
+		g.Board.Grid[0][2] = Black
+		g.Board.Grid[1][0] = Black
+		g.Board.Grid[1][1] = Black
+		g.NextPlayer = White
+		resetHistory(g)
+
+		err := g.PlayMove(Point{X: 1, Y: 0})
+		if !tc.allowed {
+			if !errors.Is(err, ErrSuicideMove) {
+				t.Fatalf("%s: expected ErrSuicideMove, got %v", tc.rules, err)
+			}
+			continue
+		}
+
+		if err != nil {
+			t.Fatalf("%s: expected suicide to be legal, got %v", tc.rules, err)
+		}
+		if g.Board.Grid[0][0] != Empty || g.Board.Grid[0][1] != Empty {
+			t.Fatalf("%s: expected suicided group to be removed", tc.rules)
+		}
+		if g.CapturesByB != 2 || len(g.Moves[0].Suicided) != 2 {
+			t.Fatalf("%s: expected 2 stones credited to Black, got %d", tc.rules, g.CapturesByB)
+		}
+	}
+}
+
+// TestRules_PassStones 测试 AGA 规则虚手交子
+func TestRules_PassStones(t *testing.T) {
+	g := newRulesGame(t, "aga")
+	_ = g.PassTurn()
+	if g.CapturesByW != 1 {
+		t.Fatalf("Expected Black's pass to give White a prisoner, got %d", g.CapturesByW)
+	}
+
+	g = newRulesGame(t, "chinese")
+	_ = g.PassTurn()
+	if g.CapturesByW != 0 {
+		t.Fatalf("Expected no pass stones under Chinese rules, got %d", g.CapturesByW)
+	}
+}
+
+// TestRules_TerritoryScoring 测试数目法计分
+func TestRules_TerritoryScoring(t *testing.T) {
+	for _, tc := range []struct {
+		rules      string
+		blackScore float64
+		whiteScore float64
+	}{
+		// 数子法: 黑 9 子 + 36 目，白 1 子 + 7.5
+		{"chinese", 45, 8.5},
+		// 数目法: 黑 36 目 + 3 提子，白 0 目 + 6.5
+		{"japanese", 39, 6.5},
+	} {
+		g := newRulesGame(t, tc.rules)
+		for y := 0; y < 9; y++ {
+			g.Board.Grid[y][4] = Black
+		}
+		g.Board.Grid[0][5] = White
+		g.CapturesByB = 3
+		g.GameOver = true
+
+		result, err := g.CalculateScore()
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", tc.rules, err)
+		}
+		if result.BlackScore != tc.blackScore || result.WhiteScore != tc.whiteScore {
+			t.Fatalf("%s: expected %.1f-%.1f, got %.1f-%.1f", tc.rules, tc.blackScore, tc.whiteScore, result.BlackScore, result.WhiteScore)
+		}
+		if result.Winner != Black {
+			t.Fatalf("%s: expected Black to win, got %v", tc.rules, result.Winner)
+		}
+	}
+}
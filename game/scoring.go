@@ -0,0 +1,129 @@
+package game
+
+import "errors"
+
+var (
+	ErrNotScoring        = errors.New("game is not in scoring phase")
+	ErrScoringInProgress = errors.New("game is in scoring phase, accept or reject the marking first")
+	ErrNoStoneAtPoint    = errors.New("no stone at point")
+)
+
+// ScoringState 记录点目阶段的死子标记和双方的确认情况
+// 任何一次死子标记的修改都会清空双方已有的确认，保证双方确认的是同一份标记
+type ScoringState struct {
+	DeadStones    []Point `json:"dead_stones" bson:"dead_stones"`       // 被标记为死子的棋子
+	BlackAccepted bool    `json:"black_accepted" bson:"black_accepted"` // 黑方已确认当前标记
+	WhiteAccepted bool    `json:"white_accepted" bson:"white_accepted"` // 白方已确认当前标记
+}
+
+// IsDead 判断指定坐标的棋子是否被标记为死子
+func (s *ScoringState) IsDead(p Point) bool {
+	for _, d := range s.DeadStones {
+		if d == p {
+			return true
+		}
+	}
+	return false
+}
+
+// startScoring 连续两次虚手后进入点目阶段
+func (g *Game) startScoring() {
+	g.Status = GameStatusScoring
+	g.Scoring = &ScoringState{DeadStones: []Point{}}
+}
+
+// ToggleDeadStones 切换指定坐标所在棋块的死活标记
+// 活棋标记为死子，已标记为死子的棋块恢复为活棋
+func (g *Game) ToggleDeadStones(p Point) error {
+	if g.Status != GameStatusScoring || g.Scoring == nil {
+		return ErrNotScoring
+	}
+	if !g.Board.InBounds(p) {
+		return ErrPointOutOfBounds
+	}
+	if g.Board.Grid[p.Y][p.X] == Empty {
+		return ErrNoStoneAtPoint
+	}
+
+	group, _ := g.Board.findGroupAndLiberties(p)
+	if g.Scoring.IsDead(p) {
+		inGroup := make(map[Point]bool, len(group))
+		for _, stone := range group {
+			inGroup[stone] = true
+		}
+		alive := g.Scoring.DeadStones[:0]
+		for _, d := range g.Scoring.DeadStones {
+			if !inGroup[d] {
+				alive = append(alive, d)
+			}
+		}
+		g.Scoring.DeadStones = alive
+	} else {
+		g.Scoring.DeadStones = append(g.Scoring.DeadStones, group...)
+	}
+
+	// 标记变化后需要双方重新确认
+	g.Scoring.BlackAccepted = false
+	g.Scoring.WhiteAccepted = false
+	return nil
+}
+
+// AcceptScore 指定玩家确认当前的死子标记，双方都确认后对局结束
+func (g *Game) AcceptScore(player Player) error {
+	if g.Status != GameStatusScoring || g.Scoring == nil {
+		return ErrNotScoring
+	}
+
+	switch player {
+	case Black:
+		g.Scoring.BlackAccepted = true
+	case White:
+		g.Scoring.WhiteAccepted = true
+	default:
+		return ErrNotAPlayer
+	}
+
+	if g.Scoring.BlackAccepted && g.Scoring.WhiteAccepted {
+		return g.finishByScore()
+	}
+	return nil
+}
+
+// RejectScore 指定玩家不同意死子标记，清除标记并恢复对局
+// 恢复后由原本轮到的一方继续行棋
+func (g *Game) RejectScore(player Player) error {
+	if g.Status != GameStatusScoring || g.Scoring == nil {
+		return ErrNotScoring
+	}
+	if player != Black && player != White {
+		return ErrNotAPlayer
+	}
+
+	g.Scoring = nil
+	g.Passes = 0
+	g.Status = GameStatusPlaying
+	g.LastMoveTime = getCurrentTimestamp() // 从恢复对局时重新计时
+	return nil
+}
+
+// scoringBoard 返回移除死子后的棋盘，以及双方被判死的棋子数
+func (g *Game) scoringBoard() (board *Board, deadBlack, deadWhite int) {
+	board = g.Board.Clone()
+	if g.Scoring == nil {
+		return board, 0, 0
+	}
+
+	for _, p := range g.Scoring.DeadStones {
+		if !board.InBounds(p) {
+			continue
+		}
+		switch board.Grid[p.Y][p.X] {
+		case Black:
+			deadBlack++
+		case White:
+			deadWhite++
+		}
+		board.Grid[p.Y][p.X] = Empty
+	}
+	return board, deadBlack, deadWhite
+}
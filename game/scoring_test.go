@@ -0,0 +1,146 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+// newScoringGame 创建一个 9 路对局：黑棋占据左侧，白棋占据右侧，
+// 黑方空中有一颗白子，然后双方连续虚手进入点目阶段
+func newScoringGame(t *testing.T) *Game {
+	t.Helper()
+	g, _ := NewGameWithOptions(GameOptions{BoardSize: 9})
+	g.Status = GameStatusPlaying
+	for y := 0; y < 9; y++ {
+		g.Board.Grid[y][4] = Black
+		g.Board.Grid[y][5] = White
+	}
+	g.Board.Grid[4][1] = White // 黑空中的死子
+
+	if err := g.PassTurn(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if err := g.PassTurn(); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	return g
+}
+
+// TestScoring_MarkDeadAndAccept 测试标记死子并由双方确认后终局
+func TestScoring_MarkDeadAndAccept(t *testing.T) {
+	g := newScoringGame(t)
+
+	if err := g.PlayMove(Point{X: 0, Y: 0}); !errors.Is(err, ErrScoringInProgress) {
+		t.Fatalf("Expected ErrScoringInProgress, got %v", err)
+	}
+
+	// 未标记死子时，白子破坏了黑空
+	before, err := g.CalculateScore()
+	if err != nil {
+		t.Fatalf("Expected score preview, got %v", err)
+	}
+
+	if err := g.ToggleDeadStones(Point{X: 1, Y: 4}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	after, _ := g.CalculateScore()
+
+	// 黑方: 9 子 + 36 目 = 45，白方: 9 子 + 27 目 + 7.5 贴目 = 43.5
+	if after.BlackScore != 45 || after.WhiteScore != 43.5 {
+		t.Fatalf("Expected 45 to 43.5 after marking, got %v to %v", after.BlackScore, after.WhiteScore)
+	}
+	if after.BlackScore <= before.BlackScore {
+		t.Fatal("Expected marking the dead stone to increase Black's score")
+	}
+
+	_ = g.AcceptScore(Black)
+	if g.GameOver {
+		t.Fatal("Game should not be over until both players accept")
+	}
+	_ = g.AcceptScore(White)
+	if !g.GameOver || g.Status != GameStatusFinished {
+		t.Fatalf("Expected game to be finished, got status %s", g.Status)
+	}
+
+	final, _ := g.CalculateScore()
+	if final.Winner != Black {
+		t.Fatalf("Expected Black to win, got %v", final.Winner)
+	}
+}
+
+// TestScoring_ToggleResetsAcceptance 测试修改标记后需要双方重新确认
+func TestScoring_ToggleResetsAcceptance(t *testing.T) {
+	g := newScoringGame(t)
+
+	_ = g.ToggleDeadStones(Point{X: 1, Y: 4})
+	_ = g.AcceptScore(Black)
+
+	// 白方把死子恢复为活棋，黑方的确认失效
+	_ = g.ToggleDeadStones(Point{X: 1, Y: 4})
+	if len(g.Scoring.DeadStones) != 0 {
+		t.Fatalf("Expected no dead stones after toggling back, got %v", g.Scoring.DeadStones)
+	}
+	if g.Scoring.BlackAccepted {
+		t.Fatal("Expected Black's acceptance to be cleared")
+	}
+
+	_ = g.AcceptScore(White)
+	if g.GameOver {
+		t.Fatal("Game should not be over with only White accepting the new marking")
+	}
+
+	if err := g.ToggleDeadStones(Point{X: 2, Y: 2}); !errors.Is(err, ErrNoStoneAtPoint) {
+		t.Fatalf("Expected ErrNoStoneAtPoint, got %v", err)
+	}
+}
+
+// TestScoring_MarkWholeGroup 测试标记会作用于整个棋块
+func TestScoring_MarkWholeGroup(t *testing.T) {
+	g := newScoringGame(t)
+
+	_ = g.ToggleDeadStones(Point{X: 5, Y: 0})
+	if len(g.Scoring.DeadStones) != 9 {
+		t.Fatalf("Expected the whole white wall to be marked, got %d stones", len(g.Scoring.DeadStones))
+	}
+}
+
+// TestScoring_RejectResumesPlay 测试不同意标记时恢复对局
+func TestScoring_RejectResumesPlay(t *testing.T) {
+	g := newScoringGame(t)
+	_ = g.ToggleDeadStones(Point{X: 1, Y: 4})
+
+	if err := g.RejectScore(White); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if g.Status != GameStatusPlaying || g.Scoring != nil || g.Passes != 0 {
+		t.Fatalf("Expected play to resume, got status %s", g.Status)
+	}
+
+	// 恢复后轮到黑棋继续落子
+	if err := g.PlayMove(Point{X: 0, Y: 0}); err != nil {
+		t.Fatalf("Expected move after resume to succeed, got %v", err)
+	}
+	if err := g.AcceptScore(Black); !errors.Is(err, ErrNotScoring) {
+		t.Fatalf("Expected ErrNotScoring, got %v", err)
+	}
+}
+
+// TestScoring_TerritoryCountsDeadAsPrisoners 测试数目法下死子计入提子
+func TestScoring_TerritoryCountsDeadAsPrisoners(t *testing.T) {
+	g, _ := NewGameWithOptions(GameOptions{BoardSize: 9, Rules: "japanese"})
+	g.Status = GameStatusPlaying
+	for y := 0; y < 9; y++ {
+		g.Board.Grid[y][4] = Black
+		g.Board.Grid[y][5] = White
+	}
+	g.Board.Grid[4][1] = White
+	_ = g.PassTurn()
+	_ = g.PassTurn()
+	_ = g.ToggleDeadStones(Point{X: 1, Y: 4})
+
+	result, _ := g.CalculateScore()
+	// 黑方: 36 目 + 1 死子，白方: 27 目 + 6.5 贴目
+	if result.BlackScore != 37 || result.WhiteScore != 33.5 {
+		t.Fatalf("Expected 37 to 33.5, got %v to %v", result.BlackScore, result.WhiteScore)
+	}
+}
@@ -0,0 +1,179 @@
+package game
+
+import "errors"
+
+var ErrInvalidTimeControl = errors.New("invalid time control settings")
+
+// TimeControlType 表示对局的计时方式
+type TimeControlType string
+
+const (
+	TimeAbsolute TimeControlType = "absolute" // 包干制：用完基本时间即超时负
+	TimeByoYomi  TimeControlType = "byoyomi"  // 日式读秒：基本时间用完后进入若干次固定时长的读秒
+	TimeCanadian TimeControlType = "canadian" // 加拿大读秒：基本时间用完后每个时段内需下完指定手数
+	TimeFischer  TimeControlType = "fischer"  // 费舍尔制：每下一手增加固定时间
+)
+
+// DefaultMainTime 是未指定计时方式时每位玩家的基本时间（秒）
+const DefaultMainTime = 3600
+
+// TimeControl 描述对局的计时设置，创建对局时选择
+type TimeControl struct {
+	Type         TimeControlType `json:"type" bson:"type"`
+	MainTime     int64           `json:"main_time" bson:"main_time"`                             // 基本时间（秒）
+	Periods      int             `json:"periods,omitempty" bson:"periods,omitempty"`             // 读秒次数 (byoyomi)
+	PeriodTime   int64           `json:"period_time,omitempty" bson:"period_time,omitempty"`     // 每次读秒 / 每个时段的时长（秒）(byoyomi, canadian)
+	PeriodStones int             `json:"period_stones,omitempty" bson:"period_stones,omitempty"` // 每个时段需下的手数 (canadian)
+	Increment    int64           `json:"increment,omitempty" bson:"increment,omitempty"`         // 每手加秒 (fischer)
+}
+
+// AbsoluteTime 返回包干制计时设置
+func AbsoluteTime(mainTime int64) TimeControl {
+	return TimeControl{Type: TimeAbsolute, MainTime: mainTime}
+}
+
+// Validate 检查计时设置是否合法
+func (tc TimeControl) Validate() error {
+	if tc.MainTime < 0 {
+		return ErrInvalidTimeControl
+	}
+
+	switch tc.Type {
+	case TimeAbsolute:
+		if tc.MainTime == 0 {
+			return ErrInvalidTimeControl
+		}
+	case TimeByoYomi:
+		if tc.Periods < 1 || tc.PeriodTime < 1 {
+			return ErrInvalidTimeControl
+		}
+	case TimeCanadian:
+		if tc.PeriodStones < 1 || tc.PeriodTime < 1 {
+			return ErrInvalidTimeControl
+		}
+	case TimeFischer:
+		if tc.MainTime == 0 || tc.Increment < 0 {
+			return ErrInvalidTimeControl
+		}
+	default:
+		return ErrInvalidTimeControl
+	}
+	return nil
+}
+
+// Overtime 记录一方的读秒状态
+type Overtime struct {
+	Active      bool `json:"active" bson:"active"`             // 是否已用完基本时间进入读秒
+	PeriodsLeft int  `json:"periods_left" bson:"periods_left"` // 剩余读秒次数 (byoyomi)
+	StonesLeft  int  `json:"stones_left" bson:"stones_left"`   // 当前时段还需下的手数 (canadian)
+}
+
+// initialOvertime 返回对局开始时的读秒状态
+func (tc TimeControl) initialOvertime() Overtime {
+	if tc.Type == TimeByoYomi {
+		return Overtime{PeriodsLeft: tc.Periods}
+	}
+	return Overtime{}
+}
+
+// timeControl 返回对局的计时设置，兼容没有保存计时方式的旧对局
+func (g *Game) timeControl() TimeControl {
+	if g.TimeControl.Type == "" {
+		return AbsoluteTime(g.TimePerPlayer)
+	}
+	return g.TimeControl
+}
+
+// setupClocks 按计时设置初始化双方的计时
+func (g *Game) setupClocks(tc TimeControl) {
+	g.TimeControl = tc
+	g.TimePerPlayer = tc.MainTime
+	g.BlackTimeLeft = tc.MainTime
+	g.WhiteTimeLeft = tc.MainTime
+	g.BlackOvertime = tc.initialOvertime()
+	g.WhiteOvertime = tc.initialOvertime()
+}
+
+// clock 返回指定玩家的剩余时间和读秒状态
+func (g *Game) clock(player Player) (*int64, *Overtime) {
+	if player == Black {
+		return &g.BlackTimeLeft, &g.BlackOvertime
+	}
+	return &g.WhiteTimeLeft, &g.WhiteOvertime
+}
+
+// chargeTime 从指定玩家的计时中扣除 elapsed 秒
+// 基本时间用完后按计时方式进入读秒，时间全部用完时返回 false
+func (g *Game) chargeTime(player Player, elapsed int64) bool {
+	tc := g.timeControl()
+	timeLeft, ot := g.clock(player)
+
+	*timeLeft -= elapsed
+	for *timeLeft <= 0 {
+		switch {
+		case tc.Type == TimeByoYomi && !ot.Active:
+			ot.Active = true
+			*timeLeft += tc.PeriodTime
+		case tc.Type == TimeByoYomi && ot.PeriodsLeft > 1:
+			// 用完一次读秒，进入下一次
+			ot.PeriodsLeft--
+			*timeLeft += tc.PeriodTime
+		case tc.Type == TimeCanadian && !ot.Active:
+			ot.Active = true
+			ot.StonesLeft = tc.PeriodStones
+			*timeLeft += tc.PeriodTime
+		default:
+			if tc.Type == TimeByoYomi {
+				ot.PeriodsLeft = 0
+			}
+			*timeLeft = 0
+			return false
+		}
+	}
+	return true
+}
+
+// completeTurn 在指定玩家完成一手后按计时方式调整其计时
+func (g *Game) completeTurn(player Player) {
+	tc := g.timeControl()
+	timeLeft, ot := g.clock(player)
+
+	switch tc.Type {
+	case TimeFischer:
+		*timeLeft += tc.Increment
+	case TimeByoYomi:
+		// 在读秒内下完一手，读秒时间重置
+		if ot.Active {
+			*timeLeft = tc.PeriodTime
+		}
+	case TimeCanadian:
+		if ot.Active {
+			ot.StonesLeft--
+			if ot.StonesLeft <= 0 {
+				// 时段内下完规定手数，开始新的时段
+				ot.StonesLeft = tc.PeriodStones
+				*timeLeft = tc.PeriodTime
+			}
+		}
+	}
+}
+
+// CheckTimeout 检查轮到行棋的一方是否已用完时间
+// 已超时则记录超时负并结束对局，返回 true；未超时不修改计时
+func (g *Game) CheckTimeout() bool {
+	if g.GameOver || g.Status != GameStatusPlaying || g.LastMoveTime == 0 {
+		return false
+	}
+
+	elapsed := getCurrentTimestamp() - g.LastMoveTime
+	timeLeft, ot := g.clock(g.NextPlayer)
+	savedTimeLeft, savedOvertime := *timeLeft, *ot
+	if g.chargeTime(g.NextPlayer, elapsed) {
+		// 只是检查，恢复计时，等到真正落子时再扣除
+		*timeLeft, *ot = savedTimeLeft, savedOvertime
+		return false
+	}
+
+	g.finish(Result{Winner: g.NextPlayer.Opponent(), Reason: ReasonTimeout})
+	return true
+}
@@ -0,0 +1,161 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+// newTimedGame 创建一个使用指定计时方式的已开始对局
+func newTimedGame(t *testing.T, tc TimeControl) *Game {
+	t.Helper()
+	g, err := NewGameWithOptions(GameOptions{TimeControl: &tc})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	g.Status = GameStatusPlaying
+	return g
+}
+
+// spend 让轮到行棋的一方用掉 seconds 秒
+func spend(g *Game, seconds int64) {
+	g.LastMoveTime = getCurrentTimestamp() - seconds
+}
+
+// TestTimeControl_Validate 测试计时设置校验
+func TestTimeControl_Validate(t *testing.T) {
+	invalid := []TimeControl{
+		{Type: "hourglass", MainTime: 600},
+		{Type: TimeAbsolute},
+		{Type: TimeByoYomi, MainTime: 600, Periods: 0, PeriodTime: 30},
+		{Type: TimeCanadian, MainTime: 600, PeriodTime: 300},
+		{Type: TimeFischer, MainTime: 600, Increment: -1},
+	}
+	for _, tc := range invalid {
+		if _, err := NewGameWithOptions(GameOptions{TimeControl: &tc}); !errors.Is(err, ErrInvalidTimeControl) {
+			t.Errorf("Expected ErrInvalidTimeControl for %+v, got %v", tc, err)
+		}
+	}
+}
+
+// TestTimeControl_ByoYomi 测试日式读秒：读秒内落子重置读秒，用完一次读秒扣减次数
+func TestTimeControl_ByoYomi(t *testing.T) {
+	g := newTimedGame(t, TimeControl{Type: TimeByoYomi, MainTime: 60, Periods: 5, PeriodTime: 30})
+	if g.BlackOvertime.PeriodsLeft != 5 {
+		t.Fatalf("Expected 5 periods, got %d", g.BlackOvertime.PeriodsLeft)
+	}
+
+	// 基本时间用完后 10 秒落子，仍在第一次读秒内
+	spend(g, 70)
+	if err := g.PlayMove(Point{X: 3, Y: 3}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if !g.BlackOvertime.Active || g.BlackOvertime.PeriodsLeft != 5 || g.BlackTimeLeft != 30 {
+		t.Fatalf("Expected fresh period with 5 left, got %+v time=%d", g.BlackOvertime, g.BlackTimeLeft)
+	}
+
+	_ = g.PlayMove(Point{X: 15, Y: 15})
+
+	// 用掉两次多读秒
+	spend(g, 70)
+	if err := g.PlayMove(Point{X: 3, Y: 15}); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if g.BlackOvertime.PeriodsLeft != 3 || g.BlackTimeLeft != 30 {
+		t.Fatalf("Expected 3 periods left, got %+v time=%d", g.BlackOvertime, g.BlackTimeLeft)
+	}
+
+	_ = g.PlayMove(Point{X: 15, Y: 3})
+
+	// 用完剩余所有读秒
+	spend(g, 90)
+	if err := g.PlayMove(Point{X: 9, Y: 9}); !errors.Is(err, ErrTimeOut) {
+		t.Fatalf("Expected ErrTimeOut, got %v", err)
+	}
+	if g.BlackOvertime.PeriodsLeft != 0 || g.Result == nil || g.Result.String() != "W+T" {
+		t.Fatalf("Expected W+T with no periods left, got %+v %v", g.BlackOvertime, g.Result)
+	}
+}
+
+// TestTimeControl_Canadian 测试加拿大读秒：时段内下完规定手数后时段重置
+func TestTimeControl_Canadian(t *testing.T) {
+	g := newTimedGame(t, TimeControl{Type: TimeCanadian, MainTime: 10, PeriodStones: 2, PeriodTime: 100})
+
+	spend(g, 20)
+	_ = g.PlayMove(Point{X: 3, Y: 3})
+	if !g.BlackOvertime.Active || g.BlackOvertime.StonesLeft != 1 || g.BlackTimeLeft != 90 {
+		t.Fatalf("Expected 1 stone left with 90s, got %+v time=%d", g.BlackOvertime, g.BlackTimeLeft)
+	}
+
+	_ = g.PlayMove(Point{X: 15, Y: 15})
+	spend(g, 40)
+	_ = g.PlayMove(Point{X: 3, Y: 15})
+	if g.BlackOvertime.StonesLeft != 2 || g.BlackTimeLeft != 100 {
+		t.Fatalf("Expected new period after 2 stones, got %+v time=%d", g.BlackOvertime, g.BlackTimeLeft)
+	}
+
+	// 一个时段内没有下完规定手数即超时
+	_ = g.PlayMove(Point{X: 15, Y: 3})
+	spend(g, 101)
+	if err := g.PlayMove(Point{X: 9, Y: 9}); !errors.Is(err, ErrTimeOut) {
+		t.Fatalf("Expected ErrTimeOut, got %v", err)
+	}
+}
+
+// TestTimeControl_Fischer 测试费舍尔制每手加秒
+func TestTimeControl_Fischer(t *testing.T) {
+	g := newTimedGame(t, TimeControl{Type: TimeFischer, MainTime: 300, Increment: 10})
+
+	spend(g, 5)
+	_ = g.PlayMove(Point{X: 3, Y: 3})
+	if g.BlackTimeLeft != 305 {
+		t.Fatalf("Expected 305s after increment, got %d", g.BlackTimeLeft)
+	}
+	if g.Moves[0].TimeLeft != 305 {
+		t.Fatalf("Expected recorded clock to include increment, got %d", g.Moves[0].TimeLeft)
+	}
+}
+
+// TestTimeControl_UndoRestoresOvertime 测试悔棋恢复读秒状态
+func TestTimeControl_UndoRestoresOvertime(t *testing.T) {
+	g := newTimedGame(t, TimeControl{Type: TimeByoYomi, MainTime: 10, Periods: 3, PeriodTime: 30})
+
+	_ = g.PlayMove(Point{X: 3, Y: 3})
+	_ = g.PlayMove(Point{X: 15, Y: 15})
+	spend(g, 50) // 用掉基本时间和一次读秒
+	_ = g.PlayMove(Point{X: 3, Y: 15})
+	if g.BlackOvertime.PeriodsLeft != 2 {
+		t.Fatalf("Expected 2 periods left, got %d", g.BlackOvertime.PeriodsLeft)
+	}
+
+	if err := g.Undo(1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if g.BlackOvertime.Active || g.BlackOvertime.PeriodsLeft != 3 || g.BlackTimeLeft != 10 {
+		t.Fatalf("Expected clock before overtime, got %+v time=%d", g.BlackOvertime, g.BlackTimeLeft)
+	}
+}
+
+// TestCheckTimeout 测试不落子时检查超时
+func TestCheckTimeout(t *testing.T) {
+	g := newTimedGame(t, TimeControl{Type: TimeByoYomi, MainTime: 60, Periods: 1, PeriodTime: 30})
+
+	// 已进入读秒但未超时，检查不会修改计时
+	spend(g, 70)
+	if g.CheckTimeout() {
+		t.Fatal("Expected no timeout within the byo-yomi period")
+	}
+	if g.BlackTimeLeft != 60 || g.BlackOvertime.Active {
+		t.Fatalf("Expected clock to be untouched, got %+v time=%d", g.BlackOvertime, g.BlackTimeLeft)
+	}
+
+	spend(g, 91)
+	if !g.CheckTimeout() {
+		t.Fatal("Expected timeout after main time and byo-yomi are used up")
+	}
+	if g.Status != GameStatusFinished || g.Result.String() != "W+T" {
+		t.Fatalf("Expected W+T, got %v", g.Result)
+	}
+	if g.CheckTimeout() {
+		t.Fatal("Expected finished game not to time out again")
+	}
+}
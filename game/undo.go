@@ -0,0 +1,112 @@
+package game
+
+import "errors"
+
+var (
+	ErrUndoPending         = errors.New("an undo request is already pending")
+	ErrNoUndoRequest       = errors.New("no pending undo request")
+	ErrInvalidUndoCount    = errors.New("undo count must be between 1 and the number of moves played")
+	ErrCannotAnswerOwnUndo = errors.New("cannot answer your own undo request")
+	ErrNotAPlayer          = errors.New("not a player in this game")
+)
+
+// UndoRequest 表示一个等待对手回应的悔棋请求
+type UndoRequest struct {
+	RequestedBy Player `json:"requested_by" bson:"requested_by"` // 发起悔棋的一方
+	Count       int    `json:"count" bson:"count"`               // 需要撤销的手数
+	CreatedAt   int64  `json:"created_at" bson:"created_at"`     // 发起时间戳（秒）
+}
+
+// RequestUndo 由指定一方发起悔棋请求，撤销最近的 count 手
+func (g *Game) RequestUndo(player Player, count int) error {
+	if g.GameOver || g.Status != GameStatusPlaying {
+		return errors.New("game is not in progress")
+	}
+	if player != Black && player != White {
+		return ErrNotAPlayer
+	}
+	if g.PendingUndo != nil {
+		return ErrUndoPending
+	}
+	if count < 1 || count > len(g.Moves) {
+		return ErrInvalidUndoCount
+	}
+
+	g.PendingUndo = &UndoRequest{
+		RequestedBy: player,
+		Count:       count,
+		CreatedAt:   getCurrentTimestamp(),
+	}
+	return nil
+}
+
+// AcceptUndo 由对手同意悔棋请求，并立即回退局面
+func (g *Game) AcceptUndo(player Player) error {
+	if err := g.checkUndoAnswer(player); err != nil {
+		return err
+	}
+	return g.Undo(g.PendingUndo.Count)
+}
+
+// DeclineUndo 由对手拒绝悔棋请求
+func (g *Game) DeclineUndo(player Player) error {
+	if err := g.checkUndoAnswer(player); err != nil {
+		return err
+	}
+	g.PendingUndo = nil
+	return nil
+}
+
+// checkUndoAnswer 检查指定一方是否可以回应当前的悔棋请求
+func (g *Game) checkUndoAnswer(player Player) error {
+	if g.PendingUndo == nil {
+		return ErrNoUndoRequest
+	}
+	if player != Black && player != White {
+		return ErrNotAPlayer
+	}
+	if player == g.PendingUndo.RequestedBy {
+		return ErrCannotAnswerOwnUndo
+	}
+	return nil
+}
+
+// Undo 撤销最近的 count 手，回退棋盘、劫争历史、提子数、轮次和双方计时
+// 计时恢复为被撤销的手之前各自最后一次落子后的剩余时间
+func (g *Game) Undo(count int) error {
+	if count < 1 || count > len(g.Moves) {
+		return ErrInvalidUndoCount
+	}
+
+	r, err := g.Replay(len(g.Moves) - count)
+	if err != nil {
+		return err
+	}
+
+	g.Board = r.Board
+	g.History = r.History
+	g.Moves = r.Moves
+	g.NextPlayer = r.NextPlayer
+	g.Passes = r.Passes
+	g.KoPoint = r.KoPoint
+	g.CapturesByB = r.CapturesByB
+	g.CapturesByW = r.CapturesByW
+	g.PendingUndo = nil
+
+	// 恢复双方计时
+	tc := g.timeControl()
+	g.BlackTimeLeft, g.WhiteTimeLeft = tc.MainTime, tc.MainTime
+	g.BlackOvertime, g.WhiteOvertime = tc.initialOvertime(), tc.initialOvertime()
+	for _, m := range g.Moves {
+		timeLeft, ot := g.clock(m.Player)
+		*timeLeft = m.TimeLeft
+		if m.Overtime != nil {
+			*ot = *m.Overtime
+		}
+	}
+	if g.Status == GameStatusPlaying {
+		g.LastMoveTime = getCurrentTimestamp()
+	}
+
+	return nil
+}
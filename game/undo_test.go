@@ -0,0 +1,117 @@
+package game
+
+import (
+	"errors"
+	"testing"
+)
+
+// newPlayingGame 创建一个已开始的双人对局
+func newPlayingGame() *Game {
+	g := NewGame()
+	g.PlayerBlack = "black-player"
+	g.PlayerWhite = "white-player"
+	g.Status = GameStatusPlaying
+	return g
+}
+
+// TestUndo_AcceptRollsBack 测试同意悔棋后局面完整回退
+func TestUndo_AcceptRollsBack(t *testing.T) {
+	g := newPlayingGame()
+
+	// 黑棋在角上提掉白子 (0,0)
+	_ = g.PlayMove(Point{X: 1, Y: 0})
+	_ = g.PlayMove(Point{X: 0, Y: 0})
+	before := g.Board.StateHash()
+	_ = g.PlayMove(Point{X: 0, Y: 1})
+	if g.CapturesByB != 1 {
+		t.Fatalf("Expected 1 capture by Black, got %d", g.CapturesByB)
+	}
+
+	// 黑棋请求悔一手，白棋同意
+	if err := g.RequestUndo(Black, 1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if g.PendingUndo == nil || g.PendingUndo.Count != 1 {
+		t.Fatal("Expected pending undo request")
+	}
+	if err := g.AcceptUndo(White); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	if g.Board.StateHash() != before {
+		t.Fatal("Expected board to be restored")
+	}
+	if g.Board.Grid[0][0] != White {
+		t.Fatal("Expected captured stone to be restored")
+	}
+	if g.CapturesByB != 0 || g.NextPlayer != Black || len(g.Moves) != 2 || g.PendingUndo != nil {
+		t.Fatalf("Unexpected state after undo: captures=%d next=%v moves=%d", g.CapturesByB, g.NextPlayer, len(g.Moves))
+	}
+
+	// 撤销的局面不再出现在劫争历史中，黑棋可以重新在原处提子
+	if err := g.PlayMove(Point{X: 0, Y: 1}); err != nil {
+		t.Fatalf("Expected replaying the undone move to succeed, got %v", err)
+	}
+}
+
+// TestUndo_Decline 测试拒绝悔棋
+func TestUndo_Decline(t *testing.T) {
+	g := newPlayingGame()
+	_ = g.PlayMove(Point{X: 3, Y: 3})
+
+	_ = g.RequestUndo(Black, 1)
+	if err := g.DeclineUndo(Black); !errors.Is(err, ErrCannotAnswerOwnUndo) {
+		t.Fatalf("Expected ErrCannotAnswerOwnUndo, got %v", err)
+	}
+	if err := g.DeclineUndo(White); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if g.PendingUndo != nil || len(g.Moves) != 1 {
+		t.Fatal("Expected game to be unchanged after decline")
+	}
+	if err := g.AcceptUndo(White); !errors.Is(err, ErrNoUndoRequest) {
+		t.Fatalf("Expected ErrNoUndoRequest, got %v", err)
+	}
+}
+
+// TestUndo_InvalidRequests 测试非法的悔棋请求
+func TestUndo_InvalidRequests(t *testing.T) {
+	g := newPlayingGame()
+
+	if err := g.RequestUndo(Black, 1); !errors.Is(err, ErrInvalidUndoCount) {
+		t.Fatalf("Expected ErrInvalidUndoCount on empty game, got %v", err)
+	}
+
+	_ = g.PlayMove(Point{X: 3, Y: 3})
+	_ = g.RequestUndo(Black, 1)
+	if err := g.RequestUndo(White, 1); !errors.Is(err, ErrUndoPending) {
+		t.Fatalf("Expected ErrUndoPending, got %v", err)
+	}
+
+	// 新的一手会使未回应的请求失效
+	_ = g.PlayMove(Point{X: 4, Y: 4})
+	if g.PendingUndo != nil {
+		t.Fatal("Expected pending undo to be cleared by a new move")
+	}
+}
+
+// TestUndo_RestoresClocks 测试悔棋后双方计时恢复
+func TestUndo_RestoresClocks(t *testing.T) {
+	g := newPlayingGame()
+	_ = g.PlayMove(Point{X: 3, Y: 3})
+	_ = g.PlayMove(Point{X: 4, Y: 4})
+
+	g.Moves[0].TimeLeft = 3500
+	g.BlackTimeLeft = 3000
+	g.WhiteTimeLeft = 2000
+
+	if err := g.Undo(1); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if g.BlackTimeLeft != 3500 {
+		t.Fatalf("Expected Black clock restored to 3500, got %d", g.BlackTimeLeft)
+	}
+	if g.WhiteTimeLeft != g.TimePerPlayer {
+		t.Fatalf("Expected White clock restored to %d, got %d", g.TimePerPlayer, g.WhiteTimeLeft)
+	}
+}
@@ -0,0 +1,92 @@
+package game
+
+import (
+	"errors"
+	"strings"
+)
+
+var ErrInvalidPositionKey = errors.New("invalid legacy position key")
+
+// maxBoardPoints 覆盖最大 (19 路) 棋盘的全部交叉点，较小的棋盘使用其中一部分
+const maxBoardPoints = BoardSize * BoardSize
+
+var (
+	// zobristStones[点][颜色] 是每个交叉点放置黑子/白子时异或的随机数
+	zobristStones [maxBoardPoints][3]uint64
+	// zobristWhiteToMove 在轮到白棋行棋时异或进局面哈希
+	zobristWhiteToMove uint64
+)
+
+func init() {
+	// 使用固定种子的 splitmix64 生成随机数表，保证哈希在重启后保持一致，
+	// 已持久化的劫争历史才能继续使用
+	state := uint64(0x5EED_2D0B_1A5E_F00D)
+	next := func() uint64 {
+		state += 0x9E3779B97F4A7C15
+		z := state
+		z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+		z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+		return z ^ (z >> 31)
+	}
+
+	for i := range zobristStones {
+		zobristStones[i][Black] = next()
+		zobristStones[i][White] = next()
+	}
+	zobristWhiteToMove = next()
+}
+
+// zobristKey 返回指定颜色的棋子位于 p 时的随机数
+func zobristKey(p Point, player Player) uint64 {
+	return zobristStones[p.Y*BoardSize+p.X][player]
+}
+
+// sideToMoveKey 返回行棋方对应的随机数
+func sideToMoveKey(next Player) uint64 {
+	if next == White {
+		return zobristWhiteToMove
+	}
+	return 0
+}
+
+// PositionHistory 记录出现过的局面哈希，用于全局同形判定
+type PositionHistory map[uint64]bool
+
+// PositionHash 返回包含行棋方在内的当前局面哈希，用于局面判同
+func (g *Game) PositionHash() uint64 {
+	return g.Board.Hash() ^ sideToMoveKey(g.NextPlayer)
+}
+
+// LegacyPositionHash 将旧版本保存的局面字符串键转换为 Zobrist 哈希
+// 旧键为 StateHash 的结果，情境超级劫规则下末尾附带行棋方名称
+func LegacyPositionHash(key string) (uint64, error) {
+	state, next := key, Black
+	for _, player := range []Player{Black, White} {
+		if name := player.String(); strings.HasSuffix(key, name) {
+			state, next = strings.TrimSuffix(key, name), player
+		}
+	}
+
+	size := 0
+	for size*size < len(state) {
+		size++
+	}
+	if size == 0 || size*size != len(state) || size > BoardSize {
+		return 0, ErrInvalidPositionKey
+	}
+
+	var hash uint64
+	for i := 0; i < len(state); i++ {
+		p := Point{X: i % size, Y: i / size}
+		switch state[i] {
+		case '0':
+		case '1':
+			hash ^= zobristKey(p, Black)
+		case '2':
+			hash ^= zobristKey(p, White)
+		default:
+			return 0, ErrInvalidPositionKey
+		}
+	}
+	return hash ^ sideToMoveKey(next), nil
+}
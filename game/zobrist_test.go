@@ -0,0 +1,73 @@
+package game
+
+import "testing"
+
+// TestZobrist_IncrementalMatchesRehash 测试增量维护的哈希与重新计算的结果一致
+func TestZobrist_IncrementalMatchesRehash(t *testing.T) {
+	g := NewGame()
+
+	// 黑棋在角上提掉白子 (0,0)，再下几手
+	moves := []Point{{X: 1, Y: 0}, {X: 0, Y: 0}, {X: 0, Y: 1}, {X: 10, Y: 10}, {X: 3, Y: 3}}
+	for _, p := range moves {
+		if err := g.PlayMove(p); err != nil {
+			t.Fatalf("Expected valid move at %v, got %v", p, err)
+		}
+		incremental := g.Board.Hash()
+		if rehashed := g.Board.Clone().Rehash(); incremental != rehashed {
+			t.Fatalf("Expected incremental hash %x to equal %x after %v", incremental, rehashed, p)
+		}
+	}
+
+	if NewBoard().Hash() != 0 {
+		t.Fatal("Expected empty board to hash to 0")
+	}
+}
+
+// TestZobrist_SameBoardSameHash 测试不同落子顺序得到的相同局面哈希相同
+func TestZobrist_SameBoardSameHash(t *testing.T) {
+	a, b := NewGame(), NewGame()
+	for _, p := range []Point{{X: 3, Y: 3}, {X: 15, Y: 15}, {X: 3, Y: 15}, {X: 15, Y: 3}} {
+		_ = a.PlayMove(p)
+	}
+	for _, p := range []Point{{X: 3, Y: 15}, {X: 15, Y: 3}, {X: 3, Y: 3}, {X: 15, Y: 15}} {
+		_ = b.PlayMove(p)
+	}
+
+	if a.Board.Hash() != b.Board.Hash() {
+		t.Fatal("Expected identical boards to have identical hashes")
+	}
+	if a.PositionHash() != b.PositionHash() {
+		t.Fatal("Expected identical positions to have identical position hashes")
+	}
+
+	// 行棋方不同时局面哈希不同
+	_ = b.PassTurn()
+	if a.PositionHash() == b.PositionHash() {
+		t.Fatal("Expected side to move to change the position hash")
+	}
+}
+
+// TestLegacyPositionHash 测试旧版字符串局面键的转换
+func TestLegacyPositionHash(t *testing.T) {
+	g, _ := NewGameWithOptions(GameOptions{BoardSize: 9})
+	_ = g.PlayMove(Point{X: 2, Y: 6})
+	_ = g.PlayMove(Point{X: 6, Y: 2})
+
+	hash, err := LegacyPositionHash(g.Board.StateHash())
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if hash != g.Board.Hash() {
+		t.Fatal("Expected legacy key to convert to the board hash")
+	}
+
+	// 情境超级劫的旧键附带行棋方
+	situational, _ := LegacyPositionHash(g.Board.StateHash() + White.String())
+	if situational != g.Board.Hash()^sideToMoveKey(White) {
+		t.Fatal("Expected side to move suffix to be hashed")
+	}
+
+	if _, err := LegacyPositionHash("012"); err == nil {
+		t.Fatal("Expected error for a key that is not a square board")
+	}
+}
@@ -0,0 +1,101 @@
+package gametree
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+var ErrInvalidPlayer = errors.New("player must be black or white")
+
+// Position 是重放到某个节点后的局面
+type Position struct {
+	Board       *game.Board `json:"board"`
+	NextPlayer  game.Player `json:"next_player"`
+	CapturesByB int         `json:"captures_by_b"`
+	CapturesByW int         `json:"captures_by_w"`
+
+	// hashes 记录最近一次摆子以来每一手后的棋面哈希，用于检查打劫
+	hashes []uint64
+}
+
+// Position 从根节点重放到指定节点，返回该节点的局面
+// 路径上的每一手都会重新检查合法性，非法着法返回错误
+func (t *Tree) Position(id NodeID) (*Position, error) {
+	path, err := t.Path(id)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := &Position{
+		Board:      game.NewBoardWithSize(t.Size),
+		NextPlayer: game.Black,
+	}
+	pos.hashes = []uint64{pos.Board.Hash()}
+	for _, nodeID := range path {
+		n, _ := t.Node(nodeID)
+		if n.Setup != nil {
+			if err := pos.setup(n.Setup); err != nil {
+				return nil, fmt.Errorf("node %d: %w", nodeID, err)
+			}
+		}
+		if n.Move != nil {
+			if _, err := pos.play(*n.Move); err != nil {
+				return nil, fmt.Errorf("node %d: %w", nodeID, err)
+			}
+		}
+	}
+	return pos, nil
+}
+
+// setup 在局面上摆子，摆子后不再追溯之前的打劫
+func (pos *Position) setup(s *Setup) error {
+	for _, group := range []struct {
+		points []game.Point
+		player game.Player
+	}{{s.Empty, game.Empty}, {s.Black, game.Black}, {s.White, game.White}} {
+		for _, p := range group.points {
+			if !pos.Board.InBounds(p) {
+				return fmt.Errorf("%w: %v", game.ErrPointOutOfBounds, p)
+			}
+			pos.Board.Grid[p.Y][p.X] = group.player
+		}
+	}
+	if s.NextPlayer != game.Empty {
+		pos.NextPlayer = s.NextPlayer
+	}
+	pos.hashes = []uint64{pos.Board.Rehash()}
+	return nil
+}
+
+// play 在局面上下一手棋，返回提子数，着法非法时局面不再可用
+// 棋谱树不区分规则，只禁止自杀和立即回提的单劫
+func (pos *Position) play(m Move) (int, error) {
+	if m.Player != game.Black && m.Player != game.White {
+		return 0, ErrInvalidPlayer
+	}
+	if m.Pass {
+		pos.NextPlayer = m.Player.Opponent()
+		pos.hashes = append(pos.hashes, pos.Board.Hash())
+		return 0, nil
+	}
+
+	captures, err := pos.Board.PlaceStone(m.Player, m.Point)
+	if err != nil {
+		return 0, err
+	}
+	hash := pos.Board.Hash()
+	if n := len(pos.hashes); n >= 2 && hash == pos.hashes[n-2] {
+		return 0, game.ErrKoViolation
+	}
+
+	if m.Player == game.Black {
+		pos.CapturesByB += captures
+	} else {
+		pos.CapturesByW += captures
+	}
+	pos.NextPlayer = m.Player.Opponent()
+	pos.hashes = append(pos.hashes, hash)
+	return captures, nil
+}
@@ -0,0 +1,362 @@
+// Package gametree 提供复盘用的棋谱树：每个节点可以有多个后续变化，
+// 支持在任意节点插入变化、删除变化、把变化提升为主线，并能重放到任意节点的局面
+package gametree
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+var (
+	ErrNodeNotFound     = errors.New("node not found")
+	ErrCannotDeleteRoot = errors.New("cannot delete the root node")
+	ErrInvalidMarkup    = errors.New("invalid markup")
+)
+
+// NodeID 是节点在树中的编号，根节点为 0
+type NodeID int
+
+// RootID 是根节点的编号
+const RootID NodeID = 0
+
+// Move 是节点上的一手棋
+type Move struct {
+	Player game.Player `json:"player" bson:"player"`
+	Pass   bool        `json:"pass,omitempty" bson:"pass,omitempty"`
+	Point  game.Point  `json:"point" bson:"point"` // 虚手时无意义
+}
+
+// Setup 是节点上的摆子：在上一局面的基础上添加黑子、白子或清空交叉点
+type Setup struct {
+	Black      []game.Point `json:"black,omitempty" bson:"black,omitempty"`
+	White      []game.Point `json:"white,omitempty" bson:"white,omitempty"`
+	Empty      []game.Point `json:"empty,omitempty" bson:"empty,omitempty"`
+	NextPlayer game.Player  `json:"next_player,omitempty" bson:"next_player,omitempty"` // 摆子后的行棋方，Empty 表示不变
+}
+
+func (s *Setup) empty() bool {
+	return len(s.Black) == 0 && len(s.White) == 0 && len(s.Empty) == 0 && s.NextPlayer == game.Empty
+}
+
+// MarkupType 表示棋盘标记的类型
+type MarkupType string
+
+const (
+	MarkCircle   MarkupType = "circle"
+	MarkSquare   MarkupType = "square"
+	MarkTriangle MarkupType = "triangle"
+	MarkCross    MarkupType = "cross"
+	MarkLabel    MarkupType = "label" // 文字标记，内容在 Label 中
+)
+
+// Markup 是节点上的一个棋盘标记
+type Markup struct {
+	Type  MarkupType `json:"type" bson:"type"`
+	Point game.Point `json:"point" bson:"point"`
+	Label string     `json:"label,omitempty" bson:"label,omitempty"`
+}
+
+// Node 是棋谱树中的一个节点，第一个子节点是主线
+type Node struct {
+	ID       NodeID   `json:"id" bson:"id"`
+	Parent   NodeID   `json:"parent" bson:"parent"` // 根节点的父节点为 -1
+	Children []NodeID `json:"children,omitempty" bson:"children,omitempty"`
+	Move     *Move    `json:"move,omitempty" bson:"move,omitempty"`
+	Setup    *Setup   `json:"setup,omitempty" bson:"setup,omitempty"`
+	Comment  string   `json:"comment,omitempty" bson:"comment,omitempty"`
+	Markup   []Markup `json:"markup,omitempty" bson:"markup,omitempty"`
+}
+
+// Tree 是一局棋的棋谱树，Current 记录复盘时所在的节点
+type Tree struct {
+	Size    int     `json:"size" bson:"size"`
+	Current NodeID  `json:"current" bson:"current"`
+	NextID  NodeID  `json:"next_id" bson:"next_id"`
+	Nodes   []*Node `json:"nodes" bson:"nodes"` // 按编号排列
+
+	index map[NodeID]*Node // 编号到节点的索引，按需构建
+}
+
+// New 创建一棵只有根节点的棋谱树
+func New(size int) *Tree {
+	return &Tree{
+		Size:   size,
+		NextID: RootID + 1,
+		Nodes:  []*Node{{ID: RootID, Parent: -1}},
+	}
+}
+
+// FromGame 按对局的摆子、让子和棋谱创建只有主线的棋谱树
+func FromGame(g *game.Game) *Tree {
+	t := New(g.Board.Size())
+	root := t.Root()
+
+	setup := &Setup{Black: slices.Clone(g.SetupStones)}
+	if g.Setup != nil {
+		setup.Black = append(slices.Clone(g.Setup.Black), setup.Black...)
+		setup.White = slices.Clone(g.Setup.White)
+		setup.NextPlayer = g.Setup.NextPlayer
+	}
+	if len(g.SetupStones) > 0 {
+		setup.NextPlayer = game.White // 让子摆完后白先
+	}
+	if len(setup.Black) > 0 || len(setup.White) > 0 || setup.NextPlayer != game.Empty {
+		root.Setup = setup
+	}
+
+	parent := RootID
+	for _, m := range g.Moves {
+		node := t.newNode(parent)
+		node.Move = &Move{Player: m.Player, Pass: m.Pass, Point: m.Point}
+		node.Comment = m.Comment
+		parent = node.ID
+	}
+	return t
+}
+
+// Root 返回根节点
+func (t *Tree) Root() *Node {
+	return t.Nodes[0]
+}
+
+// Node 返回指定编号的节点
+func (t *Tree) Node(id NodeID) (*Node, error) {
+	if t.index == nil {
+		t.index = make(map[NodeID]*Node, len(t.Nodes))
+		for _, n := range t.Nodes {
+			t.index[n.ID] = n
+		}
+	}
+	n, ok := t.index[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", ErrNodeNotFound, id)
+	}
+	return n, nil
+}
+
+// newNode 在 parent 下追加一个新的子节点
+func (t *Tree) newNode(parent NodeID) *Node {
+	p, _ := t.Node(parent)
+	n := &Node{ID: t.NextID, Parent: parent}
+	t.NextID++
+	t.Nodes = append(t.Nodes, n)
+	t.index[n.ID] = n
+	p.Children = append(p.Children, n.ID)
+	return n
+}
+
+// Path 返回从根节点到指定节点经过的所有节点编号
+func (t *Tree) Path(id NodeID) ([]NodeID, error) {
+	var path []NodeID
+	for id != -1 {
+		n, err := t.Node(id)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, id)
+		id = n.Parent
+	}
+	slices.Reverse(path)
+	return path, nil
+}
+
+// MainLine 返回从根节点沿第一个子节点走到底的主线
+func (t *Tree) MainLine() []NodeID {
+	line := []NodeID{RootID}
+	for n := t.Root(); len(n.Children) > 0; {
+		n, _ = t.Node(n.Children[0])
+		line = append(line, n.ID)
+	}
+	return line
+}
+
+// GoTo 把当前节点移动到指定节点
+func (t *Tree) GoTo(id NodeID) error {
+	if _, err := t.Node(id); err != nil {
+		return err
+	}
+	t.Current = id
+	return nil
+}
+
+// Forward 沿主线前进一步，已在末端时返回 false
+func (t *Tree) Forward() bool {
+	n, err := t.Node(t.Current)
+	if err != nil || len(n.Children) == 0 {
+		return false
+	}
+	t.Current = n.Children[0]
+	return true
+}
+
+// Back 后退到父节点，已在根节点时返回 false
+func (t *Tree) Back() bool {
+	n, err := t.Node(t.Current)
+	if err != nil || n.Parent == -1 {
+		return false
+	}
+	t.Current = n.Parent
+	return true
+}
+
+// NextVariation 切换到下一个兄弟变化，没有时返回 false
+func (t *Tree) NextVariation() bool {
+	return t.switchVariation(1)
+}
+
+// PrevVariation 切换到上一个兄弟变化，没有时返回 false
+func (t *Tree) PrevVariation() bool {
+	return t.switchVariation(-1)
+}
+
+func (t *Tree) switchVariation(step int) bool {
+	n, err := t.Node(t.Current)
+	if err != nil || n.Parent == -1 {
+		return false
+	}
+	parent, _ := t.Node(n.Parent)
+	i := slices.Index(parent.Children, n.ID) + step
+	if i < 0 || i >= len(parent.Children) {
+		return false
+	}
+	t.Current = parent.Children[i]
+	return true
+}
+
+// AddMove 在 parent 之后加入一手棋，返回新节点；已有相同的后续着法时返回已有节点
+// 着法会在 parent 的局面上检查是否合法，新节点成为当前节点
+func (t *Tree) AddMove(parent NodeID, m Move) (*Node, error) {
+	return t.Add(parent, &m, nil)
+}
+
+// AddSetup 在 parent 之后加入一个摆子节点，返回新节点
+func (t *Tree) AddSetup(parent NodeID, s Setup) (*Node, error) {
+	return t.Add(parent, nil, &s)
+}
+
+// Add 在 parent 之后加入一个节点，节点上先摆子再落子，两者都没有时是只放评注的空节点
+// 只有着法且 parent 已有相同的后续着法时返回已有节点，新节点或已有节点成为当前节点
+func (t *Tree) Add(parent NodeID, move *Move, setup *Setup) (*Node, error) {
+	if setup != nil && setup.empty() {
+		setup = nil
+	}
+	p, err := t.Node(parent)
+	if err != nil {
+		return nil, err
+	}
+	if setup == nil && move != nil {
+		for _, id := range p.Children {
+			child, _ := t.Node(id)
+			if child.Setup == nil && child.Move != nil && *child.Move == *move {
+				t.Current = child.ID
+				return child, nil
+			}
+		}
+	}
+
+	pos, err := t.Position(parent)
+	if err != nil {
+		return nil, err
+	}
+	if setup != nil {
+		if err := pos.setup(setup); err != nil {
+			return nil, err
+		}
+	}
+	if move != nil {
+		if _, err := pos.play(*move); err != nil {
+			return nil, err
+		}
+	}
+
+	n := t.newNode(parent)
+	n.Move, n.Setup = move, setup
+	t.Current = n.ID
+	return n, nil
+}
+
+// Delete 删除指定节点及其所有后续变化，当前节点在其中时回到被删节点的父节点
+func (t *Tree) Delete(id NodeID) error {
+	if id == RootID {
+		return ErrCannotDeleteRoot
+	}
+	n, err := t.Node(id)
+	if err != nil {
+		return err
+	}
+
+	removed := map[NodeID]bool{}
+	var collect func(n *Node)
+	collect = func(n *Node) {
+		removed[n.ID] = true
+		for _, child := range n.Children {
+			c, _ := t.Node(child)
+			collect(c)
+		}
+	}
+	collect(n)
+
+	parent, _ := t.Node(n.Parent)
+	parent.Children = slices.DeleteFunc(parent.Children, func(c NodeID) bool { return c == id })
+	t.Nodes = slices.DeleteFunc(t.Nodes, func(n *Node) bool { return removed[n.ID] })
+	for removedID := range removed {
+		delete(t.index, removedID)
+	}
+	if removed[t.Current] {
+		t.Current = n.Parent
+	}
+	return nil
+}
+
+// Promote 把根节点到指定节点的路径提升为主线
+func (t *Tree) Promote(id NodeID) error {
+	path, err := t.Path(id)
+	if err != nil {
+		return err
+	}
+	for i := 1; i < len(path); i++ {
+		parent, _ := t.Node(path[i-1])
+		j := slices.Index(parent.Children, path[i])
+		// 保持其它变化的相对顺序，只把路径上的节点移到最前
+		copy(parent.Children[1:j+1], parent.Children[:j])
+		parent.Children[0] = path[i]
+	}
+	return nil
+}
+
+// SetComment 设置节点的评注
+func (t *Tree) SetComment(id NodeID, comment string) error {
+	n, err := t.Node(id)
+	if err != nil {
+		return err
+	}
+	n.Comment = comment
+	return nil
+}
+
+// SetMarkup 替换节点上的棋盘标记
+func (t *Tree) SetMarkup(id NodeID, markup []Markup) error {
+	n, err := t.Node(id)
+	if err != nil {
+		return err
+	}
+	for _, m := range markup {
+		if m.Point.X < 0 || m.Point.Y < 0 || m.Point.X >= t.Size || m.Point.Y >= t.Size {
+			return fmt.Errorf("%w: point %v out of board", ErrInvalidMarkup, m.Point)
+		}
+		switch m.Type {
+		case MarkCircle, MarkSquare, MarkTriangle, MarkCross:
+		case MarkLabel:
+			if m.Label == "" {
+				return fmt.Errorf("%w: label is empty", ErrInvalidMarkup)
+			}
+		default:
+			return fmt.Errorf("%w: unknown type %q", ErrInvalidMarkup, m.Type)
+		}
+	}
+	n.Markup = markup
+	return nil
+}
@@ -0,0 +1,225 @@
+package gametree
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+func black(x, y int) Move { return Move{Player: game.Black, Point: game.Point{X: x, Y: y}} }
+func white(x, y int) Move { return Move{Player: game.White, Point: game.Point{X: x, Y: y}} }
+
+// mustAdd 依次在 parent 后加入着法，返回最后一个节点的编号
+func mustAdd(t *testing.T, tree *Tree, parent NodeID, moves ...Move) NodeID {
+	t.Helper()
+	for _, m := range moves {
+		n, err := tree.AddMove(parent, m)
+		if err != nil {
+			t.Fatalf("AddMove(%d, %+v): %v", parent, m, err)
+		}
+		parent = n.ID
+	}
+	return parent
+}
+
+// TestTree_AddVariationsAndNavigate 测试加入变化后的前进、后退和切换变化
+func TestTree_AddVariationsAndNavigate(t *testing.T) {
+	tree := New(9)
+	first := mustAdd(t, tree, RootID, black(2, 2))
+	main := mustAdd(t, tree, first, white(6, 6))
+	variation := mustAdd(t, tree, first, white(6, 2))
+
+	// 相同的着法不会重复加入
+	again, err := tree.AddMove(first, white(6, 6))
+	if err != nil || again.ID != main {
+		t.Fatalf("Expected existing node %d, got %v, %v", main, again, err)
+	}
+	if root := tree.Root(); len(root.Children) != 1 {
+		t.Fatalf("Expected root to have 1 child, got %v", root.Children)
+	}
+	if got := tree.MainLine(); !slices.Equal(got, []NodeID{RootID, first, main}) {
+		t.Fatalf("Unexpected main line %v", got)
+	}
+
+	if err := tree.GoTo(RootID); err != nil {
+		t.Fatal(err)
+	}
+	if !tree.Forward() || !tree.Forward() || tree.Current != main {
+		t.Fatalf("Expected to walk down the main line to %d, at %d", main, tree.Current)
+	}
+	if tree.Forward() {
+		t.Fatal("Expected Forward to stop at the end of the line")
+	}
+	if !tree.NextVariation() || tree.Current != variation {
+		t.Fatalf("Expected to switch to variation %d, at %d", variation, tree.Current)
+	}
+	if tree.NextVariation() {
+		t.Fatal("Expected no further variation")
+	}
+	if !tree.PrevVariation() || tree.Current != main {
+		t.Fatalf("Expected to switch back to %d, at %d", main, tree.Current)
+	}
+	if !tree.Back() || !tree.Back() || tree.Back() {
+		t.Fatal("Expected Back to stop at the root")
+	}
+	if err := tree.GoTo(99); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("Expected ErrNodeNotFound, got %v", err)
+	}
+}
+
+// TestTree_IllegalMoves 测试变化中的非法着法被拒绝且不加入节点
+func TestTree_IllegalMoves(t *testing.T) {
+	tree := New(9)
+	n := mustAdd(t, tree, RootID, black(2, 2))
+
+	if _, err := tree.AddMove(n, white(2, 2)); !errors.Is(err, game.ErrPointNotEmpty) {
+		t.Fatalf("Expected ErrPointNotEmpty, got %v", err)
+	}
+	if _, err := tree.AddMove(n, white(9, 0)); !errors.Is(err, game.ErrPointOutOfBounds) {
+		t.Fatalf("Expected ErrPointOutOfBounds, got %v", err)
+	}
+	if _, err := tree.AddMove(n, Move{Point: game.Point{X: 1, Y: 1}}); !errors.Is(err, ErrInvalidPlayer) {
+		t.Fatalf("Expected ErrInvalidPlayer, got %v", err)
+	}
+	if len(tree.Nodes) != 2 {
+		t.Fatalf("Expected illegal moves not to be added, got %d nodes", len(tree.Nodes))
+	}
+}
+
+// TestTree_Ko 测试立即回提被禁止，隔一手 (找劫材) 后可以回提
+func TestTree_Ko(t *testing.T) {
+	tree := New(9)
+	// 黑 (2,1) 被白 (1,1) 提掉后形成劫
+	ko := mustAdd(t, tree, RootID,
+		black(1, 0), white(2, 0),
+		black(0, 1), white(3, 1),
+		black(1, 2), white(2, 2),
+		black(2, 1), white(1, 1), // 白提黑 (2,1)
+	)
+	pos, err := tree.Position(ko)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.CapturesByW != 1 || pos.Board.Grid[1][2] != game.Empty {
+		t.Fatalf("Expected white to capture (2,1), captures %d", pos.CapturesByW)
+	}
+
+	if _, err := tree.AddMove(ko, black(2, 1)); !errors.Is(err, game.ErrKoViolation) {
+		t.Fatalf("Expected immediate recapture to violate ko, got %v", err)
+	}
+	threat := mustAdd(t, tree, ko, black(7, 7), white(7, 6))
+	if _, err := tree.AddMove(threat, black(2, 1)); err != nil {
+		t.Fatalf("Expected recapture after ko threat to be legal, got %v", err)
+	}
+}
+
+// TestTree_Setup 测试摆子节点以及摆子后的行棋方
+func TestTree_Setup(t *testing.T) {
+	tree := New(9)
+	n := mustAdd(t, tree, RootID, black(4, 4))
+	setup, err := tree.AddSetup(n, Setup{
+		White:      []game.Point{{X: 3, Y: 3}},
+		Empty:      []game.Point{{X: 4, Y: 4}},
+		NextPlayer: game.White,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pos, err := tree.Position(setup.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Board.Grid[4][4] != game.Empty || pos.Board.Grid[3][3] != game.White || pos.NextPlayer != game.White {
+		t.Fatalf("Unexpected position after setup:\n%s", pos.Board)
+	}
+	if _, err := tree.AddSetup(n, Setup{Black: []game.Point{{X: 9, Y: 9}}}); !errors.Is(err, game.ErrPointOutOfBounds) {
+		t.Fatalf("Expected ErrPointOutOfBounds, got %v", err)
+	}
+}
+
+// TestTree_DeleteAndPromote 测试删除变化和把变化提升为主线
+func TestTree_DeleteAndPromote(t *testing.T) {
+	tree := New(9)
+	first := mustAdd(t, tree, RootID, black(2, 2))
+	a := mustAdd(t, tree, first, white(6, 6))
+	b := mustAdd(t, tree, first, white(6, 2))
+	c := mustAdd(t, tree, first, white(2, 6))
+	deep := mustAdd(t, tree, c, black(4, 4), white(5, 5))
+
+	if err := tree.Promote(deep); err != nil {
+		t.Fatal(err)
+	}
+	node, _ := tree.Node(first)
+	if !slices.Equal(node.Children, []NodeID{c, a, b}) {
+		t.Fatalf("Expected promoted variation first keeping the others in order, got %v", node.Children)
+	}
+	if line := tree.MainLine(); line[len(line)-1] != deep {
+		t.Fatalf("Expected main line to end at %d, got %v", deep, line)
+	}
+
+	if err := tree.GoTo(deep); err != nil {
+		t.Fatal(err)
+	}
+	if err := tree.Delete(c); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Current != first {
+		t.Fatalf("Expected current node to move to the parent %d, got %d", first, tree.Current)
+	}
+	if !slices.Equal(node.Children, []NodeID{a, b}) || len(tree.Nodes) != 4 {
+		t.Fatalf("Expected subtree to be removed, children %v, %d nodes", node.Children, len(tree.Nodes))
+	}
+	if _, err := tree.Node(deep); !errors.Is(err, ErrNodeNotFound) {
+		t.Fatalf("Expected deleted node to be gone, got %v", err)
+	}
+	if err := tree.Delete(RootID); !errors.Is(err, ErrCannotDeleteRoot) {
+		t.Fatalf("Expected ErrCannotDeleteRoot, got %v", err)
+	}
+}
+
+// TestTree_Markup 测试标记的校验
+func TestTree_Markup(t *testing.T) {
+	tree := New(9)
+	if err := tree.SetMarkup(RootID, []Markup{{Type: MarkLabel, Point: game.Point{X: 1, Y: 1}, Label: "A"}}); err != nil {
+		t.Fatal(err)
+	}
+	for _, bad := range []Markup{
+		{Type: MarkLabel, Point: game.Point{X: 1, Y: 1}},
+		{Type: "star", Point: game.Point{X: 1, Y: 1}},
+		{Type: MarkCircle, Point: game.Point{X: 9, Y: 1}},
+	} {
+		if err := tree.SetMarkup(RootID, []Markup{bad}); !errors.Is(err, ErrInvalidMarkup) {
+			t.Fatalf("Expected ErrInvalidMarkup for %+v, got %v", bad, err)
+		}
+	}
+}
+
+// TestFromGame 测试由对局生成的棋谱树能重放出相同的局面
+func TestFromGame(t *testing.T) {
+	g, err := game.NewGameWithOptions(game.GameOptions{BoardSize: 9, Handicap: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range []game.Point{{X: 4, Y: 4}, {X: 3, Y: 3}, {X: 5, Y: 5}} {
+		if err := g.PlayMove(p); err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = g.PassTurn()
+
+	tree := FromGame(g)
+	line := tree.MainLine()
+	if len(line) != len(g.Moves)+1 {
+		t.Fatalf("Expected %d nodes on the main line, got %d", len(g.Moves)+1, len(line))
+	}
+	pos, err := tree.Position(line[len(line)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pos.Board.StateHash() != g.Board.StateHash() || pos.NextPlayer != g.NextPlayer {
+		t.Fatalf("Expected replayed position to match the game:\n%s\nvs\n%s", pos.Board, g.Board)
+	}
+}
@@ -0,0 +1,457 @@
+package gtp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+const (
+	protocolVersion = "2"
+	engineName      = "weiqi-go"
+	engineVersion   = "1.0.0"
+)
+
+// AIClient 是 genmove 使用的落子引擎，api.AIClient 满足该接口
+// GetMove 返回 ErrPass 或 ErrResign 表示引擎选择虚手或认输
+type AIClient interface {
+	GetMove(ctx context.Context, g *game.Game) (game.Point, error)
+}
+
+// 引擎选择虚手或认输时 GetMove 返回的错误，genmove 分别回应 pass 和 resign
+var (
+	ErrPass   = errors.New("engine passed")
+	ErrResign = errors.New("engine resigned")
+)
+
+// Engine 是以 game.Game 为后端的 GTP 引擎
+// 计时由 GTP 控制端负责，对局始终保持未开始 (waiting) 状态，不会因超时判负
+type Engine struct {
+	ai   AIClient
+	opts game.GameOptions // clear_board 时使用的设置
+	game *game.Game
+
+	// implicitPasses 记录为了让同一方连续落子而补上的对方虚手 (按手数)，悔棋时一起撤销
+	implicitPasses map[int]bool
+}
+
+// handler 处理一条 GTP 命令，返回响应内容
+type handler func(e *Engine, args []string) (string, error)
+
+// commands 是支持的 GTP 命令
+var commands map[string]handler
+
+func init() {
+	commands = map[string]handler{
+		"protocol_version": func(*Engine, []string) (string, error) { return protocolVersion, nil },
+		"name":             func(*Engine, []string) (string, error) { return engineName, nil },
+		"version":          func(*Engine, []string) (string, error) { return engineVersion, nil },
+		"known_command":    (*Engine).knownCommand,
+		"list_commands":    (*Engine).listCommands,
+		"quit":             func(*Engine, []string) (string, error) { return "", nil },
+		"boardsize":        (*Engine).boardsize,
+		"clear_board":      (*Engine).clearBoard,
+		"komi":             (*Engine).komi,
+		"play":             (*Engine).play,
+		"genmove":          (*Engine).genmove,
+		"undo":             (*Engine).undo,
+		"final_score":      (*Engine).finalScore,
+		"showboard":        (*Engine).showboard,
+		"fixed_handicap":   (*Engine).fixedHandicap,
+		"time_settings":    (*Engine).timeSettings,
+	}
+}
+
+// NewEngine 按对局设置创建 GTP 引擎，ai 为空时 genmove 不可用
+func NewEngine(ai AIClient, opts game.GameOptions) (*Engine, error) {
+	e := &Engine{ai: ai, opts: opts}
+	if err := e.reset(); err != nil {
+		return nil, err
+	}
+	return e, nil
+}
+
+// Game 返回引擎当前的对局
+func (e *Engine) Game() *game.Game {
+	return e.game
+}
+
+// reset 按当前设置开始新的对局
+func (e *Engine) reset() error {
+	g, err := game.NewGameWithOptions(e.opts)
+	if err != nil {
+		return err
+	}
+	e.game = g
+	e.implicitPasses = map[int]bool{}
+	return nil
+}
+
+// Run 从 r 逐行读取命令并把响应写入 w，直到收到 quit 或输入结束
+func (e *Engine) Run(r io.Reader, w io.Writer) error {
+	scanner := bufio.NewScanner(r)
+	out := bufio.NewWriter(w)
+	for scanner.Scan() {
+		response, quit, ok := e.Execute(scanner.Text())
+		if !ok {
+			continue
+		}
+		if _, err := out.WriteString(response); err != nil {
+			return err
+		}
+		if err := out.Flush(); err != nil {
+			return err
+		}
+		if quit {
+			return nil
+		}
+	}
+	return scanner.Err()
+}
+
+// Execute 执行一行 GTP 命令，返回完整的响应 (包括结尾的空行)
+// 空行和注释行没有响应，ok 为 false
+func (e *Engine) Execute(line string) (response string, quit, ok bool) {
+	id, name, args := parseCommand(line)
+	if name == "" {
+		return "", false, false
+	}
+
+	h, known := commands[name]
+	if !known {
+		return formatResponse(id, "", errors.New("unknown command")), false, true
+	}
+	result, err := h(e, args)
+	return formatResponse(id, result, err), name == "quit" && err == nil, true
+}
+
+// parseCommand 预处理并拆分一行命令：去掉控制字符和 # 之后的注释，取出可选的数字编号
+func parseCommand(line string) (id, name string, args []string) {
+	if i := strings.IndexByte(line, '#'); i >= 0 {
+		line = line[:i]
+	}
+	line = strings.Map(func(r rune) rune {
+		switch {
+		case r == '\t':
+			return ' '
+		case r < 32 || r == 127:
+			return -1
+		}
+		return r
+	}, line)
+
+	fields := strings.Fields(line)
+	if len(fields) > 0 {
+		if _, err := strconv.Atoi(fields[0]); err == nil {
+			id, fields = fields[0], fields[1:]
+		}
+	}
+	if len(fields) == 0 {
+		return id, "", nil
+	}
+	return id, fields[0], fields[1:]
+}
+
+// formatResponse 按 GTP 格式写出成功 ("=") 或失败 ("?") 的响应
+func formatResponse(id, result string, err error) string {
+	if err != nil {
+		return "?" + id + " " + err.Error() + "\n\n"
+	}
+	if result == "" {
+		return "=" + id + "\n\n"
+	}
+	return "=" + id + " " + strings.TrimRight(result, "\n") + "\n\n"
+}
+
+var errSyntax = errors.New("syntax error")
+
+func (e *Engine) knownCommand(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errSyntax
+	}
+	_, known := commands[args[0]]
+	return strconv.FormatBool(known), nil
+}
+
+func (e *Engine) listCommands([]string) (string, error) {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, "\n"), nil
+}
+
+// boardsize 修改棋盘大小并清空棋盘，只支持 9、13、19 路
+func (e *Engine) boardsize(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errSyntax
+	}
+	size, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "", errSyntax
+	}
+	if !game.IsSupportedBoardSize(size) {
+		return "", errors.New("unacceptable size")
+	}
+	e.opts.BoardSize = size
+	e.opts.Handicap = 0
+	return "", e.reset()
+}
+
+// clearBoard 清空棋盘，开始一局新的分先对局
+func (e *Engine) clearBoard([]string) (string, error) {
+	e.opts.Handicap = 0
+	return "", e.reset()
+}
+
+// komi 设置贴目，对当前对局立即生效
+func (e *Engine) komi(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errSyntax
+	}
+	komi, err := strconv.ParseFloat(args[0], 64)
+	if err != nil || math.IsNaN(komi) || math.IsInf(komi, 0) {
+		return "", errSyntax
+	}
+	e.opts.Komi = &komi
+	e.game.Rules.Komi = komi
+	return "", nil
+}
+
+// play 由指定一方落子或虚手
+func (e *Engine) play(args []string) (string, error) {
+	if len(args) != 2 {
+		return "", errSyntax
+	}
+	color, err := ParseColor(args[0])
+	if err != nil {
+		return "", errSyntax
+	}
+	p, pass, err := ParseVertex(args[1], e.game.Board.Size())
+	if err != nil {
+		return "", errSyntax
+	}
+	if err := e.move(color, p, pass); err != nil {
+		return "", errors.New("illegal move")
+	}
+	return "", nil
+}
+
+// genmove 由 AI 为指定一方落子，没有合法的落点或 AI 选择虚手时虚手，AI 认输时回应 resign
+func (e *Engine) genmove(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errSyntax
+	}
+	color, err := ParseColor(args[0])
+	if err != nil {
+		return "", errSyntax
+	}
+	if e.ai == nil {
+		return "", errors.New("no AI engine configured")
+	}
+
+	if err := e.turn(color); err != nil {
+		return "", err
+	}
+	size := e.game.Board.Size()
+	if len(e.game.LegalMoves()) == 0 {
+		if err := e.game.PassTurn(); err != nil {
+			return "", err
+		}
+		return "pass", nil
+	}
+
+	p, err := e.ai.GetMove(context.Background(), e.game)
+	switch {
+	case errors.Is(err, ErrPass):
+		err = e.game.PassTurn()
+		if err == nil {
+			return "pass", nil
+		}
+	case errors.Is(err, ErrResign):
+		// 认输不改变局面，由控制端结束对局
+		e.rollbackTurn()
+		return "resign", nil
+	case err == nil:
+		err = e.game.PlayMove(p)
+	}
+	if err != nil {
+		e.rollbackTurn()
+		return "", fmt.Errorf("genmove failed: %v", err)
+	}
+	return FormatVertex(p, size), nil
+}
+
+// move 让 color 在 p 落子或虚手，失败时局面不变
+func (e *Engine) move(color game.Player, p game.Point, pass bool) error {
+	if err := e.turn(color); err != nil {
+		return err
+	}
+	var err error
+	if pass {
+		err = e.game.PassTurn()
+	} else {
+		err = e.game.PlayMove(p)
+	}
+	if err != nil {
+		e.rollbackTurn()
+		return err
+	}
+	return nil
+}
+
+// turn 使 color 成为行棋方：GTP 允许同一方连续落子，此时为对方补一手虚手
+func (e *Engine) turn(color game.Player) error {
+	e.resume()
+	if e.game.NextPlayer == color {
+		return nil
+	}
+	if err := e.game.PassTurn(); err != nil {
+		return err
+	}
+	e.implicitPasses[len(e.game.Moves)] = true
+	e.resume() // 补上的虚手可能构成连续两次虚手
+	return nil
+}
+
+// rollbackTurn 落子失败时撤销 turn 补上的虚手
+func (e *Engine) rollbackTurn() {
+	n := len(e.game.Moves)
+	if !e.implicitPasses[n] {
+		return
+	}
+	delete(e.implicitPasses, n)
+	_ = e.game.Undo(1)
+	e.resume()
+}
+
+// resume 连续虚手后对局进入点目阶段，GTP 中由控制端决定是否继续，这里直接恢复对局
+func (e *Engine) resume() {
+	if e.game.Status == game.GameStatusScoring {
+		e.game.Scoring = nil
+		e.game.Status = game.GameStatusWaiting
+	}
+}
+
+// undo 撤销最后一手，连同为其补上的对方虚手
+func (e *Engine) undo([]string) (string, error) {
+	n := len(e.game.Moves)
+	if n == 0 {
+		return "", errors.New("cannot undo")
+	}
+	count := 1
+	if e.implicitPasses[n-1] {
+		count = 2
+	}
+	if err := e.game.Undo(count); err != nil {
+		return "", errors.New("cannot undo")
+	}
+	for i := n - count + 1; i <= n; i++ {
+		delete(e.implicitPasses, i)
+	}
+	e.resume()
+	return "", nil
+}
+
+// finalScore 按对局规则计算当前局面的胜负，所有棋子都视为活棋
+func (e *Engine) finalScore([]string) (string, error) {
+	g := *e.game
+	if !g.GameOver && g.Status != game.GameStatusScoring {
+		g.Status = game.GameStatusScoring
+	}
+	score, err := g.CalculateScore()
+	if err != nil {
+		return "", err
+	}
+	if score.Winner == game.Empty {
+		return "0", nil
+	}
+	result := game.Result{
+		Winner: score.Winner,
+		Margin: math.Abs(score.BlackScore - score.WhiteScore),
+		Reason: game.ReasonScore,
+	}
+	return result.String(), nil
+}
+
+// showboard 以文本形式显示棋盘
+func (e *Engine) showboard([]string) (string, error) {
+	return "\n" + e.game.Board.String(), nil
+}
+
+// fixedHandicap 在空棋盘上按星位摆放让子，返回让子的坐标
+func (e *Engine) fixedHandicap(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", errSyntax
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil {
+		return "", errSyntax
+	}
+	if len(e.game.Moves) > 0 || len(e.game.SetupStones) > 0 {
+		return "", errors.New("board not empty")
+	}
+	if n < game.MinHandicap || n > game.MaxHandicap {
+		return "", errors.New("invalid number of stones")
+	}
+
+	opts := e.opts
+	opts.Handicap = n
+	opts.HandicapPlacement = game.HandicapFixed
+	if opts.Komi == nil {
+		komi := e.game.Rules.Komi // 保留当前贴目，而不是改为让子棋的默认贴目
+		opts.Komi = &komi
+	}
+	g, err := game.NewGameWithOptions(opts)
+	if err != nil {
+		return "", err
+	}
+	e.opts.Handicap, e.opts.HandicapPlacement = n, game.HandicapFixed
+	e.game = g
+
+	size := g.Board.Size()
+	vertices := make([]string, len(g.SetupStones))
+	for i, p := range g.SetupStones {
+		vertices[i] = FormatVertex(p, size)
+	}
+	return strings.Join(vertices, " "), nil
+}
+
+// timeSettings 记录计时设置 (基本时间、读秒时间、读秒手数，单位秒)
+// 读秒时间为 0 表示包干制；读秒手数为 0 而读秒时间大于 0 表示不限时
+// 计时由控制端负责，设置只写入之后开始的对局，用于棋谱记录
+func (e *Engine) timeSettings(args []string) (string, error) {
+	if len(args) != 3 {
+		return "", errSyntax
+	}
+	var values [3]int64
+	for i, arg := range args {
+		v, err := strconv.ParseInt(arg, 10, 64)
+		if err != nil || v < 0 {
+			return "", errSyntax
+		}
+		values[i] = v
+	}
+	mainTime, byoYomiTime, byoYomiStones := values[0], values[1], values[2]
+
+	var tc *game.TimeControl
+	switch {
+	case byoYomiTime == 0 && mainTime > 0:
+		abs := game.AbsoluteTime(mainTime)
+		tc = &abs
+	case byoYomiTime > 0 && byoYomiStones > 0:
+		// GTP 的读秒是加拿大读秒
+		tc = &game.TimeControl{Type: game.TimeCanadian, MainTime: mainTime, PeriodTime: byoYomiTime, PeriodStones: int(byoYomiStones)}
+	}
+	e.opts.TimeControl = tc
+	return "", nil
+}
@@ -0,0 +1,212 @@
+package gtp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// fakeAI 依次返回预设的落子
+type fakeAI struct {
+	moves []game.Point
+	err   error
+}
+
+func (f *fakeAI) GetMove(context.Context, *game.Game) (game.Point, error) {
+	if f.err != nil {
+		return game.Point{}, f.err
+	}
+	p := f.moves[0]
+	f.moves = f.moves[1:]
+	return p, nil
+}
+
+func newTestEngine(t *testing.T, ai AIClient) *Engine {
+	t.Helper()
+	e, err := NewEngine(ai, game.GameOptions{BoardSize: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return e
+}
+
+// run 执行一条命令并返回响应
+func run(t *testing.T, e *Engine, line string) string {
+	t.Helper()
+	response, _, ok := e.Execute(line)
+	if !ok {
+		t.Fatalf("Expected a response to %q", line)
+	}
+	return response
+}
+
+// TestVertex 测试 GTP 坐标与棋盘坐标的转换
+func TestVertex(t *testing.T) {
+	cases := []struct {
+		vertex string
+		point  game.Point
+	}{
+		{"A1", game.Point{X: 0, Y: 18}},
+		{"t19", game.Point{X: 18, Y: 0}},
+		{"J10", game.Point{X: 8, Y: 9}}, // 跳过 I
+		{"D16", game.Point{X: 3, Y: 3}},
+	}
+	for _, c := range cases {
+		p, pass, err := ParseVertex(c.vertex, 19)
+		if err != nil || pass || p != c.point {
+			t.Fatalf("ParseVertex(%q) = %v, %v, %v; want %v", c.vertex, p, pass, err, c.point)
+		}
+		if got := FormatVertex(p, 19); got != strings.ToUpper(c.vertex) {
+			t.Fatalf("FormatVertex(%v) = %q, want %q", p, got, c.vertex)
+		}
+	}
+	if _, pass, err := ParseVertex("PASS", 19); err != nil || !pass {
+		t.Fatalf("Expected pass, got %v, %v", pass, err)
+	}
+	for _, bad := range []string{"I5", "Z1", "A0", "A20", "K", ""} {
+		if _, _, err := ParseVertex(bad, 19); !errors.Is(err, ErrInvalidVertex) {
+			t.Fatalf("Expected ErrInvalidVertex for %q, got %v", bad, err)
+		}
+	}
+}
+
+// TestRun 测试命令编号、注释、未知命令和 quit
+func TestRun(t *testing.T) {
+	e := newTestEngine(t, nil)
+	input := "# comment\n1 protocol_version\n\n2 name\nfoo\n3 known_command play\nknown_command foo\nquit\nname\n"
+
+	var out strings.Builder
+	if err := e.Run(strings.NewReader(input), &out); err != nil {
+		t.Fatal(err)
+	}
+	want := "=1 2\n\n=2 weiqi-go\n\n? unknown command\n\n=3 true\n\n= false\n\n=\n\n"
+	if out.String() != want {
+		t.Fatalf("Unexpected output:\n%q\nwant\n%q", out.String(), want)
+	}
+}
+
+// TestPlayAndUndo 测试落子、非法落子、同一方连续落子和悔棋
+func TestPlayAndUndo(t *testing.T) {
+	e := newTestEngine(t, nil)
+
+	if got := run(t, e, "play b E5"); got != "=\n\n" {
+		t.Fatalf("Unexpected response %q", got)
+	}
+	if got := run(t, e, "play w E5"); got != "? illegal move\n\n" {
+		t.Fatalf("Expected illegal move, got %q", got)
+	}
+	if got := run(t, e, "play x E5"); got != "? syntax error\n\n" {
+		t.Fatalf("Expected syntax error, got %q", got)
+	}
+
+	// 黑方连续落子时为白方补一手虚手，悔棋时一起撤销
+	run(t, e, "play b C3")
+	g := e.Game()
+	if len(g.Moves) != 3 || !g.Moves[1].Pass || g.NextPlayer != game.White {
+		t.Fatalf("Expected an implicit white pass, got %d moves", len(g.Moves))
+	}
+	if got := run(t, e, "undo"); got != "=\n\n" || len(e.Game().Moves) != 1 {
+		t.Fatalf("Expected undo to remove the move and the implicit pass, got %q with %d moves", got, len(e.Game().Moves))
+	}
+
+	// 连续虚手后继续落子
+	run(t, e, "play w pass")
+	run(t, e, "play b pass")
+	if got := run(t, e, "play w D4"); got != "=\n\n" {
+		t.Fatalf("Expected play to continue after two passes, got %q", got)
+	}
+
+	run(t, e, "clear_board")
+	if got := run(t, e, "undo"); got != "? cannot undo\n\n" {
+		t.Fatalf("Expected cannot undo, got %q", got)
+	}
+}
+
+// TestGenmove 测试 AI 落子以及 AI 返回非法落子时局面不变
+func TestGenmove(t *testing.T) {
+	e := newTestEngine(t, nil)
+	if got := run(t, e, "genmove b"); !strings.HasPrefix(got, "?") {
+		t.Fatalf("Expected error without AI, got %q", got)
+	}
+
+	ai := &fakeAI{moves: []game.Point{{X: 2, Y: 6}, {X: 2, Y: 6}}}
+	e = newTestEngine(t, ai)
+	if got := run(t, e, "genmove black"); got != "= C3\n\n" {
+		t.Fatalf("Unexpected response %q", got)
+	}
+	// 白方的 AI 落子与黑子重叠，补上的黑方虚手也被撤销
+	run(t, e, "play w G7")
+	if got := run(t, e, "genmove w"); !strings.HasPrefix(got, "? genmove failed") {
+		t.Fatalf("Expected genmove to fail, got %q", got)
+	}
+	if g := e.Game(); len(g.Moves) != 2 || g.NextPlayer != game.Black {
+		t.Fatalf("Expected the position to be unchanged, got %d moves", len(g.Moves))
+	}
+}
+
+// TestGenmove_Pass 测试 AI 选择虚手时回应 pass 并记录虚手
+func TestGenmove_Pass(t *testing.T) {
+	e := newTestEngine(t, &fakeAI{err: fmt.Errorf("search: %w", ErrPass)})
+	if got := run(t, e, "genmove b"); got != "= pass\n\n" {
+		t.Fatalf("Expected pass, got %q", got)
+	}
+	if g := e.Game(); len(g.Moves) != 1 || !g.Moves[0].Pass || g.NextPlayer != game.White {
+		t.Fatalf("Expected a black pass, got %d moves", len(g.Moves))
+	}
+}
+
+// TestGenmove_Resign 测试 AI 认输时回应 resign，局面不变
+func TestGenmove_Resign(t *testing.T) {
+	e := newTestEngine(t, &fakeAI{err: ErrResign})
+	run(t, e, "play b C3")
+	// 黑方连续行棋时补上的白方虚手也被撤销
+	if got := run(t, e, "genmove b"); got != "= resign\n\n" {
+		t.Fatalf("Expected resign, got %q", got)
+	}
+	if g := e.Game(); len(g.Moves) != 1 || g.NextPlayer != game.White || g.GameOver {
+		t.Fatalf("Expected the position to be unchanged, got %d moves", len(g.Moves))
+	}
+}
+
+// TestBoardSetup 测试 boardsize、komi、fixed_handicap、time_settings 和 final_score
+func TestBoardSetup(t *testing.T) {
+	e := newTestEngine(t, nil)
+
+	if got := run(t, e, "boardsize 7"); got != "? unacceptable size\n\n" {
+		t.Fatalf("Unexpected response %q", got)
+	}
+	run(t, e, "boardsize 19")
+	run(t, e, "komi 6.5")
+	if got := run(t, e, "fixed_handicap 3"); got != "= D4 Q16 D16\n\n" {
+		t.Fatalf("Unexpected handicap stones %q", got)
+	}
+	if g := e.Game(); g.NextPlayer != game.White || g.Rules.Komi != 6.5 {
+		t.Fatalf("Expected white to move with komi 6.5, got %v %v", g.NextPlayer, g.Rules.Komi)
+	}
+	if got := run(t, e, "fixed_handicap 2"); got != "? board not empty\n\n" {
+		t.Fatalf("Expected board not empty, got %q", got)
+	}
+
+	run(t, e, "time_settings 600 30 5")
+	run(t, e, "clear_board")
+	if tc := e.Game().TimeControl; tc.Type != game.TimeCanadian || tc.PeriodStones != 5 {
+		t.Fatalf("Expected canadian time control, got %+v", tc)
+	}
+
+	// 空棋盘上白方只有贴目
+	if got := run(t, e, "final_score"); got != "= W+6.5\n\n" {
+		t.Fatalf("Unexpected score %q", got)
+	}
+	run(t, e, "komi 0")
+	if got := run(t, e, "final_score"); got != "= 0\n\n" {
+		t.Fatalf("Expected a draw, got %q", got)
+	}
+
+	if got := run(t, e, "showboard"); !strings.HasPrefix(got, "= \n") || !strings.Contains(got, " . ") {
+		t.Fatalf("Unexpected board %q", got)
+	}
+}
@@ -0,0 +1,54 @@
+// Package gtp 实现围棋文本协议 (Go Text Protocol, GTP v2)
+// 使本项目的规则引擎可以接入 GoGui、Sabaki、twogtp 等支持 GTP 的程序
+package gtp
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+var (
+	ErrInvalidVertex = errors.New("invalid vertex")
+	ErrInvalidColor  = errors.New("invalid color")
+)
+
+// columns 是 GTP 坐标的列字母，跳过 I
+const columns = "ABCDEFGHJKLMNOPQRSTUVWXYZ"
+
+// ParseVertex 解析 GTP 坐标 (如 "D4"、"pass")，不区分大小写
+// GTP 的行号从棋盘下方的 1 开始，而 Point.Y 的 0 在棋盘上方
+func ParseVertex(s string, size int) (p game.Point, pass bool, err error) {
+	s = strings.ToUpper(strings.TrimSpace(s))
+	if s == "PASS" {
+		return game.Point{}, true, nil
+	}
+	if len(s) < 2 {
+		return game.Point{}, false, fmt.Errorf("%w: %q", ErrInvalidVertex, s)
+	}
+	x := strings.IndexByte(columns, s[0])
+	row, err := strconv.Atoi(s[1:])
+	if x < 0 || err != nil || x >= size || row < 1 || row > size {
+		return game.Point{}, false, fmt.Errorf("%w: %q", ErrInvalidVertex, s)
+	}
+	return game.Point{X: x, Y: size - row}, false, nil
+}
+
+// FormatVertex 将棋盘坐标转换为 GTP 坐标
+func FormatVertex(p game.Point, size int) string {
+	return string(columns[p.X]) + strconv.Itoa(size-p.Y)
+}
+
+// ParseColor 解析 GTP 颜色 ("b"、"black"、"w"、"white")，不区分大小写
+func ParseColor(s string) (game.Player, error) {
+	switch strings.ToLower(s) {
+	case "b", "black":
+		return game.Black, nil
+	case "w", "white":
+		return game.White, nil
+	}
+	return game.Empty, fmt.Errorf("%w: %q", ErrInvalidColor, s)
+}
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/nankp236270/weiqi-go/ai"
@@ -10,6 +12,7 @@ import (
 	"github.com/nankp236270/weiqi-go/config"
 	"github.com/nankp236270/weiqi-go/database"
 	"github.com/nankp236270/weiqi-go/logger"
+	"github.com/nankp236270/weiqi-go/mcts"
 	"github.com/nankp236270/weiqi-go/storage"
 	"github.com/nankp236270/weiqi-go/user"
 )
@@ -41,6 +44,13 @@ func main() {
 	store := storage.NewMongoGameStore(gameCollection)
 	logger.Info("game store initialized", "collection", cfg.CollectionName)
 
+	// 迁移旧格式的劫争历史
+	if migrated, err := store.MigrateHistories(); err != nil {
+		logger.Error("failed to migrate game histories", "error", err)
+	} else if migrated > 0 {
+		logger.Info("game histories migrated", "count", migrated)
+	}
+
 	// 初始化用户存储
 	userCollection := mongoClient.Database(cfg.DBName).Collection(cfg.UserCollection)
 	userStore := user.NewMongoUserStore(userCollection)
@@ -50,23 +60,73 @@ func main() {
 	jwtManager := auth.NewJWTManager(cfg.JWTSecret, 24*time.Hour) // Token 有效期 24 小时
 	logger.Info("JWT authentication enabled", "token_duration", "24h")
 
-	// 5. 初始化 AI 客户端（如果配置了）
-	var aiClient *ai.Client
-	if cfg.AIServiceURL != "" {
-		aiClient = ai.NewClient(cfg.AIServiceURL)
-		logger.Info("AI service configured", "url", cfg.AIServiceURL)
-
-		// 检查 AI 服务健康状态
-		if err := aiClient.HealthCheck(); err != nil {
-			logger.Warn("AI service health check failed", "error", err)
-			logger.Warn("AI features will be unavailable")
-			aiClient = nil
+	// 5. 初始化 AI 客户端，未配置或不可用时使用内置引擎
+	engine := mcts.New(mcts.Config{
+		Playouts:   cfg.MCTSPlayouts,
+		TimeBudget: cfg.MCTSTime,
+	})
+	healthCtx, stopHealth := context.WithCancel(context.Background())
+	defer stopHealth()
+
+	var aiClient api.AIClient
+	switch {
+	case cfg.AIBackend == "mcts":
+		// 直接使用下面的内置引擎
+	case cfg.AIBackend == "gtp":
+		fields := strings.Fields(cfg.GTPCommand)
+		gtpClient := ai.NewGTPClient(ai.GTPConfig{
+			Command:  fields[0],
+			Args:     fields[1:],
+			PoolSize: cfg.GTPPoolSize,
+			Timeout:  cfg.GTPTimeout,
+		})
+		defer gtpClient.Close()
+		logger.Info("GTP engine configured", "command", cfg.GTPCommand, "pool_size", cfg.GTPPoolSize)
+
+		// 启动一个引擎进程检查其能否正常响应；失败的进程在下次请求时重新启动，
+		// 引擎出错期间由内置引擎代替，恢复后自动切回
+		if err := gtpClient.HealthCheck(); err != nil {
+			logger.Warn("using built-in MCTS engine until the GTP engine recovers", "error", err)
+		} else {
+			logger.Info("GTP engine is healthy")
+		}
+		aiClient = ai.WithFallback(gtpClient, engine)
+	case cfg.AIServiceURL != "":
+		httpClient := ai.NewClientWithConfig(ai.ClientConfig{
+			BaseURL: cfg.AIServiceURL,
+			Timeout: cfg.AITimeout,
+			Retries: cfg.AIRetries,
+		})
+		logger.Info("AI service configured", "url", cfg.AIServiceURL, "timeout", cfg.AITimeout, "retries", cfg.AIRetries)
+
+		// 检查 AI 服务健康状态，不可用时由内置引擎代替，后台检查到服务恢复后自动切回
+		if err := httpClient.Probe(healthCtx); err != nil {
+			logger.Warn("using built-in MCTS engine until the AI service recovers", "health_interval", cfg.AIHealthInterval)
 		} else {
 			logger.Info("AI service is healthy")
 		}
-	} else {
+		go httpClient.WatchHealth(healthCtx, cfg.AIHealthInterval)
+		aiClient = ai.WithFallback(httpClient, engine)
+	default:
 		logger.Info("AI service not configured")
 	}
+	if aiClient == nil {
+		aiClient = engine
+		logger.Info("using built-in MCTS engine", "playouts", cfg.MCTSPlayouts, "time_budget", cfg.MCTSTime)
+	}
+
+	// 配置了 KataGo 分析引擎时由其负责局面分析
+	if cfg.KataGoCommand != "" {
+		fields := strings.Fields(cfg.KataGoCommand)
+		kataGo := ai.NewKataGoClient(ai.KataGoConfig{
+			Command: fields[0],
+			Args:    fields[1:],
+			Timeout: cfg.KataGoTimeout,
+		})
+		defer kataGo.Close()
+		aiClient = ai.WithAnalyzer(aiClient, kataGo)
+		logger.Info("KataGo analysis engine configured", "command", cfg.KataGoCommand)
+	}
 
 	// 6. 初始化并启动 API 服务器
 	addr := fmt.Sprintf(":%s", cfg.ServerPort)
@@ -0,0 +1,209 @@
+// Package mcts 实现不依赖外部服务的蒙特卡洛树搜索围棋引擎
+// 搜索使用 UCT 与 RAVE (AMAF) 统计，模拟对局按提子、逃子和 3x3 好形优先的策略走子，
+// 在没有配置 AI 服务或 AI 服务不可用时作为本地后端
+package mcts
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/ai"
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// 默认的搜索预算
+const (
+	DefaultPlayouts   = 3000
+	DefaultTimeBudget = 5 * time.Second
+)
+
+// Config 是引擎的配置
+type Config struct {
+	Playouts   int           // 每次搜索的最大模拟次数，0 表示 DefaultPlayouts
+	TimeBudget time.Duration // 每次搜索的最长时间，0 表示 DefaultTimeBudget
+	Seed       int64         // 随机数种子，0 表示每次搜索使用不同的种子
+}
+
+// Engine 是蒙特卡洛树搜索引擎，可以并发使用，每次请求独立搜索
+type Engine struct {
+	cfg Config
+}
+
+// New 创建引擎
+func New(cfg Config) *Engine {
+	if cfg.Playouts <= 0 {
+		cfg.Playouts = DefaultPlayouts
+	}
+	if cfg.TimeBudget <= 0 {
+		cfg.TimeBudget = DefaultTimeBudget
+	}
+	return &Engine{cfg: cfg}
+}
+
+// newRand 返回一次搜索使用的随机数生成器
+func (e *Engine) newRand() *rand.Rand {
+	seed := e.cfg.Seed
+	if seed == 0 {
+		seed = time.Now().UnixNano()
+	}
+	return rand.New(rand.NewSource(seed))
+}
+
+// search 对当前局面进行一次完整的搜索，playouts 为 0 时使用引擎的设置
+// ctx 被取消时停止搜索并返回 ctx 的错误
+func (e *Engine) search(ctx context.Context, g *game.Game, rng *rand.Rand, playouts int) (*search, error) {
+	if playouts <= 0 || playouts > e.cfg.Playouts {
+		playouts = e.cfg.Playouts
+	}
+	s := newSearch(g, rng)
+	s.run(ctx, playouts, e.cfg.TimeBudget)
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// GetMove 按对局的 AI 难度为当前行棋方选点，选择虚手时返回 ai.ErrEnginePassed
+// 最强难度总是选择访问次数最多的着法；较低的难度减少模拟次数，
+// 并按 AIProfile 随机落子、按温度抽样或故意选择次优的着法
+func (e *Engine) GetMove(ctx context.Context, g *game.Game) (game.Point, error) {
+	if g.GameOver {
+		return game.Point{}, errors.New("game is over")
+	}
+	profile := g.AIProfile()
+	rng := e.newRand()
+
+	if profile.Randomness > 0 && rng.Float64() < profile.Randomness {
+		s := newSearch(g, rng)
+		if i, ok := s.randomMove(); ok {
+			return s.rootPos.point(i), nil
+		}
+	}
+
+	s, err := e.search(ctx, g, rng, profile.Playouts)
+	if err != nil {
+		return game.Point{}, err
+	}
+	move := s.chooseMove(profile).move
+	if move == passMove {
+		return game.Point{}, ai.ErrEnginePassed
+	}
+	return s.rootPos.point(move), nil
+}
+
+// CalculateScore 按模拟对局的平均归属判断死子和归属，再按对局规则计分
+// 与 game.Game.CalculateScore 不同，不要求对局已经结束，可用于形势判断
+func (e *Engine) CalculateScore(ctx context.Context, g *game.Game) (game.ScoreResult, error) {
+	s, err := e.search(ctx, g, e.newRand(), 0)
+	if err != nil {
+		return game.ScoreResult{}, err
+	}
+	pos := s.rootPos
+
+	var black, white, deadBlack, deadWhite int
+	for i := range int16(pos.points()) {
+		c := pos.color(i)
+		own := s.ownerSum[i] / float64(s.sims)
+		switch {
+		case own > 0:
+			black++
+			if c == game.White {
+				deadWhite++
+			}
+		case own < 0:
+			white++
+			if c == game.Black {
+				deadBlack++
+			}
+		}
+	}
+
+	var result game.ScoreResult
+	if g.Rules.Scoring == game.ScoringTerritory {
+		// 数目法：不算活棋本身，死子既是空也是提子
+		stones := func(c game.Player) (n int) {
+			for _, row := range pos.board.Grid {
+				for _, v := range row {
+					if v == c {
+						n++
+					}
+				}
+			}
+			return n
+		}
+		result.BlackScore = float64(black - (stones(game.Black) - deadBlack) + deadWhite + g.CapturesByB)
+		result.WhiteScore = float64(white - (stones(game.White) - deadWhite) + deadBlack + g.CapturesByW)
+	} else {
+		result.BlackScore = float64(black)
+		result.WhiteScore = float64(white)
+	}
+	result.WhiteScore += g.WhiteBonus()
+
+	switch {
+	case result.BlackScore > result.WhiteScore:
+		result.Winner = game.Black
+	case result.WhiteScore > result.BlackScore:
+		result.Winner = game.White
+	default:
+		result.Winner = game.Empty
+	}
+	return result, nil
+}
+
+// Analyze 返回当前局面的胜率、目差、候选着法和归属
+// 候选着法按访问次数排列，先验取搜索树使用的棋理先验并归一化
+// 分析总是全力搜索，与对局的 AI 难度无关
+func (e *Engine) Analyze(ctx context.Context, g *game.Game, candidates int) (*game.Analysis, error) {
+	s, err := e.search(ctx, g, e.newRand(), 0)
+	if err != nil {
+		return nil, err
+	}
+	pos := s.rootPos
+
+	children := append([]*node(nil), s.root.children...)
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].visits > children[j].visits
+	})
+	priorSum := 0.0
+	for _, c := range children {
+		priorSum += c.priorWins / c.priorVisits
+	}
+
+	analysis := &game.Analysis{
+		Winrate:   s.root.winrate(),
+		ScoreLead: s.root.scoreLead(),
+	}
+	for _, c := range children {
+		if len(analysis.Candidates) >= candidates || c.visits == 0 {
+			break
+		}
+		cand := game.Candidate{
+			Pass:      c.move == passMove,
+			Visits:    c.visits,
+			Prior:     c.priorWins / c.priorVisits / priorSum,
+			Winrate:   c.winrate(),
+			ScoreLead: c.scoreLead(),
+		}
+		if !cand.Pass {
+			cand.Point = pos.point(c.move)
+		}
+		// 主要变化沿访问次数最多的子节点延伸
+		for n := c; n != nil && n.move != passMove && (n == c || n.visits > 1); n = n.bestChild() {
+			cand.PV = append(cand.PV, pos.point(n.move))
+		}
+		analysis.Candidates = append(analysis.Candidates, cand)
+	}
+
+	size := pos.size
+	analysis.Ownership = make([][]float64, size)
+	for y := range analysis.Ownership {
+		analysis.Ownership[y] = make([]float64, size)
+		for x := range analysis.Ownership[y] {
+			analysis.Ownership[y][x] = s.ownerSum[y*size+x] / float64(s.sims)
+		}
+	}
+	return analysis, nil
+}
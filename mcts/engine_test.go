@@ -0,0 +1,181 @@
+package mcts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/ai"
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// newTestGame 在 9 路棋盘上依次下出 moves，黑棋先行
+func newTestGame(t *testing.T, moves ...game.Point) *game.Game {
+	t.Helper()
+	g, err := game.NewGameWithOptions(game.GameOptions{BoardSize: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, p := range moves {
+		if err := g.PlayMove(p); err != nil {
+			t.Fatalf("move %v: %v", p, err)
+		}
+	}
+	return g
+}
+
+func newTestEngine() *Engine {
+	return New(Config{Playouts: 2000, TimeBudget: 10 * time.Second, Seed: 1})
+}
+
+// TestEngine_GetMove_Capture 测试引擎提子救出被打吃的棋块
+func TestEngine_GetMove_Capture(t *testing.T) {
+	// 黑棋五子只剩 (4,0) 一口气，上方的白棋四子也只剩这口气，黑棋提子才能活
+	g := newTestGame(t)
+	for x := 0; x < 9; x++ {
+		g.Board.Grid[6][x] = game.Black
+	}
+	for x := 0; x < 5; x++ {
+		g.Board.Grid[1][x] = game.Black
+		g.Board.Grid[2][x] = game.White
+		if x < 4 {
+			g.Board.Grid[0][x] = game.White
+		}
+	}
+	g.Board.Grid[1][5] = game.White
+
+	move, err := newTestEngine().GetMove(context.Background(), g)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if move != (game.Point{X: 4, Y: 0}) {
+		t.Fatalf("Expected capture at (4,0), got %v", move)
+	}
+}
+
+// TestEngine_GetMove_Pass 测试没有合法着法时引擎虚手
+func TestEngine_GetMove_Pass(t *testing.T) {
+	g := newTestGame(t)
+	// 黑棋占满棋盘，只留下两个眼
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 9; x++ {
+			if (x != 1 || y != 1) && (x != 7 || y != 7) {
+				g.Board.Grid[y][x] = game.Black
+			}
+		}
+	}
+	g.NextPlayer = game.White
+	if _, err := newTestEngine().GetMove(context.Background(), g); !errors.Is(err, ai.ErrEnginePassed) {
+		t.Fatalf("Expected ai.ErrEnginePassed, got %v", err)
+	}
+}
+
+// TestEngine_Analyze 测试分析结果的候选着法和归属
+func TestEngine_Analyze(t *testing.T) {
+	g := newTestGame(t, game.Point{X: 4, Y: 4})
+	analysis, err := newTestEngine().Analyze(context.Background(), g, 3)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if analysis.Winrate < 0 || analysis.Winrate > 1 {
+		t.Fatalf("Expected winrate in [0,1], got %v", analysis.Winrate)
+	}
+	if len(analysis.Candidates) != 3 {
+		t.Fatalf("Expected 3 candidates, got %d", len(analysis.Candidates))
+	}
+	for i, c := range analysis.Candidates {
+		if i > 0 && c.Visits > analysis.Candidates[i-1].Visits {
+			t.Fatalf("Expected candidates ordered by visits, got %+v", analysis.Candidates)
+		}
+		if !c.Pass && (len(c.PV) == 0 || c.PV[0] != c.Point) {
+			t.Fatalf("Expected the PV to start with the candidate, got %+v", c)
+		}
+	}
+	if len(analysis.Ownership) != 9 || len(analysis.Ownership[0]) != 9 {
+		t.Fatalf("Expected a 9x9 ownership map, got %v", analysis.Ownership)
+	}
+	if own := analysis.Ownership[4][4]; own <= 0.5 {
+		t.Fatalf("Expected the black stone to belong to black, got %v", own)
+	}
+}
+
+// TestEngine_CalculateScore 测试按归属判断死子后计分
+func TestEngine_CalculateScore(t *testing.T) {
+	g := newTestGame(t)
+	// 黑棋占据左边五路并有两个眼，白棋在右边只有一颗孤子
+	for y := 0; y < 9; y++ {
+		for x := 0; x < 5; x++ {
+			if (x != 1 || y != 1) && (x != 1 || y != 7) {
+				g.Board.Grid[y][x] = game.Black
+			}
+		}
+	}
+	g.Board.Grid[4][7] = game.White
+	g.NextPlayer = game.White
+
+	score, err := newTestEngine().CalculateScore(context.Background(), g)
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	// 白棋孤子是死子，黑方应得到几乎整个棋盘
+	if score.Winner != game.Black || score.BlackScore < 75 || score.WhiteScore > g.WhiteBonus()+6 {
+		t.Fatalf("Expected black to own almost the whole board, got %+v", score)
+	}
+}
+
+// TestEngine_Canceled 测试 ctx 被取消时停止搜索并返回取消错误
+func TestEngine_Canceled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	engine := New(Config{Playouts: 1 << 30, TimeBudget: time.Hour, Seed: 1})
+	start := time.Now()
+	if _, err := engine.GetMove(ctx, newTestGame(t)); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if _, err := engine.Analyze(ctx, newTestGame(t), 3); !errors.Is(err, context.Canceled) {
+		t.Fatalf("Expected context.Canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("Expected the search to stop at once, took %v", elapsed)
+	}
+}
+
+// TestEngine_GetMove_Difficulty 测试入门难度经常放过提子，最强难度总是提子
+func TestEngine_GetMove_Difficulty(t *testing.T) {
+	g := newTestGame(t)
+	for x := 0; x < 9; x++ {
+		g.Board.Grid[6][x] = game.Black
+	}
+	for x := 0; x < 5; x++ {
+		g.Board.Grid[1][x] = game.Black
+		g.Board.Grid[2][x] = game.White
+		if x < 4 {
+			g.Board.Grid[0][x] = game.White
+		}
+	}
+	g.Board.Grid[1][5] = game.White
+	capture := game.Point{X: 4, Y: 0}
+
+	count := func(d game.Difficulty) int {
+		g.AIDifficulty = d
+		n := 0
+		for seed := int64(1); seed <= 20; seed++ {
+			move, err := New(Config{Playouts: 1000, Seed: seed}).GetMove(context.Background(), g)
+			if err != nil {
+				t.Fatalf("Expected no error, got %v", err)
+			}
+			if move == capture {
+				n++
+			}
+		}
+		return n
+	}
+	if n := count(game.DifficultyStrong); n != 20 {
+		t.Fatalf("Expected the strong AI to always capture, got %d/20", n)
+	}
+	if n := count(game.DifficultyBeginner); n > 15 {
+		t.Fatalf("Expected the beginner AI to miss the capture sometimes, got %d/20", n)
+	}
+}
@@ -0,0 +1,147 @@
+package mcts
+
+import "github.com/nankp236270/weiqi-go/game"
+
+// patternSources 是模拟对局中优先考虑的 3x3 棋形 (MoGo 的碰、扳、断等)，中心为落子点
+// X 和 O 是两种颜色的棋子，x 和 o 分别表示"不是 X"和"不是 O"，. 为空点，# 为棋盘外，? 为任意
+// 棋形与颜色无关，匹配时考虑所有旋转、翻转和颜色互换
+var patternSources = [][3]string{
+	{"XOX", "...", "???"}, // 扳：包住对方的扳
+	{"XO.", "...", "?.?"}, // 扳：不被断的扳
+	{"XO?", "X..", "x.?"}, // 扳：拐
+	{".O.", "X..", "..."}, // 碰或尖顶
+	{"XO?", "O.o", "?o?"}, // 断：没有保护的断点
+	{"XO?", "O.X", "???"}, // 断：被窥视的断点
+	{"?X?", "O.O", "ooo"}, // 断：冲断
+	{"OX?", "o.O", "???"}, // 断：小飞的断点
+	{"X.?", "O.?", "###"}, // 边上：追
+	{"OX?", "X.O", "###"}, // 边上：挡住断点
+	{"?X?", "x.O", "###"}, // 边上：挡住连接
+	{"?XO", "x.x", "###"}, // 边上：立
+	{"?OX", "X.O", "###"}, // 边上：断
+}
+
+// 棋形编码中每个位置的取值，每个点占 2 位
+const (
+	cellEmpty = iota
+	cellBlack
+	cellWhite
+	cellEdge
+)
+
+// patterns 按 patternKey 的编码记录哪些 3x3 棋形是好形
+var patterns = buildPatterns()
+
+// patternKey 将 i 周围 8 个点的状态编码为 16 位整数
+func (pos *position) patternKey(i int16) uint16 {
+	var key uint16
+	for k, q := range pos.around(i) {
+		var cell uint16
+		switch {
+		case q < 0:
+			cell = cellEdge
+		case pos.color(q) == game.Black:
+			cell = cellBlack
+		case pos.color(q) == game.White:
+			cell = cellWhite
+		}
+		key |= cell << (2 * k)
+	}
+	return key
+}
+
+// matchesPattern 判断在 i 落子是否形成 patternSources 中的棋形
+func (pos *position) matchesPattern(i int16) bool {
+	return patterns[pos.patternKey(i)]
+}
+
+// buildPatterns 展开所有棋形的旋转、翻转和颜色互换，生成查询表
+func buildPatterns() *[1 << 16]bool {
+	table := new([1 << 16]bool)
+	for _, src := range patternSources {
+		grid := [3][3]byte{}
+		for y, row := range src {
+			copy(grid[y][:], row)
+		}
+		for _, swap := range []bool{false, true} {
+			g := grid
+			for r := 0; r < 8; r++ {
+				expandPattern(table, g, swap)
+				g = rotatePattern(g)
+				if r == 3 {
+					g = flipPattern(g)
+				}
+			}
+		}
+	}
+	return table
+}
+
+// rotatePattern 将棋形顺时针旋转 90 度
+func rotatePattern(g [3][3]byte) [3][3]byte {
+	var r [3][3]byte
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			r[x][2-y] = g[y][x]
+		}
+	}
+	return r
+}
+
+// flipPattern 将棋形左右翻转
+func flipPattern(g [3][3]byte) [3][3]byte {
+	var r [3][3]byte
+	for y := 0; y < 3; y++ {
+		for x := 0; x < 3; x++ {
+			r[y][2-x] = g[y][x]
+		}
+	}
+	return r
+}
+
+// expandPattern 把棋形匹配的所有编码写入查询表，swap 为 true 时 X 为白棋
+func expandPattern(table *[1 << 16]bool, g [3][3]byte, swap bool) {
+	x, o := uint16(cellBlack), uint16(cellWhite)
+	if swap {
+		x, o = o, x
+	}
+	// 每个位置允许的取值
+	var allowed [8][]uint16
+	k := 0
+	for y := 0; y < 3; y++ {
+		for c := 0; c < 3; c++ {
+			if y == 1 && c == 1 {
+				continue
+			}
+			switch g[y][c] {
+			case 'X':
+				allowed[k] = []uint16{x}
+			case 'O':
+				allowed[k] = []uint16{o}
+			case '.':
+				allowed[k] = []uint16{cellEmpty}
+			case '#':
+				allowed[k] = []uint16{cellEdge}
+			case 'x':
+				allowed[k] = []uint16{o, cellEmpty, cellEdge}
+			case 'o':
+				allowed[k] = []uint16{x, cellEmpty, cellEdge}
+			default:
+				allowed[k] = []uint16{cellEmpty, cellBlack, cellWhite, cellEdge}
+			}
+			k++
+		}
+	}
+
+	var walk func(k int, key uint16)
+	walk = func(k int, key uint16) {
+		if k == 8 {
+			table[key] = true
+			return
+		}
+		for _, v := range allowed[k] {
+			walk(k+1, key|v<<(2*k))
+		}
+	}
+	walk(0, 0)
+}
@@ -0,0 +1,111 @@
+package mcts
+
+import (
+	"math/rand"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// 模拟对局的走子策略：依次尝试最后两手附近的提子或逃子、最后一手周围的好形和随机落子
+const (
+	captureProb   = 0.9 // 尝试提子或逃子的概率
+	patternProb   = 0.95
+	selfAtariSkip = 0.9 // 随机落子时放弃自紧气着法的概率
+)
+
+// playedMove 是模拟中下过的一手，用于 AMAF 统计
+type playedMove struct {
+	point  int16
+	player game.Player
+}
+
+// playout 从 pos 的局面开始按走子策略下完一盘，把下过的着法追加到 moves
+// 双方连续虚手或达到手数上限时结束
+func playout(pos *position, rng *rand.Rand, moves []playedMove) []playedMove {
+	limit := 3 * pos.points()
+	for n := 0; pos.passes < 2 && n < limit; n++ {
+		player := pos.toPlay
+		i := choosePlayoutMove(pos, rng)
+		pos.play(i)
+		if i != passMove {
+			moves = append(moves, playedMove{i, player})
+		}
+	}
+	return moves
+}
+
+// choosePlayoutMove 为轮到行棋的一方选择一手，没有可下的点时虚手
+func choosePlayoutMove(pos *position, rng *rand.Rand) int16 {
+	c := pos.toPlay
+	var buf [16]int16
+
+	if rng.Float64() < captureProb {
+		if i, ok := pick(pos, rng, atariMoves(pos, buf[:0])); ok {
+			return i
+		}
+	}
+	if pos.last != passMove {
+		if rng.Float64() < patternProb {
+			cands := buf[:0]
+			for _, q := range pos.around(pos.last) {
+				if q >= 0 && pos.color(q) == game.Empty && pos.matchesPattern(q) {
+					cands = append(cands, q)
+				}
+			}
+			if i, ok := pick(pos, rng, cands); ok {
+				return i
+			}
+		}
+	}
+
+	// 从随机位置开始找一个不填自己眼的合法落点
+	n := pos.points()
+	start := rng.Intn(n)
+	for k := 0; k < n; k++ {
+		i := int16((start + k) % n)
+		if pos.color(i) != game.Empty || pos.isEye(i, c) || !pos.legal(i) {
+			continue
+		}
+		if pos.selfAtari(i, c) && rng.Float64() < selfAtariSkip {
+			continue
+		}
+		return i
+	}
+	return passMove
+}
+
+// pick 从候选点中随机选择一个合法且不自紧气的点
+func pick(pos *position, rng *rand.Rand, cands []int16) (int16, bool) {
+	c := pos.toPlay
+	for len(cands) > 0 {
+		k := rng.Intn(len(cands))
+		i := cands[k]
+		if pos.legal(i) && !pos.selfAtari(i, c) {
+			return i, true
+		}
+		cands[k] = cands[len(cands)-1]
+		cands = cands[:len(cands)-1]
+	}
+	return passMove, false
+}
+
+// atariMoves 返回最后两手及其相邻棋块中只剩一口气的棋块的气：
+// 对方的棋块可以提，己方的棋块可以长出逃跑
+func atariMoves(pos *position, cands []int16) []int16 {
+	var points [2]game.Point
+	n := 0
+	for _, p := range [2]int16{pos.last, pos.last2} {
+		if p != passMove {
+			points[n] = pos.point(p)
+			n++
+		}
+	}
+	if n == 0 {
+		return cands
+	}
+	var buf [10]game.Point
+	for _, lib := range pos.board.AtariLiberties(buf[:0], points[:n]...) {
+		cands = append(cands, pos.index(lib))
+	}
+	return cands
+}
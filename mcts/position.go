@@ -0,0 +1,186 @@
+package mcts
+
+import "github.com/nankp236270/weiqi-go/game"
+
+// passMove 表示虚手
+const passMove int16 = -1
+
+// position 是模拟对局中的局面：game.Board 加上单劫规则的劫点、最近两手和行棋方
+// 模拟对局只按单劫规则下棋，不允许自杀，全局同形和自杀规则只在根节点按 game.LegalMoves 过滤
+// 树和 AMAF 统计用点的下标 y*size+x 表示着法
+type position struct {
+	board  *game.Board
+	size   int
+	ko     int16 // 轮到行棋的一方不能立即回提的劫点，没有时为 passMove
+	last   int16 // 最后一手，虚手或没有时为 passMove
+	last2  int16 // 倒数第二手
+	toPlay game.Player
+	passes int // 连续虚手次数
+}
+
+// newPosition 根据对局的当前局面创建模拟局面
+func newPosition(g *game.Game) *position {
+	pos := &position{
+		board:  g.Board.Clone(),
+		size:   g.Board.Size(),
+		ko:     passMove,
+		last:   passMove,
+		last2:  passMove,
+		toPlay: g.NextPlayer,
+	}
+	if g.KoPoint != nil {
+		pos.ko = pos.index(*g.KoPoint)
+	}
+	if n := len(g.Moves); n > 0 && !g.Moves[n-1].Pass {
+		pos.last = pos.index(g.Moves[n-1].Point)
+	}
+	if n := len(g.Moves); n > 1 && !g.Moves[n-2].Pass {
+		pos.last2 = pos.index(g.Moves[n-2].Point)
+	}
+	return pos
+}
+
+// copyFrom 把 o 的局面复制到 pos，两者的路数必须相同
+func (pos *position) copyFrom(o *position) {
+	pos.board.CopyFrom(o.board)
+	pos.ko, pos.last, pos.last2, pos.toPlay, pos.passes = o.ko, o.last, o.last2, o.toPlay, o.passes
+}
+
+// clone 返回局面的副本
+func (pos *position) clone() *position {
+	c := *pos
+	c.board = pos.board.Clone()
+	return &c
+}
+
+func (pos *position) point(i int16) game.Point {
+	return game.Point{X: int(i) % pos.size, Y: int(i) / pos.size}
+}
+
+func (pos *position) index(p game.Point) int16 {
+	return int16(p.Y*pos.size + p.X)
+}
+
+// points 返回棋盘上的点数
+func (pos *position) points() int {
+	return pos.size * pos.size
+}
+
+// color 返回 i 上的棋子颜色
+func (pos *position) color(i int16) game.Player {
+	return pos.board.Grid[int(i)/pos.size][int(i)%pos.size]
+}
+
+// adjacent 返回 i 上下左右在棋盘内的点，有效的是前 n 个
+func (pos *position) adjacent(i int16) (adj [4]int16, n int) {
+	x, y, size := int(i)%pos.size, int(i)/pos.size, int16(pos.size)
+	if x > 0 {
+		adj[n], n = i-1, n+1
+	}
+	if x < pos.size-1 {
+		adj[n], n = i+1, n+1
+	}
+	if y > 0 {
+		adj[n], n = i-size, n+1
+	}
+	if y < pos.size-1 {
+		adj[n], n = i+size, n+1
+	}
+	return adj, n
+}
+
+// around 返回 i 周围的 8 个点 (左上、上、右上、左、右、左下、下、右下)，棋盘外为 -1
+func (pos *position) around(i int16) [8]int16 {
+	x, y := int(i)%pos.size, int(i)/pos.size
+	at := func(x, y int) int16 {
+		if x < 0 || x >= pos.size || y < 0 || y >= pos.size {
+			return -1
+		}
+		return int16(y*pos.size + x)
+	}
+	return [8]int16{
+		at(x-1, y-1), at(x, y-1), at(x+1, y-1),
+		at(x-1, y), at(x+1, y),
+		at(x-1, y+1), at(x, y+1), at(x+1, y+1),
+	}
+}
+
+// line 返回 i 到最近边线的距离，一线为 0
+func (pos *position) line(i int16) int {
+	x, y := int(i)%pos.size, int(i)/pos.size
+	return min(x, y, pos.size-1-x, pos.size-1-y)
+}
+
+// legal 判断轮到行棋的一方能否在 i 落子 (非空点、劫和自杀)
+func (pos *position) legal(i int16) bool {
+	return i != pos.ko && pos.board.CanPlay(pos.toPlay, pos.point(i))
+}
+
+// play 由轮到行棋的一方在 i 落子或虚手，调用前需用 legal 检查
+func (pos *position) play(i int16) {
+	c := pos.toPlay
+	pos.toPlay = c.Opponent()
+	pos.last, pos.last2 = i, pos.last
+	pos.ko = passMove
+	if i == passMove {
+		pos.passes++
+		return
+	}
+	pos.passes = 0
+
+	if ko, _ := pos.board.Play(c, pos.point(i)); ko != nil {
+		pos.ko = pos.index(*ko)
+	}
+}
+
+// atari 判断 i 上的棋块是否只剩一口气
+func (pos *position) atari(i int16) bool {
+	n, _ := pos.board.Liberties(pos.point(i), 2)
+	return n == 1
+}
+
+// isEye 判断 i 是否是 c 的眼
+func (pos *position) isEye(i int16, c game.Player) bool {
+	return pos.board.IsEye(c, pos.point(i))
+}
+
+// selfAtari 判断 c 在 i 落子后己方棋块是否只剩一口气 (不提子的情况下)
+func (pos *position) selfAtari(i int16, c game.Player) bool {
+	return pos.board.SelfAtari(c, pos.point(i))
+}
+
+// score 按数子法计算黑方领先的子数 (未扣除贴目)，空点只被一方的棋子包围时属于该方
+// owner 不为空时记录每个点的归属：1 为黑方，-1 为白方，0 为公气
+func (pos *position) score(owner []int8) int {
+	lead := 0
+	for i := range int16(pos.points()) {
+		v := int8(0)
+		switch pos.color(i) {
+		case game.Black:
+			v = 1
+		case game.White:
+			v = -1
+		default:
+			black, white := false, false
+			adj, n := pos.adjacent(i)
+			for _, q := range adj[:n] {
+				switch pos.color(q) {
+				case game.Black:
+					black = true
+				case game.White:
+					white = true
+				}
+			}
+			if black && !white {
+				v = 1
+			} else if white && !black {
+				v = -1
+			}
+		}
+		lead += int(v)
+		if owner != nil {
+			owner[i] = v
+		}
+	}
+	return lead
+}
@@ -0,0 +1,110 @@
+package mcts
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// TestPosition_MatchesGame 在随机对局中对照模拟局面与对局的合法性、落子和劫
+// 简单劫规则下两者的合法着法必须完全一致；同形禁止规则下模拟局面只多出对局因同形禁止的着法
+func TestPosition_MatchesGame(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, tc := range []struct {
+		rules string
+		size  int
+	}{{"japanese", 9}, {"chinese", 9}, {"japanese", 19}, {"chinese", 19}} {
+		for round := 0; round < 5; round++ {
+			g, err := game.NewGameWithOptions(game.GameOptions{BoardSize: tc.size, Rules: tc.rules})
+			if err != nil {
+				t.Fatal(err)
+			}
+			pos := newPosition(g)
+
+			for move := 0; move < tc.size*tc.size*2 && !g.GameOver; move++ {
+				where := fmt.Sprintf("%s %dx%d round %d move %d", tc.rules, tc.size, tc.size, round, move)
+
+				// 比较每个空点的合法性，收集两边都合法的着法
+				var legal []int16
+				for i := range int16(pos.points()) {
+					if pos.color(i) != game.Empty {
+						continue
+					}
+					err := g.IsLegalMove(pos.point(i))
+					switch simulated := pos.legal(i); {
+					case simulated && err == nil:
+						legal = append(legal, i)
+					case simulated && tc.rules == "chinese" && errors.Is(err, game.ErrKoViolation):
+						// 同形禁止只在根节点检查
+					case simulated || err == nil:
+						t.Fatalf("%s: %v is legal in simulation: %v, in game: %v", where, pos.point(i), simulated, err)
+					}
+				}
+
+				// 偶尔虚手，避免连续两次虚手结束对局
+				if len(legal) == 0 || (pos.passes == 0 && rng.Intn(20) == 0) {
+					if pos.passes > 0 {
+						break
+					}
+					if err := g.PassTurn(); err != nil {
+						t.Fatal(err)
+					}
+					pos.play(passMove)
+					continue
+				}
+
+				i := legal[rng.Intn(len(legal))]
+				if err := g.PlayMove(pos.point(i)); err != nil {
+					t.Fatal(err)
+				}
+				pos.play(i)
+
+				if pos.board.Hash() != g.Board.Hash() || pos.toPlay != g.NextPlayer {
+					t.Fatalf("%s: simulated position differs from the game", where)
+				}
+				if (g.KoPoint == nil) != (pos.ko == passMove) || (g.KoPoint != nil && pos.ko != pos.index(*g.KoPoint)) {
+					t.Fatalf("%s: expected ko at %v, got %d", where, g.KoPoint, pos.ko)
+				}
+			}
+		}
+	}
+}
+
+// TestPosition_CopyFrom 测试从根局面复制后模拟不影响根局面
+func TestPosition_CopyFrom(t *testing.T) {
+	g := newTestGame(t, game.Point{X: 2, Y: 2}, game.Point{X: 6, Y: 6})
+	root := newPosition(g)
+	pos := root.clone()
+	pos.play(pos.index(game.Point{X: 4, Y: 4}))
+	if root.color(root.index(game.Point{X: 4, Y: 4})) != game.Empty || root.last != root.index(game.Point{X: 6, Y: 6}) {
+		t.Fatal("Expected the root position to be unchanged")
+	}
+
+	pos.copyFrom(root)
+	if pos.board.Hash() != root.board.Hash() || pos.toPlay != game.Black || pos.last2 != pos.index(game.Point{X: 2, Y: 2}) {
+		t.Fatal("Expected copyFrom to restore the root position")
+	}
+}
+
+// TestPatterns 测试棋形在旋转和颜色互换后都能匹配
+func TestPatterns(t *testing.T) {
+	g, _ := game.NewGameWithOptions(game.GameOptions{BoardSize: 9})
+	// 扳：黑 (3,3)、(5,3)，白 (4,3)，落子点 (4,4)
+	g.Board.Grid[3][3] = game.Black
+	g.Board.Grid[3][5] = game.Black
+	g.Board.Grid[3][4] = game.White
+	pos := newPosition(g)
+	if !pos.matchesPattern(pos.index(game.Point{X: 4, Y: 4})) {
+		t.Fatal("Expected hane pattern to match")
+	}
+	// 同一棋形在下方看是上下颠倒的
+	if !pos.matchesPattern(pos.index(game.Point{X: 4, Y: 2})) {
+		t.Fatal("Expected rotated hane pattern to match")
+	}
+	if pos.matchesPattern(pos.index(game.Point{X: 7, Y: 7})) {
+		t.Fatal("Expected an empty area not to match")
+	}
+}
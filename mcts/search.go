@@ -0,0 +1,384 @@
+package mcts
+
+import (
+	"context"
+	"math"
+	"math/rand"
+	"sort"
+	"time"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// 搜索参数
+const (
+	exploration  = 0.3  // UCT 探索项系数
+	raveEquiv    = 1000 // RAVE 的等价访问次数，越大越依赖 AMAF 统计
+	expandVisits = 8    // 非根节点访问多少次后展开
+
+	priorEven        = 10 // 先验的虚拟访问次数，胜率为一半
+	priorCapture     = 15 // 能提一子或救出一子的着法的额外虚拟胜局
+	priorCaptureMany = 30 // 能提多子或救出多子的着法的额外虚拟胜局
+	priorPattern     = 10 // 在最后一手周围形成好形的额外虚拟胜局
+	priorOpening     = 10 // 空旷处三、四线的额外虚拟胜局
+	priorPenalty     = 10 // 自紧气或空旷处一、二线的额外虚拟败局
+	priorPassBase    = 2  // 虚手的虚拟胜局，还有其他着法时不鼓励虚手
+)
+
+// node 是搜索树的节点，表示 player 下出 move 之后的局面
+// 胜局数都是对 player 而言的，和棋计半局
+type node struct {
+	move   int16
+	player game.Player
+
+	visits   int
+	wins     float64
+	scoreSum float64 // 经过该节点的模拟中黑方领先目数之和
+
+	raveVisits int
+	raveWins   float64
+
+	priorVisits float64
+	priorWins   float64
+
+	children []*node
+	expanded bool
+}
+
+// value 返回节点的胜率估计：先验、实际结果和 AMAF 统计的加权
+func (n *node) value() float64 {
+	v := float64(n.visits) + n.priorVisits
+	q := (n.wins + n.priorWins) / v
+	if n.raveVisits > 0 {
+		rv := float64(n.raveVisits)
+		beta := rv / (rv + v + v*rv/raveEquiv)
+		q = (1-beta)*q + beta*n.raveWins/rv
+	}
+	return q
+}
+
+// winrate 返回模拟中黑方的胜率，没有访问时为 0.5
+func (n *node) winrate() float64 {
+	if n.visits == 0 {
+		return 0.5
+	}
+	w := n.wins / float64(n.visits)
+	if n.player == game.White {
+		w = 1 - w
+	}
+	return w
+}
+
+// scoreLead 返回模拟中黑方平均领先的目数
+func (n *node) scoreLead() float64 {
+	if n.visits == 0 {
+		return 0
+	}
+	return n.scoreSum / float64(n.visits)
+}
+
+// bestChild 返回访问次数最多的子节点
+func (n *node) bestChild() *node {
+	var best *node
+	for _, c := range n.children {
+		if best == nil || c.visits > best.visits ||
+			c.visits == best.visits && c.value() > best.value() {
+			best = c
+		}
+	}
+	return best
+}
+
+// search 是一次搜索的状态，不能并发使用
+type search struct {
+	root      *node
+	rootPos   *position
+	rootLegal []bool // 根节点的合法着法，按对局规则 (包括超级劫) 判定
+	komi      float64
+
+	pos   *position
+	rng   *rand.Rand
+	path  []*node
+	moves []playedMove
+	first []game.Player // AMAF：每个点在本次模拟中第一个落子的一方
+	owner []int8
+
+	ownerSum []float64
+	sims     int
+}
+
+func newSearch(g *game.Game, rng *rand.Rand) *search {
+	pos := newPosition(g)
+	n := pos.points()
+	s := &search{
+		root:      &node{move: passMove, player: pos.toPlay.Opponent()},
+		rootPos:   pos,
+		rootLegal: make([]bool, n),
+		komi:      g.WhiteBonus(),
+		pos:       pos.clone(),
+		rng:       rng,
+		first:     make([]game.Player, n),
+		owner:     make([]int8, n),
+		ownerSum:  make([]float64, n),
+	}
+	for _, p := range g.LegalMoves() {
+		s.rootLegal[pos.index(p)] = true
+	}
+	return s
+}
+
+// run 一直模拟到达到模拟次数、时间用完或 ctx 被取消
+func (s *search) run(ctx context.Context, playouts int, budget time.Duration) {
+	deadline := time.Now().Add(budget)
+	for s.sims < playouts {
+		s.simulate()
+		// 每 16 次模拟检查一次时间和取消，减少系统调用
+		if s.sims%16 == 0 && (time.Now().After(deadline) || ctx.Err() != nil) {
+			break
+		}
+	}
+}
+
+// simulate 进行一次模拟：沿树选择着法，从叶节点下完一盘并回传结果
+func (s *search) simulate() {
+	pos := s.pos
+	pos.copyFrom(s.rootPos)
+	s.path = append(s.path[:0], s.root)
+	s.moves = s.moves[:0]
+
+	n := s.root
+	for pos.passes < 2 {
+		if !n.expanded {
+			if n != s.root && n.visits < expandVisits {
+				break
+			}
+			s.expand(n, pos)
+		}
+		child := s.selectChild(n)
+		pos.play(child.move)
+		if child.move != passMove {
+			s.moves = append(s.moves, playedMove{child.move, child.player})
+		}
+		s.path = append(s.path, child)
+		n = child
+	}
+	treeMoves := len(s.moves)
+	s.moves = playout(pos, s.rng, s.moves)
+
+	lead := float64(pos.score(s.owner)) - s.komi
+	winner := game.Empty
+	if lead > 0 {
+		winner = game.Black
+	} else if lead < 0 {
+		winner = game.White
+	}
+	s.backup(lead, winner, treeMoves)
+
+	for i, v := range s.owner {
+		s.ownerSum[i] += float64(v)
+	}
+	s.sims++
+}
+
+// backup 把模拟结果回传到路径上的节点，并更新各节点子节点的 AMAF 统计
+func (s *search) backup(lead float64, winner game.Player, treeMoves int) {
+	for i := range s.first {
+		s.first[i] = game.Empty
+	}
+	// 倒序填写，保留每个点最早的落子方
+	for k := len(s.moves) - 1; k >= treeMoves; k-- {
+		s.first[s.moves[k].point] = s.moves[k].player
+	}
+
+	for d := len(s.path) - 1; d >= 0; d-- {
+		n := s.path[d]
+		n.visits++
+		n.scoreSum += lead
+		n.wins += result(n.player, winner)
+
+		for _, c := range n.children {
+			if c.move != passMove && s.first[c.move] == c.player {
+				c.raveVisits++
+				c.raveWins += result(c.player, winner)
+			}
+		}
+
+		// 上一层的子节点也能看到这一层之前的着法
+		if d > 0 && n.move != passMove {
+			s.first[n.move] = n.player
+		}
+	}
+}
+
+// result 返回一局对 player 而言的得分：胜 1、负 0、和 0.5
+func result(player, winner game.Player) float64 {
+	switch winner {
+	case player:
+		return 1
+	case game.Empty:
+		return 0.5
+	}
+	return 0
+}
+
+// selectChild 按 UCT 选择子节点
+func (s *search) selectChild(n *node) *node {
+	logN := math.Log(float64(n.visits) + 1)
+	var best *node
+	bestValue := math.Inf(-1)
+	for _, c := range n.children {
+		v := float64(c.visits) + c.priorVisits
+		u := c.value() + exploration*math.Sqrt(logN/v)
+		if u > bestValue {
+			best, bestValue = c, u
+		}
+	}
+	return best
+}
+
+// expand 为节点创建所有合法着法 (不含填自己的眼) 和虚手的子节点，并按棋理设置先验
+func (s *search) expand(n *node, pos *position) {
+	n.expanded = true
+	c := pos.toPlay
+	for i := range int16(pos.points()) {
+		if n == s.root {
+			if !s.rootLegal[i] {
+				continue
+			}
+		} else if pos.color(i) != game.Empty || !pos.legal(i) {
+			continue
+		}
+		if pos.isEye(i, c) {
+			continue
+		}
+		child := &node{move: i, player: c}
+		child.priorVisits, child.priorWins = s.prior(pos, i, c)
+		n.children = append(n.children, child)
+	}
+	pass := &node{move: passMove, player: c, priorVisits: priorEven, priorWins: priorPassBase}
+	if len(n.children) == 0 {
+		pass.priorWins = priorEven / 2
+	}
+	n.children = append(n.children, pass)
+}
+
+// prior 返回在 i 落子的先验虚拟访问次数和胜局数
+func (s *search) prior(pos *position, i int16, c game.Player) (visits, wins float64) {
+	visits, wins = priorEven, priorEven/2
+
+	captured, saved := 0, 0
+	adj, k := pos.adjacent(i)
+	for _, q := range adj[:k] {
+		if pos.color(q) != game.Empty && pos.atari(q) {
+			if pos.color(q) == c {
+				saved += pos.board.ChainSize(pos.point(q))
+			} else {
+				captured += pos.board.ChainSize(pos.point(q))
+			}
+		}
+	}
+	selfAtari := pos.selfAtari(i, c)
+	if saved > 0 && !selfAtari {
+		captured += saved // 长出逃跑与提子同等对待
+	}
+	switch {
+	case captured == 1:
+		visits += priorCapture
+		wins += priorCapture
+	case captured > 1:
+		visits += priorCaptureMany
+		wins += priorCaptureMany
+	}
+
+	if pos.last != passMove {
+		for _, q := range pos.around(pos.last) {
+			if q == i {
+				if pos.matchesPattern(i) {
+					visits += priorPattern
+					wins += priorPattern
+				}
+				break
+			}
+		}
+	}
+
+	if selfAtari {
+		visits += priorPenalty
+	}
+
+	empty := true
+	for _, q := range pos.around(i) {
+		if q >= 0 && pos.color(q) != game.Empty {
+			empty = false
+			break
+		}
+	}
+	if empty {
+		switch pos.line(i) {
+		case 0, 1:
+			visits += priorPenalty
+		case 2, 3:
+			visits += priorOpening
+			wins += priorOpening
+		}
+	}
+	return visits, wins
+}
+
+// 故意失误时从访问次数排在第 2 到 mistakeRanks 位的着法中选择
+const mistakeRanks = 5
+
+// chooseMove 按难度参数从根节点的子节点中选点
+func (s *search) chooseMove(profile game.AIProfile) *node {
+	best := s.root.bestChild()
+	if best.move == passMove {
+		return best
+	}
+
+	// 候选着法按访问次数从多到少排列，不含虚手和没有访问过的着法
+	var cands []*node
+	for _, c := range s.root.children {
+		if c.move != passMove && c.visits > 0 {
+			cands = append(cands, c)
+		}
+	}
+	sort.SliceStable(cands, func(i, j int) bool {
+		return cands[i].visits > cands[j].visits
+	})
+
+	if profile.MistakeRate > 0 && len(cands) > 1 && s.rng.Float64() < profile.MistakeRate {
+		n := min(len(cands), mistakeRanks)
+		return cands[1+s.rng.Intn(n-1)]
+	}
+	if profile.Temperature > 0 {
+		weights := make([]float64, len(cands))
+		total := 0.0
+		for i, c := range cands {
+			weights[i] = math.Pow(float64(c.visits)/float64(cands[0].visits), 1/profile.Temperature)
+			total += weights[i]
+		}
+		r := s.rng.Float64() * total
+		for i, w := range weights {
+			if r < w {
+				return cands[i]
+			}
+			r -= w
+		}
+	}
+	return best
+}
+
+// randomMove 从根节点的合法着法中随机选择一个不填自己眼的点
+func (s *search) randomMove() (int16, bool) {
+	pos := s.rootPos
+	var moves []int16
+	for i, ok := range s.rootLegal {
+		if ok && !pos.isEye(int16(i), pos.toPlay) {
+			moves = append(moves, int16(i))
+		}
+	}
+	if len(moves) == 0 {
+		return passMove, false
+	}
+	return moves[s.rng.Intn(len(moves))], true
+}
@@ -0,0 +1,102 @@
+package record
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+var (
+	ErrUnknownFormat       = errors.New("unknown game record format")
+	ErrInvalidRecordFormat = errors.New("invalid game record")
+)
+
+// Format 表示棋谱文件格式
+type Format string
+
+const (
+	FormatSGF Format = "sgf" // 通用的 SGF (FF[4])
+	FormatGIB Format = "gib" // Tygem (弈城)
+	FormatNGF Format = "ngf" // WBaduk 等
+	FormatUGI Format = "ugi" // 野狐等 (UGI/UGF)
+)
+
+// ParseFormat 按名称或文件扩展名查找棋谱格式，名称为空时返回空格式 (自动识别)
+func ParseFormat(name string) (Format, error) {
+	switch strings.TrimPrefix(strings.ToLower(strings.TrimSpace(name)), ".") {
+	case "":
+		return "", nil
+	case "sgf":
+		return FormatSGF, nil
+	case "gib":
+		return FormatGIB, nil
+	case "ngf":
+		return FormatNGF, nil
+	case "ugi", "ugf":
+		return FormatUGI, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// DetectFormat 根据内容识别棋谱格式
+func DetectFormat(data []byte) (Format, error) {
+	text := bytes.TrimSpace(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")))
+	switch {
+	case bytes.HasPrefix(text, []byte("(")) && bytes.Contains(text, []byte(";")):
+		return FormatSGF, nil
+	case bytes.Contains(text, []byte(`\HS`)) || bytes.Contains(text, []byte(`\[GAME`)):
+		return FormatGIB, nil
+	case bytes.Contains(bytes.ToLower(text), []byte("[header]")):
+		return FormatUGI, nil
+	}
+
+	// NGF 没有标记，第二行是棋盘大小
+	if lines := splitLines(text); len(lines) >= ngfHeaderLines {
+		if _, err := strconv.Atoi(strings.TrimSpace(lines[1])); err == nil {
+			return FormatNGF, nil
+		}
+	}
+	return "", ErrUnknownFormat
+}
+
+// Decode 将棋谱解析为 Record，format 为空时根据内容自动识别格式
+// SGF 只取第一局棋谱的主线
+func Decode(data []byte, format Format) (*game.Record, error) {
+	if format == "" {
+		detected, err := DetectFormat(data)
+		if err != nil {
+			return nil, err
+		}
+		format = detected
+	}
+
+	switch format {
+	case FormatSGF:
+		return DecodeSGF(data)
+	case FormatGIB:
+		return DecodeGIB(data)
+	case FormatNGF:
+		return DecodeNGF(data)
+	case FormatUGI:
+		return DecodeUGI(data)
+	}
+	return nil, fmt.Errorf("%w: %q", ErrUnknownFormat, format)
+}
+
+// splitLines 按行拆分文本，兼容 \r\n 换行和 UTF-8 BOM
+func splitLines(data []byte) []string {
+	text := strings.TrimPrefix(string(data), "\ufeff")
+	text = strings.ReplaceAll(text, "\r\n", "\n")
+	return strings.Split(strings.ReplaceAll(text, "\r", "\n"), "\n")
+}
+
+// formatDate 将年月日格式化为 YYYY-MM-DD
+func formatDate(year, month, day string) string {
+	m, _ := strconv.Atoi(month)
+	d, _ := strconv.Atoi(day)
+	return fmt.Sprintf("%s-%02d-%02d", year, m, d)
+}
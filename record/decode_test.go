@@ -0,0 +1,147 @@
+package record
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+const testGIB = `\HS
+\[GAMEBLACKNAME=黑方 (5D)\]
+\[GAMEWHITENAME=白方 (6D)\]
+\[GAMEDATE=2024- 5- 1-20-30-00\]
+\[GAMEINFOMAIN=GBKIND:3,GTYPE:0,GRLT:4,ZIPSU:0,GONGJE:65\]
+\HE
+\GS
+2 1 0
+INI 0 1 0 &4
+STO 0 2 1 15 3
+STO 0 3 2 3 15
+SKI 0 4
+\GE
+`
+
+const testNGF = `Test Game
+19
+WhitePlayer   6D*
+BlackPlayer   5D*
+www.wbaduk.com
+0
+0
+6.5
+20100829 [15:08]
+0
+Black wins by 3.5
+3
+PMABBQD
+PMACWDQ
+PMADBAA
+`
+
+const testUGI = `[Header]
+Lang=1
+Hdcp=2,0.5
+Size=19
+Rule=JPN
+PlayerB=野狐黑,3d,0
+PlayerW=野狐白,4d,0
+Winner=W,T
+Date=2024/05/01,20:30
+[Data]
+QQ,W2,1,0
+CC,B1,2,0
+`
+
+// TestDecode_DetectFormat 测试根据内容自动识别棋谱格式
+func TestDecode_DetectFormat(t *testing.T) {
+	for data, want := range map[string]Format{
+		"(;GM[1]SZ[19];B[pd])":       FormatSGF,
+		"\ufeff(;GM[1]SZ[19];B[pd])": FormatSGF,
+		testGIB:                      FormatGIB,
+		testNGF:                      FormatNGF,
+		testUGI:                      FormatUGI,
+	} {
+		got, err := DetectFormat([]byte(data))
+		if err != nil || got != want {
+			t.Fatalf("Expected format %s, got %s (%v)", want, got, err)
+		}
+	}
+
+	if _, err := Decode([]byte("hello world"), ""); !errors.Is(err, ErrUnknownFormat) {
+		t.Fatalf("Expected ErrUnknownFormat, got %v", err)
+	}
+	if f, err := ParseFormat(".UGF"); err != nil || f != FormatUGI {
+		t.Fatalf("Expected ugf extension to map to UGI, got %s (%v)", f, err)
+	}
+}
+
+// TestDecodeGIB 测试解析 Tygem 棋谱
+func TestDecodeGIB(t *testing.T) {
+	rec, err := Decode([]byte(testGIB), "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rec.Info.BlackName != "黑方" || rec.Info.BlackRank != "5D" || rec.Info.WhiteRank != "6D" {
+		t.Fatalf("Unexpected players: %+v", rec.Info)
+	}
+	if rec.Info.Date != "2024-05-01" || rec.Komi != 6.5 {
+		t.Fatalf("Expected date 2024-05-01 and komi 6.5, got %s %v", rec.Info.Date, rec.Komi)
+	}
+	if rec.Result == nil || rec.Result.String() != "W+R" {
+		t.Fatalf("Expected W+R, got %v", rec.Result)
+	}
+	if len(rec.Moves) != 3 || rec.Moves[0].Point != (game.Point{X: 15, Y: 3}) || !rec.Moves[2].Pass || rec.Moves[2].Player != game.Black {
+		t.Fatalf("Unexpected moves: %+v", rec.Moves)
+	}
+	if _, err := rec.Game(); err != nil {
+		t.Fatalf("Expected GIB record to import, got %v", err)
+	}
+}
+
+// TestDecodeNGF 测试解析 NGF 棋谱
+func TestDecodeNGF(t *testing.T) {
+	rec, err := Decode([]byte(testNGF), "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rec.Info.WhiteName != "WhitePlayer" || rec.Info.BlackRank != "5D" || rec.Info.Date != "2010-08-29" {
+		t.Fatalf("Unexpected info: %+v", rec.Info)
+	}
+	if rec.Result == nil || rec.Result.String() != "B+3.5" || rec.Komi != 6.5 {
+		t.Fatalf("Expected B+3.5 with komi 6.5, got %v %v", rec.Result, rec.Komi)
+	}
+	// 'B' 表示 0，"AA" 超出棋盘为虚手
+	if len(rec.Moves) != 3 || rec.Moves[0].Point != (game.Point{X: 15, Y: 2}) || rec.Moves[1].Player != game.White || !rec.Moves[2].Pass {
+		t.Fatalf("Unexpected moves: %+v", rec.Moves)
+	}
+	if _, err := rec.Game(); err != nil {
+		t.Fatalf("Expected NGF record to import, got %v", err)
+	}
+}
+
+// TestDecodeUGI 测试解析 UGI 棋谱，让子按固定星位摆放
+func TestDecodeUGI(t *testing.T) {
+	rec, err := Decode([]byte(testUGI), "")
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rec.Handicap != 2 || rec.Komi != 0.5 || rec.Rules != "japanese" {
+		t.Fatalf("Unexpected settings: handicap %d komi %v rules %s", rec.Handicap, rec.Komi, rec.Rules)
+	}
+	if rec.Info.BlackName != "野狐黑" || rec.Info.WhiteRank != "4d" || rec.Result.String() != "W+T" {
+		t.Fatalf("Unexpected info: %+v %v", rec.Info, rec.Result)
+	}
+	// 行从下方开始计数
+	if len(rec.Moves) != 2 || rec.Moves[0].Point != (game.Point{X: 16, Y: 2}) || rec.Moves[1].Point != (game.Point{X: 2, Y: 16}) {
+		t.Fatalf("Unexpected moves: %+v", rec.Moves)
+	}
+
+	g, err := rec.Game()
+	if err != nil {
+		t.Fatalf("Expected UGI record to import, got %v", err)
+	}
+	if len(g.SetupStones) != 2 || g.Handicap != 2 || g.Board.Grid[15][3] != game.Black {
+		t.Fatalf("Expected fixed handicap stones, got handicap %d", g.Handicap)
+	}
+}
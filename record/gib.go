@@ -0,0 +1,147 @@
+package record
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// GIB 是 Tygem (弈城) 的棋谱格式，头部为 \[KEY=VALUE\] 形式的对局信息，
+// 棋谱部分每行一个指令：INI 记录让子，STO 记录落子，SKI 记录虚手
+//
+//	\HS
+//	\[GAMEBLACKNAME=name (5D)\]
+//	\[GAMEINFOMAIN=GONGJE:65,GRLT:3,ZIPSU:0,...\]
+//	\HE
+//	\GS
+//	INI 0 1 0 &4
+//	STO 0 2 1 15 3
+//	\GE
+
+var (
+	gibHeaderPattern = regexp.MustCompile(`^\\\[([A-Z0-9_]+)=(.*?)\\?\]?$`)
+	gibRankPattern   = regexp.MustCompile(`^(.*?)\s*\(([^()]*)\)\s*$`)
+	gibDatePattern   = regexp.MustCompile(`(\d{4})\D{1,3}(\d{1,2})\D{1,3}(\d{1,2})`)
+)
+
+// DecodeGIB 解析 Tygem 的 GIB 棋谱
+func DecodeGIB(data []byte) (*game.Record, error) {
+	rec := &game.Record{Size: game.BoardSize}
+	header := map[string]string{}
+
+	for _, line := range splitLines(data) {
+		if m := gibHeaderPattern.FindStringSubmatch(line); m != nil {
+			header[m[1]] = strings.TrimSpace(m[2])
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) == 0 {
+			continue
+		}
+		switch fields[0] {
+		case "INI":
+			// INI 0 1 <让子数> ...
+			if len(fields) >= 4 {
+				if handicap, err := strconv.Atoi(fields[3]); err == nil && handicap >= game.MinHandicap {
+					rec.Handicap = handicap
+				}
+			}
+		case "STO":
+			// STO 0 <手数> <颜色 1 黑 2 白> <列> <行>
+			if len(fields) < 6 {
+				return nil, fmt.Errorf("%w: bad GIB move %q", ErrInvalidRecordFormat, line)
+			}
+			player, err := gibPlayer(fields[3])
+			if err != nil {
+				return nil, err
+			}
+			x, errX := strconv.Atoi(fields[4])
+			y, errY := strconv.Atoi(fields[5])
+			if errX != nil || errY != nil {
+				return nil, fmt.Errorf("%w: bad GIB move %q", ErrInvalidRecordFormat, line)
+			}
+			rec.Moves = append(rec.Moves, game.Move{Player: player, Point: game.Point{X: x, Y: y}})
+		case "SKI":
+			// SKI 0 <手数>，虚手方为上一手的对手
+			player := game.Black
+			if n := len(rec.Moves); n > 0 {
+				player = rec.Moves[n-1].Player.Opponent()
+			} else if rec.Handicap > 0 {
+				player = game.White
+			}
+			rec.Moves = append(rec.Moves, game.Move{Player: player, Pass: true})
+		}
+	}
+
+	if len(header) == 0 {
+		return nil, fmt.Errorf("%w: missing GIB header", ErrInvalidRecordFormat)
+	}
+
+	rec.Info.BlackName, rec.Info.BlackRank = splitGIBName(header["GAMEBLACKNAME"])
+	rec.Info.WhiteName, rec.Info.WhiteRank = splitGIBName(header["GAMEWHITENAME"])
+	rec.Info.Event = header["GAMENAME"]
+	rec.Info.Place = header["GAMEPLACE"]
+	if m := gibDatePattern.FindStringSubmatch(header["GAMEDATE"]); m != nil {
+		rec.Info.Date = formatDate(m[1], m[2], m[3])
+	}
+
+	info := parseGIBInfo(header["GAMEINFOMAIN"])
+	if komi, err := strconv.ParseFloat(info["GONGJE"], 64); err == nil {
+		rec.Komi = komi / 10
+	}
+	rec.Result = gibResult(info["GRLT"], info["ZIPSU"])
+	return rec, nil
+}
+
+// parseGIBInfo 解析 GAMEINFOMAIN 中逗号分隔的 KEY:VALUE 列表
+func parseGIBInfo(s string) map[string]string {
+	info := map[string]string{}
+	for _, item := range strings.Split(s, ",") {
+		if key, value, ok := strings.Cut(item, ":"); ok {
+			info[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return info
+}
+
+// gibResult 将 GRLT (结果类型) 和 ZIPSU (胜负目数 x10) 转换为对局结果
+func gibResult(grlt, zipsu string) *game.Result {
+	margin, _ := strconv.ParseFloat(zipsu, 64)
+	switch grlt {
+	case "0":
+		return &game.Result{Winner: game.Black, Margin: margin / 10, Reason: game.ReasonScore}
+	case "1":
+		return &game.Result{Winner: game.White, Margin: margin / 10, Reason: game.ReasonScore}
+	case "3":
+		return &game.Result{Winner: game.Black, Reason: game.ReasonResign}
+	case "4":
+		return &game.Result{Winner: game.White, Reason: game.ReasonResign}
+	case "7":
+		return &game.Result{Winner: game.Black, Reason: game.ReasonTimeout}
+	case "8":
+		return &game.Result{Winner: game.White, Reason: game.ReasonTimeout}
+	}
+	return nil
+}
+
+func gibPlayer(color string) (game.Player, error) {
+	switch color {
+	case "1":
+		return game.Black, nil
+	case "2":
+		return game.White, nil
+	}
+	return game.Empty, fmt.Errorf("%w: bad GIB color %q", ErrInvalidRecordFormat, color)
+}
+
+// splitGIBName 将 "name (5D)" 拆分为姓名和段位
+func splitGIBName(s string) (name, rank string) {
+	if m := gibRankPattern.FindStringSubmatch(s); m != nil {
+		return m[1], m[2]
+	}
+	return s, ""
+}
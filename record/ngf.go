@@ -0,0 +1,114 @@
+package record
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// NGF 是 WBaduk 等平台使用的棋谱格式，前 12 行为固定顺序的对局信息：
+//
+//	1 对局名称      2 棋盘大小      3 白方 (姓名 段位)  4 黑方 (姓名 段位)
+//	5 网站          6 让子数        7 (未使用)          8 贴目
+//	9 日期 (20100829 [15:08])      10 (未使用)          11 结果  12 手数
+//
+// 之后每手一行，如 "PMABBQD"：第 3-4 位为手数，第 5 位为颜色，
+// 第 6、7 位为列和行，'B' 表示 0，超出棋盘表示虚手
+var ngfNumberPattern = regexp.MustCompile(`[0-9]+(\.[0-9]+)?`)
+
+// ngfHeaderLines 是 NGF 头部的行数
+const ngfHeaderLines = 12
+
+// DecodeNGF 解析 NGF 棋谱
+func DecodeNGF(data []byte) (*game.Record, error) {
+	lines := splitLines(data)
+	if len(lines) < ngfHeaderLines {
+		return nil, fmt.Errorf("%w: NGF header too short", ErrInvalidRecordFormat)
+	}
+
+	size, err := strconv.Atoi(strings.TrimSpace(lines[1]))
+	if err != nil {
+		return nil, fmt.Errorf("%w: bad NGF board size %q", ErrInvalidRecordFormat, lines[1])
+	}
+	rec := &game.Record{Size: size}
+	rec.Info.Event = strings.TrimSpace(lines[0])
+	rec.Info.WhiteName, rec.Info.WhiteRank = splitNGFPlayer(lines[2])
+	rec.Info.BlackName, rec.Info.BlackRank = splitNGFPlayer(lines[3])
+	rec.Info.Place = strings.TrimSpace(lines[4])
+	if handicap, err := strconv.Atoi(strings.TrimSpace(lines[5])); err == nil && handicap >= game.MinHandicap {
+		rec.Handicap = handicap
+	}
+	if komi, err := strconv.ParseFloat(strings.TrimSpace(lines[7]), 64); err == nil {
+		rec.Komi = komi
+	}
+	if date := strings.TrimSpace(lines[8]); len(date) >= 8 {
+		rec.Info.Date = formatDate(date[0:4], date[4:6], date[6:8])
+	}
+	rec.Result = ngfResult(lines[10])
+
+	for _, line := range lines[ngfHeaderLines:] {
+		line = strings.ToUpper(strings.TrimSpace(line))
+		if len(line) < 7 || !strings.HasPrefix(line, "PM") {
+			continue
+		}
+		var player game.Player
+		switch line[4] {
+		case 'B':
+			player = game.Black
+		case 'W':
+			player = game.White
+		default:
+			return nil, fmt.Errorf("%w: bad NGF move %q", ErrInvalidRecordFormat, line)
+		}
+
+		x, y := int(line[5])-'B', int(line[6])-'B'
+		if x < 0 || y < 0 || x >= size || y >= size {
+			rec.Moves = append(rec.Moves, game.Move{Player: player, Pass: true})
+			continue
+		}
+		rec.Moves = append(rec.Moves, game.Move{Player: player, Point: game.Point{X: x, Y: y}})
+	}
+	return rec, nil
+}
+
+// splitNGFPlayer 将 "name   5D*" 拆分为姓名和段位
+func splitNGFPlayer(s string) (name, rank string) {
+	fields := strings.Fields(s)
+	switch len(fields) {
+	case 0:
+		return "", ""
+	case 1:
+		return fields[0], ""
+	}
+	return strings.Join(fields[:len(fields)-1], " "), strings.TrimRight(fields[len(fields)-1], "*")
+}
+
+// ngfResult 解析如 "White wins by resignation"、"Black wins by 3.5" 的结果描述
+func ngfResult(s string) *game.Result {
+	s = strings.ToLower(s)
+	r := &game.Result{}
+	switch {
+	case strings.Contains(s, "white win"):
+		r.Winner = game.White
+	case strings.Contains(s, "black win"):
+		r.Winner = game.Black
+	default:
+		return nil
+	}
+
+	switch {
+	case strings.Contains(s, "resign"):
+		r.Reason = game.ReasonResign
+	case strings.Contains(s, "time"):
+		r.Reason = game.ReasonTimeout
+	default:
+		r.Reason = game.ReasonScore
+		if m := ngfNumberPattern.FindString(s); m != "" {
+			r.Margin, _ = strconv.ParseFloat(m, 64)
+		}
+	}
+	return r
+}
@@ -0,0 +1,340 @@
+// Package record 负责外部棋谱格式 (SGF 等) 与 game.Record 之间的转换
+package record
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+var ErrInvalidSGF = errors.New("invalid SGF")
+
+// Property 是 SGF 节点上的一个属性，如 B[pd]、AB[dd][pp]
+type Property struct {
+	ID     string
+	Values []string
+}
+
+// Node 是 SGF 棋谱树中的一个节点
+// 第一个子节点是主线，其余子节点是变化图
+type Node struct {
+	Properties []Property
+	Children   []*Node
+}
+
+// Get 返回属性的第一个值，属性不存在时返回空字符串
+func (n *Node) Get(id string) string {
+	if values := n.Values(id); len(values) > 0 {
+		return values[0]
+	}
+	return ""
+}
+
+// Has 判断节点是否有指定属性
+func (n *Node) Has(id string) bool {
+	for _, p := range n.Properties {
+		if p.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// Values 返回属性的所有值
+func (n *Node) Values(id string) []string {
+	for _, p := range n.Properties {
+		if p.ID == id {
+			return p.Values
+		}
+	}
+	return nil
+}
+
+// Set 设置属性的值，已有的同名属性被替换
+func (n *Node) Set(id string, values ...string) {
+	for i, p := range n.Properties {
+		if p.ID == id {
+			n.Properties[i].Values = values
+			return
+		}
+	}
+	n.Properties = append(n.Properties, Property{ID: id, Values: values})
+}
+
+// MainLine 返回从该节点开始沿第一个子节点走到底的所有节点
+func (n *Node) MainLine() []*Node {
+	var line []*Node
+	for node := n; node != nil; {
+		line = append(line, node)
+		if len(node.Children) == 0 {
+			break
+		}
+		node = node.Children[0]
+	}
+	return line
+}
+
+// ParseSGF 解析 SGF 文本，返回其中每一局棋谱树的根节点
+func ParseSGF(data []byte) ([]*Node, error) {
+	p := &sgfParser{data: bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))}
+	var trees []*Node
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			break
+		}
+		if p.data[p.pos] != '(' {
+			if len(trees) > 0 {
+				break // 忽略棋谱之后的多余内容
+			}
+			return nil, p.errorf("expected '('")
+		}
+		root, err := p.parseTree()
+		if err != nil {
+			return nil, err
+		}
+		trees = append(trees, root)
+	}
+	if len(trees) == 0 {
+		return nil, fmt.Errorf("%w: no game tree", ErrInvalidSGF)
+	}
+	return trees, nil
+}
+
+// sgfParser 是递归下降的 SGF 解析器
+type sgfParser struct {
+	data []byte
+	pos  int
+}
+
+func (p *sgfParser) errorf(format string, args ...any) error {
+	return fmt.Errorf("%w at offset %d: %s", ErrInvalidSGF, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *sgfParser) skipSpace() {
+	for p.pos < len(p.data) && strings.IndexByte(" \t\r\n", p.data[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+// parseTree 解析 "(" 节点序列 子树* ")"，返回序列的第一个节点
+func (p *sgfParser) parseTree() (*Node, error) {
+	p.pos++ // '('
+	var first, last *Node
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return nil, p.errorf("unexpected end of input")
+		}
+		switch p.data[p.pos] {
+		case ';':
+			node, err := p.parseNode()
+			if err != nil {
+				return nil, err
+			}
+			if first == nil {
+				first = node
+			} else {
+				last.Children = append(last.Children, node)
+			}
+			last = node
+		case '(':
+			if first == nil {
+				return nil, p.errorf("variation before any node")
+			}
+			child, err := p.parseTree()
+			if err != nil {
+				return nil, err
+			}
+			last.Children = append(last.Children, child)
+		case ')':
+			if first == nil {
+				return nil, p.errorf("empty game tree")
+			}
+			p.pos++
+			return first, nil
+		default:
+			return nil, p.errorf("unexpected character %q", p.data[p.pos])
+		}
+	}
+}
+
+// parseNode 解析 ";" 后的属性列表
+func (p *sgfParser) parseNode() (*Node, error) {
+	p.pos++ // ';'
+	node := &Node{}
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return node, nil
+		}
+		c := p.data[p.pos]
+		if !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z') {
+			return node, nil
+		}
+
+		// 旧版 SGF 的属性名中可能夹有小写字母 (如 AddBlack)，只保留大写字母
+		var id strings.Builder
+		for p.pos < len(p.data) {
+			c := p.data[p.pos]
+			if c >= 'A' && c <= 'Z' {
+				id.WriteByte(c)
+			} else if !(c >= 'a' && c <= 'z') {
+				break
+			}
+			p.pos++
+		}
+
+		var values []string
+		for {
+			p.skipSpace()
+			if p.pos >= len(p.data) || p.data[p.pos] != '[' {
+				break
+			}
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		if len(values) == 0 {
+			return nil, p.errorf("property %s has no value", id.String())
+		}
+		if id.Len() == 0 {
+			continue
+		}
+		// 同一属性重复出现时合并取值
+		if existing := node.Values(id.String()); existing != nil {
+			values = append(existing, values...)
+		}
+		node.Set(id.String(), values...)
+	}
+}
+
+// parseValue 解析 "[...]" 中的属性值，处理转义和软换行
+func (p *sgfParser) parseValue() (string, error) {
+	p.pos++ // '['
+	var sb strings.Builder
+	for p.pos < len(p.data) {
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case ']':
+			return sb.String(), nil
+		case '\\':
+			if p.pos >= len(p.data) {
+				return "", p.errorf("unterminated escape")
+			}
+			next := p.data[p.pos]
+			p.pos++
+			// 反斜杠后的换行是软换行，直接去掉
+			if next == '\r' || next == '\n' {
+				if p.pos < len(p.data) && (p.data[p.pos] == '\r' || p.data[p.pos] == '\n') && p.data[p.pos] != next {
+					p.pos++
+				}
+				continue
+			}
+			sb.WriteByte(next)
+		default:
+			sb.WriteByte(c)
+		}
+	}
+	return "", p.errorf("unterminated property value")
+}
+
+// SGF 将以该节点为根的棋谱树写成 SGF 文本
+func (n *Node) SGF() string {
+	var sb strings.Builder
+	writeTree(&sb, n)
+	sb.WriteByte('\n')
+	return sb.String()
+}
+
+// writeTree 写出 "(" 节点序列 变化图* ")"，主线上每 10 手换一行
+func writeTree(sb *strings.Builder, n *Node) {
+	sb.WriteByte('(')
+	count := 0
+	for node := n; ; {
+		if count > 0 && (count == 1 || count%10 == 1) {
+			sb.WriteByte('\n')
+		}
+		writeNode(sb, node)
+		count++
+		if len(node.Children) != 1 {
+			for _, child := range node.Children {
+				sb.WriteByte('\n')
+				writeTree(sb, child)
+			}
+			break
+		}
+		node = node.Children[0]
+	}
+	sb.WriteByte(')')
+}
+
+func writeNode(sb *strings.Builder, n *Node) {
+	sb.WriteByte(';')
+	for _, p := range n.Properties {
+		sb.WriteString(p.ID)
+		for _, v := range p.Values {
+			sb.WriteByte('[')
+			sb.WriteString(escapeValue(v))
+			sb.WriteByte(']')
+		}
+	}
+}
+
+// escapeValue 转义属性值中的 "]" 和 "\"
+func escapeValue(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `]`, `\]`).Replace(v)
+}
+
+// decodePoint 将 SGF 坐标 (如 "pd") 转换为棋盘坐标
+// 空值以及 19 路以下棋盘上的 "tt" 表示虚手
+func decodePoint(v string, size int) (p game.Point, pass bool, err error) {
+	if v == "" || (v == "tt" && size <= 19) {
+		return game.Point{}, true, nil
+	}
+	if len(v) != 2 {
+		return game.Point{}, false, fmt.Errorf("%w: bad point %q", ErrInvalidSGF, v)
+	}
+	x, y := int(v[0]-'a'), int(v[1]-'a')
+	if x < 0 || y < 0 || x >= size || y >= size {
+		return game.Point{}, false, fmt.Errorf("%w: point %q out of board", ErrInvalidSGF, v)
+	}
+	return game.Point{X: x, Y: y}, false, nil
+}
+
+// decodePointList 解析点列表属性，支持 "aa:cc" 形式的压缩矩形
+func decodePointList(values []string, size int) ([]game.Point, error) {
+	var points []game.Point
+	for _, v := range values {
+		from, to, compressed := strings.Cut(v, ":")
+		a, pass, err := decodePoint(from, size)
+		if err != nil || pass {
+			return nil, fmt.Errorf("%w: bad point list value %q", ErrInvalidSGF, v)
+		}
+		if !compressed {
+			points = append(points, a)
+			continue
+		}
+		b, pass, err := decodePoint(to, size)
+		if err != nil || pass {
+			return nil, fmt.Errorf("%w: bad point list value %q", ErrInvalidSGF, v)
+		}
+		for y := min(a.Y, b.Y); y <= max(a.Y, b.Y); y++ {
+			for x := min(a.X, b.X); x <= max(a.X, b.X); x++ {
+				points = append(points, game.Point{X: x, Y: y})
+			}
+		}
+	}
+	return points, nil
+}
+
+// encodePoint 将棋盘坐标转换为 SGF 坐标
+func encodePoint(p game.Point) string {
+	return string([]byte{byte('a' + p.X), byte('a' + p.Y)})
+}
@@ -0,0 +1,286 @@
+package record
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// sgfRuleNames 是规则名称在 SGF RU 属性中的写法
+var sgfRuleNames = map[string]string{
+	"chinese":      "Chinese",
+	"japanese":     "Japanese",
+	"aga":          "AGA",
+	"new_zealand":  "NZ",
+	"tromp_taylor": "Tromp-Taylor",
+}
+
+// DecodeSGF 解析 SGF 文本，返回第一局棋谱主线的 Record
+func DecodeSGF(data []byte) (*game.Record, error) {
+	trees, err := ParseSGF(data)
+	if err != nil {
+		return nil, err
+	}
+	return FromSGF(trees[0])
+}
+
+// FromSGF 将 SGF 棋谱树的主线转换为 Record，变化图被忽略
+// 摆子 (AB/AW/PL) 只能出现在第一手之前
+func FromSGF(root *Node) (*game.Record, error) {
+	if gm := root.Get("GM"); gm != "" && gm != "1" {
+		return nil, fmt.Errorf("%w: not a go game (GM[%s])", ErrInvalidSGF, gm)
+	}
+
+	size, err := sgfBoardSize(root)
+	if err != nil {
+		return nil, err
+	}
+	rec := &game.Record{Size: size}
+	if km := root.Get("KM"); km != "" {
+		komi, err := strconv.ParseFloat(strings.TrimSpace(km), 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: bad komi %q", ErrInvalidSGF, km)
+		}
+		rec.Komi = komi
+	}
+	if ha := root.Get("HA"); ha != "" {
+		handicap, err := strconv.Atoi(strings.TrimSpace(ha))
+		if err != nil || handicap < 0 {
+			return nil, fmt.Errorf("%w: bad handicap %q", ErrInvalidSGF, ha)
+		}
+		if handicap >= game.MinHandicap {
+			rec.Handicap = handicap
+		}
+	}
+	rec.Rules = strings.ReplaceAll(strings.ToLower(strings.TrimSpace(root.Get("RU"))), " ", "_")
+	rec.TimeControl = decodeTime(root.Get("TM"), root.Get("OT"))
+	if re := root.Get("RE"); re != "" {
+		rec.Result, _ = game.ParseResult(re) // "?" 等无法识别的结果视为没有结果
+	}
+
+	rec.Info = game.RecordInfo{
+		BlackName: root.Get("PB"),
+		BlackRank: root.Get("BR"),
+		WhiteName: root.Get("PW"),
+		WhiteRank: root.Get("WR"),
+		Date:      root.Get("DT"),
+		Event:     root.Get("EV"),
+		Place:     root.Get("PC"),
+		Comment:   root.Get("GC"),
+	}
+	if rec.Info.Comment == "" {
+		rec.Info.Comment = root.Get("C")
+	}
+
+	for _, node := range root.MainLine() {
+		if err := decodeSGFNode(rec, node, node == root); err != nil {
+			return nil, err
+		}
+	}
+	return rec, nil
+}
+
+// sgfBoardSize 读取根节点的 SZ 属性，只支持正方形棋盘
+func sgfBoardSize(root *Node) (int, error) {
+	sz := root.Get("SZ")
+	if sz == "" {
+		return game.BoardSize, nil
+	}
+	cols, rows, rect := strings.Cut(sz, ":")
+	size, err := strconv.Atoi(strings.TrimSpace(cols))
+	if err != nil || (rect && strings.TrimSpace(rows) != strings.TrimSpace(cols)) {
+		return 0, fmt.Errorf("%w: unsupported board size %q", ErrInvalidSGF, sz)
+	}
+	return size, nil
+}
+
+// decodeSGFNode 将主线上的一个节点 (摆子或一手棋) 追加到 Record
+func decodeSGFNode(rec *game.Record, node *Node, isRoot bool) error {
+	if node.Has("AB") || node.Has("AW") || node.Has("PL") || node.Has("AE") {
+		if len(rec.Moves) > 0 || node.Has("AE") {
+			return fmt.Errorf("%w: setup after the first move is not supported", ErrInvalidSGF)
+		}
+		black, err := decodePointList(node.Values("AB"), rec.Size)
+		if err != nil {
+			return err
+		}
+		white, err := decodePointList(node.Values("AW"), rec.Size)
+		if err != nil {
+			return err
+		}
+		rec.Setup.Black = append(rec.Setup.Black, black...)
+		rec.Setup.White = append(rec.Setup.White, white...)
+		switch strings.ToUpper(node.Get("PL")) {
+		case "B":
+			rec.Setup.NextPlayer = game.Black
+		case "W":
+			rec.Setup.NextPlayer = game.White
+		}
+	}
+
+	var player game.Player
+	var value string
+	switch {
+	case node.Has("B") && node.Has("W"):
+		return fmt.Errorf("%w: node has both B and W", ErrInvalidSGF)
+	case node.Has("B"):
+		player, value = game.Black, node.Get("B")
+	case node.Has("W"):
+		player, value = game.White, node.Get("W")
+	default:
+		return nil
+	}
+
+	p, pass, err := decodePoint(value, rec.Size)
+	if err != nil {
+		return err
+	}
+	m := game.Move{Player: player, Pass: pass, Point: p}
+	if !isRoot {
+		m.Comment = node.Get("C")
+	}
+	rec.Moves = append(rec.Moves, m)
+	return nil
+}
+
+// EncodeSGF 将 Record 写成 SGF (FF[4]) 文本
+func EncodeSGF(rec *game.Record) []byte {
+	return []byte(ToSGF(rec).SGF())
+}
+
+// ToSGF 将 Record 转换为只有主线的 SGF 棋谱树
+func ToSGF(rec *game.Record) *Node {
+	size := rec.Size
+	if size == 0 {
+		size = game.BoardSize
+	}
+
+	root := &Node{}
+	root.Set("FF", "4")
+	root.Set("GM", "1")
+	root.Set("CA", "UTF-8")
+	root.Set("AP", "weiqi-go")
+	root.Set("SZ", strconv.Itoa(size))
+	root.Set("KM", strconv.FormatFloat(rec.Komi, 'f', -1, 64))
+	if ru, ok := sgfRuleNames[rec.Rules]; ok {
+		root.Set("RU", ru)
+	} else if rec.Rules != "" {
+		root.Set("RU", rec.Rules)
+	}
+	if rec.Handicap > 0 {
+		root.Set("HA", strconv.Itoa(rec.Handicap))
+	}
+
+	info := rec.Info
+	for _, prop := range []struct{ id, value string }{
+		{"PB", info.BlackName}, {"BR", info.BlackRank},
+		{"PW", info.WhiteName}, {"WR", info.WhiteRank},
+		{"DT", info.Date}, {"EV", info.Event}, {"PC", info.Place},
+	} {
+		if prop.value != "" {
+			root.Set(prop.id, prop.value)
+		}
+	}
+	if rec.Result != nil {
+		root.Set("RE", rec.Result.String())
+	}
+	if tm, ot := encodeTime(rec.TimeControl); tm != "" {
+		root.Set("TM", tm)
+		if ot != "" {
+			root.Set("OT", ot)
+		}
+	}
+	if info.Comment != "" {
+		root.Set("GC", info.Comment)
+	}
+
+	if len(rec.Setup.Black) > 0 {
+		root.Set("AB", encodePoints(rec.Setup.Black)...)
+	}
+	if len(rec.Setup.White) > 0 {
+		root.Set("AW", encodePoints(rec.Setup.White)...)
+	}
+	switch rec.Setup.NextPlayer {
+	case game.Black:
+		root.Set("PL", "B")
+	case game.White:
+		root.Set("PL", "W")
+	}
+
+	parent := root
+	for _, m := range rec.Moves {
+		node := &Node{}
+		id := "B"
+		if m.Player == game.White {
+			id = "W"
+		}
+		if m.Pass {
+			node.Set(id, "")
+		} else {
+			node.Set(id, encodePoint(m.Point))
+		}
+		if m.Comment != "" {
+			node.Set("C", m.Comment)
+		}
+		parent.Children = []*Node{node}
+		parent = node
+	}
+	return root
+}
+
+func encodePoints(points []game.Point) []string {
+	values := make([]string, len(points))
+	for i, p := range points {
+		values[i] = encodePoint(p)
+	}
+	return values
+}
+
+// encodeTime 将计时设置写成 SGF 的 TM (基本时间，秒) 和 OT (读秒方式) 属性
+func encodeTime(tc *game.TimeControl) (tm, ot string) {
+	if tc == nil || tc.Type == "" {
+		return "", ""
+	}
+	tm = strconv.FormatInt(tc.MainTime, 10)
+	switch tc.Type {
+	case game.TimeByoYomi:
+		ot = fmt.Sprintf("%dx%d byo-yomi", tc.Periods, tc.PeriodTime)
+	case game.TimeCanadian:
+		ot = fmt.Sprintf("%d/%d Canadian", tc.PeriodStones, tc.PeriodTime)
+	case game.TimeFischer:
+		ot = fmt.Sprintf("%d fischer", tc.Increment)
+	}
+	return tm, ot
+}
+
+// decodeTime 解析 TM 和 OT 属性，无法识别读秒方式时按包干制处理
+func decodeTime(tm, ot string) *game.TimeControl {
+	mainTime, err := strconv.ParseFloat(strings.TrimSpace(tm), 64)
+	if err != nil || mainTime < 0 {
+		return nil
+	}
+	tc := game.TimeControl{Type: game.TimeAbsolute, MainTime: int64(mainTime)}
+
+	ot = strings.ToLower(strings.TrimSpace(ot))
+	var a, b int64
+	switch {
+	case strings.HasSuffix(ot, "byo-yomi"):
+		if _, err := fmt.Sscanf(ot, "%dx%d", &a, &b); err == nil {
+			tc.Type, tc.Periods, tc.PeriodTime = game.TimeByoYomi, int(a), b
+		}
+	case strings.HasSuffix(ot, "canadian"):
+		if _, err := fmt.Sscanf(ot, "%d/%d", &a, &b); err == nil {
+			tc.Type, tc.PeriodStones, tc.PeriodTime = game.TimeCanadian, int(a), b
+		}
+	case strings.HasSuffix(ot, "fischer"):
+		if _, err := fmt.Sscanf(ot, "%d", &a); err == nil {
+			tc.Type, tc.Increment = game.TimeFischer, a
+		}
+	}
+	if tc.Validate() != nil {
+		return nil
+	}
+	return &tc
+}
@@ -0,0 +1,163 @@
+package record
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// TestParseSGF_Variations 测试解析带变化图的棋谱树
+func TestParseSGF_Variations(t *testing.T) {
+	data := `(;GM[1]FF[4]SZ[9]C[root \] comment]
+;B[ee];W[gc]
+(;B[cg]C[main line];W[gg])
+(;B[gg]C[soft\
+break]))`
+
+	trees, err := ParseSGF([]byte(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	root := trees[0]
+	if root.Get("C") != "root ] comment" {
+		t.Fatalf("Expected escaped comment, got %q", root.Get("C"))
+	}
+
+	line := root.MainLine()
+	if len(line) != 5 || line[3].Get("C") != "main line" {
+		t.Fatalf("Expected main line of 5 nodes, got %d", len(line))
+	}
+	branch := line[2]
+	if len(branch.Children) != 2 {
+		t.Fatalf("Expected 2 variations after W[gc], got %d", len(branch.Children))
+	}
+	if got := branch.Children[1].Get("C"); got != "softbreak" {
+		t.Fatalf("Expected soft line break to be removed, got %q", got)
+	}
+
+	// 写出后再解析，树的结构和属性保持不变
+	again, err := ParseSGF([]byte(root.SGF()))
+	if err != nil {
+		t.Fatalf("Expected written SGF to parse, got %v", err)
+	}
+	if again[0].SGF() != root.SGF() {
+		t.Fatalf("Expected stable round trip, got:\n%s\n%s", root.SGF(), again[0].SGF())
+	}
+}
+
+// TestParseSGF_Invalid 测试非法的 SGF
+func TestParseSGF_Invalid(t *testing.T) {
+	for _, data := range []string{"", "hello", "(;B[aa]", "(;B)", "()"} {
+		if _, err := ParseSGF([]byte(data)); !errors.Is(err, ErrInvalidSGF) {
+			t.Fatalf("Expected ErrInvalidSGF for %q, got %v", data, err)
+		}
+	}
+}
+
+// TestDecodeSGF 测试将 SGF 主线转换为棋谱并导入对局
+func TestDecodeSGF(t *testing.T) {
+	data := `(;FF[4]GM[1]SZ[19]KM[6.5]RU[Japanese]HA[2]PB[Shusaku]BR[4d]PW[Gennan]WR[8d]
+RE[B+2]DT[1846-09-11]TM[600]OT[3x30 byo-yomi]AB[pd][dp]
+;W[qp]C[first move];B[tt];W[dd](;B[pq])(;B[oq]))`
+
+	rec, err := DecodeSGF([]byte(data))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if rec.Size != 19 || rec.Komi != 6.5 || rec.Rules != "japanese" || rec.Handicap != 2 {
+		t.Fatalf("Unexpected root properties: %+v", rec)
+	}
+	if rec.Info.BlackName != "Shusaku" || rec.Info.WhiteRank != "8d" || rec.Info.Date != "1846-09-11" {
+		t.Fatalf("Unexpected info: %+v", rec.Info)
+	}
+	if rec.Result == nil || rec.Result.Winner != game.Black || rec.Result.Margin != 2 {
+		t.Fatalf("Expected B+2, got %v", rec.Result)
+	}
+	if rec.TimeControl == nil || rec.TimeControl.Type != game.TimeByoYomi || rec.TimeControl.Periods != 3 {
+		t.Fatalf("Expected byo-yomi time control, got %+v", rec.TimeControl)
+	}
+	if len(rec.Moves) != 4 || !rec.Moves[1].Pass || rec.Moves[0].Comment != "first move" {
+		t.Fatalf("Unexpected moves: %+v", rec.Moves)
+	}
+	if rec.Moves[3].Point != (game.Point{X: 15, Y: 16}) {
+		t.Fatalf("Expected main line B[pq], got %v", rec.Moves[3].Point)
+	}
+
+	g, err := rec.Game()
+	if err != nil {
+		t.Fatalf("Expected record to import, got %v", err)
+	}
+	if g.Status != game.GameStatusFinished || g.Handicap != 2 || len(g.Moves) != 4 {
+		t.Fatalf("Unexpected imported game: status %s, handicap %d, %d moves", g.Status, g.Handicap, len(g.Moves))
+	}
+	if g.Board.Grid[3][15] != game.Black || g.Board.Grid[16][15] != game.Black {
+		t.Fatal("Expected handicap stone and last move on the board")
+	}
+}
+
+// TestEncodeSGF 测试对局导出为 SGF 后可以重新导入
+func TestEncodeSGF(t *testing.T) {
+	g, _ := game.NewGameWithOptions(game.GameOptions{BoardSize: 9, Rules: "chinese"})
+	g.Status = game.GameStatusPlaying
+	g.Info = &game.RecordInfo{BlackName: "Alice", WhiteName: "Bob", Comment: "a [test]"}
+	for _, p := range []game.Point{{X: 2, Y: 2}, {X: 6, Y: 6}} {
+		_ = g.PlayMove(p)
+	}
+	_ = g.PassTurn()
+	_ = g.Resign(game.Black)
+
+	data := string(EncodeSGF(g.Record()))
+	for _, want := range []string{"FF[4]", "SZ[9]", "KM[7.5]", "RU[Chinese]", "PB[Alice]", "PW[Bob]", "RE[W+R]", "TM[3600]", `GC[a [test\]]`, ";B[cc]", ";W[gg]", ";B[]", "DT["} {
+		if !strings.Contains(data, want) {
+			t.Fatalf("Expected %q in SGF:\n%s", want, data)
+		}
+	}
+
+	rec, err := DecodeSGF([]byte(data))
+	if err != nil {
+		t.Fatalf("Expected exported SGF to parse, got %v", err)
+	}
+	imported, err := rec.Game()
+	if err != nil {
+		t.Fatalf("Expected exported SGF to import, got %v", err)
+	}
+	if imported.Board.Hash() != g.Board.Hash() || imported.Result.String() != "W+R" || imported.Info.BlackName != "Alice" {
+		t.Fatal("Expected imported game to match the original")
+	}
+}
+
+// TestDecodeSGF_SetupAndReview 测试摆子局面和没有结果的棋谱
+func TestDecodeSGF_SetupAndReview(t *testing.T) {
+	rec, err := DecodeSGF([]byte(`(;GM[1]SZ[9]AB[aa:bb]AW[cc]PL[W];W[dd];B[ee])`))
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(rec.Setup.Black) != 4 || rec.Setup.NextPlayer != game.White {
+		t.Fatalf("Expected 4 black setup stones with white to play, got %+v", rec.Setup)
+	}
+
+	g, err := rec.Game()
+	if err != nil {
+		t.Fatalf("Expected record to import, got %v", err)
+	}
+	if g.Status != game.GameStatusReview || g.Result != nil || !g.GameOver {
+		t.Fatalf("Expected review game without result, got %s", g.Status)
+	}
+	if err := g.PlayMove(game.Point{X: 8, Y: 8}); err == nil {
+		t.Fatal("Expected review game not to accept moves")
+	}
+
+	// 重放时同样先摆放摆子局面
+	r, err := g.Replay(len(g.Moves))
+	if err != nil || r.Board.Hash() != g.Board.Hash() {
+		t.Fatalf("Expected replay to rebuild the same position, got %v", err)
+	}
+
+	// 非法的一手
+	rec, _ = DecodeSGF([]byte(`(;GM[1]SZ[9];B[ee];W[ee])`))
+	if _, err := rec.Game(); !errors.Is(err, game.ErrInvalidRecord) {
+		t.Fatalf("Expected ErrInvalidRecord, got %v", err)
+	}
+}
@@ -0,0 +1,220 @@
+package record
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/gametree"
+)
+
+// sgfMarkup 是 SGF 标记属性与棋谱树标记类型的对应关系
+var sgfMarkup = []struct {
+	id  string
+	typ gametree.MarkupType
+}{
+	{"CR", gametree.MarkCircle},
+	{"SQ", gametree.MarkSquare},
+	{"TR", gametree.MarkTriangle},
+	{"MA", gametree.MarkCross},
+}
+
+// TreeFromSGF 将包含变化图的 SGF 棋谱树转换为 gametree.Tree
+// 每个变化中的着法都会重放检查，根节点的 C 属性作为对局评注，不放入棋谱树
+func TreeFromSGF(root *Node) (*gametree.Tree, error) {
+	size, err := sgfBoardSize(root)
+	if err != nil {
+		return nil, err
+	}
+	t := gametree.New(size)
+
+	move, setup, err := decodeTreeNode(root, size)
+	if err != nil {
+		return nil, err
+	}
+	treeRoot := t.Root()
+	treeRoot.Move, treeRoot.Setup = move, setup
+	if _, err := t.Position(gametree.RootID); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidSGF, err)
+	}
+	if err := decodeTreeMarkup(t, gametree.RootID, root, size); err != nil {
+		return nil, err
+	}
+
+	var walk func(parent gametree.NodeID, n *Node) error
+	walk = func(parent gametree.NodeID, n *Node) error {
+		for _, child := range n.Children {
+			move, setup, err := decodeTreeNode(child, size)
+			if err != nil {
+				return err
+			}
+			node, err := t.Add(parent, move, setup)
+			if err != nil {
+				return fmt.Errorf("%w: %v", ErrInvalidSGF, err)
+			}
+			node.Comment = child.Get("C")
+			if err := decodeTreeMarkup(t, node.ID, child, size); err != nil {
+				return err
+			}
+			if err := walk(node.ID, child); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(gametree.RootID, root); err != nil {
+		return nil, err
+	}
+	t.Current = gametree.RootID
+	return t, nil
+}
+
+// decodeTreeNode 解析节点上的着法 (B/W) 和摆子 (AB/AW/AE/PL)
+func decodeTreeNode(n *Node, size int) (*gametree.Move, *gametree.Setup, error) {
+	var setup *gametree.Setup
+	if n.Has("AB") || n.Has("AW") || n.Has("AE") || n.Has("PL") {
+		setup = &gametree.Setup{}
+		for _, list := range []struct {
+			id     string
+			points *[]game.Point
+		}{{"AB", &setup.Black}, {"AW", &setup.White}, {"AE", &setup.Empty}} {
+			points, err := decodePointList(n.Values(list.id), size)
+			if err != nil {
+				return nil, nil, err
+			}
+			*list.points = points
+		}
+		switch strings.ToUpper(n.Get("PL")) {
+		case "B":
+			setup.NextPlayer = game.Black
+		case "W":
+			setup.NextPlayer = game.White
+		}
+	}
+
+	var player game.Player
+	var value string
+	switch {
+	case n.Has("B") && n.Has("W"):
+		return nil, nil, fmt.Errorf("%w: node has both B and W", ErrInvalidSGF)
+	case n.Has("B"):
+		player, value = game.Black, n.Get("B")
+	case n.Has("W"):
+		player, value = game.White, n.Get("W")
+	default:
+		return nil, setup, nil
+	}
+	p, pass, err := decodePoint(value, size)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &gametree.Move{Player: player, Pass: pass, Point: p}, setup, nil
+}
+
+// decodeTreeMarkup 解析节点上的 CR/SQ/TR/MA/LB 标记
+func decodeTreeMarkup(t *gametree.Tree, id gametree.NodeID, n *Node, size int) error {
+	var markup []gametree.Markup
+	for _, m := range sgfMarkup {
+		points, err := decodePointList(n.Values(m.id), size)
+		if err != nil {
+			return err
+		}
+		for _, p := range points {
+			markup = append(markup, gametree.Markup{Type: m.typ, Point: p})
+		}
+	}
+	for _, v := range n.Values("LB") {
+		point, label, _ := strings.Cut(v, ":")
+		p, pass, err := decodePoint(point, size)
+		if err != nil || pass || label == "" {
+			return fmt.Errorf("%w: bad label %q", ErrInvalidSGF, v)
+		}
+		markup = append(markup, gametree.Markup{Type: gametree.MarkLabel, Point: p, Label: label})
+	}
+	if len(markup) == 0 {
+		return nil
+	}
+	return t.SetMarkup(id, markup)
+}
+
+// TreeToSGF 将棋谱树连同变化图转换为 SGF 棋谱树
+// 根节点的对局信息和摆子取自 Record，棋谱树根节点上的摆子被忽略
+func TreeToSGF(rec *game.Record, t *gametree.Tree) *Node {
+	root := ToSGF(rec)
+	root.Children = nil
+
+	var walk func(dst *Node, src *gametree.Node)
+	walk = func(dst *Node, src *gametree.Node) {
+		if src.Comment != "" && src.ID != gametree.RootID {
+			dst.Set("C", src.Comment)
+		}
+		encodeTreeMarkup(dst, src.Markup)
+		for _, id := range src.Children {
+			child, err := t.Node(id)
+			if err != nil {
+				continue
+			}
+			node := &Node{}
+			encodeTreeNode(node, child)
+			dst.Children = append(dst.Children, node)
+			walk(node, child)
+		}
+	}
+	walk(root, t.Root())
+	return root
+}
+
+// encodeTreeNode 写出节点上的摆子和着法
+func encodeTreeNode(dst *Node, src *gametree.Node) {
+	if s := src.Setup; s != nil {
+		for _, list := range []struct {
+			id     string
+			points []game.Point
+		}{{"AB", s.Black}, {"AW", s.White}, {"AE", s.Empty}} {
+			if len(list.points) > 0 {
+				dst.Set(list.id, encodePoints(list.points)...)
+			}
+		}
+		switch s.NextPlayer {
+		case game.Black:
+			dst.Set("PL", "B")
+		case game.White:
+			dst.Set("PL", "W")
+		}
+	}
+	if m := src.Move; m != nil {
+		id := "B"
+		if m.Player == game.White {
+			id = "W"
+		}
+		if m.Pass {
+			dst.Set(id, "")
+		} else {
+			dst.Set(id, encodePoint(m.Point))
+		}
+	}
+}
+
+// encodeTreeMarkup 写出节点上的标记
+func encodeTreeMarkup(dst *Node, markup []gametree.Markup) {
+	for _, m := range sgfMarkup {
+		var values []string
+		for _, mark := range markup {
+			if mark.Type == m.typ {
+				values = append(values, encodePoint(mark.Point))
+			}
+		}
+		if len(values) > 0 {
+			dst.Set(m.id, values...)
+		}
+	}
+	var labels []string
+	for _, mark := range markup {
+		if mark.Type == gametree.MarkLabel {
+			labels = append(labels, encodePoint(mark.Point)+":"+mark.Label)
+		}
+	}
+	if len(labels) > 0 {
+		dst.Set("LB", labels...)
+	}
+}
@@ -0,0 +1,64 @@
+package record
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/gametree"
+)
+
+// TestTreeFromSGF 测试 SGF 变化图、摆子和标记转换为棋谱树后能原样写回
+func TestTreeFromSGF(t *testing.T) {
+	data := `(;GM[1]FF[4]SZ[9]AB[cc]
+;B[ee]C[main]TR[ee]
+(;W[gc];AE[ee]AW[dd]PL[W]LB[cc:A])
+(;W[cg]SQ[cc][gg]))`
+
+	trees, err := ParseSGF([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	tree, err := TreeFromSGF(trees[0])
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(tree.Nodes) != 5 || tree.Current != gametree.RootID {
+		t.Fatalf("Expected 5 nodes with current at root, got %d at %d", len(tree.Nodes), tree.Current)
+	}
+
+	line := tree.MainLine()
+	pos, err := tree.Position(line[len(line)-1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	grid := pos.Board.Grid
+	if grid[2][2] != game.Black || grid[4][4] != game.Empty || grid[3][3] != game.White || pos.NextPlayer != game.White {
+		t.Fatalf("Unexpected position at the end of the main line:\n%s", pos.Board)
+	}
+	first, _ := tree.Node(line[1])
+	if first.Comment != "main" || len(first.Markup) != 1 || first.Markup[0].Type != gametree.MarkTriangle {
+		t.Fatalf("Expected comment and markup on the first move, got %+v", first)
+	}
+
+	// 主线中途有摆子的棋谱不能转换为 Record，这里直接构造根节点信息
+	rec := &game.Record{Size: 9, Setup: game.Setup{Black: []game.Point{{X: 2, Y: 2}}}}
+	sgf := TreeToSGF(rec, tree).SGF()
+	for _, want := range []string{"AB[cc]", ";B[ee]C[main]TR[ee]", "(;W[gc]", ";AW[dd]AE[ee]PL[W]LB[cc:A]", "(;W[cg]SQ[cc][gg])"} {
+		if !strings.Contains(sgf, want) {
+			t.Fatalf("Expected %q in SGF:\n%s", want, sgf)
+		}
+	}
+}
+
+// TestTreeFromSGF_IllegalVariation 测试变化图中的非法着法被拒绝
+func TestTreeFromSGF_IllegalVariation(t *testing.T) {
+	trees, err := ParseSGF([]byte(`(;SZ[9];B[ee](;W[gg])(;W[ee]))`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := TreeFromSGF(trees[0]); !errors.Is(err, ErrInvalidSGF) {
+		t.Fatalf("Expected ErrInvalidSGF, got %v", err)
+	}
+}
@@ -0,0 +1,142 @@
+package record
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// UGI/UGF 是野狐等平台导出的 INI 风格棋谱格式：
+//
+//	[Header]
+//	Hdcp=0,6.5              让子数,贴目
+//	Size=19
+//	PlayerB=name,5d,...     姓名,段位
+//	PlayerW=name,6d,...
+//	Winner=B,3.5            胜方,目数 (非数字表示中盘胜)
+//	Date=2024/05/01,...
+//	[Data]
+//	QD,B1,1,0               坐标,颜色,手数,用时
+//
+// 坐标的列从左侧 'A' 开始，行从下方 'A' 开始；超出棋盘的坐标表示虚手
+
+// DecodeUGI 解析 UGI/UGF 棋谱
+func DecodeUGI(data []byte) (*game.Record, error) {
+	header := map[string]string{}
+	var moves []string
+	section := ""
+
+	for _, line := range splitLines(data) {
+		line = strings.TrimSpace(line)
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			section = strings.ToLower(line[1 : len(line)-1])
+			continue
+		}
+		switch section {
+		case "header":
+			if key, value, ok := strings.Cut(line, "="); ok {
+				header[strings.ToLower(strings.TrimSpace(key))] = strings.TrimSpace(value)
+			}
+		case "data":
+			if line != "" {
+				moves = append(moves, line)
+			}
+		}
+	}
+	if len(header) == 0 {
+		return nil, fmt.Errorf("%w: missing UGI header", ErrInvalidRecordFormat)
+	}
+
+	rec := &game.Record{Size: game.BoardSize}
+	if size, err := strconv.Atoi(header["size"]); err == nil {
+		rec.Size = size
+	}
+	handicap, komi, _ := strings.Cut(header["hdcp"], ",")
+	if n, err := strconv.Atoi(strings.TrimSpace(handicap)); err == nil && n >= game.MinHandicap {
+		rec.Handicap = n
+	}
+	if k, err := strconv.ParseFloat(strings.TrimSpace(komi), 64); err == nil {
+		rec.Komi = k
+	}
+	switch strings.ToUpper(header["rule"]) {
+	case "JPN", "JAPANESE":
+		rec.Rules = "japanese"
+	case "CHN", "CHINESE":
+		rec.Rules = "chinese"
+	}
+
+	rec.Info.BlackName, rec.Info.BlackRank = splitUGIPlayer(header["playerb"])
+	rec.Info.WhiteName, rec.Info.WhiteRank = splitUGIPlayer(header["playerw"])
+	rec.Info.Event = header["title"]
+	rec.Info.Place = header["place"]
+	if m := gibDatePattern.FindStringSubmatch(header["date"]); m != nil {
+		rec.Info.Date = formatDate(m[1], m[2], m[3])
+	}
+	rec.Result = ugiResult(header["winner"])
+
+	for _, line := range moves {
+		fields := strings.Split(line, ",")
+		if len(fields) < 2 || len(fields[0]) != 2 || fields[1] == "" {
+			return nil, fmt.Errorf("%w: bad UGI move %q", ErrInvalidRecordFormat, line)
+		}
+		var player game.Player
+		switch strings.ToUpper(fields[1])[0] {
+		case 'B':
+			player = game.Black
+		case 'W':
+			player = game.White
+		default:
+			return nil, fmt.Errorf("%w: bad UGI move %q", ErrInvalidRecordFormat, line)
+		}
+
+		coord := strings.ToUpper(fields[0])
+		x, row := int(coord[0])-'A', int(coord[1])-'A'
+		if x < 0 || row < 0 || x >= rec.Size || row >= rec.Size {
+			rec.Moves = append(rec.Moves, game.Move{Player: player, Pass: true})
+			continue
+		}
+		rec.Moves = append(rec.Moves, game.Move{Player: player, Point: game.Point{X: x, Y: rec.Size - 1 - row}})
+	}
+	return rec, nil
+}
+
+// splitUGIPlayer 解析 "name,rank,..." 形式的棋手信息
+func splitUGIPlayer(s string) (name, rank string) {
+	fields := strings.Split(s, ",")
+	name = strings.TrimSpace(fields[0])
+	if len(fields) > 1 {
+		rank = strings.TrimSpace(fields[1])
+	}
+	return name, rank
+}
+
+// ugiResult 解析 "B,3.5"、"W,C" 形式的结果，目数不是正数时视为中盘胜
+func ugiResult(s string) *game.Result {
+	winner, margin, _ := strings.Cut(s, ",")
+	r := &game.Result{}
+	switch strings.ToUpper(strings.TrimSpace(winner)) {
+	case "B":
+		r.Winner = game.Black
+	case "W":
+		r.Winner = game.White
+	case "D", "DRAW":
+		return &game.Result{Winner: game.Empty, Reason: game.ReasonScore}
+	default:
+		return nil
+	}
+
+	margin = strings.ToUpper(strings.TrimSpace(margin))
+	switch margin {
+	case "T", "TIME":
+		r.Reason = game.ReasonTimeout
+	default:
+		if m, err := strconv.ParseFloat(margin, 64); err == nil && m > 0 {
+			r.Reason, r.Margin = game.ReasonScore, m
+		} else {
+			r.Reason = game.ReasonResign
+		}
+	}
+	return r
+}
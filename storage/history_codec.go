@@ -0,0 +1,120 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/nankp236270/weiqi-go/game"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsonrw"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
+)
+
+var tPositionHistory = reflect.TypeOf(game.PositionHistory{})
+
+// newGameRegistry 返回注册了劫争历史编解码器的 BSON registry
+func newGameRegistry() *bsoncodec.Registry {
+	reg := bson.NewRegistry()
+	reg.RegisterTypeEncoder(tPositionHistory, bsoncodec.ValueEncoderFunc(encodePositionHistory))
+	reg.RegisterTypeDecoder(tPositionHistory, bsoncodec.ValueDecoderFunc(decodePositionHistory))
+	return reg
+}
+
+// encodePositionHistory 将劫争历史编码为 int64 数组 (按位保存 64 位 Zobrist 哈希)
+func encodePositionHistory(_ bsoncodec.EncodeContext, vw bsonrw.ValueWriter, val reflect.Value) error {
+	if !val.IsValid() || val.Type() != tPositionHistory {
+		return bsoncodec.ValueEncoderError{Name: "PositionHistoryEncodeValue", Types: []reflect.Type{tPositionHistory}, Received: val}
+	}
+	if val.IsNil() {
+		return vw.WriteNull()
+	}
+
+	history := val.Interface().(game.PositionHistory)
+	hashes := make([]uint64, 0, len(history))
+	for hash, seen := range history {
+		if seen {
+			hashes = append(hashes, hash)
+		}
+	}
+	slices.Sort(hashes) // 保证相同的历史编码结果一致
+
+	aw, err := vw.WriteArray()
+	if err != nil {
+		return err
+	}
+	for _, hash := range hashes {
+		ew, err := aw.WriteArrayElement()
+		if err != nil {
+			return err
+		}
+		if err := ew.WriteInt64(int64(hash)); err != nil {
+			return err
+		}
+	}
+	return aw.WriteArrayEnd()
+}
+
+// decodePositionHistory 解码劫争历史
+// 兼容旧版本以棋面字符串为键的文档格式，读取时转换为 Zobrist 哈希
+func decodePositionHistory(_ bsoncodec.DecodeContext, vr bsonrw.ValueReader, val reflect.Value) error {
+	if !val.CanSet() || val.Type() != tPositionHistory {
+		return bsoncodec.ValueDecoderError{Name: "PositionHistoryDecodeValue", Types: []reflect.Type{tPositionHistory}, Received: val}
+	}
+
+	history := game.PositionHistory{}
+	switch vr.Type() {
+	case bsontype.Null:
+		if err := vr.ReadNull(); err != nil {
+			return err
+		}
+	case bsontype.Array:
+		ar, err := vr.ReadArray()
+		if err != nil {
+			return err
+		}
+		for {
+			evr, err := ar.ReadValue()
+			if errors.Is(err, bsonrw.ErrEOA) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			hash, err := evr.ReadInt64()
+			if err != nil {
+				return err
+			}
+			history[uint64(hash)] = true
+		}
+	case bsontype.EmbeddedDocument:
+		dr, err := vr.ReadDocument()
+		if err != nil {
+			return err
+		}
+		for {
+			key, evr, err := dr.ReadElement()
+			if errors.Is(err, bsonrw.ErrEOD) {
+				break
+			}
+			if err != nil {
+				return err
+			}
+			if err := evr.Skip(); err != nil {
+				return err
+			}
+			hash, err := game.LegacyPositionHash(key)
+			if err != nil {
+				return fmt.Errorf("decode legacy history key: %w", err)
+			}
+			history[hash] = true
+		}
+	default:
+		return fmt.Errorf("cannot decode %v into game.PositionHistory", vr.Type())
+	}
+
+	val.Set(reflect.ValueOf(history))
+	return nil
+}
@@ -0,0 +1,66 @@
+package storage
+
+import (
+	"testing"
+
+	"github.com/nankp236270/weiqi-go/game"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// TestPositionHistoryCodec 测试劫争历史以哈希数组保存并能还原
+func TestPositionHistoryCodec(t *testing.T) {
+	g := game.NewGame()
+	_ = g.PlayMove(game.Point{X: 3, Y: 3})
+	_ = g.PlayMove(game.Point{X: 15, Y: 15})
+
+	reg := newGameRegistry()
+	data, err := bson.MarshalWithRegistry(reg, mongoGame{ID: "g1", State: g})
+	if err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+
+	raw := bson.Raw(data)
+	if kind := raw.Lookup("state", "history").Type; kind != bson.TypeArray {
+		t.Fatalf("Expected history to be stored as an array, got %v", kind)
+	}
+
+	var doc mongoGame
+	if err := bson.UnmarshalWithRegistry(reg, data, &doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(doc.State.History) != len(g.History) {
+		t.Fatalf("Expected %d history entries, got %d", len(g.History), len(doc.State.History))
+	}
+	for hash := range g.History {
+		if !doc.State.History[hash] {
+			t.Fatalf("Expected hash %x to survive the round trip", hash)
+		}
+	}
+}
+
+// TestPositionHistoryCodec_Legacy 测试旧版字符串键的文档读取时被转换
+func TestPositionHistoryCodec_Legacy(t *testing.T) {
+	g := game.NewGame()
+	_ = g.PlayMove(game.Point{X: 3, Y: 3})
+
+	legacy := bson.M{
+		"_id": "old",
+		"state": bson.M{
+			"board":       bson.M{"grid": g.Board.Grid},
+			"next_player": int32(game.White),
+			"history": bson.M{
+				game.NewBoard().StateHash(): true,
+				g.Board.StateHash():         true,
+			},
+		},
+	}
+	data, _ := bson.Marshal(legacy)
+
+	var doc mongoGame
+	if err := bson.UnmarshalWithRegistry(newGameRegistry(), data, &doc); err != nil {
+		t.Fatalf("Expected no error, got %v", err)
+	}
+	if len(doc.State.History) != 2 || !doc.State.History[g.Board.Hash()] || !doc.State.History[0] {
+		t.Fatalf("Expected legacy keys to be converted, got %v", doc.State.History)
+	}
+}
@@ -6,8 +6,10 @@ import (
 	"fmt"
 
 	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/gametree"
 	"go.mongodb.org/mongo-driver/bson"
 	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
 // MongoGameStore 是 GameStore 接口的 MongoDB 实现
@@ -17,15 +19,22 @@ type MongoGameStore struct {
 
 // NewMongoGameStore 创建一个新的 MongoDB 存储实例
 func NewMongoGameStore(collection *mongo.Collection) *MongoGameStore {
+	// 使用自定义 registry，劫争历史以 64 位哈希数组的紧凑格式保存
+	if c, err := collection.Clone(options.Collection().SetRegistry(newGameRegistry())); err == nil {
+		collection = c
+	}
 	return &MongoGameStore{
 		collection: collection,
 	}
 }
 
-// mongoGame 是存储在 MongoDB 中的文档结构
+// mongoGame 是存储在 MongoDB 中的文档结构，棋谱树与对局状态保存在同一个文档中
+// 版本号放在文档顶层，保存对局状态时用 $inc 递增；旧文档没有版本号，视为 0
 type mongoGame struct {
-	ID    string     `bson:"_id"`
-	State *game.Game `bson:"state"`
+	ID      string         `bson:"_id"`
+	State   *game.Game     `bson:"state"`
+	Tree    *gametree.Tree `bson:"tree,omitempty"`
+	Version int64          `bson:"version"`
 }
 
 func (s *MongoGameStore) CreateGame(gameID string, g *game.Game) error {
@@ -49,22 +58,93 @@ func (s *MongoGameStore) GetGame(gameID string) (*game.Game, error) {
 
 		return nil, err
 	}
+	doc.State.Version = doc.Version
 	return doc.State, nil
 }
 
+// UpdateGame 只更新对局状态，不会覆盖文档中的棋谱树
 func (s *MongoGameStore) UpdateGame(gameID string, g *game.Game) error {
-	doc := mongoGame{
-		ID:    gameID,
-		State: g,
+	filter := bson.M{"_id": gameID}
+
+	var doc struct {
+		Version int64 `bson:"version"`
+	}
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After).SetProjection(bson.M{"version": 1})
+	err := s.collection.FindOneAndUpdate(context.TODO(), filter, gameUpdate(g), opts).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return fmt.Errorf("game with %s not found for updata", gameID)
+		}
+		return err
+	}
+	g.Version = doc.Version
+	return nil
+}
+
+// UpdateGameIfVersion 以版本号为条件更新对局状态，版本号不符时返回 ErrVersionConflict
+func (s *MongoGameStore) UpdateGameIfVersion(gameID string, g *game.Game, version int64) error {
+	filter := bson.M{"_id": gameID, "version": version}
+	if version == 0 {
+		filter = bson.M{"_id": gameID, "$or": []bson.M{
+			{"version": 0},
+			{"version": bson.M{"$exists": false}},
+		}}
+	}
+
+	res, err := s.collection.UpdateOne(context.TODO(), filter, gameUpdate(g))
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		// 区分对局不存在和版本冲突
+		n, err := s.collection.CountDocuments(context.TODO(), bson.M{"_id": gameID})
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return fmt.Errorf("game with %s not found for update", gameID)
+		}
+		return ErrVersionConflict
+	}
+	g.Version = version + 1
+	return nil
+}
+
+// gameUpdate 返回保存对局状态并递增版本号的更新
+func gameUpdate(g *game.Game) bson.M {
+	return bson.M{
+		"$set": bson.M{"state": g},
+		"$inc": bson.M{"version": 1},
+	}
+}
+
+func (s *MongoGameStore) GetGameTree(gameID string) (*gametree.Tree, error) {
+	var doc struct {
+		Tree *gametree.Tree `bson:"tree"`
 	}
 	filter := bson.M{"_id": gameID}
+	opts := options.FindOne().SetProjection(bson.M{"tree": 1})
 
-	res, err := s.collection.ReplaceOne(context.TODO(), filter, doc)
+	err := s.collection.FindOne(context.TODO(), filter, opts).Decode(&doc)
+	if err != nil {
+		if errors.Is(err, mongo.ErrNoDocuments) {
+			return nil, fmt.Errorf("game with ID %s not found", gameID)
+		}
+		return nil, err
+	}
+	return doc.Tree, nil
+}
+
+func (s *MongoGameStore) SaveGameTree(gameID string, t *gametree.Tree) error {
+	filter := bson.M{"_id": gameID}
+	update := bson.M{"$set": bson.M{"tree": t}}
+
+	res, err := s.collection.UpdateOne(context.TODO(), filter, update)
 	if err != nil {
 		return err
 	}
 	if res.MatchedCount == 0 {
-		return fmt.Errorf("game with %s not found for updata", gameID)
+		return fmt.Errorf("game with %s not found for update", gameID)
 	}
 	return nil
 }
@@ -89,15 +169,7 @@ func (s *MongoGameStore) GetGamesByPlayer(playerID string) ([]GameInfo, error) {
 		if err := cursor.Decode(&doc); err != nil {
 			continue
 		}
-		games = append(games, GameInfo{
-			ID:          doc.ID,
-			PlayerBlack: doc.State.PlayerBlack,
-			PlayerWhite: doc.State.PlayerWhite,
-			Status:      doc.State.Status,
-			IsAIGame:    doc.State.IsAIGame,
-			NextPlayer:  doc.State.NextPlayer,
-			GameOver:    doc.State.GameOver,
-		})
+		games = append(games, newGameInfo(doc.ID, doc.State))
 	}
 
 	return games, nil
@@ -118,16 +190,55 @@ func (s *MongoGameStore) GetWaitingGames() ([]GameInfo, error) {
 		if err := cursor.Decode(&doc); err != nil {
 			continue
 		}
-		games = append(games, GameInfo{
-			ID:          doc.ID,
-			PlayerBlack: doc.State.PlayerBlack,
-			PlayerWhite: doc.State.PlayerWhite,
-			Status:      doc.State.Status,
-			IsAIGame:    doc.State.IsAIGame,
-			NextPlayer:  doc.State.NextPlayer,
-			GameOver:    doc.State.GameOver,
-		})
+		games = append(games, newGameInfo(doc.ID, doc.State))
+	}
+
+	return games, nil
+}
+
+func (s *MongoGameStore) GetPlayingGames() ([]GameInfo, error) {
+	filter := bson.M{"state.status": game.GameStatusPlaying}
+
+	cursor, err := s.collection.Find(context.TODO(), filter)
+	if err != nil {
+		return nil, err
+	}
+	defer cursor.Close(context.TODO())
+
+	var games []GameInfo
+	for cursor.Next(context.TODO()) {
+		var doc mongoGame
+		if err := cursor.Decode(&doc); err != nil {
+			continue
+		}
+		games = append(games, newGameInfo(doc.ID, doc.State))
 	}
 
 	return games, nil
 }
+
+// MigrateHistories 将旧版本以棋面字符串保存劫争历史的文档改写为哈希数组格式
+// 读取时旧格式会自动转换，迁移只是把转换结果写回，返回迁移的文档数
+func (s *MongoGameStore) MigrateHistories() (int, error) {
+	filter := bson.M{"state.history": bson.M{"$type": "object"}}
+
+	cursor, err := s.collection.Find(context.TODO(), filter)
+	if err != nil {
+		return 0, err
+	}
+	defer cursor.Close(context.TODO())
+
+	migrated := 0
+	for cursor.Next(context.TODO()) {
+		var doc mongoGame
+		if err := cursor.Decode(&doc); err != nil {
+			return migrated, fmt.Errorf("decode game %v: %w", cursor.Current.Lookup("_id"), err)
+		}
+		if err := s.UpdateGame(doc.ID, doc.State); err != nil {
+			return migrated, fmt.Errorf("migrate game %s: %w", doc.ID, err)
+		}
+		migrated++
+	}
+
+	return migrated, cursor.Err()
+}
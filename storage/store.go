@@ -1,19 +1,34 @@
 package storage
 
 import (
+	"errors"
 	"fmt"
 	"sync"
 
 	"github.com/nankp236270/weiqi-go/game"
+	"github.com/nankp236270/weiqi-go/gametree"
 )
 
+// ErrVersionConflict 表示条件更新时对局在读取之后已被其他请求修改
+var ErrVersionConflict = errors.New("game was modified concurrently")
+
 // GameStore 定义了游戏数据持久化层所需要实现的方法
 type GameStore interface {
 	CreateGame(gameID string, g *game.Game) error
+	// GetGame 返回对局的副本，调用方对它的修改只有保存后才会生效
 	GetGame(gameID string) (*game.Game, error)
 	UpdateGame(gameID string, g *game.Game) error
+	// UpdateGameIfVersion 仅当存储中的对局版本仍为 version 时保存 g，
+	// 读取之后对局已被其他请求保存过时返回 ErrVersionConflict
+	UpdateGameIfVersion(gameID string, g *game.Game, version int64) error
 	GetGamesByPlayer(playerID string) ([]GameInfo, error)
 	GetWaitingGames() ([]GameInfo, error)
+	GetPlayingGames() ([]GameInfo, error)
+
+	// GetGameTree 返回对局的棋谱树，对局存在但还没有保存过棋谱树时返回 nil
+	GetGameTree(gameID string) (*gametree.Tree, error)
+	// SaveGameTree 保存对局的棋谱树，与对局数据存放在一起
+	SaveGameTree(gameID string, t *gametree.Tree) error
 }
 
 // GameInfo 游戏信息（用于列表）
@@ -23,20 +38,43 @@ type GameInfo struct {
 	PlayerWhite string          `json:"player_white"`
 	Status      game.GameStatus `json:"status"`
 	IsAIGame    bool            `json:"is_ai_game"`
+	BoardSize   int             `json:"board_size"`
 	NextPlayer  game.Player     `json:"next_player"`
 	GameOver    bool            `json:"game_over"`
+	Result      *game.Result    `json:"result,omitempty"` // 对局结果，未结束时为空
+}
+
+// newGameInfo 根据游戏状态构建列表信息
+func newGameInfo(id string, g *game.Game) GameInfo {
+	return GameInfo{
+		ID:          id,
+		PlayerBlack: g.PlayerBlack,
+		PlayerWhite: g.PlayerWhite,
+		Status:      g.Status,
+		IsAIGame:    g.IsAIGame,
+		BoardSize:   g.Board.Size(),
+		NextPlayer:  g.NextPlayer,
+		GameOver:    g.GameOver,
+		Result:      g.Result,
+	}
 }
 
 // InMemoryGameStore 是 GameStore 接口的一个内存实现
+// 与数据库存储一样，保存和读取的都是对局的副本：调用方修改读取到的对局不会影响存储，
+// 只有通过 UpdateGame 或 UpdateGameIfVersion 才能发布修改
 type InMemoryGameStore struct {
-	store map[string]*game.Game
-	mu    sync.RWMutex
+	store    map[string]*game.Game
+	versions map[string]int64
+	trees    map[string]*gametree.Tree
+	mu       sync.RWMutex
 }
 
 // NewInMemoryGameStore 创建一个新的内存存储实例
 func NewInMemoryGameStore() *InMemoryGameStore {
 	return &InMemoryGameStore{
-		store: make(map[string]*game.Game),
+		store:    make(map[string]*game.Game),
+		versions: make(map[string]int64),
+		trees:    make(map[string]*gametree.Tree),
 	}
 }
 
@@ -44,7 +82,9 @@ func (s *InMemoryGameStore) CreateGame(gameID string, g *game.Game) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.store[gameID] = g
+	g.Version = 0
+	s.store[gameID] = g.Clone()
+	s.versions[gameID] = 0
 	return nil
 }
 
@@ -56,7 +96,7 @@ func (s *InMemoryGameStore) GetGame(gameID string) (*game.Game, error) {
 	if !ok {
 		return nil, fmt.Errorf("game with ID %s not found", gameID)
 	}
-	return g, nil
+	return g.Clone(), nil
 }
 
 func (s *InMemoryGameStore) UpdateGame(gameID string, g *game.Game) error {
@@ -67,10 +107,32 @@ func (s *InMemoryGameStore) UpdateGame(gameID string, g *game.Game) error {
 		return fmt.Errorf("game with ID %s not found", gameID)
 	}
 
-	s.store[gameID] = g
+	s.put(gameID, g)
 	return nil
 }
 
+func (s *InMemoryGameStore) UpdateGameIfVersion(gameID string, g *game.Game, version int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.store[gameID]; !ok {
+		return fmt.Errorf("game with ID %s not found", gameID)
+	}
+	if s.versions[gameID] != version {
+		return ErrVersionConflict
+	}
+
+	s.put(gameID, g)
+	return nil
+}
+
+// put 保存对局并递增版本号，调用方需要持有写锁
+func (s *InMemoryGameStore) put(gameID string, g *game.Game) {
+	s.versions[gameID]++
+	g.Version = s.versions[gameID]
+	s.store[gameID] = g.Clone()
+}
+
 func (s *InMemoryGameStore) GetGamesByPlayer(playerID string) ([]GameInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -78,15 +140,7 @@ func (s *InMemoryGameStore) GetGamesByPlayer(playerID string) ([]GameInfo, error
 	var games []GameInfo
 	for id, g := range s.store {
 		if g.PlayerBlack == playerID || g.PlayerWhite == playerID {
-			games = append(games, GameInfo{
-				ID:          id,
-				PlayerBlack: g.PlayerBlack,
-				PlayerWhite: g.PlayerWhite,
-				Status:      g.Status,
-				IsAIGame:    g.IsAIGame,
-				NextPlayer:  g.NextPlayer,
-				GameOver:    g.GameOver,
-			})
+			games = append(games, newGameInfo(id, g))
 		}
 	}
 	return games, nil
@@ -99,16 +153,42 @@ func (s *InMemoryGameStore) GetWaitingGames() ([]GameInfo, error) {
 	var games []GameInfo
 	for id, g := range s.store {
 		if g.Status == game.GameStatusWaiting {
-			games = append(games, GameInfo{
-				ID:          id,
-				PlayerBlack: g.PlayerBlack,
-				PlayerWhite: g.PlayerWhite,
-				Status:      g.Status,
-				IsAIGame:    g.IsAIGame,
-				NextPlayer:  g.NextPlayer,
-				GameOver:    g.GameOver,
-			})
+			games = append(games, newGameInfo(id, g))
 		}
 	}
 	return games, nil
 }
+
+func (s *InMemoryGameStore) GetPlayingGames() ([]GameInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var games []GameInfo
+	for id, g := range s.store {
+		if g.Status == game.GameStatusPlaying {
+			games = append(games, newGameInfo(id, g))
+		}
+	}
+	return games, nil
+}
+
+func (s *InMemoryGameStore) GetGameTree(gameID string) (*gametree.Tree, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.store[gameID]; !ok {
+		return nil, fmt.Errorf("game with ID %s not found", gameID)
+	}
+	return s.trees[gameID], nil
+}
+
+func (s *InMemoryGameStore) SaveGameTree(gameID string, t *gametree.Tree) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.store[gameID]; !ok {
+		return fmt.Errorf("game with ID %s not found", gameID)
+	}
+	s.trees[gameID] = t
+	return nil
+}
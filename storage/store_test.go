@@ -0,0 +1,41 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/nankp236270/weiqi-go/game"
+)
+
+// TestInMemoryGameStore_Copies 测试读取和保存的都是副本，只有保存才会发布修改
+func TestInMemoryGameStore_Copies(t *testing.T) {
+	s := NewInMemoryGameStore()
+	g := game.NewGame()
+	g.Status = game.GameStatusPlaying
+	if err := s.CreateGame("g", g); err != nil {
+		t.Fatal(err)
+	}
+
+	read, _ := s.GetGame("g")
+	if err := read.PlayMove(game.Point{X: 3, Y: 3}); err != nil {
+		t.Fatal(err)
+	}
+	if stored, _ := s.GetGame("g"); len(stored.Moves) != 0 || stored.Board.Grid[3][3] != game.Empty {
+		t.Fatal("Expected an unsaved change not to reach the store")
+	}
+
+	// 条件保存失败时存储中的对局保持不变
+	if err := s.UpdateGameIfVersion("g", read, read.Version+1); !errors.Is(err, ErrVersionConflict) {
+		t.Fatalf("Expected ErrVersionConflict, got %v", err)
+	}
+	if err := s.UpdateGameIfVersion("g", read, read.Version); err != nil {
+		t.Fatalf("Expected the save to succeed, got %v", err)
+	}
+
+	// 保存之后继续修改调用方的对象也不会影响存储
+	_ = read.Resign(game.White)
+	stored, _ := s.GetGame("g")
+	if len(stored.Moves) != 1 || stored.Board.Grid[3][3] != game.Black || stored.GameOver || stored.Version != 1 {
+		t.Fatalf("Expected the saved move only, got %d moves, game over %v, version %d", len(stored.Moves), stored.GameOver, stored.Version)
+	}
+}
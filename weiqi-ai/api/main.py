@@ -6,10 +6,11 @@ FastAPI 主应用
 
 from fastapi import FastAPI, HTTPException
 from pydantic import BaseModel, Field
-from typing import List
+from typing import List, Optional
+import math
 import random
 
-from core.board import Board, Player, Point
+from core.board import Board, Player, Point, BOARD_SIZE, SUPPORTED_BOARD_SIZES
 from core.game import Game
 
 app = FastAPI(
@@ -21,11 +22,22 @@ app = FastAPI(
 
 # ==================== 请求/响应模型 ====================
 
+class AIProfile(BaseModel):
+    """AI 难度对应的引擎参数"""
+    playouts: int = Field(0, description="每步搜索的模拟次数上限，0 表示不限制")
+    randomness: float = Field(0.0, ge=0, le=1, description="不经搜索随机落子的概率")
+    temperature: float = Field(0.0, ge=0, description="按搜索结果抽样选点的温度")
+    mistake_rate: float = Field(0.0, ge=0, le=1, description="故意选择次优着法的概率")
+
+
 class MoveRequest(BaseModel):
     """AI 落子请求"""
-    board: List[List[int]] = Field(..., description="19x19 棋盘状态")
+    board_size: int = Field(BOARD_SIZE, description=f"棋盘大小 {SUPPORTED_BOARD_SIZES}")
+    board: List[List[int]] = Field(..., description="board_size x board_size 棋盘状态")
     next_player: int = Field(..., description="下一个玩家 (1=黑, 2=白)")
     history: List[str] = Field(default_factory=list, description="历史状态哈希列表")
+    difficulty: Optional[str] = Field(None, description="AI 难度 (beginner, easy, medium, hard, strong)")
+    profile: Optional[AIProfile] = Field(None, description="难度对应的引擎参数")
 
 
 class MoveResponse(BaseModel):
@@ -37,7 +49,8 @@ class MoveResponse(BaseModel):
 
 class ScoreRequest(BaseModel):
     """计分请求"""
-    board: List[List[int]] = Field(..., description="19x19 棋盘状态")
+    board_size: int = Field(BOARD_SIZE, description=f"棋盘大小 {SUPPORTED_BOARD_SIZES}")
+    board: List[List[int]] = Field(..., description="board_size x board_size 棋盘状态")
 
 
 class ScoreResponse(BaseModel):
@@ -47,8 +60,53 @@ class ScoreResponse(BaseModel):
     winner: int = Field(..., description="胜者 (1=黑, 2=白)")
 
 
+class AnalyzeRequest(MoveRequest):
+    """局面分析请求"""
+    komi: float = Field(7.5, description="贴目（目）")
+    candidates: int = Field(5, ge=1, le=20, description="返回的候选着法数")
+
+
+class PVPoint(BaseModel):
+    """变化图中的一手"""
+    x: int
+    y: int
+
+
+class CandidateMove(BaseModel):
+    """候选着法"""
+    x: int
+    y: int
+    visits: int
+    prior: float
+    winrate: float
+    score_lead: float
+    pv: List[PVPoint]
+
+
+class AnalyzeResponse(BaseModel):
+    """局面分析响应，胜率和目差以黑方为准"""
+    winrate: float
+    score_lead: float
+    candidates: List[CandidateMove]
+    ownership: List[List[float]] = Field(..., description="每个点的归属，1=黑, -1=白")
+
+
 # ==================== API 端点 ====================
 
+def load_game(board_size: int, board: List[List[int]]) -> Game:
+    """
+    根据请求中的棋盘重建游戏状态
+
+    Raises:
+        ValueError: 棋盘大小不受支持，或与 board_size 不一致
+    """
+    if len(board) != board_size:
+        raise ValueError(f"board has {len(board)} rows, expected {board_size}")
+    game = Game(board_size)
+    game.board = Board.from_list(board)
+    return game
+
+
 @app.get("/")
 async def root():
     """健康检查端点"""
@@ -71,12 +129,11 @@ async def get_ai_move(request: MoveRequest):
     获取 AI 的下一步落子
     
     当前实现：随机选择一个合法的落子位置
-    未来会升级为 MCTS + 神经网络
+    未来会升级为 MCTS + 神经网络，届时按 profile 调节棋力；随机落子已是最低难度，暂不使用
     """
     try:
         # 1. 重建游戏状态
-        game = Game()
-        game.board = Board.from_list(request.board)
+        game = load_game(request.board_size, request.board)
         game.next_player = Player(request.next_player)
         
         # 重建历史记录
@@ -116,8 +173,7 @@ async def calculate_score(request: ScoreRequest):
     """
     try:
         # 1. 重建游戏状态
-        game = Game()
-        game.board = Board.from_list(request.board)
+        game = load_game(request.board_size, request.board)
         game.game_over = True  # 标记为已结束
         
         # 2. 计算得分
@@ -135,6 +191,92 @@ async def calculate_score(request: ScoreRequest):
         raise HTTPException(status_code=500, detail=f"Internal error: {str(e)}")
 
 
+def estimate_ownership(board: Board) -> List[List[float]]:
+    """
+    估计每个点的归属：棋子属于己方，只被一方包围的空点属于该方
+
+    简化实现：假设所有棋子都是活棋
+    """
+    ownership = [[0.0] * board.size for _ in range(board.size)]
+    visited = [[False] * board.size for _ in range(board.size)]
+
+    for i in range(board.size):
+        for j in range(board.size):
+            if board.grid[i][j] == Player.BLACK:
+                ownership[i][j] = 1.0
+            elif board.grid[i][j] == Player.WHITE:
+                ownership[i][j] = -1.0
+            elif not visited[i][j]:
+                # BFS 找出整块空点以及相邻的棋子颜色
+                region = [Point(i, j)]
+                visited[i][j] = True
+                touches = set()
+                k = 0
+                while k < len(region):
+                    for neighbor in board._get_neighbors(region[k]):
+                        color = board.grid[neighbor.x][neighbor.y]
+                        if color != Player.EMPTY:
+                            touches.add(color)
+                        elif not visited[neighbor.x][neighbor.y]:
+                            visited[neighbor.x][neighbor.y] = True
+                            region.append(neighbor)
+                    k += 1
+
+                if touches == {Player.BLACK}:
+                    value = 1.0
+                elif touches == {Player.WHITE}:
+                    value = -1.0
+                else:
+                    value = 0.0
+                for p in region:
+                    ownership[p.x][p.y] = value
+
+    return ownership
+
+
+@app.post("/v1/ai/analyze", response_model=AnalyzeResponse)
+async def analyze(request: AnalyzeRequest):
+    """
+    分析当前局面
+
+    当前实现：按归属估计目差，胜率由目差换算，候选着法从合法落子中随机选择
+    未来会升级为 MCTS + 神经网络
+    """
+    try:
+        game = load_game(request.board_size, request.board)
+        game.next_player = Player(request.next_player)
+
+        for hash_str in request.history:
+            game.history[hash_str] = True
+
+        ownership = estimate_ownership(game.board)
+        score_lead = sum(sum(row) for row in ownership) - request.komi
+        winrate = 1.0 / (1.0 + math.exp(-score_lead / 10.0))
+
+        legal_moves = game.get_legal_moves()
+        chosen = random.sample(legal_moves, min(request.candidates, len(legal_moves)))
+        prior = 1.0 / len(legal_moves) if legal_moves else 0.0
+
+        return AnalyzeResponse(
+            winrate=winrate,
+            score_lead=score_lead,
+            candidates=[
+                CandidateMove(
+                    x=m.x, y=m.y, visits=0, prior=prior,
+                    winrate=winrate, score_lead=score_lead,
+                    pv=[PVPoint(x=m.x, y=m.y)],
+                )
+                for m in chosen
+            ],
+            ownership=ownership,
+        )
+
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=f"Invalid board state: {str(e)}")
+    except Exception as e:
+        raise HTTPException(status_code=500, detail=f"Internal error: {str(e)}")
+
+
 # ==================== 调试端点 ====================
 
 @app.post("/v1/debug/legal-moves")
@@ -143,8 +285,7 @@ async def get_legal_moves(request: MoveRequest):
     获取所有合法落子位置（调试用）
     """
     try:
-        game = Game()
-        game.board = Board.from_list(request.board)
+        game = load_game(request.board_size, request.board)
         game.next_player = Player(request.next_player)
         
         for hash_str in request.history:
@@ -157,6 +298,8 @@ async def get_legal_moves(request: MoveRequest):
             "moves": [{"x": m.x, "y": m.y} for m in legal_moves]
         }
         
+    except ValueError as e:
+        raise HTTPException(status_code=400, detail=f"Invalid board state: {str(e)}")
     except Exception as e:
         raise HTTPException(status_code=500, detail=str(e))
 
@@ -11,9 +11,12 @@ from dataclasses import dataclass
 import hashlib
 
 
-# 棋盘大小
+# 默认棋盘大小
 BOARD_SIZE = 19
 
+# 支持的棋盘大小，与 Go 后端的 SupportedBoardSizes 一致
+SUPPORTED_BOARD_SIZES = (9, 13, 19)
+
 
 class Player(IntEnum):
     """玩家/棋子类型"""
@@ -61,15 +64,23 @@ class Board:
     """
     围棋棋盘
     
-    使用 19x19 的二维数组表示棋盘状态。
-    坐标系：左上角为 (0, 0)，右下角为 (18, 18)
+    使用 size x size 的二维数组表示棋盘状态。
+    坐标系：左上角为 (0, 0)，右下角为 (size-1, size-1)
     """
 
-    def __init__(self):
-        """创建一个空棋盘"""
+    def __init__(self, size: int = BOARD_SIZE):
+        """
+        创建一个空棋盘
+
+        Raises:
+            ValueError: 棋盘大小不受支持
+        """
+        if size not in SUPPORTED_BOARD_SIZES:
+            raise ValueError(f"board size must be one of {SUPPORTED_BOARD_SIZES}, got {size}")
+        self.size = size
         self.grid: List[List[Player]] = [
-            [Player.EMPTY for _ in range(BOARD_SIZE)]
-            for _ in range(BOARD_SIZE)
+            [Player.EMPTY for _ in range(size)]
+            for _ in range(size)
         ]
 
     def place_stone(self, player: Player, point: Point) -> int:
@@ -124,7 +135,7 @@ class Board:
 
     def _is_on_board(self, point: Point) -> bool:
         """检查坐标是否在棋盘范围内"""
-        return 0 <= point.x < BOARD_SIZE and 0 <= point.y < BOARD_SIZE
+        return 0 <= point.x < self.size and 0 <= point.y < self.size
 
     def _get_neighbors(self, point: Point) -> List[Point]:
         """获取一个点的所有合法邻居（上下左右）"""
@@ -133,7 +144,7 @@ class Board:
         
         for dx, dy in directions:
             x, y = point.x + dx, point.y + dy
-            if 0 <= x < BOARD_SIZE and 0 <= y < BOARD_SIZE:
+            if 0 <= x < self.size and 0 <= y < self.size:
                 neighbors.append(Point(x, y))
         
         return neighbors
@@ -186,37 +197,45 @@ class Board:
         """
         state_str = ''.join(
             str(int(self.grid[i][j]))
-            for i in range(BOARD_SIZE)
-            for j in range(BOARD_SIZE)
+            for i in range(self.size)
+            for j in range(self.size)
         )
         return hashlib.md5(state_str.encode()).hexdigest()
 
     def clone(self) -> 'Board':
         """创建棋盘的深拷贝"""
-        new_board = Board()
+        new_board = Board(self.size)
         new_board.grid = [row[:] for row in self.grid]
         return new_board
 
     def to_list(self) -> List[List[int]]:
         """将棋盘转换为二维列表（用于 JSON 序列化）"""
-        return [[int(self.grid[i][j]) for j in range(BOARD_SIZE)] for i in range(BOARD_SIZE)]
+        return [[int(self.grid[i][j]) for j in range(self.size)] for i in range(self.size)]
 
     @classmethod
     def from_list(cls, grid_list: List[List[int]]) -> 'Board':
-        """从二维列表创建棋盘"""
-        board = cls()
-        for i in range(BOARD_SIZE):
-            for j in range(BOARD_SIZE):
+        """
+        从二维列表创建棋盘，棋盘大小由列表的行数决定
+
+        Raises:
+            ValueError: 棋盘大小不受支持或不是正方形
+        """
+        size = len(grid_list)
+        board = cls(size)
+        for i in range(size):
+            if len(grid_list[i]) != size:
+                raise ValueError(f"row {i} has {len(grid_list[i])} points, expected {size}")
+            for j in range(size):
                 board.grid[i][j] = Player(grid_list[i][j])
         return board
 
     def __str__(self) -> str:
         """返回棋盘的字符串表示（用于调试）"""
-        lines = ["   " + " ".join(f"{i:2d}" for i in range(BOARD_SIZE))]
+        lines = ["   " + " ".join(f"{i:2d}" for i in range(self.size))]
         
-        for i in range(BOARD_SIZE):
+        for i in range(self.size):
             row = f"{i:2d} "
-            for j in range(BOARD_SIZE):
+            for j in range(self.size):
                 if self.grid[i][j] == Player.EMPTY:
                     row += " . "
                 elif self.grid[i][j] == Player.BLACK:
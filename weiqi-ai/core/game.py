@@ -35,9 +35,9 @@ class Game:
     - 提子统计
     """
 
-    def __init__(self):
+    def __init__(self, size: int = BOARD_SIZE):
         """创建新游戏"""
-        self.board = Board()
+        self.board = Board(size)
         self.history: Dict[str, bool] = {}
         self.next_player = Player.BLACK
         self.passes = 0
@@ -128,19 +128,20 @@ class Game:
         black_territory = 0
         white_territory = 0
 
-        visited = [[False] * BOARD_SIZE for _ in range(BOARD_SIZE)]
+        size = self.board.size
+        visited = [[False] * size for _ in range(size)]
 
         # 1. 计算双方棋子数
-        for i in range(BOARD_SIZE):
-            for j in range(BOARD_SIZE):
+        for i in range(size):
+            for j in range(size):
                 if self.board.grid[i][j] == Player.BLACK:
                     black_stones += 1
                 elif self.board.grid[i][j] == Player.WHITE:
                     white_stones += 1
 
         # 2. 使用 BFS 计算领地
-        for i in range(BOARD_SIZE):
-            for j in range(BOARD_SIZE):
+        for i in range(size):
+            for j in range(size):
                 if self.board.grid[i][j] == Player.EMPTY and not visited[i][j]:
                     queue = [Point(i, j)]
                     visited[i][j] = True
@@ -173,8 +174,8 @@ class Game:
         black_score = float(black_stones + black_territory)
         white_score = float(white_stones + white_territory) + 3.75  # 贴子
 
-        # 4. 判断胜负（黑方需要 > 184.25 才能赢）
-        winner = Player.BLACK if black_score > 184.25 else Player.WHITE
+        # 4. 判断胜负（黑方需要超过半盘加 3.75 子才能赢，19 路为 184.25）
+        winner = Player.BLACK if black_score > size * size / 2 + 3.75 else Player.WHITE
 
         return ScoreResult(
             black_score=black_score,
@@ -191,8 +192,8 @@ class Game:
         """
         legal_moves = []
         
-        for i in range(BOARD_SIZE):
-            for j in range(BOARD_SIZE):
+        for i in range(self.board.size):
+            for j in range(self.board.size):
                 point = Point(i, j)
                 
                 # 跳过已有棋子的位置
@@ -228,7 +229,7 @@ class Game:
     @classmethod
     def from_dict(cls, data: dict) -> 'Game':
         """从字典创建游戏状态"""
-        game = cls()
+        game = cls(len(data["board"]))
         game.board = Board.from_list(data["board"])
         game.next_player = Player(data["next_player"])
         game.passes = data["passes"]
@@ -164,3 +164,31 @@ def test_clone():
     assert board1.grid[4][4] == Player.EMPTY
     assert board2.grid[4][4] == Player.WHITE
 
+
+
+def test_small_board():
+    """测试 9 路和 13 路棋盘：边界和提子按棋盘大小计算"""
+    for size in (9, 13):
+        board = Board(size)
+        assert len(board.grid) == size
+        with pytest.raises(PointOutOfBoundsError):
+            board.place_stone(Player.BLACK, Point(size, 0))
+
+        corner = size - 1
+        board.place_stone(Player.WHITE, Point(corner, corner))
+        board.place_stone(Player.BLACK, Point(corner - 1, corner))
+        assert board.place_stone(Player.BLACK, Point(corner, corner - 1)) == 1
+
+        restored = Board.from_list(board.to_list())
+        assert restored.size == size
+        assert restored.to_list() == board.to_list()
+
+
+def test_invalid_board_size():
+    """测试不支持的棋盘大小和不是正方形的棋盘"""
+    with pytest.raises(ValueError):
+        Board(7)
+    with pytest.raises(ValueError):
+        Board.from_list([[0] * 9 for _ in range(7)])
+    with pytest.raises(ValueError):
+        Board.from_list([[0] * 8 for _ in range(9)])
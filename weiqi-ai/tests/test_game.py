@@ -202,3 +202,18 @@ def test_get_legal_moves():
     legal_moves = game.get_legal_moves()
     assert len(legal_moves) == 360
 
+
+
+def test_calculate_score_small_board():
+    """测试 9 路棋盘的计分：黑方需要超过半盘加 3.75 子"""
+    game = Game(9)
+    for i in range(9):
+        game.board.grid[i][4] = Player.BLACK
+        game.board.grid[i][5] = Player.WHITE
+    game.game_over = True
+
+    result = game.calculate_score()
+    # 黑方 9 子 36 目共 45 子，超过 9 路的 44.25 子
+    assert result.black_score == 45.0
+    assert result.white_score == 36.0 + 3.75
+    assert result.winner == Player.BLACK